@@ -0,0 +1,132 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// Aggregate summarises the points held within a node and its children.
+type Aggregate struct {
+	// Count is the number of points
+	Count int
+	// Weight is the sum of the point weights
+	Weight float64
+	// X and Y are the weighted centroid of the points
+	X, Y float64
+	// Min and Max are the extremes of the aggregated value
+	Min, Max float64
+}
+
+// Cell is the aggregate of the points within a node's boundary.
+type Cell struct {
+	Boundary *AABB
+	Depth    int
+	Aggregate
+}
+
+// touch marks a node and its ancestors as needing their aggregates
+// recomputed. A dirty node always has dirty ancestors so the walk stops
+// at the first one found.
+func (qt *QuadTree) touch() {
+	if !qt.state.aggregates {
+		return
+	}
+
+	for n := qt; n != nil && !n.dirty; n = n.parent {
+		n.dirty = true
+	}
+}
+
+func (a *Aggregate) merge(b *Aggregate) {
+	if b.Count == 0 {
+		return
+	}
+
+	if a.Count == 0 {
+		*a = *b
+		return
+	}
+
+	w := a.Weight + b.Weight
+	if w != 0 {
+		a.X = (a.X*a.Weight + b.X*b.Weight) / w
+		a.Y = (a.Y*a.Weight + b.Y*b.Weight) / w
+	}
+
+	a.Count += b.Count
+	a.Weight = w
+	a.Min = math.Min(a.Min, b.Min)
+	a.Max = math.Max(a.Max, b.Max)
+}
+
+func (qt *QuadTree) aggregate() *Aggregate {
+	if qt.agg != nil && !qt.dirty {
+		return qt.agg
+	}
+
+	agg := &Aggregate{}
+
+	for _, p := range qt.leaf() {
+		pa := &Aggregate{Count: 1, Weight: 1, X: p.x, Y: p.y}
+		if qt.state.weight != nil {
+			pa.Weight = qt.state.weight(p)
+		}
+		if qt.state.value != nil {
+			pa.Min = qt.state.value(p)
+			pa.Max = pa.Min
+		}
+		agg.merge(pa)
+	}
+
+	if qt.nodes[0] != nil {
+		for _, node := range qt.nodes {
+			agg.merge(node.aggregate())
+		}
+	}
+
+	// nodes shared with snapshots are read concurrently so left as they
+	// are
+	if qt.state.readOnly || !qt.owned() {
+		return agg
+	}
+	qt.agg = agg
+	qt.dirty = false
+
+	return agg
+}
+
+// Aggregate returns the aggregate of all the points within the node. The
+// tree must be created using WithAggregates.
+func (qt *QuadTree) Aggregate() Aggregate {
+	if !qt.state.aggregates {
+		return Aggregate{}
+	}
+	return *qt.aggregate()
+}
+
+// Tiles returns the aggregates of the non empty nodes at the zoom depth
+// provided, or of leaves above it. Only nodes which changed since the last
+// read are recomputed so zoomed out views cost one cell per visible node
+// rather than one per point. The tree must be created using
+// WithAggregates.
+func (qt *QuadTree) Tiles(zoom int) []Cell {
+	results := []Cell{}
+
+	if !qt.state.aggregates {
+		return results
+	}
+
+	agg := qt.aggregate()
+	if agg.Count == 0 {
+		return results
+	}
+
+	if qt.depth >= zoom || qt.nodes[0] == nil {
+		return append(results, Cell{qt.boundary, qt.depth, *agg})
+	}
+
+	for _, node := range qt.nodes {
+		results = append(results, node.Tiles(zoom)...)
+	}
+
+	return results
+}
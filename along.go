@@ -0,0 +1,220 @@
+package quadtree
+
+import (
+	"math"
+	"slices"
+)
+
+// SearchAlong returns the points within a corridor of the width in metres
+// either side of a path, given by its vertices in order, e.g. the
+// charging stations within 500m of a route. Nodes further than the width
+// from every segment of the path are pruned, and each segment is only
+// tested against the nodes near it. Distances are measured in a plane
+// tangent to the Earth at the midpoint of each segment, metre accurate
+// for segments up to a few tens of kilometres, or in the units of the
+// coordinates of a tree created using WithCoordinateSystem(Cartesian).
+// Each point is returned once, in the order the nodes are visited.
+func (qt *QuadTree) SearchAlong(path []*Point, widthMeters float64, opts ...QueryOption) []*Point {
+	results := []*Point{}
+
+	if len(path) == 0 || widthMeters < 0 || slices.Contains(path, nil) {
+		return results
+	}
+
+	c := qt.corridor(path)
+	qt.along(c, c.segments, widthMeters, qt.newQuery(opts), &results)
+	return results
+}
+
+// corridor is a path with each segment projected onto its own plane.
+type corridor struct {
+	segments []segment
+	// returns the geographic location of a point of the tree, or the
+	// point itself for planar trees
+	geo func(p *Point) *Point
+}
+
+// segment is a segment of a path projected onto a plane tangent at its
+// midpoint, x along the meridian and y along the parallel, in metres.
+type segment struct {
+	// origin of the plane and the metres per unit of x and y
+	x0, y0 float64
+	kx, ky float64
+	// longitude wraps around the antimeridian
+	wrap bool
+	// ends of the segment in the plane
+	a, b [2]float64
+}
+
+func (qt *QuadTree) corridor(path []*Point) *corridor {
+	c := &corridor{geo: func(p *Point) *Point { return p }}
+
+	cs := qt.state.coordinates()
+	planar := cs == Cartesian
+	if m, ok := cs.(mercator); ok {
+		c.geo = m.geo
+	}
+
+	ends := path
+	if len(path) == 1 {
+		ends = []*Point{path[0], path[0]}
+	}
+
+	for i := 0; i+1 < len(ends); i++ {
+		a, b := c.geo(ends[i]), c.geo(ends[i+1])
+
+		s := segment{kx: 1, ky: 1}
+		if planar {
+			s.x0, s.y0 = (a.x+b.x)/2, (a.y+b.y)/2
+		} else {
+			// the midpoint the short way around the antimeridian
+			s.x0, s.y0 = (a.x+b.x)/2, a.y+wrapLng(b.y-a.y)/2
+			s.kx = deg2Rad(1) * meanRadius
+			s.ky = s.kx * math.Cos(deg2Rad(s.x0))
+			s.wrap = true
+		}
+		s.a = s.project(a)
+		s.b = s.project(b)
+
+		c.segments = append(c.segments, s)
+	}
+
+	return c
+}
+
+// wrapLng returns the difference in longitude within [-180, 180).
+func wrapLng(d float64) float64 {
+	return math.Mod(d+540, 360) - 180
+}
+
+func (s *segment) project(p *Point) [2]float64 {
+	dy := p.y - s.y0
+	if s.wrap {
+		dy = wrapLng(dy)
+	}
+	return [2]float64{(p.x - s.x0) * s.kx, dy * s.ky}
+}
+
+// dist returns the distance from the segment to a point in the plane.
+func (s *segment) dist(p [2]float64) float64 {
+	dx, dy := s.b[0]-s.a[0], s.b[1]-s.a[1]
+
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, ((p[0]-s.a[0])*dx+(p[1]-s.a[1])*dy)/l))
+	}
+
+	return math.Hypot(p[0]-s.a[0]-t*dx, p[1]-s.a[1]-t*dy)
+}
+
+// boxDist returns the distance from the segment to the box with the
+// corners lo and hi. A box spanning the plane's antimeridian is measured
+// a whole turn either side too.
+func (s *segment) boxDist(lo, hi *Point) float64 {
+	x0, x1 := (lo.x-s.x0)*s.kx, (hi.x-s.x0)*s.kx
+
+	turns := []float64{0}
+	if s.wrap {
+		turns = []float64{-360, 0, 360}
+	}
+
+	d := math.Inf(1)
+	for _, turn := range turns {
+		y0, y1 := (lo.y-s.y0+turn)*s.ky, (hi.y-s.y0+turn)*s.ky
+		d = math.Min(d, s.rectDist(x0, x1, y0, y1))
+	}
+	return d
+}
+
+// rectDist returns the distance from the segment to the rectangle
+// [x0, x1] by [y0, y1] in the plane, 0 if they intersect.
+func (s *segment) rectDist(x0, x1, y0, y1 float64) float64 {
+	if s.clips(x0, x1, y0, y1) {
+		return 0
+	}
+
+	// apart, the nearest points are an end of the segment or a corner
+	d := math.Min(rectPointDist(s.a, x0, x1, y0, y1), rectPointDist(s.b, x0, x1, y0, y1))
+	for _, c := range [4][2]float64{{x0, y0}, {x0, y1}, {x1, y0}, {x1, y1}} {
+		d = math.Min(d, s.dist(c))
+	}
+	return d
+}
+
+// clips reports whether the segment intersects the rectangle, clipping
+// it to each edge in turn.
+func (s *segment) clips(x0, x1, y0, y1 float64) bool {
+	t0, t1 := 0.0, 1.0
+	dx, dy := s.b[0]-s.a[0], s.b[1]-s.a[1]
+
+	edges := [4][2]float64{
+		{-dx, s.a[0] - x0},
+		{dx, x1 - s.a[0]},
+		{-dy, s.a[1] - y0},
+		{dy, y1 - s.a[1]},
+	}
+	for _, e := range edges {
+		p, q := e[0], e[1]
+		if p == 0 {
+			if q < 0 {
+				return false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			t0 = math.Max(t0, t)
+		} else {
+			t1 = math.Min(t1, t)
+		}
+		if t0 > t1 {
+			return false
+		}
+	}
+	return true
+}
+
+func rectPointDist(p [2]float64, x0, x1, y0, y1 float64) float64 {
+	dx := math.Max(math.Max(x0-p[0], 0), p[0]-x1)
+	dy := math.Max(math.Max(y0-p[1], 0), p[1]-y1)
+	return math.Hypot(dx, dy)
+}
+
+func (qt *QuadTree) along(c *corridor, segments []segment, width float64, q *query, results *[]*Point) {
+	b := qt.boundary
+	lo := c.geo(&Point{x: b.center.x - b.half.x, y: b.center.y - b.half.y})
+	hi := c.geo(&Point{x: b.center.x + b.half.x, y: b.center.y + b.half.y})
+
+	var near []segment
+	for _, s := range segments {
+		if s.boxDist(lo, hi) <= width {
+			near = append(near, s)
+		}
+	}
+	if len(near) == 0 || q.excludes(b) {
+		return
+	}
+
+	qt.hit()
+
+	for _, p := range qt.leaf() {
+		if !q.match(p) {
+			continue
+		}
+		g := c.geo(p)
+		for _, s := range near {
+			if s.dist(s.project(g)) <= width {
+				*results = append(*results, p)
+				break
+			}
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.along(c, near, width, q, results)
+	}
+}
@@ -0,0 +1,56 @@
+package quadtree
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// AnonymousCell is the aggregate of a cell exported by ExportAggregates,
+// holding no coordinates or values of individual points.
+type AnonymousCell struct {
+	MinLat float64 `json:"minLat"`
+	MinLng float64 `json:"minLng"`
+	MaxLat float64 `json:"maxLat"`
+	MaxLng float64 `json:"maxLng"`
+	Count  int     `json:"count"`
+	Weight float64 `json:"weight"`
+}
+
+// AnonymousCells returns the aggregates of the cells at the zoom depth, as
+// returned by Tiles, holding at least minCount points. Cells with fewer
+// points are suppressed, and centroids and value extremes, which may
+// reveal individual points, are left out. The tree must be created using
+// WithAggregates.
+func (qt *QuadTree) AnonymousCells(zoom, minCount int) ([]AnonymousCell, error) {
+	if !qt.state.aggregates {
+		return nil, ErrNoAggregates
+	}
+
+	cells := []AnonymousCell{}
+	for _, c := range qt.Tiles(zoom) {
+		if c.Count < max(minCount, 1) {
+			continue
+		}
+		b := c.Boundary
+		cells = append(cells, AnonymousCell{
+			MinLat: b.center.x - b.half.x,
+			MinLng: b.center.y - b.half.y,
+			MaxLat: b.center.x + b.half.x,
+			MaxLng: b.center.y + b.half.y,
+			Count:  c.Count,
+			Weight: c.Weight,
+		})
+	}
+
+	return cells, nil
+}
+
+// ExportAggregates writes the cells of AnonymousCells as a JSON array, an
+// aggregate only snapshot which can be shared without the raw points.
+func (qt *QuadTree) ExportAggregates(w io.Writer, zoom, minCount int) error {
+	cells, err := qt.AnonymousCells(zoom, minCount)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(cells)
+}
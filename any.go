@@ -0,0 +1,31 @@
+package quadtree
+
+// Any returns the first point found within the bounding box which passes
+// the filter, if not nil, stopping as soon as it is found. Which point is
+// found first is not specified, so Any suits questions such as whether
+// any obstacle is within a cell rather than which is nearest.
+func (qt *QuadTree) Any(a *AABB, fn filter, opts ...QueryOption) (*Point, bool) {
+	if noBox(a) {
+		return nil, false
+	}
+
+	var found *Point
+
+	qt.visit(a, qt.newQuery(opts), func(p *Point) bool {
+		if fn != nil && !fn(p) {
+			return true
+		}
+		found = p
+		return false
+	})
+
+	return found, found != nil
+}
+
+// ContainsAny reports whether any point lies within the bounding box,
+// stopping at the first found. Unlike Contains it tests a region rather
+// than a point's membership of the tree.
+func (qt *QuadTree) ContainsAny(a *AABB, opts ...QueryOption) bool {
+	_, ok := qt.Any(a, nil, opts...)
+	return ok
+}
@@ -0,0 +1,51 @@
+package quadtree
+
+// arenaSlab is the number of nodes, and of boundaries, a tree created
+// using WithArena allocates at a time.
+const arenaSlab = 256
+
+// WithArena allocates the nodes of the tree and their boundaries in slabs
+// rather than one at a time, and reuses the nodes removed by Compact and
+// WithAutoCompact as well as those removed by Clear, so a large tree
+// leaves far fewer objects for the garbage collector to trace. A slab is
+// only freed once none of its nodes are in use, so memory is not returned
+// as the tree shrinks. A LeafView must not be kept across a Compact.
+func WithArena() Option {
+	return func(s *state) {
+		s.arena = &arena{}
+	}
+}
+
+// arena hands out nodes and boundaries from the unused end of slabs.
+type arena struct {
+	nodes  []QuadTree
+	boxes  []AABB
+	points []Point
+}
+
+func (a *arena) node() *QuadTree {
+	if len(a.nodes) == 0 {
+		a.nodes = make([]QuadTree, arenaSlab)
+	}
+
+	n := &a.nodes[0]
+	a.nodes = a.nodes[1:]
+	return n
+}
+
+// box returns the bounding box with the center and half extents.
+func (a *arena) box(cx, cy, hx, hy float64) *AABB {
+	if len(a.boxes) == 0 {
+		a.boxes = make([]AABB, arenaSlab)
+		a.points = make([]Point, 2*arenaSlab)
+	}
+
+	b := &a.boxes[0]
+	a.boxes = a.boxes[1:]
+	b.center, b.half = &a.points[0], &a.points[1]
+	a.points = a.points[2:]
+
+	b.center.x, b.center.y = cx, cy
+	b.half.x, b.half.y = hx, hy
+	return b
+}
@@ -0,0 +1,81 @@
+package quadtree
+
+import (
+	"math"
+	"strings"
+)
+
+// asciiRamp maps relative density to characters, from empty to densest.
+const asciiRamp = " .:-=+*#%@"
+
+// WithNodeBoundaries draws the boundaries of leaf nodes in renderings.
+func WithNodeBoundaries() QueryOption {
+	return func(q *query) {
+		q.boundaries = true
+	}
+}
+
+// RenderASCII draws the density of points within the axis aligned bounding
+// box as a character grid of cols by rows cells. Rows run along the x axis
+// with x increasing upwards, like latitude, and columns along the y axis.
+// Using WithNodeBoundaries the edges of leaf nodes are drawn in empty cells.
+func (qt *QuadTree) RenderASCII(a *AABB, cols, rows int, opts ...QueryOption) string {
+	if noBox(a) || cols <= 0 || rows <= 0 {
+		return ""
+	}
+
+	q := qt.newQuery(opts)
+	counts := make([]int, cols*rows)
+	grid := make([]byte, cols*rows)
+	for i := range grid {
+		grid[i] = ' '
+	}
+
+	minX, minY := a.center.x-a.half.x, a.center.y-a.half.y
+	cw, ch := 2*a.half.y/float64(cols), 2*a.half.x/float64(rows)
+
+	cell := func(x, y float64) (int, int) {
+		c := int(math.Floor((y - minY) / cw))
+		r := rows - 1 - int(math.Floor((x-minX)/ch))
+		return min(max(c, 0), cols-1), min(max(r, 0), rows-1)
+	}
+
+	if q.boundaries {
+		for leaf := range qt.LeavesIntersecting(a) {
+			b := leaf.Boundary()
+			c0, r1 := cell(b.center.x-b.half.x, b.center.y-b.half.y)
+			c1, r0 := cell(b.center.x+b.half.x, b.center.y+b.half.y)
+			for c := c0; c <= c1; c++ {
+				grid[r0*cols+c], grid[r1*cols+c] = '-', '-'
+			}
+			for r := r0; r <= r1; r++ {
+				grid[r*cols+c0], grid[r*cols+c1] = '|', '|'
+			}
+			for _, i := range []int{r0*cols + c0, r0*cols + c1, r1*cols + c0, r1*cols + c1} {
+				grid[i] = '+'
+			}
+		}
+	}
+
+	var peak int
+	for _, p := range qt.search(a, q) {
+		c, r := cell(p.x, p.y)
+		counts[r*cols+c]++
+		peak = max(peak, counts[r*cols+c])
+	}
+
+	var sb strings.Builder
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			i := r*cols + c
+			if n := counts[i]; n > 0 {
+				level := 1 + (n-1)*(len(asciiRamp)-2)/max(peak-1, 1)
+				grid[i] = asciiRamp[level]
+			}
+		}
+		sb.Write(grid[r*cols : (r+1)*cols])
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,85 @@
+package quadtree
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// AssignNearest assigns each source to its nearest point in the tree, e.g.
+// users to their nearest depot. Sources are processed in spatial order and
+// the point assigned to the previous source bounds the search for the
+// next, so neighbouring sources share most of the pruning work. Sources
+// are absent from the result if the tree is empty, as are nil sources.
+func (qt *QuadTree) AssignNearest(sources []*Point) map[*Point]*Point {
+	results := make(map[*Point]*Point, len(sources))
+
+	order := make([]*Point, 0, len(sources))
+	for _, s := range sources {
+		if s != nil {
+			order = append(order, s)
+		}
+	}
+
+	codes := make(map[*Point]uint64, len(order))
+	for _, s := range order {
+		codes[s] = morton(qt.boundary, s)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return codes[order[i]] < codes[order[j]]
+	})
+
+	q := qt.newQuery(nil)
+
+	var prev *Point
+	for _, s := range order {
+		best, bound := prev, math.Inf(1)
+		if prev != nil {
+			bound = planar(s, prev)
+		}
+
+		if p := qt.nearestWithin(s, bound, q); p != nil {
+			best = p
+		}
+
+		if best != nil {
+			results[s] = best
+			prev = best
+		}
+	}
+
+	return results
+}
+
+// nearestWithin returns the point nearest to p closer than the squared
+// planar bound, or nil if there is none.
+func (qt *QuadTree) nearestWithin(p *Point, bound float64, q *query) *Point {
+	var best *Point
+
+	queue := &candidates{{node: qt, dist: qt.boundary.minDist(p)}}
+
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(candidate)
+		if c.dist >= bound {
+			break
+		}
+
+		for _, ep := range c.node.leaf() {
+			if d := planar(ep, p); d < bound && q.match(ep) {
+				best, bound = ep, d
+			}
+		}
+
+		if c.node.nodes[0] == nil {
+			continue
+		}
+
+		for _, node := range c.node.nodes {
+			if d := node.boundary.minDist(p); d < bound {
+				heap.Push(queue, candidate{node: node, dist: d})
+			}
+		}
+	}
+
+	return best
+}
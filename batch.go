@@ -0,0 +1,205 @@
+package quadtree
+
+import (
+	"sort"
+)
+
+// InsertFailure describes a point which could not be inserted by
+// InsertBatch.
+type InsertFailure struct {
+	// Index of the point within the batch
+	Index int
+	Point *Point
+	Err   error
+}
+
+// morton returns the Z-order curve code of a point within the boundary,
+// so points close in space sort close together.
+func morton(b *AABB, p *Point) uint64 {
+	scale := func(v, min, size float64) uint64 {
+		if size <= 0 {
+			return 0
+		}
+		f := (v - min) / size
+		if f < 0 {
+			f = 0
+		} else if f > 1 {
+			f = 1
+		}
+		return uint64(f * 0xffffffff)
+	}
+
+	x := scale(p.x, b.center.x-b.half.x, 2*b.half.x)
+	y := scale(p.y, b.center.y-b.half.y, 2*b.half.y)
+
+	var code uint64
+	for i := 0; i < 32; i++ {
+		code |= (x>>i&1)<<(2*i+1) | (y>>i&1)<<(2*i)
+	}
+	return code
+}
+
+// InsertBatch inserts many points in one call. The points are inserted in
+// spatial order so consecutive inserts descend the same path of the tree,
+// and each point which fails is reported with the reason.
+func (qt *QuadTree) InsertBatch(points []*Point) (inserted int, failures []InsertFailure) {
+	for i, p := range points {
+		if p == nil {
+			failures = append(failures, InsertFailure{i, p, ErrNilPoint})
+		}
+	}
+
+	for _, i := range qt.spatial(len(points), func(i int) *Point { return points[i] }) {
+		if err := qt.add(points[i]); err != nil {
+			failures = append(failures, InsertFailure{i, points[i], err})
+			continue
+		}
+		inserted++
+	}
+
+	sort.Slice(failures, func(a, b int) bool {
+		return failures[a].Index < failures[b].Index
+	})
+
+	return inserted, failures
+}
+
+// Move is an update of a point to a new location.
+type Move struct {
+	Point *Point
+	To    *Point
+}
+
+// spatial returns the indices of the points in Z-order, skipping nil
+// points.
+func (qt *QuadTree) spatial(n int, point func(int) *Point) []int {
+	order := make([]int, 0, n)
+	codes := make([]uint64, n)
+
+	for i := 0; i < n; i++ {
+		if p := point(i); p != nil {
+			codes[i] = morton(qt.boundary, p)
+			order = append(order, i)
+		}
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return codes[order[a]] < codes[order[b]]
+	})
+
+	return order
+}
+
+// near returns the leaf holding the location of p, to be written,
+// searching up from the finger node of the previous operation rather than
+// down from the root.
+func (qt *QuadTree) near(finger *QuadTree, p *Point) *QuadTree {
+	n := finger
+	for n != qt && !n.boundary.ContainsPoint(p) {
+		n = n.parent
+	}
+
+	for n.nodes[0] != nil {
+		var next *QuadTree
+		for i, node := range n.nodes {
+			if node.boundary.ContainsPoint(p) {
+				next = n.own(i)
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		n = next
+	}
+
+	return n
+}
+
+// InsertAll inserts many points, returning the error of each point by
+// index, nil for those inserted. Points are inserted in spatial order,
+// each starting from the leaf of the previous one, so neighbouring
+// inserts share the traversal of the tree.
+func (qt *QuadTree) InsertAll(points []*Point) []error {
+	errs := make([]error, len(points))
+	finger := qt
+
+	for i, p := range points {
+		if p == nil {
+			errs[i] = ErrNilPoint
+		}
+	}
+
+	for _, i := range qt.spatial(len(points), func(i int) *Point { return points[i] }) {
+		p := points[i]
+		start := qt
+		if qt.boundary.ContainsPoint(p) {
+			start = qt.near(finger, p)
+		}
+		if errs[i] = qt.addVia(p, start.rinsert); errs[i] == nil {
+			finger = start
+		}
+	}
+
+	return errs
+}
+
+// RemoveAll removes many points, returning whether each was removed by
+// index. Points are removed in spatial order, each starting from the leaf
+// of the previous one.
+func (qt *QuadTree) RemoveAll(points []*Point) []bool {
+	ok := make([]bool, len(points))
+	finger := qt
+
+	for _, i := range qt.spatial(len(points), func(i int) *Point { return points[i] }) {
+		p := qt.state.current(points[i])
+		if qt.state.locked(p) || !qt.state.allowWrite() {
+			continue
+		}
+
+		// collapsing subtrees may detach the previous leaf
+		if qt.state.autoCompact || !qt.boundary.ContainsPoint(p) {
+			finger = qt
+		}
+		finger = qt.near(finger, p)
+
+		if !finger.remove(p) && !qt.remove(p) {
+			continue
+		}
+		qt.dropped(p)
+		ok[i] = true
+	}
+
+	return ok
+}
+
+// UpdateAll moves many points, returning whether each was moved by index.
+// Moves are made in spatial order of the points' current locations, each
+// starting from the leaf of the previous one.
+func (qt *QuadTree) UpdateAll(moves []Move) []bool {
+	ok := make([]bool, len(moves))
+	finger := qt
+
+	point := func(i int) *Point {
+		if moves[i].To == nil {
+			return nil
+		}
+		return moves[i].Point
+	}
+
+	for _, i := range qt.spatial(len(moves), point) {
+		p, np := qt.state.current(moves[i].Point), moves[i].To
+		if qt.state.locked(p, np) || !qt.state.allowWrite() {
+			continue
+		}
+
+		if !qt.boundary.ContainsPoint(p) {
+			finger = qt
+		}
+		finger = qt.near(finger, p)
+
+		ok[i] = finger.update(p, np) || qt.update(p, np)
+	}
+
+	return ok
+}
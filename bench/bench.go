@@ -0,0 +1,325 @@
+// Package bench provides reproducible benchmarks of a quadtree over
+// uniform, clustered and GPS trace distributions of points, and a sweep of
+// capacity and max depth reporting the best settings for a point count.
+//
+// The benchmarks are functions taking a *testing.B so they can be run from
+// a test file of any package, e.g.
+//
+//	func BenchmarkQuadTree(b *testing.B) {
+//		bench.Run(b, 100000)
+//	}
+//
+// and compared across commits with benchstat. Points and queries are
+// generated from a fixed seed so every run measures the same work.
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/asim/quadtree"
+	"github.com/asim/quadtree/testdata"
+)
+
+const (
+	// Seed seeds the generation of points and queries.
+	Seed = 1
+	// Queries is the number of distinct queries each benchmark cycles
+	// through.
+	Queries = 1024
+	// SearchRadius is the half extent in metres of the Search queries.
+	SearchRadius = 1000
+	// NearestRadius is the half extent in metres of the KNearest queries.
+	NearestRadius = 10000
+	// K is the number of points returned by the KNearest queries.
+	K = 10
+)
+
+// World is the boundary of the benchmarked trees.
+var World = quadtree.WorldBounds()
+
+// Distribution generates n points within World, each holding its index as
+// its data.
+type Distribution struct {
+	Name     string
+	Generate func(r *rand.Rand, n int) []*quadtree.Point
+}
+
+var (
+	// Uniform spreads points evenly over the world.
+	Uniform = Distribution{"uniform", uniform}
+	// Clustered gathers points normally around a few dozen city centers,
+	// as with users or stores.
+	Clustered = Distribution{"clustered", clustered}
+	// GPS lays points along simulated vehicle traces leaving city centers,
+	// each a few metres on from the last, as with recorded GPS tracks.
+	GPS = Distribution{"gps", gps}
+
+	// Distributions are those run by Run.
+	Distributions = []Distribution{Uniform, Clustered, GPS}
+)
+
+// populated is the part of the world away from the poles in which the
+// clustered and GPS distributions place their cities.
+var populated = quadtree.NewAABB(quadtree.NewPoint(0, 0, nil), quadtree.NewPoint(60, 180, nil))
+
+func uniform(r *rand.Rand, n int) []*quadtree.Point {
+	return testdata.Uniform(r, World, n)
+}
+
+func clustered(r *rand.Rand, n int) []*quadtree.Point {
+	return testdata.Clustered(r, populated, n, 32, 0.2)
+}
+
+func gps(r *rand.Rand, n int) []*quadtree.Point {
+	// fixes per trace and the distance between them in degrees, roughly
+	// 10 metres
+	return testdata.Traces(r, populated, n, 500, 1e-4)
+}
+
+// fresh returns new points at the locations of the points, for inserting
+// into another tree.
+func fresh(points []*quadtree.Point) []*quadtree.Point {
+	copies := make([]*quadtree.Point, len(points))
+	for i, p := range points {
+		x, y := p.Coordinates()
+		copies[i] = quadtree.NewPoint(x, y, p.Data())
+	}
+	return copies
+}
+
+// clamp returns x, y moved onto World if outside it.
+func clamp(x, y float64) (float64, float64) {
+	return math.Max(-90, math.Min(x, 90)), math.Max(-180, math.Min(y, 180))
+}
+
+// fixture is a tree of n points of a distribution along with the queries
+// run against it.
+type fixture struct {
+	tree   *quadtree.QuadTree
+	points []*quadtree.Point
+	boxes  []*quadtree.AABB
+	nearby []*quadtree.AABB
+	moves  [][2]float64
+}
+
+// newFixture generates n points of the distribution and queries centered on
+// further points of it, so queries fall where the points are dense. The
+// points are inserted by fill.
+func newFixture(d Distribution, n int, opts []quadtree.Option) *fixture {
+	r := rand.New(rand.NewSource(Seed))
+
+	f := &fixture{
+		tree:   quadtree.New(World, 0, nil, opts...),
+		points: d.Generate(r, max(n, 0)),
+	}
+
+	for _, c := range d.Generate(r, Queries) {
+		x, y := c.Coordinates()
+		center := quadtree.NewPoint(x, y, nil)
+		f.boxes = append(f.boxes, quadtree.NewAABB(center, center.HalfPoint(SearchRadius)))
+		f.nearby = append(f.nearby, quadtree.NewAABB(center, center.HalfPoint(NearestRadius)))
+	}
+
+	// moves of up to 10 metres or so, as between GPS fixes
+	f.moves = make([][2]float64, Queries)
+	for i := range f.moves {
+		f.moves[i] = [2]float64{r.NormFloat64() * 1e-4, r.NormFloat64() * 1e-4}
+	}
+
+	return f
+}
+
+func (f *fixture) fill() {
+	for _, p := range f.points {
+		f.tree.Insert(p)
+	}
+}
+
+func (f *fixture) search(i int) {
+	f.tree.Search(f.boxes[i%Queries])
+}
+
+func (f *fixture) knearest(i int) {
+	f.tree.KNearest(f.nearby[i%Queries], K, nil)
+}
+
+func (f *fixture) update(i int) {
+	if len(f.points) == 0 {
+		return
+	}
+
+	p := f.points[i%len(f.points)]
+	m := f.moves[i%Queries]
+	x, y := p.Coordinates()
+	x, y = clamp(x+m[0], y+m[1])
+	f.tree.Update(p, quadtree.NewPoint(x, y, nil))
+}
+
+// Benchmark is a named benchmark function.
+type Benchmark struct {
+	Name string
+	F    func(b *testing.B)
+}
+
+// Benchmarks returns the Insert, Search, KNearest and Update benchmarks of
+// a tree holding n points of the distribution, created with the options.
+func Benchmarks(d Distribution, n int, opts ...quadtree.Option) []Benchmark {
+	return []Benchmark{
+		{"Insert", func(b *testing.B) { insert(b, d, n, opts) }},
+		{"Search", func(b *testing.B) { query(b, d, n, opts, (*fixture).search) }},
+		{"KNearest", func(b *testing.B) { query(b, d, n, opts, (*fixture).knearest) }},
+		{"Update", func(b *testing.B) { query(b, d, n, opts, (*fixture).update) }},
+	}
+}
+
+// Run runs the benchmarks of each distribution as sub-benchmarks of b,
+// named by distribution and operation, e.g. "clustered/KNearest".
+func Run(b *testing.B, n int, opts ...quadtree.Option) {
+	for _, d := range Distributions {
+		b.Run(d.Name, func(b *testing.B) {
+			for _, bm := range Benchmarks(d, n, opts...) {
+				b.Run(bm.Name, bm.F)
+			}
+		})
+	}
+}
+
+// insert measures inserting points into a tree growing to n points,
+// starting again with an empty tree every n inserts.
+func insert(b *testing.B, d Distribution, n int, opts []quadtree.Option) {
+	if n <= 0 {
+		b.Skip("no points to insert")
+	}
+
+	generated := d.Generate(rand.New(rand.NewSource(Seed)), n)
+
+	var (
+		tree *quadtree.QuadTree
+		pts  []*quadtree.Point
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%n == 0 {
+			b.StopTimer()
+			tree = quadtree.New(World, 0, nil, opts...)
+			pts = fresh(generated)
+			b.StartTimer()
+		}
+		tree.Insert(pts[i%n])
+	}
+}
+
+func query(b *testing.B, d Distribution, n int, opts []quadtree.Option, fn func(*fixture, int)) {
+	f := newFixture(d, n, opts)
+	f.fill()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fn(f, i)
+	}
+}
+
+// Setting is the time per operation measured by Tune for a capacity and
+// max depth.
+type Setting struct {
+	Capacity int
+	MaxDepth int
+	Insert   time.Duration
+	Search   time.Duration
+	KNearest time.Duration
+	Update   time.Duration
+}
+
+// Total returns the sum of the times per operation.
+func (s Setting) Total() time.Duration {
+	return s.Insert + s.Search + s.KNearest + s.Update
+}
+
+var (
+	// Capacities are the capacities swept by Tune.
+	Capacities = []int{1, 2, 4, 8, 16, 32, 64, 128}
+	// MaxDepths are the max depths swept by Tune.
+	MaxDepths = []int{4, 6, 8, 10, 12, 16, 20}
+)
+
+// Tune builds a tree of n points of the distribution for each combination
+// of Capacities and MaxDepths, timing the inserts and Queries runs of each
+// query, and returns the settings fastest first by total time. Timings
+// are taken once per setting rather than to statistical significance, so
+// treat close results as ties and confirm a choice with the benchmarks.
+func Tune(d Distribution, n int) []Setting {
+	var settings []Setting
+
+	for _, c := range Capacities {
+		for _, depth := range MaxDepths {
+			settings = append(settings, measure(d, n, c, depth))
+		}
+	}
+
+	sort.SliceStable(settings, func(i, j int) bool {
+		return settings[i].Total() < settings[j].Total()
+	})
+
+	return settings
+}
+
+func measure(d Distribution, n, capacity, depth int) Setting {
+	s := Setting{Capacity: capacity, MaxDepth: depth}
+	opts := []quadtree.Option{quadtree.WithCapacity(capacity), quadtree.WithMaxDepth(depth)}
+
+	f := newFixture(d, n, opts)
+	start := time.Now()
+	f.fill()
+	s.Insert = time.Since(start) / time.Duration(max(n, 1))
+
+	timed := func(fn func(*fixture, int)) time.Duration {
+		start := time.Now()
+		for i := 0; i < Queries; i++ {
+			fn(f, i)
+		}
+		return time.Since(start) / Queries
+	}
+
+	s.Search = timed((*fixture).search)
+	s.KNearest = timed((*fixture).knearest)
+	s.Update = timed((*fixture).update)
+
+	return s
+}
+
+// Report writes the settings as a table, the first marked as best, along
+// with the options to create a tree using it.
+func Report(w io.Writer, settings []Setting) error {
+	if len(settings) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "capacity\tmax depth\tinsert\tsearch\tknearest\tupdate\ttotal\t\t")
+	for i, s := range settings {
+		mark := ""
+		if i == 0 {
+			mark = "best"
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%v\t%v\t%v\t%v\t%v\t%s\t\n",
+			s.Capacity, s.MaxDepth, s.Insert, s.Search, s.KNearest, s.Update, s.Total(), mark)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	best := settings[0]
+	_, err := fmt.Fprintf(w, "\nquadtree.New(boundary, 0, nil, quadtree.WithCapacity(%d), quadtree.WithMaxDepth(%d))\n",
+		best.Capacity, best.MaxDepth)
+	return err
+}
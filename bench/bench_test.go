@@ -0,0 +1,67 @@
+package bench
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func BenchmarkQuadTree1K(b *testing.B) {
+	Run(b, 1000)
+}
+
+func BenchmarkQuadTree10K(b *testing.B) {
+	Run(b, 10000)
+}
+
+func BenchmarkQuadTree100K(b *testing.B) {
+	Run(b, 100000)
+}
+
+func TestDistributions(t *testing.T) {
+	for _, d := range Distributions {
+		for _, p := range d.Generate(rand.New(rand.NewSource(Seed)), 0) {
+			t.Errorf("%s generated %v for no points", d.Name, p)
+		}
+		for _, p := range newFixture(d, 1000, nil).points {
+			if !World.ContainsPoint(p) {
+				x, y := p.Coordinates()
+				t.Fatalf("%s generated %v, %v outside the world", d.Name, x, y)
+			}
+		}
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	for _, d := range Distributions {
+		f := newFixture(d, 0, nil)
+		f.fill()
+		for i := 0; i < Queries; i++ {
+			f.search(i)
+			f.knearest(i)
+			// must not divide by zero on an empty tree
+			f.update(i)
+		}
+	}
+}
+
+func TestTune(t *testing.T) {
+	settings := Tune(Clustered, 100)
+	if got, want := len(settings), len(Capacities)*len(MaxDepths); got != want {
+		t.Fatalf("Tune returned %d settings, want %d", got, want)
+	}
+	for i := 1; i < len(settings); i++ {
+		if settings[i].Total() < settings[i-1].Total() {
+			t.Fatalf("setting %d is faster than setting %d", i, i-1)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Report(&buf, settings); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "best") {
+		t.Errorf("Report did not mark the best setting:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,25 @@
+package quadtree
+
+// WithMaxResultsBytes caps the approximate memory of the points returned
+// by Search at n bytes, measured as by BytesUsed including the WithSizer
+// size of their data. Search stops once the budget is reached, returning
+// the points found so far; SearchStrict also returns ErrTruncated.
+func WithMaxResultsBytes(n int64) QueryOption {
+	return func(q *query) {
+		q.maxBytes = n
+	}
+}
+
+// charge accounts for a point about to be added to the results, reporting
+// false and marking the query truncated if it exceeds the budget.
+func (q *query) charge(p *Point) bool {
+	if q.maxBytes <= 0 {
+		return true
+	}
+	if q.truncated || q.used+p.size() > q.maxBytes {
+		q.truncated = true
+		return false
+	}
+	q.used += p.size()
+	return true
+}
@@ -0,0 +1,102 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// Query composes the region, predicates, ordering and limit of a query
+// run against a tree, e.g.
+//
+//	qt.Query().Within(a).Where(fn).Limit(10).OrderByDistance(p).Run()
+type Query struct {
+	qt    *QuadTree
+	box   *AABB
+	from  *Point
+	limit int
+	opts  []QueryOption
+}
+
+// Query returns a new query against the tree, matching every point until
+// it is restricted.
+func (qt *QuadTree) Query() *Query {
+	return &Query{qt: qt}
+}
+
+// Within restricts the query to points within the bounding box.
+func (b *Query) Within(a *AABB) *Query {
+	b.box = a
+	return b
+}
+
+// Where restricts the query to points for which fn returns true. Several
+// predicates must all hold.
+func (b *Query) Where(fn func(*Point) bool) *Query {
+	b.opts = append(b.opts, Where(fn))
+	return b
+}
+
+// Limit returns at most n points, all of them if n is 0 or less.
+func (b *Query) Limit(n int) *Query {
+	b.limit = n
+	return b
+}
+
+// OrderByDistance orders the points by their distance from p, so with a
+// limit the query returns the nearest points.
+func (b *Query) OrderByDistance(p *Point) *Query {
+	b.from = p
+	return b
+}
+
+// With adds query options, e.g. WithRemoved or DistinctBy.
+func (b *Query) With(opts ...QueryOption) *Query {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Run runs the query returning the matching points.
+func (b *Query) Run() []*Point {
+	qt := b.qt
+	qt.state.acquire()
+	defer qt.state.release()
+
+	t := qt.begin()
+	q := qt.newQuery(b.opts)
+
+	a := b.box
+	if a == nil {
+		a = qt.boundary
+	}
+
+	n := b.limit
+	if n <= 0 {
+		n = math.MaxInt
+	}
+
+	var results []*Point
+	if b.from != nil {
+		results = qt.nearest(b.from, n, func(p *Point) bool {
+			return a.ContainsPoint(p) && q.match(p)
+		}, q.distinct)
+	} else {
+		limit := n
+		if q.distinct != nil {
+			// the most recently updated point of a key may be found last
+			limit = math.MaxInt
+		}
+		qt.visit(a, q, func(p *Point) bool {
+			results = append(results, p)
+			return len(results) < limit
+		})
+
+		if q.distinct != nil {
+			results = q.dedupe(results, newer)
+			if len(results) > n {
+				results = results[:n]
+			}
+		}
+	}
+
+	qt.end(t, "query", a, b.limit)
+	return results
+}
@@ -0,0 +1,103 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// NewFromPoints creates a tree holding the points, built top down by
+// partitioning the points between the children of each node rather than
+// inserting them one at a time, so no node is divided more than once. It
+// is equivalent to inserting the points in order into an empty tree, and
+// each point which could not be inserted is reported with the reason.
+func NewFromPoints(boundary *AABB, points []*Point, opts ...Option) (*QuadTree, []InsertFailure) {
+	var failures []InsertFailure
+
+	qt := New(boundary, 0, nil, opts...)
+
+	accepted := make([]*Point, 0, len(points))
+	replaced := make(map[*Point]bool)
+	// points accepted by coordinates, for duplicates within the batch
+	at := make(map[[2]float64]*Point)
+
+	for i, p := range points {
+		if p != nil && !boundary.ContainsPoint(p) {
+			failures = append(failures, InsertFailure{i, p, ErrOutOfBounds})
+			continue
+		}
+
+		old, err := qt.admit(p)
+		if err != nil {
+			failures = append(failures, InsertFailure{i, p, err})
+			continue
+		}
+
+		if qt.state.duplicates != DuplicateAllow {
+			key := [2]float64{p.x, p.y}
+			if dup, ok := at[key]; ok && !replaced[dup] {
+				if qt.state.duplicates == DuplicateReject {
+					failures = append(failures, InsertFailure{i, p, ErrDuplicatePoint})
+					continue
+				}
+				replaced[dup] = true
+				qt.dropped(dup)
+			}
+			at[key] = p
+		}
+
+		if old != nil && !replaced[old] {
+			replaced[old] = true
+			qt.dropped(old)
+		}
+
+		qt.inserted(p, nil)
+		accepted = append(accepted, p)
+	}
+
+	if len(replaced) > 0 {
+		kept := accepted[:0]
+		for _, p := range accepted {
+			if !replaced[p] {
+				kept = append(kept, p)
+			}
+		}
+		accepted = kept
+	}
+
+	qt.pack(accepted)
+	qt.trim(nil)
+	return qt, failures
+}
+
+// pack places the points beneath an empty node, dividing it only if a
+// leaf would not hold them.
+func (qt *QuadTree) pack(points []*Point) {
+	for _, p := range points {
+		qt.radius = math.Max(qt.radius, p.meta().radius)
+		qt.weight = math.Max(qt.weight, p.Weight())
+		qt.tags |= p.meta().tagBits
+	}
+
+	if !qt.splits(len(points)) {
+		for _, p := range points {
+			qt.appendPoint(p)
+		}
+		qt.touch()
+		return
+	}
+
+	qt.divide()
+
+	var parts [4][]*Point
+	for _, p := range points {
+		for i, node := range qt.nodes {
+			if node.boundary.ContainsPoint(p) {
+				parts[i] = append(parts[i], p)
+				break
+			}
+		}
+	}
+
+	for i, node := range qt.nodes {
+		node.pack(parts[i])
+	}
+}
@@ -0,0 +1,132 @@
+package quadtree
+
+import (
+	"container/list"
+	"sort"
+)
+
+// EvictionPolicy chooses the points evicted from a tree capped by
+// WithMaxPoints.
+type EvictionPolicy int
+
+const (
+	// EvictLeastRecentlyUpdated evicts the point inserted or updated
+	// longest ago.
+	EvictLeastRecentlyUpdated EvictionPolicy = iota
+	// EvictOldest evicts the point inserted longest ago, however recently
+	// it was updated.
+	EvictOldest
+)
+
+// WithMaxPoints caps the number of points in the tree at n for use as a
+// bounded spatial cache. Inserting a point into a full tree evicts the
+// point chosen by the policy, which is reported to OnEvict as well as
+// OnRemove.
+func WithMaxPoints(n int, policy EvictionPolicy) Option {
+	return func(s *state) {
+		s.capped = &capped{
+			max:    n,
+			policy: policy,
+			order:  list.New(),
+			elems:  make(map[*Point]*list.Element),
+		}
+	}
+}
+
+// OnEvict registers a function called with each point evicted from a tree
+// capped by WithMaxPoints once it has been removed.
+func (qt *QuadTree) OnEvict(fn func(p *Point)) {
+	qt.state.hooks.evict = append(qt.state.hooks.evict, fn)
+}
+
+// capped orders the points of a capped tree from the next to be evicted.
+type capped struct {
+	max    int
+	policy EvictionPolicy
+	order  *list.List
+	elems  map[*Point]*list.Element
+}
+
+func (c *capped) add(p *Point) {
+	if e, ok := c.elems[p]; ok {
+		c.order.MoveToBack(e)
+		return
+	}
+	c.elems[p] = c.order.PushBack(p)
+}
+
+func (c *capped) remove(p *Point) {
+	if e, ok := c.elems[p]; ok {
+		c.order.Remove(e)
+		delete(c.elems, p)
+	}
+}
+
+// replace orders cp where p was, for a copy replacing p in the tree.
+func (c *capped) replace(p, cp *Point) {
+	if e, ok := c.elems[p]; ok {
+		e.Value = cp
+		delete(c.elems, p)
+		c.elems[cp] = e
+	}
+}
+
+// touch records an update of p.
+func (c *capped) touch(p *Point) {
+	if e, ok := c.elems[p]; ok && c.policy == EvictLeastRecentlyUpdated {
+		c.order.MoveToBack(e)
+	}
+}
+
+// next returns the point to be evicted next, nil if there is none.
+func (c *capped) next() *Point {
+	if e := c.order.Front(); e != nil {
+		return e.Value.(*Point)
+	}
+	return nil
+}
+
+// rebuild orders the points of a restored tree by the policy.
+func (c *capped) rebuild(qt *QuadTree) *capped {
+	nc := &capped{max: c.max, policy: c.policy, order: list.New(), elems: make(map[*Point]*list.Element)}
+
+	var points []*Point
+	qt.walk(&query{removed: true}, func(p *Point) bool {
+		points = append(points, p)
+		return true
+	})
+	sort.Slice(points, func(i, j int) bool {
+		if c.policy == EvictLeastRecentlyUpdated && points[i].updated != points[j].updated {
+			return points[i].updated < points[j].updated
+		}
+		return points[i].seq < points[j].seq
+	})
+
+	for _, p := range points {
+		nc.add(p)
+	}
+	return nc
+}
+
+// trim evicts points until the tree holds no more than its cap, sparing
+// the point just inserted.
+func (qt *QuadTree) trim(keep *Point) {
+	c := qt.state.capped
+	if c == nil {
+		return
+	}
+
+	for qt.state.count > c.max {
+		p := c.next()
+		if p == nil || p == keep {
+			return
+		}
+
+		if !qt.root().remove(p) {
+			c.remove(p)
+			continue
+		}
+		qt.dropped(p)
+		qt.state.hooks.evicted(p)
+	}
+}
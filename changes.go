@@ -0,0 +1,128 @@
+package quadtree
+
+// OpType is the kind of write of an Op.
+type OpType int
+
+const (
+	// OpInsert inserts the point, replacing any with its ID
+	OpInsert OpType = iota
+	// OpRemove removes the point with its ID
+	OpRemove
+	// OpUpdate moves the point with its ID and replaces its data
+	OpUpdate
+)
+
+// Op is a write to a tree sent by Changes, replayed on a replica by Apply.
+type Op struct {
+	// Seq numbers the writes of the tree from 1 in the order they were
+	// made.
+	Seq  uint64
+	Type OpType
+	// Point is a copy of the point as inserted or updated, or as it was
+	// when removed.
+	Point *Point
+}
+
+// changeBuffer is the number of writes a change feed holds before writes
+// to the tree wait for it to be read.
+const changeBuffer = 1024
+
+type changes struct {
+	seq   uint64
+	feeds []chan Op
+}
+
+// Changes returns a feed of the writes to the tree in order, for a replica
+// or cache to stay in sync by Apply. Every insert, removal, move and
+// SetData is sent, including removals by eviction or replacement of an ID,
+// so no write is lost: once the feed is full the tree waits for it to be
+// read, and it must be drained by another goroutine. Each call returns a
+// new feed, closed by CloseChanges.
+func (qt *QuadTree) Changes() <-chan Op {
+	s := qt.state
+	if s.changes == nil {
+		c := &changes{}
+		s.changes = c
+		s.hooks.insert = append(s.hooks.insert, func(p *Point) {
+			c.send(OpInsert, p)
+		})
+		s.hooks.remove = append(s.hooks.remove, func(p *Point) {
+			c.send(OpRemove, p)
+		})
+		s.hooks.move = append(s.hooks.move, func(p *Point, from *Point) {
+			c.send(OpUpdate, p)
+		})
+	}
+
+	ch := make(chan Op, changeBuffer)
+	s.changes.feeds = append(s.changes.feeds, ch)
+	return ch
+}
+
+// CloseChanges closes every feed returned by Changes.
+func (qt *QuadTree) CloseChanges() {
+	c := qt.state.changes
+	if c == nil {
+		return
+	}
+	for _, ch := range c.feeds {
+		close(ch)
+	}
+	c.feeds = nil
+}
+
+func (c *changes) send(t OpType, p *Point) {
+	if c == nil || len(c.feeds) == 0 {
+		return
+	}
+
+	c.seq++
+	cp := p.clone()
+	for _, ch := range c.feeds {
+		ch <- Op{Seq: c.seq, Type: t, Point: cp}
+	}
+}
+
+// Apply replays a write sent by the Changes of another tree, so the tree
+// follows it as a replica when every write is applied in order. Points are
+// removed and updated by ID, so ErrNoID is returned for those without one,
+// and ErrNotFound if the tree holds no point with the ID. Inserts return
+// the error Insert would have failed with. Points are copied, and take the
+// sequence and versions of the tree they are applied to.
+func (qt *QuadTree) Apply(op Op) error {
+	if op.Point == nil {
+		return ErrNilPoint
+	}
+
+	if op.Type == OpInsert {
+		p := op.Point.clone()
+		p.seq = 0
+		if p.md != nil {
+			p.md.version = 0
+		}
+		return qt.add(p)
+	}
+
+	if op.Point.id == "" {
+		return ErrNoID
+	}
+	ep := qt.Get(op.Point.id)
+	if ep == nil {
+		return ErrNotFound
+	}
+
+	switch op.Type {
+	case OpRemove:
+		if !qt.Remove(ep) {
+			return ErrNotFound
+		}
+	case OpUpdate:
+		if ep.x != op.Point.x || ep.y != op.Point.y {
+			if !qt.Update(ep, &Point{x: op.Point.x, y: op.Point.y}) {
+				return ErrNotFound
+			}
+		}
+		return qt.SetData(ep.id, op.Point.data)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package quadtree
+
+import (
+	"sort"
+)
+
+// WriterStat is the number of updates of the point with an ID.
+type WriterStat struct {
+	ID     string
+	Writes uint64
+}
+
+// WithWriteCounters counts the updates of points by the leaf holding them
+// and by their ID, reported by TopWriters and HotWriteRegions, e.g. to
+// find devices spamming position updates and regions which need a shard
+// of their own. Counts are kept until ResetWriteCounters, including those
+// of IDs no longer in the tree.
+func WithWriteCounters() Option {
+	return func(s *state) {
+		s.writers = make(map[string]uint64)
+	}
+}
+
+func (qt *QuadTree) wrote(p *Point) {
+	if qt.state.writers == nil {
+		return
+	}
+	qt.writes++
+	if p.id != "" {
+		qt.state.writers[p.id]++
+	}
+}
+
+// TopWriters returns the n IDs updated most often, ordered by count and
+// then by ID, or every ID if n is negative. It returns none for a tree
+// created without WithWriteCounters.
+func (qt *QuadTree) TopWriters(n int) []WriterStat {
+	var stats []WriterStat
+	for id, writes := range qt.state.writers {
+		stats = append(stats, WriterStat{id, writes})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Writes != stats[j].Writes {
+			return stats[i].Writes > stats[j].Writes
+		}
+		return stats[i].ID < stats[j].ID
+	})
+
+	if n >= 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// HotWriteRegions returns the n leaves whose points were updated most
+// often since the leaf was created, ordered by count and then by the
+// points they hold, or every leaf if n is negative.
+func (qt *QuadTree) HotWriteRegions(n int) []RegionStat {
+	stats := qt.HotRegions(-1)
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Writes != stats[j].Writes {
+			return stats[i].Writes > stats[j].Writes
+		}
+		return stats[i].Points > stats[j].Points
+	})
+
+	if n >= 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// ResetWriteCounters zeroes the counts of WithWriteCounters, e.g. to
+// report the writers of each interval.
+func (qt *QuadTree) ResetWriteCounters() {
+	if qt.state.writers == nil {
+		return
+	}
+	clear(qt.state.writers)
+	qt.root().resetWrites()
+}
+
+func (qt *QuadTree) resetWrites() {
+	qt.writes = 0
+	if qt.nodes[0] != nil {
+		for i := range qt.nodes {
+			qt.own(i).resetWrites()
+		}
+	}
+}
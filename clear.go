@@ -0,0 +1,212 @@
+package quadtree
+
+// Clear removes every point and extent from the tree, keeping its
+// boundary and options. The nodes removed are kept to be reused as the
+// tree divides again, so a tree refilled every frame of a simulation
+// allocates little once it has reached its size. OnRemove hooks are
+// called for each point removed. It returns ErrReadOnly for a snapshot
+// and ErrRegionLocked while any region of the tree is locked.
+func (qt *QuadTree) Clear() error {
+	root := qt.root()
+	s := root.state
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	if len(s.locks) > 0 {
+		return ErrRegionLocked
+	}
+
+	var removed []*Point
+	if len(s.hooks.remove) > 0 || s.metrics != nil {
+		root.walk(&query{removed: true}, func(p *Point) bool {
+			removed = append(removed, p)
+			return true
+		})
+	}
+
+	root.restock(false)
+	if root.nodes[0] != nil {
+		for _, node := range root.nodes {
+			node.recycle()
+		}
+	}
+	root.reset()
+
+	clear(s.ids)
+	clear(s.tenants)
+	clear(s.fresh)
+	clear(s.forward)
+	clear(s.back)
+	s.count = 0
+	s.bytes = 0
+	s.expiring = false
+	if s.capped != nil {
+		s.capped = s.capped.rebuild(root)
+	}
+
+	for _, p := range removed {
+		if s.metrics != nil {
+			s.metrics.Removed()
+		}
+		s.hooks.removed(p)
+	}
+	return nil
+}
+
+// Reset is Clear moving the boundary of the tree, so its nodes are reused
+// for a new extent. It returns ErrInvalidAABB, leaving the tree unchanged,
+// if the boundary is invalid.
+func (qt *QuadTree) Reset(boundary *AABB) error {
+	if err := boundary.Validate(); err != nil {
+		return err
+	}
+	if err := qt.Clear(); err != nil {
+		return err
+	}
+
+	qt.root().boundary = boundary
+	return nil
+}
+
+// Rebuild moves the boundary of the tree, reinserting the points within
+// it and removing and returning those outside it. Extents no longer
+// within the boundary are removed. It returns ErrInvalidAABB for an
+// invalid boundary, ErrReadOnly for a snapshot and ErrRegionLocked while
+// any region is locked, leaving the tree unchanged.
+func (qt *QuadTree) Rebuild(boundary *AABB) ([]*Point, error) {
+	if err := boundary.Validate(); err != nil {
+		return nil, err
+	}
+
+	root := qt.root()
+	s := root.state
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+	if len(s.locks) > 0 {
+		return nil, ErrRegionLocked
+	}
+
+	var points, outside []*Point
+	root.walk(&query{removed: true}, func(p *Point) bool {
+		if boundary.ContainsPoint(p) {
+			points = append(points, p)
+		} else {
+			outside = append(outside, p)
+		}
+		return true
+	})
+
+	var extents []*Extent
+	root.walkExtents(func(e *Extent) {
+		if boundary.contains(e.box) {
+			extents = append(extents, e)
+		}
+	})
+
+	root.restock(false)
+	if root.nodes[0] != nil {
+		for _, node := range root.nodes {
+			node.recycle()
+		}
+	}
+	root.reset()
+	root.boundary = boundary
+
+	for _, p := range points {
+		root.insert(p)
+	}
+	for _, e := range extents {
+		root.place(e)
+	}
+
+	for _, p := range outside {
+		root.dropped(p)
+	}
+	return outside, nil
+}
+
+// walkExtents calls fn with each extent of the node and its children.
+func (qt *QuadTree) walkExtents(fn func(e *Extent)) {
+	for _, e := range qt.extents {
+		fn(e)
+	}
+
+	if qt.nodes[0] != nil {
+		for _, node := range qt.nodes {
+			node.walkExtents(fn)
+		}
+	}
+}
+
+// reset empties the node, keeping the storage of its points.
+func (qt *QuadTree) reset() {
+	points := qt.resident
+	clear(points)
+
+	*qt = QuadTree{
+		boundary: qt.boundary,
+		depth:    qt.depth,
+		parent:   qt.parent,
+		state:    qt.state,
+		gen:      qt.gen,
+	}
+
+	qt.resident = qt.inline[:0]
+	if cap(points) > inlinePoints {
+		qt.resident = points[:0]
+	}
+}
+
+// recycle empties the node and its children, keeping them to be reused by
+// divide. Nodes shared with a snapshot are left to it.
+func (qt *QuadTree) recycle() {
+	if !qt.owned() {
+		return
+	}
+
+	if qt.nodes[0] != nil {
+		for _, node := range qt.nodes {
+			node.recycle()
+		}
+	}
+
+	qt.reset()
+	qt.parent = nil
+	qt.state.spare = append(qt.state.spare, qt)
+}
+
+// newChild returns a child of the node with the center and half extents,
+// reusing a spare node if there is one and otherwise allocating it from
+// the arena of a tree created using WithArena.
+func (qt *QuadTree) newChild(cx, cy, hx, hy float64) *QuadTree {
+	s := qt.state
+
+	var boundary *AABB
+	if s.arena != nil {
+		boundary = s.arena.box(cx, cy, hx, hy)
+	} else {
+		boundary = &AABB{&Point{x: cx, y: cy}, &Point{x: hx, y: hy}}
+	}
+
+	n := len(s.spare)
+	if n == 0 {
+		if s.arena == nil {
+			return New(boundary, qt.depth+1, qt)
+		}
+		node := s.arena.node()
+		*node = QuadTree{boundary: boundary, depth: qt.depth + 1, parent: qt, state: s, gen: qt.gen}
+		node.resident = node.inline[:0]
+		return node
+	}
+
+	node := s.spare[n-1]
+	s.spare[n-1] = nil
+	s.spare = s.spare[:n-1]
+
+	node.boundary = boundary
+	node.depth = qt.depth + 1
+	node.parent = qt
+	node.gen = qt.gen
+	return node
+}
@@ -0,0 +1,56 @@
+package quadtree
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// WithClock sets the clock used to timestamp inserts and updates, and by
+// Heat, Evict and WithWriteRate, instead of time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(s *state) {
+		s.now = now
+	}
+}
+
+// WithDeterministic makes the tree reproducible across runs and machines,
+// for simulations and replay based tests. Timestamps come from a logical
+// clock starting at seed nanoseconds past the Unix epoch and advancing a
+// nanosecond on each reading, and random choices use a source seeded by
+// seed. Traversals already visit children in a fixed order and break
+// ties between equally ranked points by insertion order.
+func WithDeterministic(seed int64) Option {
+	return func(s *state) {
+		var tick atomic.Int64
+		tick.Store(seed)
+		s.now = func() time.Time {
+			return time.Unix(0, tick.Add(1))
+		}
+		s.rng = rand.New(rand.NewSource(seed))
+	}
+}
+
+// clock returns the current time of the tree.
+func (s *state) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// updated timestamps an update of the point.
+func (s *state) updated(p *Point) {
+	p.updated = s.clock().UnixNano()
+	if s.capped != nil {
+		s.capped.touch(p)
+	}
+}
+
+// random returns the source of random choices of the tree.
+func (s *state) random() *rand.Rand {
+	if s.rng == nil {
+		s.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return s.rng
+}
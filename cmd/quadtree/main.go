@@ -0,0 +1,297 @@
+// Command quadtree loads points from a CSV or GeoJSON file into a tree and
+// queries it, prints its statistics or serves it over HTTP, for exploring
+// datasets and trying tuning parameters without writing Go.
+//
+//	quadtree search   -bbox minLat,minLng,maxLat,maxLng points.csv
+//	quadtree knearest -lat 51.5 -lng -0.12 -k 10 -radius 5000 points.csv
+//	quadtree radius   -lat 51.5 -lng -0.12 -m 1000 points.geojson
+//	quadtree stats    -capacity 16 -max-depth 10 points.csv
+//	quadtree serve    -address :8080 points.csv
+//
+// CSV files hold one point per row with the columns id, lat and lng, in
+// that order or named by a header row, and any further columns become the
+// point data keyed by their header. GeoJSON files hold a FeatureCollection
+// of Point features. Query results are written as JSON, one per line.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asim/quadtree"
+	"github.com/asim/quadtree/server"
+)
+
+const usage = `usage: quadtree <command> [flags] file
+
+commands:
+  search    points within a bounding box
+  knearest  the k nearest points to a location
+  radius    points within a distance of a location
+  stats     statistics of the tree built from the file
+  serve     serve the tree over HTTP
+
+Run quadtree <command> -h for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := run(os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "quadtree:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	format := fs.String("format", "", "input format, csv or geojson, by file extension if empty")
+	capacity := fs.Int("capacity", quadtree.Capacity, "points per leaf before dividing")
+	maxDepth := fs.Int("max-depth", quadtree.MaxDepth, "maximum depth of the tree")
+
+	var (
+		bbox   *string
+		lat    *float64
+		lng    *float64
+		k      *int
+		radius *float64
+		addr   *string
+	)
+
+	switch cmd {
+	case "search":
+		bbox = fs.String("bbox", "", "minLat,minLng,maxLat,maxLng")
+	case "knearest":
+		lat = fs.Float64("lat", 0, "latitude of the location")
+		lng = fs.Float64("lng", 0, "longitude of the location")
+		k = fs.Int("k", server.DefaultK, "number of points")
+		radius = fs.Float64("radius", server.DefaultRadius, "search radius in metres")
+	case "radius":
+		lat = fs.Float64("lat", 0, "latitude of the location")
+		lng = fs.Float64("lng", 0, "longitude of the location")
+		radius = fs.Float64("m", server.DefaultRadius, "radius in metres")
+	case "stats":
+	case "serve":
+		addr = fs.String("address", server.DefaultAddress, "address to listen on")
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", cmd, usage)
+	}
+
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%s: expected one input file", cmd)
+	}
+
+	opts := []quadtree.Option{
+		quadtree.WithCapacity(*capacity),
+		quadtree.WithMaxDepth(*maxDepth),
+	}
+
+	start := time.Now()
+	tree, err := load(fs.Arg(0), *format, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "loaded %d points in %v\n", tree.Len(), time.Since(start))
+
+	switch cmd {
+	case "search":
+		box, err := parseBBox(*bbox)
+		if err != nil {
+			return err
+		}
+		return write(tree.SearchResults(box))
+	case "knearest":
+		center := quadtree.NewPoint(*lat, *lng, nil)
+		box := quadtree.NewAABB(center, center.HalfPoint(*radius))
+		return write(tree.KNearestResults(box, *k, nil))
+	case "radius":
+		center := quadtree.NewPoint(*lat, *lng, nil)
+		var results []quadtree.Result
+		for _, pd := range tree.WithinRadius(center, *radius, nil) {
+			r := quadtree.Results([]*quadtree.Point{pd.Point}, nil)[0]
+			r.Distance = pd.Distance
+			results = append(results, r)
+		}
+		return write(results)
+	case "stats":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tree.Stats())
+	case "serve":
+		fmt.Fprintf(os.Stderr, "listening on %s\n", *addr)
+		return server.New(tree, server.WithAddress(*addr)).ListenAndServe()
+	}
+
+	return nil
+}
+
+// load builds a tree covering the world from the points in the file.
+func load(path, format string, opts []quadtree.Option) (*quadtree.QuadTree, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".geojson", ".json":
+			format = "geojson"
+		default:
+			format = "csv"
+		}
+	}
+
+	switch format {
+	case "geojson":
+		return quadtree.FromGeoJSON(f, append(opts, quadtree.WithCodec(server.Codec))...)
+	case "csv":
+		world := quadtree.WorldBounds()
+		tree := quadtree.New(world, 0, nil, opts...)
+		if err := loadCSV(f, tree); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return tree, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// loadCSV inserts the rows of the CSV into the tree.
+func loadCSV(r io.Reader, tree *quadtree.QuadTree) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	// column indices of id, lat and lng, and the names of the others
+	cols := [3]int{0, 1, 2}
+	var names []string
+	header := true
+
+	for line := 1; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if header {
+			header = false
+			if _, err := strconv.ParseFloat(strings.TrimSpace(row[min(1, len(row)-1)]), 64); err != nil {
+				cols, names = columns(row)
+				continue
+			}
+		}
+
+		if len(row) < 3 {
+			return fmt.Errorf("line %d: expected id, lat and lng", line)
+		}
+
+		lat, err := strconv.ParseFloat(strings.TrimSpace(row[cols[1]]), 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid lat: %w", line, err)
+		}
+		lng, err := strconv.ParseFloat(strings.TrimSpace(row[cols[2]]), 64)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid lng: %w", line, err)
+		}
+
+		var data map[string]string
+		for i, v := range row {
+			if i == cols[0] || i == cols[1] || i == cols[2] {
+				continue
+			}
+			if data == nil {
+				data = make(map[string]string)
+			}
+			name := strconv.Itoa(i)
+			if i < len(names) && names[i] != "" {
+				name = names[i]
+			}
+			data[name] = v
+		}
+
+		p := quadtree.NewPointID(row[cols[0]], lat, lng, data)
+		if err := tree.InsertAll([]*quadtree.Point{p})[0]; err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// columns finds the id, lat and lng columns of a header row, falling back
+// to the first three columns.
+func columns(header []string) ([3]int, []string) {
+	cols := [3]int{-1, -1, -1}
+	names := make([]string, len(header))
+
+	for i, h := range header {
+		names[i] = strings.TrimSpace(h)
+		switch strings.ToLower(names[i]) {
+		case "id":
+			cols[0] = i
+		case "lat", "latitude", "y":
+			cols[1] = i
+		case "lng", "lon", "long", "longitude", "x":
+			cols[2] = i
+		}
+	}
+
+	for i, c := range cols {
+		if c < 0 {
+			cols = [3]int{0, 1, 2}
+			break
+		}
+		cols[i] = c
+	}
+
+	return cols, names
+}
+
+func parseBBox(s string) (*quadtree.AABB, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, errors.New("bbox must be minLat,minLng,maxLat,maxLng")
+	}
+
+	var v [4]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bbox: %w", err)
+		}
+		v[i] = f
+	}
+
+	return quadtree.NewAABBFromMinMax(
+		quadtree.NewPoint(v[0], v[1], nil),
+		quadtree.NewPoint(v[2], v[3], nil),
+	), nil
+}
+
+func write(results []quadtree.Result) error {
+	enc := json.NewEncoder(os.Stdout)
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package quadtree
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ColdStore is a secondary index holding points evicted from the tree.
+type ColdStore interface {
+	// Store adds the points to the store.
+	Store(points []*Point) error
+	// Search returns the stored points within the bounding box.
+	Search(a *AABB) ([]*Point, error)
+}
+
+// WithColdStorage sets the store Evict moves points into once they have
+// not been inserted or updated within the window, keeping the memory of
+// the tree bounded. SearchAll queries both the tree and the store.
+func WithColdStorage(store ColdStore, window time.Duration) Option {
+	return func(s *state) {
+		s.cold = store
+		s.coldWindow = window
+	}
+}
+
+// Evict moves the points not updated within the cold storage window into
+// the cold store, returning the number moved. Points are only removed
+// from the tree once they have been stored.
+func (qt *QuadTree) Evict() (int, error) {
+	if qt.state.cold == nil {
+		return 0, nil
+	}
+	if qt.state.readOnly {
+		return 0, ErrReadOnly
+	}
+
+	cutoff := qt.state.clock().Add(-qt.state.coldWindow).UnixNano()
+
+	var stale []*Point
+	qt.collectStale(cutoff, &stale)
+
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	if err := qt.state.cold.Store(stale); err != nil {
+		return 0, err
+	}
+
+	for _, p := range stale {
+		if qt.remove(p) {
+			qt.dropped(p)
+		}
+	}
+
+	return len(stale), nil
+}
+
+func (qt *QuadTree) collectStale(cutoff int64, stale *[]*Point) {
+	for _, p := range qt.leaf() {
+		if p.updated < cutoff {
+			*stale = append(*stale, p)
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.collectStale(cutoff, stale)
+	}
+}
+
+// SearchAll returns the points within the bounding box from both the tree
+// and its cold store. Points read from the cold store are copies, with
+// data decoded by the store.
+func (qt *QuadTree) SearchAll(a *AABB, opts ...QueryOption) ([]*Point, error) {
+	results := qt.Search(a, opts...)
+
+	if qt.state.cold == nil {
+		return results, nil
+	}
+
+	cold, err := qt.state.cold.Search(a)
+	if err != nil {
+		return results, err
+	}
+
+	q := qt.newQuery(opts)
+	for _, p := range cold {
+		if a.ContainsPoint(p) && q.match(p) {
+			results = append(results, p)
+		}
+	}
+
+	return results, nil
+}
+
+// DirStore is a ColdStore keeping points in a directory of files, one per
+// grid cell, so only the cells intersecting a search are read. Point data
+// is encoded as JSON and decoded into interface{} values.
+type DirStore struct {
+	mu   sync.Mutex
+	dir  string
+	cell float64
+}
+
+// coldRecord is the encoding of a point in a DirStore.
+type coldRecord struct {
+	X       float64     `json:"x"`
+	Y       float64     `json:"y"`
+	ID      string      `json:"id,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	Updated int64       `json:"updated"`
+	Version uint64      `json:"version,omitempty"`
+	Removed bool        `json:"removed,omitempty"`
+	Tenant  string      `json:"tenant,omitempty"`
+	Radius  float64     `json:"radius,omitempty"`
+	State   State       `json:"state,omitempty"`
+	Weight  float64     `json:"weight,omitempty"`
+	TTL     int64       `json:"ttl,omitempty"`
+	Tags    []string    `json:"tags,omitempty"`
+}
+
+// NewDirStore creates a *DirStore in the directory, creating it if needed,
+// with grid cells of the size given in coordinate units.
+func NewDirStore(dir string, cell float64) (*DirStore, error) {
+	if cell <= 0 {
+		return nil, fmt.Errorf("quadtree: invalid cell size %v", cell)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirStore{dir: dir, cell: cell}, nil
+}
+
+func (d *DirStore) index(v float64) int {
+	return int(math.Floor(v / d.cell))
+}
+
+func (d *DirStore) file(i, j int) string {
+	return filepath.Join(d.dir, fmt.Sprintf("cell_%d_%d.jsonl", i, j))
+}
+
+// Store appends the points to the files of their cells.
+func (d *DirStore) Store(points []*Point) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cells := make(map[[2]int][]*Point)
+	for _, p := range points {
+		c := [2]int{d.index(p.x), d.index(p.y)}
+		cells[c] = append(cells[c], p)
+	}
+
+	for c, ps := range cells {
+		if err := d.append(d.file(c[0], c[1]), ps); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *DirStore) append(name string, points []*Point) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+
+	for _, p := range points {
+		m := p.meta()
+		err = enc.Encode(coldRecord{
+			p.x, p.y, p.id, p.data, p.updated, m.version,
+			m.removed, m.tenant, m.radius, m.lifecycle, m.weight,
+			int64(m.ttl), m.tags,
+		})
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Search reads the points within the bounding box from the files of the
+// cells it intersects.
+func (d *DirStore) Search(a *AABB) ([]*Point, error) {
+	var results []*Point
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	minI, maxI := d.index(a.center.x-a.half.x), d.index(a.center.x+a.half.x)
+	minJ, maxJ := d.index(a.center.y-a.half.y), d.index(a.center.y+a.half.y)
+
+	for _, e := range entries {
+		var i, j int
+		if _, err := fmt.Sscanf(e.Name(), "cell_%d_%d.jsonl", &i, &j); err != nil {
+			continue
+		}
+		if i < minI || i > maxI || j < minJ || j > maxJ {
+			continue
+		}
+		if results, err = d.read(filepath.Join(d.dir, e.Name()), a, results); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func (d *DirStore) read(name string, a *AABB, results []*Point) ([]*Point, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return results, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var r coldRecord
+		if err := dec.Decode(&r); err != nil {
+			return results, err
+		}
+
+		p := &Point{x: r.X, y: r.Y, id: r.ID, data: r.Data, updated: r.Updated}
+		p.restoreMeta(pointMeta{
+			version: r.Version, removed: r.Removed, tenant: r.Tenant,
+			radius: r.Radius, lifecycle: r.State, weight: r.Weight,
+			ttl: time.Duration(r.TTL),
+		})
+		for _, t := range r.Tags {
+			p.AddTag(t)
+		}
+		if a.ContainsPoint(p) {
+			results = append(results, p)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,81 @@
+package quadtree
+
+// WithAutoCompact collapses a node's subtree into a single leaf whenever a
+// removal leaves it holding fewer than the capacity of a leaf, so trees with
+// churn don't stay deep and fragmented.
+func WithAutoCompact() Option {
+	return func(s *state) {
+		s.autoCompact = true
+	}
+}
+
+// Compact collapses every subtree holding fewer points than the capacity
+// of a leaf into a single leaf, returning the number of nodes removed.
+func (qt *QuadTree) Compact() int {
+	if qt.nodes[0] == nil || qt.state.readOnly {
+		return 0
+	}
+
+	n := 0
+	for i, node := range qt.nodes {
+		if node.compacts() {
+			n += qt.own(i).Compact()
+		}
+	}
+
+	if qt.total < qt.state.capacity {
+		n += qt.collapse()
+	}
+
+	return n
+}
+
+// collapse moves the points beneath the node into it and removes its
+// children, returning the number of nodes removed.
+func (qt *QuadTree) collapse() int {
+	if qt.nodes[0] == nil {
+		return 0
+	}
+
+	n := 0
+	var points []*Point
+	for i := range qt.nodes {
+		node := qt.own(i)
+		n += node.collapse() + 1
+		node.loadLeaf()
+		points = append(points, node.resident...)
+		node.unstored(0, len(node.resident))
+		qt.extents = append(qt.extents, node.extents...)
+		if qt.state.arena != nil {
+			node.recycle()
+		}
+	}
+
+	qt.nodes = [4]*QuadTree{}
+	qt.codes = nil
+	qt.resident = qt.inline[:0]
+	qt.counted(-len(points))
+	for _, p := range points {
+		qt.appendPoint(p)
+	}
+	qt.touch()
+
+	return n
+}
+
+// compacts reports whether Compact would collapse any subtree beneath the
+// node.
+func (qt *QuadTree) compacts() bool {
+	if qt.nodes[0] == nil {
+		return false
+	}
+	if qt.total < qt.state.capacity {
+		return true
+	}
+	for _, node := range qt.nodes {
+		if node.compacts() {
+			return true
+		}
+	}
+	return false
+}
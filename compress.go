@@ -0,0 +1,104 @@
+package quadtree
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// Compressor compresses the blocks of binary snapshots. Implementations
+// wrapping snappy or zstd can be given to WithSnapshotCompression in
+// place of FlateCompressor.
+type Compressor interface {
+	Compress(b []byte) ([]byte, error)
+	Decompress(b []byte) ([]byte, error)
+}
+
+// FlateCompressor compresses snapshot blocks with DEFLATE. It is used to
+// decompress snapshots unless WithSnapshotCompression is given.
+var FlateCompressor Compressor = flateCompressor{}
+
+type flateCompressor struct{}
+
+func (flateCompressor) Compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (flateCompressor) Decompress(b []byte) ([]byte, error) {
+	return io.ReadAll(flate.NewReader(bytes.NewReader(b)))
+}
+
+// WithSnapshotCompression compresses each block of the snapshots written
+// by MarshalBinary with c, such as FlateCompressor. Blocks hold subtrees
+// of a few thousand points, so similar point data within a region
+// compresses well. Trees decoding the snapshots must be created with the
+// same compressor, unless it is FlateCompressor.
+func WithSnapshotCompression(c Compressor) Option {
+	return func(s *state) {
+		s.compressor = c
+	}
+}
+
+// WithSnapshotDictionary writes the point data repeated in the snapshots
+// written by MarshalBinary once, in a dictionary referenced by each point
+// holding it, as with many points of a few kinds. Data which is only
+// similar is left to WithSnapshotCompression.
+func WithSnapshotDictionary() Option {
+	return func(s *state) {
+		s.dictionary = true
+	}
+}
+
+func (s *state) snapshotCompressor() Compressor {
+	if s.compressor == nil {
+		return FlateCompressor
+	}
+	return s.compressor
+}
+
+// newDictionary returns the point data held by more than one point of the
+// snapshot, in the order first held, along with the index of each.
+func newDictionary(root *snapshotNode) ([][]byte, map[string]uint64) {
+	held := make(map[string]int)
+	var order []string
+
+	var walk func(n *snapshotNode)
+	walk = func(n *snapshotNode) {
+		for _, p := range n.Points {
+			// data no longer than its reference is left inline
+			if len(p.Data) < 2 {
+				continue
+			}
+			k := string(p.Data)
+			if held[k] == 0 {
+				order = append(order, k)
+			}
+			held[k]++
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	var dict [][]byte
+	index := make(map[string]uint64)
+	for _, k := range order {
+		if held[k] > 1 {
+			index[k] = uint64(len(dict))
+			dict = append(dict, []byte(k))
+		}
+	}
+	return dict, index
+}
@@ -0,0 +1,417 @@
+package quadtree
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrentQuadTree is safe for concurrent use by multiple goroutines.
+// The boundary is divided into a grid of regions, each an independent
+// QuadTree with its own lock, so writes in one region do not block
+// queries or writes in another and queries within a region run in
+// parallel. Writes are routed to the region of their point and queries
+// fan out across the regions they touch.
+//
+// Options apply to each region separately, e.g. WithUniqueIDs enforces
+// IDs unique within a region. Writes to the same point must not be made
+// concurrently.
+//
+// Filter functions run under a region's read lock, concurrently with
+// other queries and with the other regions of their own, and must not
+// write to the tree. They are passed a copy
+// of each point so changes made to it are not seen by the tree. A panic
+// in a callback is recovered, releasing the locks held, and returned by
+// SearchStrict and KNearestStrict as a *PanicError.
+type ConcurrentQuadTree struct {
+	boundary *AABB
+	// columns along x and rows along y of the grid of regions
+	cols, rows int
+	regions    []*region
+	// insertion sequence shared by the regions, so ties between points
+	// of different regions are broken by the order they were inserted
+	seq atomic.Uint64
+}
+
+type region struct {
+	mu    sync.RWMutex
+	tree  *QuadTree
+	index int
+	stats shardStats
+}
+
+// NewConcurrent creates a *ConcurrentQuadTree covering the boundary with a
+// grid of 4^level regions.
+func NewConcurrent(boundary *AABB, level int, opts ...Option) *ConcurrentQuadTree {
+	n := 1 << uint(max(level, 0))
+	return newConcurrent(boundary, n, n, max(level, 0), opts)
+}
+
+// NewConcurrentGrid creates a *ConcurrentQuadTree covering the boundary
+// with a grid of cols regions along x by rows along y, e.g. one column per
+// core for writes spread along x. The grid has at least one region each
+// way.
+func NewConcurrentGrid(boundary *AABB, cols, rows int, opts ...Option) *ConcurrentQuadTree {
+	cols, rows = max(cols, 1), max(rows, 1)
+
+	// the depth of the regions below the boundary, as for NewConcurrent
+	level := bits.Len(uint(max(cols, rows) - 1))
+	return newConcurrent(boundary, cols, rows, level, opts)
+}
+
+func newConcurrent(boundary *AABB, cols, rows, level int, opts []Option) *ConcurrentQuadTree {
+	ct := &ConcurrentQuadTree{
+		boundary: boundary,
+		cols:     cols,
+		rows:     rows,
+		regions:  make([]*region, cols*rows),
+	}
+
+	half := &Point{x: boundary.half.x / float64(cols), y: boundary.half.y / float64(rows)}
+	minX := boundary.center.x - boundary.half.x
+	minY := boundary.center.y - boundary.half.y
+
+	for j := 0; j < rows; j++ {
+		for i := 0; i < cols; i++ {
+			center := &Point{
+				x: minX + half.x*float64(2*i+1),
+				y: minY + half.y*float64(2*j+1),
+			}
+			ct.regions[j*cols+i] = &region{
+				tree:  New(&AABB{center, half}, level, nil, opts...),
+				index: j*cols + i,
+			}
+		}
+	}
+
+	return ct
+}
+
+// region returns the region a point within the boundary belongs to.
+func (ct *ConcurrentQuadTree) region(x, y float64) *region {
+	cell := func(v, center, half float64, n int) int {
+		c := int((v - center + half) / (2 * half) * float64(n))
+		return min(max(c, 0), n-1)
+	}
+
+	i := cell(x, ct.boundary.center.x, ct.boundary.half.x, ct.cols)
+	j := cell(y, ct.boundary.center.y, ct.boundary.half.y, ct.rows)
+	r := ct.regions[j*ct.cols+i]
+
+	// rounding may place points on an edge in the wrong cell
+	p := &Point{x: x, y: y}
+	if !r.tree.boundary.ContainsPoint(p) {
+		for _, rr := range ct.regions {
+			if rr.tree.boundary.ContainsPoint(p) {
+				return rr
+			}
+		}
+	}
+
+	return r
+}
+
+// Insert inserts a point into the region containing it.
+func (ct *ConcurrentQuadTree) Insert(p *Point) bool {
+	if p == nil || !ct.boundary.ContainsPoint(p) {
+		return false
+	}
+
+	r := ct.region(p.x, p.y)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return ct.sequenced(p, r.tree.Insert)
+}
+
+// InsertWithTTL inserts a point expiring d after it was last inserted or
+// updated into the region containing it.
+func (ct *ConcurrentQuadTree) InsertWithTTL(p *Point, d time.Duration) bool {
+	if p == nil || !ct.boundary.ContainsPoint(p) {
+		return false
+	}
+
+	r := ct.region(p.x, p.y)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return ct.sequenced(p, func(p *Point) bool {
+		return r.tree.InsertWithTTL(p, d)
+	})
+}
+
+// sequenced inserts a point not yet inserted using insert, giving it the
+// next sequence of the tree rather than of its region.
+func (ct *ConcurrentQuadTree) sequenced(p *Point, insert func(*Point) bool) bool {
+	if p.seq != 0 {
+		return insert(p)
+	}
+
+	p.seq = ct.seq.Add(1)
+	if !insert(p) {
+		p.seq = 0
+		return false
+	}
+	return true
+}
+
+// Expire removes the expired points from each region in turn, returning
+// the number removed.
+func (ct *ConcurrentQuadTree) Expire() int {
+	n := 0
+	for _, r := range ct.regions {
+		r.mu.Lock()
+		n += r.tree.Expire()
+		r.mu.Unlock()
+	}
+	return n
+}
+
+// ExpireEvery calls Expire every interval in the background until stop is
+// called.
+func (ct *ConcurrentQuadTree) ExpireEvery(interval time.Duration) (stop func()) {
+	return every(interval, func() {
+		ct.Expire()
+	})
+}
+
+// Remove removes a point from the tree.
+func (ct *ConcurrentQuadTree) Remove(p *Point) bool {
+	if p == nil || !ct.boundary.ContainsPoint(p) {
+		return false
+	}
+
+	r := ct.region(p.x, p.y)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tree.Remove(p)
+}
+
+// Update moves a point to the location of np, locking both regions when
+// the point moves from one region to another. The region it moves to is
+// checked before the point leaves the one it is in, so a point it refuses,
+// e.g. for the ID or location of another point, is left where it was.
+func (ct *ConcurrentQuadTree) Update(p *Point, np *Point) bool {
+	if p == nil || np == nil || !ct.boundary.ContainsPoint(p) || !ct.boundary.ContainsPoint(np) {
+		return false
+	}
+
+	src := ct.region(p.x, p.y)
+	dst := ct.region(np.x, np.y)
+
+	if src == dst {
+		src.mu.Lock()
+		defer src.mu.Unlock()
+		return src.tree.Update(p, np)
+	}
+
+	// lock in a consistent order to avoid deadlock
+	first, second := src, dst
+	if dst.index < src.index {
+		first, second = dst, src
+	}
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	second.mu.Lock()
+	defer second.mu.Unlock()
+
+	if src.tree.state.readOnly || dst.tree.state.readOnly || src.tree.state.locked(p) || dst.tree.state.locked(np) {
+		return false
+	}
+
+	// check dst takes the point at its new location before removing it
+	// from src, so a point refused by its ID, location or the quota of dst
+	// is left where it was
+	x, y, size := p.x, p.y, p.size()
+	p.x, p.y = np.x, np.y
+	old, err := dst.tree.admit(p)
+	p.x, p.y = x, y
+	p.setSize(size)
+	if err != nil || !src.tree.state.allowWrite() {
+		return false
+	}
+
+	if !src.tree.remove(p) {
+		return false
+	}
+	src.tree.dropped(p)
+
+	p.x, p.y = np.x, np.y
+	p.setSize(dst.tree.sizeOf(p))
+	p.setMeta().version++
+	if !dst.tree.insert(p) {
+		// put the point back rather than lose it
+		p.x, p.y = x, y
+		p.setSize(size)
+		p.setMeta().version--
+		src.tree.rinsert(p)
+		src.tree.inserted(p, nil)
+		return false
+	}
+
+	dst.tree.inserted(p, old)
+	dst.tree.trim(p)
+	return true
+}
+
+// merged is a result of a region along with the fields used to merge it
+// with the results of other regions, read while the region is locked.
+type merged struct {
+	point   *Point
+	rank    float64
+	seq     uint64
+	updated int64
+	key     string
+}
+
+// collect runs a query against each region intersecting a in turn under a
+// read lock, recording the fields needed to merge the results, or returns
+// the panic of a callback.
+func (ct *ConcurrentQuadTree) collect(a *AABB, q *query, fn func(*QuadTree) []*Point, rank func(*Point) float64) ([]merged, error) {
+	var results []merged
+
+	for _, r := range ct.regions {
+		if !r.tree.boundary.Intersect(a) {
+			continue
+		}
+
+		ans := r.collect(q, fn, rank)
+		if ans.err != nil {
+			return nil, ans.err
+		}
+		results = append(results, ans.results...)
+	}
+
+	return results, nil
+}
+
+// distinct keeps the first result for each key unless better reports a
+// later result is preferable.
+func distinct(results []merged, better func(p, q merged) bool) []merged {
+	seen := make(map[string]int, len(results))
+	out := results[:0]
+
+	for _, m := range results {
+		i, ok := seen[m.key]
+		if !ok {
+			seen[m.key] = len(out)
+			out = append(out, m)
+			continue
+		}
+		if better != nil && better(m, out[i]) {
+			out[i] = m
+		}
+	}
+
+	return out
+}
+
+func points(results []merged) []*Point {
+	points := make([]*Point, len(results))
+	for i, m := range results {
+		points[i] = m.point
+	}
+	return points
+}
+
+// Search returns all the points within the bounding box, querying each
+// region it intersects under a read lock. It returns no points if a
+// callback of the query panics.
+func (ct *ConcurrentQuadTree) Search(a *AABB, opts ...QueryOption) []*Point {
+	points, _ := ct.search(a, opts)
+	return points
+}
+
+// SearchStrict is Search returning ErrInvalidAABB for an invalid query box
+// and a *PanicError if a callback of the query panics.
+func (ct *ConcurrentQuadTree) SearchStrict(a *AABB, opts ...QueryOption) ([]*Point, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return ct.search(a, opts)
+}
+
+func (ct *ConcurrentQuadTree) search(a *AABB, opts []QueryOption) (_ []*Point, err error) {
+	if noBox(a) {
+		return []*Point{}, nil
+	}
+	defer recovered(&err)
+
+	q := newQuery(opts)
+
+	results, err := ct.collect(a, q, func(qt *QuadTree) []*Point {
+		return qt.Search(a, opts...)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.distinct != nil {
+		results = distinct(results, func(p, q merged) bool {
+			return p.updated > q.updated
+		})
+	}
+
+	return points(results), nil
+}
+
+// KNearest returns the k nearest points within the bounding box, merging
+// the nearest points of each region it intersects. Regions are searched in
+// parallel, nearest first, and those which cannot hold a point nearer than
+// the kth found are skipped; ShardStats reports the time taken to search
+// each and Hedged searches slow regions again. Points at the same
+// distance are ordered by when they were inserted into the tree, whatever
+// their region. It returns no points if the filter or another callback of
+// the query panics.
+func (ct *ConcurrentQuadTree) KNearest(a *AABB, i int, fn filter, opts ...QueryOption) []*Point {
+	points, _ := ct.kNearest(a, i, fn, opts)
+	return points
+}
+
+// KNearestStrict is KNearest returning ErrInvalidAABB for an invalid query
+// box and a *PanicError if the filter or another callback panics.
+func (ct *ConcurrentQuadTree) KNearestStrict(a *AABB, i int, fn filter, opts ...QueryOption) ([]*Point, error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return ct.kNearest(a, i, fn, opts)
+}
+
+func (ct *ConcurrentQuadTree) kNearest(a *AABB, i int, fn filter, opts []QueryOption) (_ []*Point, err error) {
+	if noBox(a) || i <= 0 {
+		return []*Point{}, nil
+	}
+	defer recovered(&err)
+
+	q := newQuery(opts)
+	fn = readOnly(fn)
+
+	dist := ct.regions[0].tree.distance()
+	rank := func(p *Point) float64 {
+		return dist(p, a.center)
+	}
+	if q.cost != nil {
+		rank = func(p *Point) float64 {
+			return q.cost(p, Distance(a.center, p))
+		}
+	} else if q.rank != nil {
+		rank = func(p *Point) float64 {
+			return q.rank(Distance(a.center, p), p.Weight())
+		}
+	}
+
+	// points of a region are no nearer than its bound, but may rank
+	// better by cost or weight
+	prune := q.cost == nil && q.rank == nil
+
+	results, err := ct.fanOut(a, i, q, prune, func(qt *QuadTree) []*Point {
+		return qt.KNearest(a, i, fn, opts...)
+	}, rank)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) > i {
+		results = results[:i]
+	}
+	return points(results), nil
+}
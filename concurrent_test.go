@@ -0,0 +1,122 @@
+package quadtree_test
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asim/quadtree"
+	"github.com/asim/quadtree/testdata"
+)
+
+func TestConcurrentUpdateRefused(t *testing.T) {
+	ct := quadtree.NewConcurrent(quadtree.WorldBounds(), 1, quadtree.WithDuplicates(quadtree.DuplicateReject))
+
+	p := quadtree.NewPoint(-45, -90, "moving")
+	q := quadtree.NewPoint(45, 90, "taken")
+	if !ct.Insert(p) || !ct.Insert(q) {
+		t.Fatal("insert failed")
+	}
+
+	if ct.Update(p, quadtree.NewPoint(45, 90, nil)) {
+		t.Fatal("update onto another point in another region succeeded")
+	}
+	if x, y := p.Coordinates(); x != -45 || y != -90 {
+		t.Errorf("refused update left the point at %v, %v", x, y)
+	}
+	if p.Version() != 0 {
+		t.Errorf("refused update set the version to %d", p.Version())
+	}
+	if got := ct.Search(quadtree.NewAABB(quadtree.NewPoint(-45, -90, nil), quadtree.NewPoint(0, 0, nil))); len(got) != 1 || got[0] != p {
+		t.Errorf("refused update removed the point, search returned %v", got)
+	}
+	if n := len(ct.Search(quadtree.WorldBounds())); n != 2 {
+		t.Errorf("tree holds %d points, want 2", n)
+	}
+
+	if !ct.Update(p, quadtree.NewPoint(44, 89, nil)) {
+		t.Fatal("update into another region failed")
+	}
+	if got := ct.Search(quadtree.NewAABB(quadtree.NewPoint(44, 89, nil), quadtree.NewPoint(0, 0, nil))); len(got) != 1 || got[0] != p {
+		t.Errorf("search at the new location returned %v", got)
+	}
+}
+
+func TestConcurrentUpdateUniqueIDs(t *testing.T) {
+	ct := quadtree.NewConcurrent(quadtree.WorldBounds(), 1, quadtree.WithUniqueIDs(quadtree.IDReject))
+
+	p := quadtree.NewPointID("a", -45, -90, nil)
+	q := quadtree.NewPointID("a", 45, 90, nil)
+	if !ct.Insert(p) || !ct.Insert(q) {
+		t.Fatal("insert failed")
+	}
+
+	if ct.Update(p, quadtree.NewPoint(40, 80, nil)) {
+		t.Fatal("update into a region holding the ID succeeded")
+	}
+	if n := len(ct.Search(quadtree.WorldBounds())); n != 2 {
+		t.Errorf("tree holds %d points, want 2", n)
+	}
+}
+
+func TestConcurrentKNearestFanOut(t *testing.T) {
+	ct := quadtree.NewConcurrentGrid(quadtree.WorldBounds(), 8, 8)
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil)
+	for _, p := range testdata.Uniform(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 2000) {
+		x, y := p.Coordinates()
+		ct.Insert(quadtree.NewPoint(x, y, nil))
+		qt.Insert(quadtree.NewPoint(x, y, nil))
+	}
+
+	same := func(got, want []*quadtree.Point) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			gx, gy := got[i].Coordinates()
+			wx, wy := want[i].Coordinates()
+			if gx != wx || gy != wy {
+				return false
+			}
+		}
+		return true
+	}
+
+	a := quadtree.NewAABB(quadtree.NewPoint(-60, -120, nil), quadtree.NewPoint(90, 180, nil))
+	for _, k := range []int{1, 5, 50} {
+		if got, want := ct.KNearest(a, k, nil), qt.KNearest(a, k, nil); !same(got, want) {
+			t.Fatalf("KNearest of %d returned %v, want %v", k, got, want)
+		}
+	}
+
+	var queries, pruned uint64
+	for _, s := range ct.ShardStats() {
+		queries += s.Queries
+		pruned += s.Pruned
+	}
+	if queries == 0 || pruned == 0 {
+		t.Fatalf("regions searched %d times and pruned %d, want both", queries, pruned)
+	}
+
+	// a region slow to answer is searched again
+	var slow atomic.Bool
+	slow.Store(true)
+	filter := func(p *quadtree.Point) bool {
+		if slow.CompareAndSwap(true, false) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return true
+	}
+	if got, want := ct.KNearest(a, 5, filter, quadtree.Hedged(time.Millisecond)), qt.KNearest(a, 5, nil); !same(got, want) {
+		t.Fatalf("hedged KNearest returned %v, want %v", got, want)
+	}
+
+	var hedged uint64
+	for _, s := range ct.ShardStats() {
+		hedged += s.Hedged
+	}
+	if hedged == 0 {
+		t.Fatal("no region was searched again")
+	}
+}
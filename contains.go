@@ -0,0 +1,42 @@
+package quadtree
+
+// Contains reports whether the point is stored in the tree, descending
+// directly to the leaf owning its location. Points hidden by SoftRemove
+// are still contained.
+func (qt *QuadTree) Contains(p *Point) bool {
+	return p != nil && qt.owner(qt.state.current(p)) != nil
+}
+
+// owner returns the node holding the point, descending only to the nodes
+// containing its location, or nil if it is not in the tree. A point moved
+// by Update onto the edge between nodes stays in the node it was in, so
+// each node sharing the edge is searched.
+func (qt *QuadTree) owner(p *Point) *QuadTree {
+	if !qt.boundary.ContainsPoint(p) {
+		return nil
+	}
+
+	for _, ep := range qt.scan(&AABB{p, &Point{}}) {
+		if ep == p {
+			return qt
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return nil
+	}
+
+	for _, child := range qt.nodes {
+		if node := child.owner(p); node != nil {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// ContainsID reports whether the ID index holds a point with the ID.
+func (qt *QuadTree) ContainsID(id string) bool {
+	_, ok := qt.state.index()[id]
+	return ok
+}
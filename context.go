@@ -0,0 +1,61 @@
+package quadtree
+
+import (
+	"context"
+)
+
+// SearchCtx is Search stopping once the context is done, checked as each
+// node is visited, so a query over a dense region can be bounded by the
+// deadline of a request. A cancelled query returns the points found so
+// far along with the error of the context.
+func (qt *QuadTree) SearchCtx(ctx context.Context, a *AABB, opts ...QueryOption) ([]*Point, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	qt.state.acquire()
+	defer qt.state.release()
+
+	q := qt.newQuery(opts)
+	q.done = ctx.Done()
+	results := qt.searchQuery(a, q)
+	if q.cancelled {
+		return results, ctx.Err()
+	}
+	return results, nil
+}
+
+// KNearestCtx is KNearest stopping once the context is done, checked as
+// each node is visited. A cancelled query returns the nearest of the
+// points found so far along with the error of the context.
+func (qt *QuadTree) KNearestCtx(ctx context.Context, a *AABB, i int, fn filter, opts ...QueryOption) ([]*Point, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	qt.state.acquire()
+	defer qt.state.release()
+
+	q := qt.newQuery(opts)
+	q.done = ctx.Done()
+	results := qt.kNearestQuery(nil, a, i, fn, q)
+	if q.cancelled {
+		return results, ctx.Err()
+	}
+	return results, nil
+}
+
+// stopped reports whether the context of the query is done, marking the
+// query cancelled.
+func (q *query) stopped() bool {
+	if q.done == nil || q.cancelled {
+		return q.cancelled
+	}
+
+	select {
+	case <-q.done:
+		q.cancelled = true
+	default:
+	}
+	return q.cancelled
+}
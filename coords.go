@@ -0,0 +1,130 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// CoordinateSystem gives meaning to the coordinates of a tree: how far
+// apart two points are, how near a point can be to a bounding box, and
+// how large a box must be to hold every point within a distance.
+type CoordinateSystem interface {
+	// Distance returns the distance between two points.
+	Distance(p, q *Point) float64
+	// Bound returns a lower bound of the distance from p to any point
+	// within the bounding box.
+	Bound(p *Point, a *AABB) float64
+	// Half returns the half point of a box centered on center holding
+	// every point within the distance of it.
+	Half(center *Point, distance float64) *Point
+}
+
+var (
+	// Cartesian is the plane, for game or screen coordinates. Distances
+	// are straight lines in the units of the coordinates.
+	Cartesian CoordinateSystem = cartesian{}
+	// WGS84 holds latitude as x and longitude as y in degrees, longitude
+	// within [-180, 180]. Distances are great-circle metres. Query boxes
+	// crossing the antimeridian are split in two and their latitudes
+	// clamped to the poles.
+	WGS84 CoordinateSystem = wgs84{}
+	// Mercator holds coordinates projected by WebMercator, easting as x and
+	// northing as y. Distances are great-circle metres on the ground
+	// rather than the inflated metres of the projection. Query boxes wrap
+	// around the antimeridian as for WGS84.
+	Mercator CoordinateSystem = mercator{}
+)
+
+// WithCoordinateSystem sets the coordinate system of the tree, which
+// orders the results of KNearest and NearestN and measures WithinRadius
+// and RadiusBox. Without it KNearest orders by planar distance and radius
+// queries are WGS84.
+func WithCoordinateSystem(cs CoordinateSystem) Option {
+	return func(s *state) {
+		s.coords = cs
+		s.distance = cs.Distance
+		s.bound = cs.Bound
+	}
+}
+
+// coordinates returns the coordinate system of radius queries.
+func (s *state) coordinates() CoordinateSystem {
+	if s.coords == nil {
+		return WGS84
+	}
+	return s.coords
+}
+
+// RadiusBox returns the bounding box centered on center holding every
+// point within the distance of it in the coordinate system of the tree,
+// or nil for a nil center.
+func (qt *QuadTree) RadiusBox(center *Point, distance float64) *AABB {
+	if center == nil {
+		return nil
+	}
+	return &AABB{center, qt.state.coordinates().Half(center, distance)}
+}
+
+type cartesian struct{}
+
+func (cartesian) Distance(p, q *Point) float64 {
+	return math.Hypot(p.x-q.x, p.y-q.y)
+}
+
+func (c cartesian) Bound(p *Point, a *AABB) float64 {
+	return c.Distance(p, a.closest(p))
+}
+
+func (cartesian) Half(center *Point, distance float64) *Point {
+	return &Point{x: distance, y: distance}
+}
+
+type wgs84 struct{}
+
+func (wgs84) Distance(p, q *Point) float64 {
+	return Distance(p, q)
+}
+
+func (wgs84) Bound(p *Point, a *AABB) float64 {
+	return geoMinDist(p, a)
+}
+
+func (wgs84) Half(center *Point, distance float64) *Point {
+	return radiusHalf(center, distance)
+}
+
+type mercator struct{}
+
+// geo returns the geographic location of a projected point.
+func (mercator) geo(p *Point) *Point {
+	lat, lng := WebMercator.Unproject(p.x, p.y)
+	return &Point{x: lat, y: lng}
+}
+
+func (m mercator) Distance(p, q *Point) float64 {
+	return Distance(m.geo(p), m.geo(q))
+}
+
+func (m mercator) Bound(p *Point, a *AABB) float64 {
+	// easting and northing map to longitude and latitude independently,
+	// so the corners of the box map to those of a geographic box
+	lo := m.geo(&Point{x: a.center.x - a.half.x, y: a.center.y - a.half.y})
+	hi := m.geo(&Point{x: a.center.x + a.half.x, y: a.center.y + a.half.y})
+
+	return geoMinDist(m.geo(p), &AABB{
+		&Point{x: (lo.x + hi.x) / 2, y: (lo.y + hi.y) / 2},
+		&Point{x: (hi.x - lo.x) / 2, y: (hi.y - lo.y) / 2},
+	})
+}
+
+func (m mercator) Half(center *Point, distance float64) *Point {
+	g := m.geo(center)
+	h := radiusHalf(g, distance)
+
+	x0, y0 := WebMercator.Project(g.x-h.x, g.y-h.y)
+	x1, y1 := WebMercator.Project(g.x+h.x, g.y+h.y)
+
+	return &Point{
+		x: math.Max(center.x-x0, x1-center.x),
+		y: math.Max(center.y-y0, y1-center.y),
+	}
+}
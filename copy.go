@@ -0,0 +1,189 @@
+package quadtree
+
+import (
+	"maps"
+	"slices"
+	"sync/atomic"
+)
+
+// Snapshot returns a read-only copy of the whole tree for long running
+// queries to read a stable view while writers continue to mutate the
+// tree. It takes constant time: the snapshot shares the nodes and points
+// of the tree, and from then on the tree copies the nodes on the path of
+// each write and each point it changes, leaving those the snapshot holds
+// as they were. The point the tree holds may so be a copy of one inserted or
+// returned by an earlier query. Writes through the earlier point, such as
+// Update and Remove, reach the copy, as does Get, while the earlier point
+// keeps the coordinates and data the snapshot sees. Points changed
+// directly, e.g. by Point.SetData, are seen by the snapshots holding them.
+//
+// Snapshot writes to the tree, so must not run concurrently with its
+// other writes and queries, while the snapshot may be queried by any
+// number of goroutines without locks. The snapshot rejects writes, with
+// ErrReadOnly where an error is reported, builds its ID index the first
+// time it is used and keeps no per ID write counts. The leaves of a tree
+// released to its point store are read back into the tree first.
+func (qt *QuadTree) Snapshot() *QuadTree {
+	if qt.state.readOnly {
+		return qt
+	}
+	qt = qt.root()
+
+	s := *qt.state
+	s.ids = nil
+	s.lazy = &lazyIndex{}
+	s.tenants = maps.Clone(qt.state.tenants)
+	s.writers = nil
+	s.locks = nil
+	s.rng = nil
+	s.ops = nil
+	s.store = nil
+	s.hooks = hooks{}
+	s.changes = nil
+	s.metrics = nil
+	s.capped = nil
+	s.spare = nil
+	s.arena = nil
+	s.fresh = nil
+	s.forward = nil
+	s.back = nil
+	s.readOnly = true
+
+	if qt.state.store != nil {
+		qt.loadLeaves()
+	}
+
+	snap := new(QuadTree)
+	snap.shallow(qt)
+	snap.parent = nil
+	snap.state = &s
+	s.lazy.root = snap
+
+	// the children written since the last snapshot are now only ever
+	// written as copies, so may be moved to the snapshot
+	for _, node := range qt.nodes {
+		if node != nil && node.owned() {
+			node.parent = snap
+		}
+	}
+
+	qt.gen = atomic.AddUint64(&qt.state.gen, 1)
+	qt.state.fresh = make(map[*Point]struct{})
+	return snap
+}
+
+// loadLeaves reads the points of the released leaves of the subtree back
+// into the tree.
+func (qt *QuadTree) loadLeaves() {
+	qt.loadLeaf()
+	if qt.nodes[0] != nil {
+		for _, node := range qt.nodes {
+			node.loadLeaves()
+		}
+	}
+}
+
+// Clone returns an independent copy of the tree which can be written to.
+// Points and extents are copied, so writes to either tree are not seen by
+// the other, while point data is shared. Hooks, change feeds, metrics,
+// region locks and the point store are not carried over, the write rate
+// limit and slow query log of the clone start afresh, and its random
+// choices are unseeded.
+func (qt *QuadTree) Clone() *QuadTree {
+	s := *qt.state
+	s.detach()
+	s.ids = make(map[string]*Point)
+	s.tenants = maps.Clone(qt.state.tenants)
+	s.writers = maps.Clone(qt.state.writers)
+	s.locks = nil
+	s.rng = nil
+	s.store = nil
+	s.storeErr = nil
+	s.hooks = hooks{}
+	s.changes = nil
+	s.metrics = nil
+	s.spare = nil
+	if s.arena != nil {
+		s.arena = &arena{}
+	}
+
+	if l := qt.state.limit; l != nil {
+		s.limit = &limiter{rate: l.rate, burst: l.burst, tokens: l.burst}
+		if l.queries != nil {
+			s.limit.queries = make(chan struct{}, cap(l.queries))
+		}
+	}
+	if sl := qt.state.slow; sl != nil {
+		s.slow = &slowLog{threshold: sl.threshold, entries: make([]SlowQuery, len(sl.entries))}
+	}
+	if o := qt.state.ops; o != nil {
+		s.ops = &opLog{keys: maps.Clone(o.keys), ring: slices.Clone(o.ring), next: o.next}
+	}
+
+	points := make([]Point, qt.total)
+	root := qt.copy(nil, &s, &points)
+	root.cloneExtents()
+	root.RebuildIndex()
+	if s.capped != nil {
+		s.capped = s.capped.rebuild(root)
+	}
+	return root
+}
+
+// cloneExtents replaces the extents of a copied node and its children with
+// copies.
+func (qt *QuadTree) cloneExtents() {
+	for i, e := range qt.extents {
+		c := *e
+		qt.extents[i] = &c
+	}
+
+	if qt.nodes[0] != nil {
+		for _, node := range qt.nodes {
+			node.cloneExtents()
+		}
+	}
+}
+
+// copy returns a copy of the node and its children, taking the copied
+// points from the front of points.
+func (qt *QuadTree) copy(parent *QuadTree, s *state, points *[]Point) *QuadTree {
+	n := &QuadTree{
+		boundary: qt.boundary,
+		depth:    qt.depth,
+		parent:   parent,
+		state:    s,
+		agg:      qt.agg,
+		dirty:    qt.dirty,
+		radius:   qt.radius,
+		weight:   qt.weight,
+		tags:     qt.tags,
+		total:    qt.total,
+		codes:    append([]uint64(nil), qt.codes...),
+		extents:  append([]*Extent(nil), qt.extents...),
+	}
+
+	leaf := qt.leaf()
+	n.resident = n.inline[:0]
+	if len(leaf) > inlinePoints {
+		n.resident = make([]*Point, 0, len(leaf))
+	}
+	for _, p := range leaf {
+		cp := &(*points)[0]
+		*points = (*points)[1:]
+		*cp = *p
+		if p.md != nil {
+			m := *p.md
+			cp.md = &m
+		}
+		n.resident = append(n.resident, cp)
+	}
+
+	if qt.nodes[0] != nil {
+		for i, node := range qt.nodes {
+			n.nodes[i] = node.copy(n, s, points)
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,61 @@
+package quadtree
+
+// Count returns the number of points within the bounding box. Each node
+// keeps count of the points beneath it as they are inserted and removed,
+// so nodes wholly within the box are counted without visiting their
+// points. Like Len it includes soft removed and expired points.
+func (qt *QuadTree) Count(a *AABB) int {
+	if noBox(a) {
+		return 0
+	}
+
+	qt.state.acquire()
+	defer qt.state.release()
+
+	t := qt.begin()
+
+	n := 0
+	if boxes, ok := qt.state.wrap(a); ok {
+		for _, b := range boxes {
+			n += qt.countIn(b)
+		}
+	} else {
+		n = qt.countIn(a)
+	}
+
+	qt.end(t, "count", a, 0)
+	return n
+}
+
+func (qt *QuadTree) countIn(a *AABB) int {
+	if !qt.boundary.Intersect(a) {
+		return 0
+	}
+	if a.contains(qt.boundary) {
+		return qt.total
+	}
+
+	n := 0
+	for _, p := range qt.leaf() {
+		if a.ContainsPoint(p) {
+			n++
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return n
+	}
+
+	for _, node := range qt.nodes {
+		n += node.countIn(a)
+	}
+	return n
+}
+
+// counted adds n to the count of points beneath the node and each of its
+// ancestors.
+func (qt *QuadTree) counted(n int) {
+	for node := qt; node != nil; node = node.parent {
+		node.total += n
+	}
+}
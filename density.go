@@ -0,0 +1,92 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// Density counts the points within the bounding box on a grid of rows by
+// cols cells for rendering heatmaps. Rows divide the box along x and
+// columns along y, counts[0][0] being the cell at the low x and low y
+// corner. A node lying within a single cell is counted as a whole by its
+// counter as for Count, so soft removed and expired points are counted too.
+func (qt *QuadTree) Density(a *AABB, cols, rows int) [][]int {
+	if noBox(a) || cols <= 0 || rows <= 0 {
+		return [][]int{}
+	}
+
+	counts := make([][]int, rows)
+	cells := make([]int, rows*cols)
+	for r := range counts {
+		counts[r] = cells[r*cols : (r+1)*cols]
+	}
+
+	qt.density(a, newGrid(a, cols, rows), counts)
+	return counts
+}
+
+// grid maps coordinates within a box to the cells of a Density grid.
+type grid struct {
+	minX, minY   float64
+	sizeX, sizeY float64
+	rows, cols   int
+}
+
+func newGrid(a *AABB, cols, rows int) *grid {
+	return &grid{
+		minX:  a.center.x - a.half.x,
+		minY:  a.center.y - a.half.y,
+		sizeX: 2 * a.half.x / float64(rows),
+		sizeY: 2 * a.half.y / float64(cols),
+		rows:  rows,
+		cols:  cols,
+	}
+}
+
+// cell returns the row and column of the cell holding p, the last row or
+// column for points on the high edges of the box.
+func (g *grid) cell(p *Point) (int, int) {
+	index := func(v, lo, size float64, n int) int {
+		if size == 0 {
+			return 0
+		}
+		return max(0, min(n-1, int(math.Floor((v-lo)/size))))
+	}
+	return index(p.x, g.minX, g.sizeX, g.rows), index(p.y, g.minY, g.sizeY, g.cols)
+}
+
+// within returns the cell holding the whole of b if b lies within the box
+// a of the grid and a single one of its cells.
+func (g *grid) within(a, b *AABB) (int, int, bool) {
+	if !a.contains(b) {
+		return 0, 0, false
+	}
+	r0, c0 := g.cell(&Point{x: b.center.x - b.half.x, y: b.center.y - b.half.y})
+	r1, c1 := g.cell(&Point{x: b.center.x + b.half.x, y: b.center.y + b.half.y})
+	return r0, c0, r0 == r1 && c0 == c1
+}
+
+func (qt *QuadTree) density(a *AABB, g *grid, counts [][]int) {
+	if !qt.boundary.Intersect(a) {
+		return
+	}
+
+	if r, c, ok := g.within(a, qt.boundary); ok {
+		counts[r][c] += qt.total
+		return
+	}
+
+	for _, p := range qt.leaf() {
+		if a.ContainsPoint(p) {
+			r, c := g.cell(p)
+			counts[r][c]++
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.density(a, g, counts)
+	}
+}
@@ -0,0 +1,103 @@
+package quadtree
+
+import (
+	"iter"
+	"reflect"
+	"sort"
+)
+
+// ChangeType is the kind of change between two trees.
+type ChangeType int
+
+const (
+	// Added points are only in the new tree
+	Added ChangeType = iota
+	// Removed points are only in the old tree
+	Removed
+	// Moved points changed location
+	Moved
+	// Changed points kept their location but changed data
+	Changed
+)
+
+// ChangeEvent describes the change of a point between two trees. Old is
+// nil for an added point and New is nil for a removed one.
+type ChangeEvent struct {
+	Type ChangeType
+	Old  *Point
+	New  *Point
+}
+
+// diffKey identifies a point across trees by ID, or by identity for
+// points without one.
+type diffKey struct {
+	id    string
+	point *Point
+}
+
+func keyOf(p *Point) diffKey {
+	if p.id != "" {
+		return diffKey{id: p.id}
+	}
+	return diffKey{point: p}
+}
+
+// DiffStream returns an iterator over the point level changes between two
+// trees, e.g. snapshots before and after a bulk reload. Points are matched
+// by ID, or by identity if they have none. Events are ordered by region,
+// along a Z-order curve over the new tree's boundary, so consumers can
+// invalidate tiles and caches region by region.
+func DiffStream(old, new *QuadTree) iter.Seq[ChangeEvent] {
+	return func(yield func(ChangeEvent) bool) {
+		before := make(map[diffKey]*Point)
+		for _, p := range old.search(old.boundary, &query{removed: true}) {
+			before[keyOf(p)] = p
+		}
+
+		var events []ChangeEvent
+
+		for _, p := range new.search(new.boundary, &query{removed: true}) {
+			k := keyOf(p)
+			op, ok := before[k]
+			delete(before, k)
+
+			switch {
+			case !ok:
+				events = append(events, ChangeEvent{Added, nil, p})
+			case op.x != p.x || op.y != p.y:
+				events = append(events, ChangeEvent{Moved, op, p})
+			case !reflect.DeepEqual(op.data, p.data):
+				events = append(events, ChangeEvent{Changed, op, p})
+			}
+		}
+
+		for _, op := range before {
+			events = append(events, ChangeEvent{Removed, op, nil})
+		}
+
+		codes := make(map[*Point]uint64, len(events))
+		at := func(e ChangeEvent) *Point {
+			if e.New != nil {
+				return e.New
+			}
+			return e.Old
+		}
+		for _, e := range events {
+			codes[at(e)] = morton(new.boundary, at(e))
+		}
+
+		sort.SliceStable(events, func(i, j int) bool {
+			ci, cj := codes[at(events[i])], codes[at(events[j])]
+			if ci != cj {
+				return ci < cj
+			}
+			return at(events[i]).seq < at(events[j]).seq
+		})
+
+		for _, e := range events {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
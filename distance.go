@@ -0,0 +1,159 @@
+package quadtree
+
+import (
+	"math"
+	"sort"
+)
+
+// meanRadius is the mean radius of the Earth [m]
+const meanRadius = 6371008.8
+
+// Distance returns the great-circle distance in metres between two points
+// holding latitude and longitude in degrees, using the haversine formula.
+func Distance(p, q *Point) float64 {
+	lat1 := deg2Rad(p.x)
+	lat2 := deg2Rad(q.x)
+	dlat := lat2 - lat1
+	dlng := deg2Rad(q.y - p.y)
+
+	h := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dlng/2)*math.Sin(dlng/2)
+
+	return 2 * meanRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// DistanceMatrix returns the pairwise distances between the points within
+// the axis aligned bounding box, along with the points in matrix order.
+// Distances are those of the tree's distance function if it was created
+// using WithDistance, WithHaversine or WithCoordinateSystem, and otherwise
+// great-circle metres as for WGS84. At most maxPoints points are used, or
+// all of them if maxPoints is 0 or less.
+func (qt *QuadTree) DistanceMatrix(a *AABB, maxPoints int) ([][]float64, []*Point) {
+	dist := qt.state.distance
+	if dist == nil {
+		dist = qt.state.coordinates().Distance
+	}
+
+	points := qt.Search(a)
+	if maxPoints > 0 && len(points) > maxPoints {
+		points = points[:maxPoints]
+	}
+
+	n := len(points)
+	cells := make([]float64, n*n)
+	matrix := make([][]float64, n)
+
+	for i := range matrix {
+		matrix[i] = cells[i*n : (i+1)*n]
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			d := dist(points[i], points[j])
+			matrix[i][j] = d
+			matrix[j][i] = d
+		}
+	}
+
+	return matrix, points
+}
+
+// DistanceFunc returns the distance between two points.
+type DistanceFunc func(p, q *Point) float64
+
+// WithDistance orders the results of KNearest by the distance function
+// rather than planar distance in degrees. Nearest searches prune nodes by
+// the distance to the closest point of their boundary, so the function
+// must not decrease as points move further away in coordinates.
+func WithDistance(fn DistanceFunc) Option {
+	return func(s *state) {
+		s.distance = fn
+	}
+}
+
+// WithHaversine orders the results of KNearest by great-circle distance,
+// which unlike planar distance in degrees remains correct away from the
+// equator.
+func WithHaversine() Option {
+	return func(s *state) {
+		s.distance = Distance
+		s.bound = geoMinDist
+	}
+}
+
+// distance returns the distance between two points used to order results.
+func (qt *QuadTree) distance() DistanceFunc {
+	if qt.state.distance == nil {
+		return planar
+	}
+	return qt.state.distance
+}
+
+// bound returns a lower bound of the distance from p to any point within
+// the bounding box, consistent with distance.
+func (qt *QuadTree) bound(p *Point, a *AABB) float64 {
+	if qt.state.bound != nil {
+		return qt.state.bound(p, a)
+	}
+	if qt.state.distance == nil {
+		return a.minDist(p)
+	}
+	return qt.distance()(p, a.closest(p))
+}
+
+// geoMinDist returns the great-circle distance in metres from p to the
+// nearest point of the bounding box, holding latitude and longitude in
+// degrees. Unlike the distance to the closest point in coordinates it
+// accounts for the antimeridian and meridians converging at the poles.
+func geoMinDist(p *Point, a *AABB) float64 {
+	minLat, maxLat := a.center.x-a.half.x, a.center.x+a.half.x
+	minLng, maxLng := a.center.y-a.half.y, a.center.y+a.half.y
+
+	if a.half.y >= 180 {
+		return Distance(p, &Point{x: math.Max(minLat, math.Min(p.x, maxLat)), y: p.y})
+	}
+
+	// longitude offsets east of each edge in [0, 360)
+	west := math.Mod(math.Mod(p.y-minLng, 360)+360, 360)
+	if west <= maxLng-minLng {
+		return Distance(p, &Point{x: math.Max(minLat, math.Min(p.x, maxLat)), y: p.y})
+	}
+	east := math.Mod(math.Mod(p.y-maxLng, 360)+360, 360)
+
+	// nearest edge meridian and the longitude difference to it
+	edge, dlng := maxLng, east
+	if 360-west < east {
+		edge, dlng = minLng, 360-west
+	}
+
+	if dlng >= 90 {
+		return math.Min(
+			Distance(p, &Point{x: minLat, y: edge}),
+			Distance(p, &Point{x: maxLat, y: edge}),
+		)
+	}
+
+	// latitude of the point of the meridian nearest p
+	lat := rad2Deg(math.Atan(math.Tan(deg2Rad(p.x)) / math.Cos(deg2Rad(dlng))))
+	return Distance(p, &Point{x: math.Max(minLat, math.Min(lat, maxLat)), y: edge})
+}
+
+// sortPoints orders points by distance from c, breaking ties by the order
+// in which the points were first inserted.
+func sortPoints(points []*Point, c *Point, dist DistanceFunc) {
+	sort.SliceStable(points, func(i, j int) bool {
+		di := dist(points[i], c)
+		dj := dist(points[j], c)
+		if di != dj {
+			return di < dj
+		}
+		return points[i].seq < points[j].seq
+	})
+}
+
+// planar returns the squared planar distance between two points.
+func planar(p, q *Point) float64 {
+	dx := p.x - q.x
+	dy := p.y - q.y
+	return dx*dx + dy*dy
+}
@@ -0,0 +1,12 @@
+// Package quadtree is a quadtree of points for spatial search, indexed by
+// latitude and longitude unless created with another coordinate system.
+//
+// Queries share a contract for arguments which cannot match a point. A
+// nil bounding box, center or point, a k of zero or less, and a box wholly
+// outside the root all return an empty slice rather than nil, and Count
+// and the other queries returning a number return 0. Results are never
+// nil, so they encode as an empty JSON array. Writes of a nil point fail.
+// The Strict variants of Search and KNearest instead return
+// ErrInvalidAABB for a nil or invalid box, and queries returning an error
+// return nil with it.
+package quadtree
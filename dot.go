@@ -0,0 +1,50 @@
+package quadtree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// DOT writes the structure of the tree as a Graphviz graph, each node
+// labelled with its depth, the number of points beneath it and its
+// boundary, e.g. to see why a region subdivides more than expected.
+// Leaves are drawn as boxes, filled if they overflow the capacity, which
+// only leaves at the max depth can since they no longer divide.
+//
+//	dot -Tsvg tree.dot > tree.svg
+func (qt *QuadTree) DOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph quadtree {")
+	fmt.Fprintln(bw, `	node [fontname="monospace" fontsize=10];`)
+
+	id := 0
+	var write func(node *QuadTree) int
+	write = func(node *QuadTree) int {
+		n := id
+		id++
+
+		label := fmt.Sprintf("depth %d\\n%d points\\n%s", node.depth, node.total, boxString(node.boundary))
+
+		attrs := "shape=ellipse"
+		if node.nodes[0] == nil {
+			attrs = "shape=box"
+			if len(node.leaf()) > node.state.capacity {
+				attrs += ` style=filled fillcolor="#ffcccc"`
+			}
+		}
+		fmt.Fprintf(bw, "\tn%d [label=\"%s\" %s];\n", n, label, attrs)
+
+		if node.nodes[0] != nil {
+			for _, child := range node.nodes {
+				fmt.Fprintf(bw, "\tn%d -> n%d;\n", n, write(child))
+			}
+		}
+		return n
+	}
+	write(qt)
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
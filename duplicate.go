@@ -0,0 +1,70 @@
+package quadtree
+
+// DuplicatePolicy controls how Insert treats a point at the coordinates of
+// a point already in the tree.
+type DuplicatePolicy int
+
+const (
+	// DuplicateAllow permits several points at the same coordinates, all
+	// held by one leaf once the tree can divide no further.
+	DuplicateAllow DuplicatePolicy = iota
+	// DuplicateReject fails the insert of a point at the coordinates of
+	// another.
+	DuplicateReject
+	// DuplicateReplace removes the point at the coordinates before
+	// inserting, upserting by location as IDReplace does by ID.
+	DuplicateReplace
+)
+
+// WithDuplicates sets the policy for inserting a point at the coordinates
+// of another, defaulting to DuplicateAllow.
+func WithDuplicates(policy DuplicatePolicy) Option {
+	return func(s *state) {
+		s.duplicates = policy
+	}
+}
+
+// checkDuplicate applies the duplicate policy to a point about to be
+// inserted, given the point it replaces by ID if any. It returns the point
+// to be replaced, or ErrDuplicatePoint if the insert must not proceed.
+func (qt *QuadTree) checkDuplicate(p *Point, old *Point) (*Point, error) {
+	if qt.state.duplicates == DuplicateAllow {
+		return old, nil
+	}
+
+	dup := qt.root().occupant(p)
+	if dup == nil || dup == old {
+		return old, nil
+	}
+
+	// a point replacing another by ID cannot replace a second by location
+	if qt.state.duplicates == DuplicateReject || old != nil {
+		return nil, ErrDuplicatePoint
+	}
+	return dup, nil
+}
+
+// occupant returns a point other than p at its coordinates, or nil if
+// there is none. A point moved by Update onto the edge between nodes stays
+// in the node it was in rather than the one insert descends to, so every
+// leaf containing the coordinates is searched.
+func (qt *QuadTree) occupant(p *Point) *Point {
+	if qt.nodes[0] != nil {
+		for _, child := range qt.nodes {
+			if !child.boundary.ContainsPoint(p) {
+				continue
+			}
+			if ep := child.occupant(p); ep != nil {
+				return ep
+			}
+		}
+		return nil
+	}
+
+	for _, ep := range qt.scan(&AABB{p, &Point{}}) {
+		if ep != p && ep.x == p.x && ep.y == p.y {
+			return ep
+		}
+	}
+	return nil
+}
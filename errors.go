@@ -0,0 +1,63 @@
+package quadtree
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNilBoundary is returned when a tree is created without a boundary.
+	ErrNilBoundary = errors.New("quadtree: nil boundary")
+	// ErrInvalidAABB is returned for a bounding box which is missing its
+	// center or half point, is not finite, or has non positive half
+	// extents, e.g. one built from inverted corners.
+	ErrInvalidAABB = errors.New("quadtree: invalid bounding box")
+	// ErrNilPoint is returned when a nil point is passed to the tree.
+	ErrNilPoint = errors.New("quadtree: nil point")
+	// ErrOutOfBounds is returned when a point lies outside the boundary
+	// of the tree.
+	ErrOutOfBounds = errors.New("quadtree: point out of bounds")
+	// ErrDuplicateID is returned when a point's ID is already in use by
+	// a tree created using WithUniqueIDs(IDReject).
+	ErrDuplicateID = errors.New("quadtree: duplicate point id")
+	// ErrDuplicatePoint is returned when a point is at the coordinates of
+	// another in a tree created using WithDuplicates(DuplicateReject).
+	ErrDuplicatePoint = errors.New("quadtree: duplicate point coordinates")
+	// ErrQuotaExceeded is returned when inserting a point would take the
+	// tree over its WithMaxBytes limit.
+	ErrQuotaExceeded = errors.New("quadtree: byte quota exceeded")
+	// ErrRateLimited is returned when a write exceeds the WithWriteRate
+	// limit of the tree.
+	ErrRateLimited = errors.New("quadtree: write rate exceeded")
+	// ErrTooManyQueries is returned when a query would exceed the
+	// WithMaxConcurrentQueries limit of the tree.
+	ErrTooManyQueries = errors.New("quadtree: too many concurrent queries")
+	// ErrNotFound is returned when a point is not in the tree.
+	ErrNotFound = errors.New("quadtree: point not found")
+	// ErrVersionConflict is returned when a conditional write finds the
+	// point at a different version than expected.
+	ErrVersionConflict = errors.New("quadtree: version conflict")
+	// ErrTruncated is returned along with the partial results of a query
+	// which exceeded its WithMaxResultsBytes budget.
+	ErrTruncated = errors.New("quadtree: results truncated")
+	// ErrInvalidSnapshot is returned when decoding a malformed snapshot.
+	ErrInvalidSnapshot = errors.New("quadtree: invalid snapshot")
+	// ErrRegionLocked is returned when writing to a point within a region
+	// locked by LockRegion.
+	ErrRegionLocked = errors.New("quadtree: region locked")
+	// ErrNoAggregates is returned by aggregate exports of a tree created
+	// without WithAggregates.
+	ErrNoAggregates = errors.New("quadtree: aggregates not enabled")
+	// ErrReadOnly is returned when writing to a tree returned by Snapshot.
+	ErrReadOnly = errors.New("quadtree: read only tree")
+	// ErrSnapshotVersion is returned when decoding a snapshot written by
+	// another format version.
+	ErrSnapshotVersion = errors.New("quadtree: unsupported snapshot version")
+	// ErrNoID is returned when applying a change to a point without an
+	// ID, which cannot be found in a replica.
+	ErrNoID = errors.New("quadtree: point has no id")
+	// ErrResolverMismatch is returned when a Resolver returns a different
+	// number of payloads than the IDs it was given.
+	ErrResolverMismatch = errors.New("quadtree: resolver returned wrong number of payloads")
+	// ErrInvalidTree is wrapped by the errors of Validate.
+	ErrInvalidTree = errors.New("quadtree: invalid tree")
+)
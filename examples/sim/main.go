@@ -0,0 +1,117 @@
+// Command sim simulates agents moving around a tree, updating their
+// positions and querying their nearest neighbours every tick, and reports
+// the throughput of each. It exits non-zero if any agent is lost from the
+// tree, making it a quick check of Update and RInsert under load.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/asim/quadtree"
+)
+
+var (
+	agents  = flag.Int("agents", 10000, "number of moving agents")
+	ticks   = flag.Int("ticks", 100, "number of ticks to simulate")
+	k       = flag.Int("k", 8, "neighbours queried per agent each tick")
+	queries = flag.Float64("queries", 0.1, "fraction of agents querying each tick")
+	radius  = flag.Float64("radius", 50000, "neighbour search radius in metres")
+	speed   = flag.Float64("speed", 0.05, "maximum agent speed in degrees per tick")
+	seed    = flag.Int64("seed", 1, "random seed")
+)
+
+type agent struct {
+	point  *quadtree.Point
+	dx, dy float64
+}
+
+func main() {
+	flag.Parse()
+
+	rnd := rand.New(rand.NewSource(*seed))
+	world := quadtree.WorldBounds()
+	tree := quadtree.New(world, 0, nil)
+
+	all := make([]*agent, *agents)
+	for i := range all {
+		a := &agent{
+			point: quadtree.NewPoint(rnd.Float64()*180-90, rnd.Float64()*360-180, i),
+			dx:    (rnd.Float64()*2 - 1) * *speed,
+			dy:    (rnd.Float64()*2 - 1) * *speed,
+		}
+		if !tree.Insert(a.point) {
+			fmt.Fprintf(os.Stderr, "failed to insert agent %d\n", i)
+			os.Exit(1)
+		}
+		all[i] = a
+	}
+
+	var updates, searches, found int
+	var updateTime, searchTime time.Duration
+
+	for t := 0; t < *ticks; t++ {
+		start := time.Now()
+		for _, a := range all {
+			x, y := a.point.Coordinates()
+			x, a.dx = bounce(x+a.dx, a.dx, 90)
+			y, a.dy = bounce(y+a.dy, a.dy, 180)
+
+			if !tree.Update(a.point, quadtree.NewPoint(x, y, nil)) {
+				fmt.Fprintf(os.Stderr, "tick %d: failed to update agent %v\n", t, a.point.Data())
+				os.Exit(1)
+			}
+			updates++
+		}
+		updateTime += time.Since(start)
+
+		start = time.Now()
+		for _, a := range all {
+			if rnd.Float64() >= *queries {
+				continue
+			}
+			box := quadtree.NewAABB(a.point, a.point.HalfPoint(*radius))
+			found += len(tree.KNearest(box, *k, nil))
+			searches++
+		}
+		searchTime += time.Since(start)
+	}
+
+	if n := len(tree.Search(world)); n != *agents {
+		fmt.Fprintf(os.Stderr, "tree holds %d points, want %d\n", n, *agents)
+		os.Exit(1)
+	}
+
+	fmt.Printf("agents:   %d\n", *agents)
+	fmt.Printf("ticks:    %d\n", *ticks)
+	fmt.Printf("updates:  %d (%.0f/s)\n", updates, rate(updates, updateTime))
+	fmt.Printf("queries:  %d (%.0f/s, %.1f neighbours avg)\n", searches, rate(searches, searchTime), avg(found, searches))
+}
+
+// bounce reflects a coordinate moving by d off the limits ±limit.
+func bounce(v, d, limit float64) (float64, float64) {
+	if v > limit {
+		return 2*limit - v, -d
+	}
+	if v < -limit {
+		return -2*limit - v, -d
+	}
+	return v, d
+}
+
+func rate(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
+
+func avg(n, of int) float64 {
+	if of == 0 {
+		return 0
+	}
+	return float64(n) / float64(of)
+}
@@ -0,0 +1,32 @@
+package quadtree
+
+// Excluding leaves out points within any of the bounding boxes, e.g. a
+// restricted area within a city. Nodes entirely within an excluded box
+// are not visited. Nil boxes exclude nothing.
+func Excluding(boxes ...*AABB) QueryOption {
+	return func(q *query) {
+		for _, b := range boxes {
+			if !noBox(b) {
+				q.exclude = append(q.exclude, b)
+			}
+		}
+	}
+}
+
+// contains reports whether the bounding box b lies entirely within a.
+func (a *AABB) contains(b *AABB) bool {
+	return b.center.x-b.half.x >= a.center.x-a.half.x &&
+		b.center.y-b.half.y >= a.center.y-a.half.y &&
+		b.center.x+b.half.x <= a.center.x+a.half.x &&
+		b.center.y+b.half.y <= a.center.y+a.half.y
+}
+
+// excludes reports whether every point within b is excluded by the query.
+func (q *query) excludes(b *AABB) bool {
+	for _, e := range q.exclude {
+		if e.contains(b) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// Expanding makes KNearest treat its bounding box as a starting region
+// around the query point, doubling it until k points are found or it
+// covers the whole tree, then growing it once more to cover the distance
+// of the kth point so no nearer point is missed. Distances of trees
+// created using WithDistance are taken to be metres, and those created
+// using WithCoordinateSystem are in its units. Ranked by WithCost,
+// the first region holding k points is used.
+func Expanding() QueryOption {
+	return func(q *query) {
+		q.expand = true
+	}
+}
+
+// expanding appends the results of an Expanding KNearest query to dst,
+// reusing the space following dst for those of each region.
+func (qt *QuadTree) expanding(dst []*Point, a *AABB, i int, fn filter, q *query) []*Point {
+	root := qt.root().boundary
+
+	// the smallest leaf of the tree bounds a degenerate starting box
+	scale := math.Ldexp(1, -qt.state.maxDepth)
+	half := &Point{
+		x: math.Max(a.half.x, root.half.x*scale),
+		y: math.Max(a.half.y, root.half.y*scale),
+	}
+	box := &AABB{a.center, half}
+
+	for {
+		results := qt.kNearestIn(dst, box, i, fn, q)
+		found := len(results) - len(dst)
+
+		if box.contains(root) || q.cancelled {
+			return results
+		}
+
+		if found >= i && (i <= 0 || q.cost != nil || q.rank != nil) {
+			return results
+		}
+
+		if found >= i {
+			// rank every point within reach of the furthest found
+			r := qt.reach(a.center, results[len(results)-1], q)
+			return qt.kNearestIn(dst, &AABB{a.center, r}, i, fn, q)
+		}
+
+		box = &AABB{a.center, &Point{x: half.x * 2, y: half.y * 2}}
+		half = box.half
+	}
+}
+
+// reach returns the half extents of a box around center holding every
+// point nearer to it than p.
+func (qt *QuadTree) reach(center, p *Point, q *query) *Point {
+	if qt.state.distance == nil && !q.local {
+		d := math.Sqrt(planar(center, p))
+		return &Point{x: d, y: d}
+	}
+
+	if qt.state.coords != nil && !q.local {
+		cs := qt.state.coords
+		return cs.Half(center, cs.Distance(center, p))
+	}
+
+	meters := Distance(center, p)
+	if qt.state.distance != nil && !q.local {
+		meters = qt.state.distance(center, p)
+	}
+
+	return radiusHalf(center, meters)
+}
+
+// radiusHalf returns the half extents in degrees of a box around center
+// holding every point within meters of it, widening the longitude at the
+// latitude furthest from the equator the box reaches.
+func radiusHalf(center *Point, meters float64) *Point {
+	dlat := rad2Deg(meters / meanRadius)
+	lat := math.Abs(center.x) + dlat
+	if lat >= 90 {
+		return &Point{x: dlat, y: 180}
+	}
+	dlng := rad2Deg(meters / (meanRadius * math.Cos(deg2Rad(lat))))
+	return &Point{x: dlat, y: math.Min(dlng, 180)}
+}
@@ -0,0 +1,82 @@
+package quadtree
+
+// Reason explains why a candidate point was not returned by a query.
+type Reason int
+
+const (
+	// ReasonReturned is given for points in the results.
+	ReasonReturned Reason = iota
+	// ReasonOutside is given for points outside the query box.
+	ReasonOutside
+	// ReasonFiltered is given for points rejected by the filter function
+	// or the conditions of the query.
+	ReasonFiltered
+	// ReasonOutranked is given for points scored worse than the k'th
+	// result.
+	ReasonOutranked
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonReturned:
+		return "returned"
+	case ReasonOutside:
+		return "outside"
+	case ReasonFiltered:
+		return "filtered"
+	case ReasonOutranked:
+		return "outranked"
+	}
+	return "unknown"
+}
+
+// Explanation describes how a candidate point was ranked by KBest, or by
+// KNearest using WithCost. Distance and Score are zero for points which
+// were never scored.
+type Explanation struct {
+	Point *Point
+	// distance in metres from the center
+	Distance float64
+	Score    float64
+	// position in the results, -1 if the point was not returned
+	Rank   int
+	Reason Reason
+}
+
+// Explain calls fn with the explanation of every candidate point a ranked
+// query considered, in the order they were visited, once the query has
+// completed. Points in nodes pruned by the search are not considered.
+func Explain(fn func(Explanation)) QueryOption {
+	return func(q *query) {
+		q.explain = fn
+	}
+}
+
+// explainer collects explanations while a query runs. A nil explainer
+// discards them.
+type explainer struct {
+	entries []Explanation
+}
+
+func (e *explainer) add(p *Point, dist, score float64, reason Reason) {
+	if e == nil {
+		return
+	}
+	e.entries = append(e.entries, Explanation{p, dist, score, -1, reason})
+}
+
+// flush ranks the explanations of the results and passes each to fn.
+func (e *explainer) flush(best []scored, fn func(Explanation)) {
+	rank := make(map[*Point]int, len(best))
+	for i, b := range best {
+		rank[b.point] = i
+	}
+
+	for _, x := range e.entries {
+		if i, ok := rank[x.Point]; ok {
+			x.Rank = i
+			x.Reason = ReasonReturned
+		}
+		fn(x)
+	}
+}
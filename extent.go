@@ -0,0 +1,139 @@
+package quadtree
+
+// Extent is an item with a bounding box stored in the tree, e.g. the
+// bounds of a polygon or way. Extents are held by the smallest node whose
+// boundary contains them, so those straddling the edges of children stay
+// at internal nodes.
+type Extent struct {
+	box  *AABB
+	data interface{}
+}
+
+// Bounds returns the bounding box of the extent.
+func (e *Extent) Bounds() *AABB {
+	return e.box
+}
+
+// Data returns the data stored within the extent.
+func (e *Extent) Data() interface{} {
+	return e.data
+}
+
+// InsertAABB inserts an item covering the bounding box, returning the
+// extent stored or nil if the box is invalid or not within the boundary
+// of the tree. Extents are kept apart from points: they are returned by
+// SearchExtents rather than Search, are not counted by Len and are not
+// written to snapshots.
+func (qt *QuadTree) InsertAABB(box *AABB, data interface{}) *Extent {
+	if box.Validate() != nil || !qt.boundary.contains(box) {
+		return nil
+	}
+	if !qt.state.allowWrite() {
+		return nil
+	}
+
+	e := &Extent{box, data}
+	qt.place(e)
+	return e
+}
+
+// place stores the extent in the smallest node containing it, dividing
+// leaves holding more than capacity extents.
+func (qt *QuadTree) place(e *Extent) {
+	node := qt
+	for {
+		if node.nodes[0] == nil {
+			if len(node.extents) < node.state.capacity || node.depth >= node.state.maxDepth {
+				node.extents = append(node.extents, e)
+				return
+			}
+			node.divide()
+		}
+
+		next := node.child(e.box)
+		if next == nil {
+			node.extents = append(node.extents, e)
+			return
+		}
+		node = next
+	}
+}
+
+// child returns the child node containing the box, to be written, or nil
+// if none does.
+func (qt *QuadTree) child(box *AABB) *QuadTree {
+	for i, node := range qt.nodes {
+		if node.boundary.contains(box) {
+			return qt.own(i)
+		}
+	}
+	return nil
+}
+
+// pushExtents moves the extents of a node which has been divided into the
+// children containing them.
+func (qt *QuadTree) pushExtents() {
+	extents := qt.extents
+	qt.extents = nil
+	for _, e := range extents {
+		if node := qt.child(e.box); node != nil {
+			node.place(e)
+			continue
+		}
+		qt.extents = append(qt.extents, e)
+	}
+}
+
+// RemoveExtent removes an extent previously inserted into the tree.
+func (qt *QuadTree) RemoveExtent(e *Extent) bool {
+	if e == nil || !qt.state.allowWrite() {
+		return false
+	}
+
+	for node := qt; node != nil; {
+		for i, ee := range node.extents {
+			if ee == e {
+				node.extents = append(node.extents[:i], node.extents[i+1:]...)
+				return true
+			}
+		}
+
+		if node.nodes[0] == nil {
+			return false
+		}
+		node = node.child(e.box)
+	}
+
+	return false
+}
+
+// SearchExtents returns the extents intersecting the bounding box.
+func (qt *QuadTree) SearchExtents(a *AABB) []*Extent {
+	results := []*Extent{}
+	if noBox(a) {
+		return results
+	}
+
+	qt.searchExtents(a, &results)
+	return results
+}
+
+func (qt *QuadTree) searchExtents(a *AABB, results *[]*Extent) {
+	if !qt.boundary.Intersect(a) {
+		return
+	}
+
+	for _, e := range qt.extents {
+		if e.box.Intersect(a) {
+			*results = append(*results, e)
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.searchExtents(a, results)
+	}
+}
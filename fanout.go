@@ -0,0 +1,216 @@
+package quadtree
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// ShardStats holds the timing of the KNearest queries of a region of a
+// ConcurrentQuadTree, for tuning its grid and the delay of Hedged.
+type ShardStats struct {
+	// Boundary is the boundary of the region
+	Boundary *AABB
+	// Queries is the number of queries which searched the region, Pruned
+	// the number which skipped it having found k points nearer than any
+	// it could hold, and Hedged the number which searched it again as it
+	// was slow to answer
+	Queries, Pruned, Hedged uint64
+	// Total and Max are the total and longest time queries took to search
+	// the region, including waiting for its lock
+	Total, Max time.Duration
+}
+
+// shardStats counts the KNearest queries of a region.
+type shardStats struct {
+	queries, pruned, hedged atomic.Uint64
+	total, max              atomic.Int64
+}
+
+func (s *shardStats) observe(d time.Duration) {
+	s.queries.Add(1)
+	s.total.Add(int64(d))
+	for {
+		m := s.max.Load()
+		if int64(d) <= m || s.max.CompareAndSwap(m, int64(d)) {
+			return
+		}
+	}
+}
+
+// ShardStats returns the timing of the KNearest queries of each region,
+// in the order of the grid from its minimum x and y, along x first.
+func (ct *ConcurrentQuadTree) ShardStats() []ShardStats {
+	stats := make([]ShardStats, len(ct.regions))
+	for i, r := range ct.regions {
+		stats[i] = ShardStats{
+			Boundary: r.tree.boundary,
+			Queries:  r.stats.queries.Load(),
+			Pruned:   r.stats.pruned.Load(),
+			Hedged:   r.stats.hedged.Load(),
+			Total:    time.Duration(r.stats.total.Load()),
+			Max:      time.Duration(r.stats.max.Load()),
+		}
+	}
+	return stats
+}
+
+// Hedged searches a region of a ConcurrentQuadTree again if it has not
+// answered a KNearest query after the delay, using whichever search
+// answers first, so one slow search does not hold up the query. The
+// filter may be called twice for the points of a hedged region.
+func Hedged(after time.Duration) QueryOption {
+	return func(q *query) {
+		q.hedge = after
+	}
+}
+
+// shard is a region searched by a query, with the least distance from the
+// query center of any point it could hold.
+type shard struct {
+	*region
+	bound float64
+}
+
+// answer is the results of searching a region, or the panic of a callback
+// while searching it.
+type answer struct {
+	results []merged
+	err     error
+}
+
+// fanOut searches the regions intersecting the box nearest its center
+// first, up to GOMAXPROCS at once, returning the results ordered by rank
+// and distinct if the query asks. If prune, ranks are distances from the
+// center and regions are left unsearched once k results are nearer than
+// any point they could hold.
+func (ct *ConcurrentQuadTree) fanOut(a *AABB, k int, q *query, prune bool, fn func(*QuadTree) []*Point, rank func(*Point) float64) ([]merged, error) {
+	var shards []shard
+	for _, r := range ct.regions {
+		if r.tree.boundary.Intersect(a) {
+			shards = append(shards, shard{r, r.tree.bound(a.center, r.tree.boundary)})
+		}
+	}
+	sort.SliceStable(shards, func(i, j int) bool {
+		return shards[i].bound < shards[j].bound
+	})
+
+	var (
+		results []merged
+		err     error
+	)
+	answers := make(chan answer, len(shards))
+	workers := min(runtime.GOMAXPROCS(0), len(shards))
+
+	for next, running := 0, 0; ; {
+		for ; err == nil && running < workers && next < len(shards); next++ {
+			if prune && kth(results, k) < shards[next].bound {
+				// the regions left are at least as far
+				for _, s := range shards[next:] {
+					s.stats.pruned.Add(1)
+				}
+				next = len(shards)
+				break
+			}
+
+			s := shards[next]
+			running++
+			go func() {
+				answers <- s.search(q, fn, rank)
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		ans := <-answers
+		running--
+		if ans.err != nil {
+			if err == nil {
+				err = ans.err
+			}
+			continue
+		}
+
+		results = append(results, ans.results...)
+		sort.SliceStable(results, func(i, j int) bool {
+			if results[i].rank != results[j].rank {
+				return results[i].rank < results[j].rank
+			}
+			return results[i].seq < results[j].seq
+		})
+		if q.distinct != nil {
+			// a nearer point of a region yet to answer may displace
+			// one of the nearest k, so none are dropped
+			results = distinct(results, nil)
+		} else if len(results) > k {
+			results = results[:k]
+		}
+	}
+
+	return results, err
+}
+
+// kth returns the rank of the kth result, +Inf if there are fewer.
+func kth(results []merged, k int) float64 {
+	if len(results) < k {
+		return math.Inf(1)
+	}
+	return results[k-1].rank
+}
+
+// search searches the region, again after the hedge delay of the query if
+// it has not answered, recording the time taken.
+func (r *region) search(q *query, fn func(*QuadTree) []*Point, rank func(*Point) float64) answer {
+	start := time.Now()
+	defer func() {
+		r.stats.observe(time.Since(start))
+	}()
+
+	if q.hedge <= 0 {
+		return r.collect(q, fn, rank)
+	}
+
+	answers := make(chan answer, 2)
+	go func() {
+		answers <- r.collect(q, fn, rank)
+	}()
+
+	timer := time.NewTimer(q.hedge)
+	defer timer.Stop()
+
+	select {
+	case ans := <-answers:
+		return ans
+	case <-timer.C:
+	}
+
+	r.stats.hedged.Add(1)
+	go func() {
+		answers <- r.collect(q, fn, rank)
+	}()
+	return <-answers
+}
+
+// collect returns the points fn finds in the region under its read lock,
+// ranked and keyed for merging with those of other regions.
+func (r *region) collect(q *query, fn func(*QuadTree) []*Point, rank func(*Point) float64) (ans answer) {
+	defer recovered(&ans.err)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range fn(r.tree) {
+		m := merged{point: p, seq: p.seq, updated: p.updated}
+		if rank != nil {
+			m.rank = rank(p)
+		}
+		if q.distinct != nil {
+			m.key = q.distinct(p)
+		}
+		ans.results = append(ans.results, m)
+	}
+	return ans
+}
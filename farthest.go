@@ -0,0 +1,141 @@
+package quadtree
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// KFarthest returns the k points farthest from the center, farthest first,
+// e.g. to find outliers. Points as far as each other are ordered by when
+// they were inserted. Distances are measured as by KNearest, and nodes
+// which cannot hold a point farther than the k'th found are pruned by
+// their farthest corner, unless the tree measures distance using
+// WithDistance or WithCoordinateSystem, when every point is measured.
+func (qt *QuadTree) KFarthest(center *Point, k int, opts ...QueryOption) []*Point {
+	if center == nil || k <= 0 {
+		return []*Point{}
+	}
+
+	q := qt.newQuery(opts)
+	dist := qt.distance()
+	bound := func(a *AABB) float64 {
+		return math.Inf(1)
+	}
+	if qt.state.distance == nil {
+		bound = func(a *AABB) float64 {
+			dx := math.Abs(center.x-a.center.x) + a.half.x
+			dy := math.Abs(center.y-a.center.y) + a.half.y
+			return dx*dx + dy*dy
+		}
+	}
+
+	// nodes queued farthest bound first, the farthest points found kept
+	// with the nearest of them on top
+	queue := &candidates{{node: qt, dist: -bound(qt.boundary)}}
+	best := &farthest{}
+
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(candidate)
+		if best.Len() == k && -c.dist < -(*best)[0].dist {
+			break
+		}
+
+		c.node.hit()
+
+		for _, p := range c.node.leaf() {
+			if !q.match(p) {
+				continue
+			}
+
+			// negated so the nearest of the farthest is on top
+			e := candidate{point: p, dist: -dist(p, center)}
+			if best.Len() < k {
+				heap.Push(best, e)
+				continue
+			}
+			top := (*best)[0]
+			if e.dist > top.dist || e.dist == top.dist && e.point.seq > top.point.seq {
+				continue
+			}
+			(*best)[0] = e
+			heap.Fix(best, 0)
+		}
+
+		if c.node.nodes[0] == nil {
+			continue
+		}
+
+		for _, node := range c.node.nodes {
+			if node.total > 0 {
+				heap.Push(queue, candidate{node: node, dist: -bound(node.boundary)})
+			}
+		}
+	}
+
+	results := make([]*Point, best.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		results[i] = heap.Pop(best).(candidate).point
+	}
+	return results
+}
+
+// Extent returns the smallest bounding box covering every point of the
+// tree, e.g. to zoom a map to fit the data, whose Min and Max are the
+// least and greatest coordinates along each axis. It reports false for a
+// tree without points. The box of a single point has no extent so is not
+// valid for queries. Subtrees within the extent of the points found so far
+// are skipped.
+func (qt *QuadTree) Extent(opts ...QueryOption) (*AABB, bool) {
+	q := qt.newQuery(opts)
+
+	lo := &Point{x: math.Inf(1), y: math.Inf(1)}
+	hi := &Point{x: math.Inf(-1), y: math.Inf(-1)}
+	qt.extent(q, lo, hi)
+
+	if lo.x > hi.x {
+		return nil, false
+	}
+	return NewAABBFromMinMax(lo, hi), true
+}
+
+func (qt *QuadTree) extent(q *query, lo, hi *Point) {
+	b := qt.boundary
+	if qt.total == 0 {
+		return
+	}
+	if b.center.x-b.half.x >= lo.x && b.center.x+b.half.x <= hi.x &&
+		b.center.y-b.half.y >= lo.y && b.center.y+b.half.y <= hi.y {
+		return
+	}
+
+	for _, p := range qt.leaf() {
+		if !q.match(p) {
+			continue
+		}
+		lo.x, lo.y = math.Min(lo.x, p.x), math.Min(lo.y, p.y)
+		hi.x, hi.y = math.Max(hi.x, p.x), math.Max(hi.y, p.y)
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	// visit the outermost children first to widen the extent soonest
+	nodes := qt.nodes
+	sort.Slice(nodes[:], func(i, j int) bool {
+		return outside(nodes[i].boundary, lo, hi) > outside(nodes[j].boundary, lo, hi)
+	})
+	for _, node := range nodes {
+		node.extent(q, lo, hi)
+	}
+}
+
+// outside returns how far the box reaches beyond the extent lo to hi.
+func outside(a *AABB, lo, hi *Point) float64 {
+	if lo.x > hi.x {
+		return 0
+	}
+	return math.Max(0, lo.x-(a.center.x-a.half.x)) + math.Max(0, a.center.x+a.half.x-hi.x) +
+		math.Max(0, lo.y-(a.center.y-a.half.y)) + math.Max(0, a.center.y+a.half.y-hi.y)
+}
@@ -0,0 +1,154 @@
+package quadtree
+
+import (
+	"math"
+	"sort"
+)
+
+// FrozenTree is an immutable, query optimised copy of a QuadTree. Nodes are
+// stored in a flat array with the four children of a node adjacent, and
+// the points of every subtree are contiguous so fully covered subtrees are
+// copied in one step. Each node records the tight bounds of the points
+// beneath it which prunes more than the node boundary. The points of each
+// leaf are sorted by x so a search scans only those within the x range of
+// its box. A FrozenTree has no methods which modify it.
+type FrozenTree struct {
+	nodes    []frozenNode
+	points   []*Point
+	distance DistanceFunc
+	// codec encodes point data written by WriteFile
+	codec Codec
+}
+
+type frozenNode struct {
+	// tight bounds of the points in the subtree
+	minX, minY, maxX, maxY float64
+	// index of the first of four children, 0 for a leaf
+	child int32
+	// range of the subtree's points
+	start, end int32
+}
+
+// Freeze compacts the tree into a *FrozenTree. The tree may continue to be
+// mutated but changes are not reflected in the frozen copy. Point data is
+// shared between the two. Points hidden by SoftRemove are left out.
+func (qt *QuadTree) Freeze() *FrozenTree {
+	ft := &FrozenTree{
+		nodes:    make([]frozenNode, 1),
+		points:   make([]*Point, 0, qt.total),
+		distance: qt.distance(),
+		codec:    qt.state.dataCodec(),
+	}
+	ft.freeze(qt, 0)
+	return ft
+}
+
+func (ft *FrozenTree) freeze(qt *QuadTree, i int) {
+	n := frozenNode{
+		minX:  math.Inf(1),
+		minY:  math.Inf(1),
+		maxX:  math.Inf(-1),
+		maxY:  math.Inf(-1),
+		start: int32(len(ft.points)),
+	}
+
+	for _, p := range qt.leaf() {
+		if p.meta().removed {
+			continue
+		}
+		n.minX, n.maxX = math.Min(n.minX, p.x), math.Max(n.maxX, p.x)
+		n.minY, n.maxY = math.Min(n.minY, p.y), math.Max(n.maxY, p.y)
+		ft.points = append(ft.points, p)
+	}
+
+	if qt.nodes[0] == nil {
+		leaf := ft.points[n.start:]
+		sort.SliceStable(leaf, func(i, j int) bool {
+			return leaf[i].x < leaf[j].x
+		})
+	} else {
+		n.child = int32(len(ft.nodes))
+		ft.nodes = append(ft.nodes, make([]frozenNode, 4)...)
+
+		for j, node := range qt.nodes {
+			ft.freeze(node, int(n.child)+j)
+			c := ft.nodes[int(n.child)+j]
+			n.minX, n.maxX = math.Min(n.minX, c.minX), math.Max(n.maxX, c.maxX)
+			n.minY, n.maxY = math.Min(n.minY, c.minY), math.Max(n.maxY, c.maxY)
+		}
+	}
+
+	n.end = int32(len(ft.points))
+	ft.nodes[i] = n
+}
+
+// Len returns the number of points in the frozen tree.
+func (ft *FrozenTree) Len() int {
+	return len(ft.points)
+}
+
+// Search returns all the points within the axis aligned bounding box.
+func (ft *FrozenTree) Search(a *AABB) []*Point {
+	if noBox(a) {
+		return []*Point{}
+	}
+	return nonNil(ft.search(0, a, nil, nil))
+}
+
+// KNearest returns the k points within the axis aligned bounding box
+// nearest to its center which pass the filter, ordered by distance.
+func (ft *FrozenTree) KNearest(a *AABB, i int, fn filter) []*Point {
+	if noBox(a) || i <= 0 {
+		return []*Point{}
+	}
+
+	results := nonNil(ft.search(0, a, fn, nil))
+	sortPoints(results, a.center, ft.distance)
+	if len(results) > i {
+		results = results[:i]
+	}
+	return results
+}
+
+func (ft *FrozenTree) search(i int, a *AABB, fn filter, results []*Point) []*Point {
+	n := &ft.nodes[i]
+	if n.start == n.end {
+		return results
+	}
+
+	minX, minY := a.center.x-a.half.x, a.center.y-a.half.y
+	maxX, maxY := a.center.x+a.half.x, a.center.y+a.half.y
+
+	if n.maxX < minX || n.maxY < minY || n.minX > maxX || n.minY > maxY {
+		return results
+	}
+
+	// subtree entirely within the box
+	if fn == nil && n.minX >= minX && n.minY >= minY && n.maxX <= maxX && n.maxY <= maxY {
+		return append(results, ft.points[n.start:n.end]...)
+	}
+
+	points := ft.points[n.start:n.end]
+	if n.child != 0 {
+		points = ft.points[n.start:ft.nodes[n.child].start]
+	} else {
+		// the leaf is sorted by x
+		lo := sort.Search(len(points), func(i int) bool { return points[i].x >= minX })
+		hi := sort.Search(len(points), func(i int) bool { return points[i].x > maxX })
+		points = points[lo:hi]
+	}
+
+	for _, p := range points {
+		if a.ContainsPoint(p) && (fn == nil || fn(p)) {
+			results = append(results, p)
+		}
+	}
+
+	if n.child != 0 {
+		for j := int(n.child); j < int(n.child)+4; j++ {
+			results = ft.search(j, a, fn, results)
+		}
+	}
+
+	return results
+}
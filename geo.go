@@ -0,0 +1,32 @@
+package quadtree
+
+// NewGeoPoint creates a point at the latitude and longitude in degrees,
+// the x and y of NewPoint in that order.
+func NewGeoPoint(lat, lng float64, data interface{}) *Point {
+	return &Point{x: lat, y: lng, data: data}
+}
+
+// Lat returns the latitude of a geographic point, its x coordinate.
+func (p *Point) Lat() float64 {
+	return p.x
+}
+
+// Lng returns the longitude of a geographic point, its y coordinate.
+func (p *Point) Lng() float64 {
+	return p.y
+}
+
+// WorldBounds returns the bounding box of the whole world, from -90,-180
+// to 90,180, the boundary of a geographic tree.
+func WorldBounds() *AABB {
+	return &AABB{&Point{x: 0, y: 0}, &Point{x: 90, y: 180}}
+}
+
+// NewGeoAABB returns the bounding box centered on the latitude and
+// longitude holding every point within the radius in metres, e.g. the
+// query box of KNearest. Boxes reaching past the poles cover every
+// longitude.
+func NewGeoAABB(lat, lng, radiusMeters float64) *AABB {
+	center := &Point{x: lat, y: lng}
+	return &AABB{center, radiusHalf(center, radiusMeters)}
+}
@@ -0,0 +1,314 @@
+package quadtree
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Fence is a named region tracked by a Geofence.
+type Fence struct {
+	name     string
+	box      *AABB
+	contains func(p *Point) bool
+}
+
+// Name returns the name of the fence.
+func (f *Fence) Name() string {
+	return f.name
+}
+
+// Bounds returns the bounding box of the fence.
+func (f *Fence) Bounds() *AABB {
+	return f.box
+}
+
+// Contains reports whether the point lies within the fence.
+func (f *Fence) Contains(p *Point) bool {
+	return f.box.ContainsPoint(p) && f.contains(p)
+}
+
+// CircleFence creates a fence holding the points within a great-circle
+// radius in metres of the center.
+func CircleFence(name string, center *Point, meters float64) *Fence {
+	return &Fence{
+		name: name,
+		box:  &AABB{center, radiusHalf(center, meters)},
+		contains: func(p *Point) bool {
+			return Distance(center, p) <= meters
+		},
+	}
+}
+
+// BoxFence creates a fence holding the points within the bounding box.
+func BoxFence(name string, a *AABB) *Fence {
+	return &Fence{
+		name: name,
+		box:  a,
+		contains: func(p *Point) bool {
+			return true
+		},
+	}
+}
+
+// PolygonFence creates a fence holding the points inside the polygon,
+// which may be concave, given by its vertices in order as for
+// SearchPolygon.
+func PolygonFence(name string, poly []*Point) *Fence {
+	poly = append([]*Point(nil), poly...)
+	return &Fence{
+		name: name,
+		box:  polygonBounds(poly),
+		contains: func(p *Point) bool {
+			return pointInPolygon(p, poly)
+		},
+	}
+}
+
+// FenceEventType is the kind of a FenceEvent.
+type FenceEventType int
+
+const (
+	// FenceEnter is sent when a point moves into a fence.
+	FenceEnter FenceEventType = iota
+	// FenceExit is sent when a point moves out of a fence.
+	FenceExit
+)
+
+func (t FenceEventType) String() string {
+	if t == FenceEnter {
+		return "enter"
+	}
+	return "exit"
+}
+
+// FenceEvent is sent as a tracked point crosses the boundary of a fence.
+type FenceEvent struct {
+	Type  FenceEventType
+	Fence string
+	ID    string
+	// Point is the location of the tracked point after the update, nil
+	// when it is no longer tracked
+	Point *Point
+}
+
+// Geofence tracks points by ID against a set of named fences, reporting
+// the fences each point enters and leaves as it is updated. Fences are
+// indexed by their bounding boxes in a tree covering the boundary, so an
+// update only tests the fences whose boxes hold the point. It is safe for
+// concurrent use.
+type Geofence struct {
+	mu     sync.Mutex
+	tree   *QuadTree
+	fences map[string]*Extent
+	// names of the fences holding each tracked point
+	inside map[string]map[string]bool
+	// last location of each tracked point
+	points map[string]*Point
+
+	handlers []func(FenceEvent)
+	subs     map[chan FenceEvent]bool
+}
+
+// NewGeofence creates a *Geofence for fences and points within the
+// boundary.
+func NewGeofence(boundary *AABB, opts ...Option) *Geofence {
+	return &Geofence{
+		tree:   New(boundary, 0, nil, opts...),
+		fences: make(map[string]*Extent),
+		inside: make(map[string]map[string]bool),
+		points: make(map[string]*Point),
+		subs:   make(map[chan FenceEvent]bool),
+	}
+}
+
+// Add adds the fence, replacing any with the same name. It returns false
+// if the fence lies outside the boundary. Tracked points are not tested
+// against the fence until they are next updated.
+func (g *Geofence) Add(f *Fence) bool {
+	box := clip(f.box, g.tree.boundary)
+	if box == nil {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if e, ok := g.fences[f.name]; ok {
+		g.tree.RemoveExtent(e)
+	}
+
+	e := g.tree.InsertAABB(box, f)
+	if e == nil {
+		delete(g.fences, f.name)
+		return false
+	}
+	g.fences[f.name] = e
+	return true
+}
+
+// Remove removes the fence with the name, sending an exit event for each
+// point within it.
+func (g *Geofence) Remove(name string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	e, ok := g.fences[name]
+	if !ok {
+		return false
+	}
+
+	g.tree.RemoveExtent(e)
+	delete(g.fences, name)
+
+	var events []FenceEvent
+	for id, in := range g.inside {
+		if in[name] {
+			delete(in, name)
+			events = append(events, FenceEvent{FenceExit, name, id, g.points[id]})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+
+	g.send(events)
+	return true
+}
+
+// Fences returns the names of the fences holding the point with the ID.
+func (g *Geofence) Fences(id string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var names []string
+	for name := range g.inside[id] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Update moves the point with the ID to the location of p, tracking it if
+// it is not already, and returns the events for the fences it left and
+// entered, exits first, ordered by fence name. The events are also passed
+// to the handlers and subscribers of the geofence.
+func (g *Geofence) Update(id string, p *Point) []FenceEvent {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	loc := &Point{x: p.x, y: p.y, id: id, data: p.data}
+
+	now := make(map[string]bool)
+	for _, e := range g.tree.SearchExtents(&AABB{loc, &Point{}}) {
+		f := e.data.(*Fence)
+		if f.Contains(loc) {
+			now[f.name] = true
+		}
+	}
+
+	events := g.diff(id, g.inside[id], now, loc)
+	g.inside[id] = now
+	g.points[id] = loc
+
+	g.send(events)
+	return events
+}
+
+// Forget stops tracking the point with the ID, returning exit events for
+// the fences it was within.
+func (g *Geofence) Forget(id string) []FenceEvent {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	events := g.diff(id, g.inside[id], nil, nil)
+	delete(g.inside, id)
+	delete(g.points, id)
+
+	g.send(events)
+	return events
+}
+
+// diff returns the exit and enter events of a point moving from the fences
+// in before to those in after.
+func (g *Geofence) diff(id string, before, after map[string]bool, p *Point) []FenceEvent {
+	var exits, enters []FenceEvent
+
+	for name := range before {
+		if !after[name] {
+			exits = append(exits, FenceEvent{FenceExit, name, id, p})
+		}
+	}
+	for name := range after {
+		if !before[name] {
+			enters = append(enters, FenceEvent{FenceEnter, name, id, p})
+		}
+	}
+
+	byFence := func(events []FenceEvent) {
+		sort.Slice(events, func(i, j int) bool { return events[i].Fence < events[j].Fence })
+	}
+	byFence(exits)
+	byFence(enters)
+
+	return append(exits, enters...)
+}
+
+// OnEvent registers a function called with every event, in order, while
+// the geofence is locked. The function must not call the geofence.
+func (g *Geofence) OnEvent(fn func(FenceEvent)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.handlers = append(g.handlers, fn)
+}
+
+// Subscribe returns a channel receiving every event, buffering up to n.
+// Events for a subscriber which falls further behind are dropped. The
+// returned function cancels the subscription and closes the channel.
+func (g *Geofence) Subscribe(n int) (<-chan FenceEvent, func()) {
+	ch := make(chan FenceEvent, n)
+
+	g.mu.Lock()
+	g.subs[ch] = true
+	g.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			g.mu.Lock()
+			delete(g.subs, ch)
+			g.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (g *Geofence) send(events []FenceEvent) {
+	for _, ev := range events {
+		for _, fn := range g.handlers {
+			fn(ev)
+		}
+		for ch := range g.subs {
+			select {
+			case ch <- ev:
+			default:
+			}
+		}
+	}
+}
+
+// clip returns the part of a within b, or nil if they do not intersect.
+func clip(a, b *AABB) *AABB {
+	minX := math.Max(a.center.x-a.half.x, b.center.x-b.half.x)
+	maxX := math.Min(a.center.x+a.half.x, b.center.x+b.half.x)
+	minY := math.Max(a.center.y-a.half.y, b.center.y-b.half.y)
+	maxY := math.Min(a.center.y+a.half.y, b.center.y+b.half.y)
+
+	if minX > maxX || minY > maxY {
+		return nil
+	}
+
+	return &AABB{
+		&Point{x: (minX + maxX) / 2, y: (minY + maxY) / 2},
+		&Point{x: (maxX - minX) / 2, y: (maxY - minY) / 2},
+	}
+}
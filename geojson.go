@@ -0,0 +1,141 @@
+package quadtree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	ID         interface{}     `json:"id,omitempty"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties json.RawMessage `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ToGeoJSON returns the points of the tree as a GeoJSON FeatureCollection
+// of Point features, with longitude and latitude in GeoJSON order. Points
+// hidden by SoftRemove are left out. Point
+// IDs become feature IDs and point data, encoded by the codec of the tree,
+// becomes the feature properties, wrapped as {"data": ...} when it does
+// not encode to a JSON object.
+func (qt *QuadTree) ToGeoJSON() ([]byte, error) {
+	c := qt.state.dataCodec()
+	fc := geoJSONCollection{
+		Type:     "FeatureCollection",
+		Features: []geoJSONFeature{},
+	}
+
+	var err error
+	qt.walk(qt.newQuery(nil), func(p *Point) bool {
+		f := geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: geoJSONPosition(p),
+			},
+			Properties: json.RawMessage("null"),
+		}
+		if p.id != "" {
+			f.ID = p.id
+		}
+		if p.data != nil {
+			if f.Properties, err = geoJSONProperties(c, p.data); err != nil {
+				return false
+			}
+		}
+		fc.Features = append(fc.Features, f)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(fc)
+}
+
+// geoJSONPosition returns the coordinates of a point as a GeoJSON
+// position, longitude first.
+func geoJSONPosition(p *Point) json.RawMessage {
+	b, _ := json.Marshal([]float64{p.y, p.x})
+	return b
+}
+
+func geoJSONProperties(c Codec, data interface{}) (json.RawMessage, error) {
+	b, err := c.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(b) {
+		// properties must be JSON, carry other encodings as a string
+		b, _ = json.Marshal(string(b))
+	}
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '{' {
+		return b, nil
+	}
+	return json.Marshal(map[string]json.RawMessage{"data": b})
+}
+
+// FromGeoJSON builds a tree covering the world from a GeoJSON
+// FeatureCollection of Point features. Feature IDs become point IDs and
+// the properties are decoded into the point data by the codec of the
+// tree, JSONCodec unless WithCodec is given.
+func FromGeoJSON(r io.Reader, opts ...Option) (*QuadTree, error) {
+	var fc geoJSONCollection
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, err
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("quadtree: unsupported GeoJSON type %q", fc.Type)
+	}
+
+	world := &AABB{&Point{}, &Point{x: 90, y: 180}}
+	qt := New(world, 0, nil, opts...)
+	c := qt.state.dataCodec()
+
+	for i, f := range fc.Features {
+		g := f.Geometry
+		if g.Type != "Point" {
+			return nil, fmt.Errorf("quadtree: feature %d: unsupported geometry %q", i, g.Type)
+		}
+
+		var pos []float64
+		if err := json.Unmarshal(g.Coordinates, &pos); err != nil || len(pos) < 2 {
+			return nil, fmt.Errorf("quadtree: feature %d: invalid coordinates", i)
+		}
+
+		p := &Point{x: pos[1], y: pos[0]}
+		switch id := f.ID.(type) {
+		case string:
+			p.id = id
+		case float64:
+			p.id = strconv.FormatFloat(id, 'f', -1, 64)
+		}
+
+		if props := bytes.TrimSpace(f.Properties); len(props) > 0 && !bytes.Equal(props, []byte("null")) {
+			data, err := c.Unmarshal(props)
+			if err != nil {
+				return nil, fmt.Errorf("quadtree: feature %d: %w", i, err)
+			}
+			p.data = data
+		}
+
+		if err := qt.add(p); err != nil {
+			return nil, fmt.Errorf("quadtree: feature %d: %w", i, err)
+		}
+	}
+
+	return qt, nil
+}
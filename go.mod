@@ -1,3 +1,3 @@
 module github.com/asim/quadtree
 
-go 1.18
+go 1.23
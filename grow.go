@@ -0,0 +1,110 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// WithAutoGrow grows the root of the tree to take in points inserted or
+// moved by Update outside its boundary rather than rejecting them, for
+// streams whose extent is not known up front. Each step doubles the boundary away from
+// the point, the existing tree becoming one quadrant of the new root, so
+// no point is moved. The max depth is raised by one with each step so the
+// smallest leaves keep their size.
+func WithAutoGrow() Option {
+	return func(s *state) {
+		s.grow = true
+	}
+}
+
+// grow doubles the boundary of the root until it contains the point,
+// reporting false if it cannot.
+func (qt *QuadTree) grow(p *Point) bool {
+	if !finite(p.x) || !finite(p.y) {
+		return false
+	}
+
+	for !qt.boundary.ContainsPoint(p) {
+		b := qt.boundary
+		if math.IsInf(b.half.x*2, 0) || math.IsInf(b.half.y*2, 0) {
+			return false
+		}
+		qt.regrow(p.x >= b.center.x, p.y >= b.center.y)
+	}
+	return true
+}
+
+// regrow makes the root a quadrant of a root of twice its size, extending
+// it towards greater x if up and greater y if right.
+func (qt *QuadTree) regrow(up, right bool) {
+	s := qt.state
+	qt.restock(false)
+	// every node moves down a level
+	qt.unshare()
+
+	c, h := qt.boundary.center, qt.boundary.half
+
+	// the existing root, moved to a node of its own
+	old := qt.newChild(c.x, c.y, h.x, h.y)
+	*old = *qt
+	if cap(old.resident) > 0 && &old.resident[:1][0] == &qt.inline[0] {
+		old.resident = old.inline[:len(old.resident)]
+	}
+	for _, node := range old.nodes {
+		if node != nil {
+			node.parent = old
+		}
+	}
+	old.deepen()
+
+	cx, cy := c.x-h.x, c.y-h.y
+	if up {
+		cx = c.x + h.x
+	}
+	if right {
+		cy = c.y + h.y
+	}
+
+	*qt = QuadTree{
+		boundary: &AABB{&Point{x: cx, y: cy}, &Point{x: h.x * 2, y: h.y * 2}},
+		state:    s,
+		total:    old.total,
+		radius:   old.radius,
+		weight:   old.weight,
+		tags:     old.tags,
+		gen:      old.gen,
+	}
+	qt.resident = qt.inline[:0]
+
+	// children in the order of divide, the old root where it lies
+	qt.nodes[0] = qt.newChild(cx-h.x, cy+h.y, h.x, h.y)
+	qt.nodes[1] = qt.newChild(cx+h.x, cy+h.y, h.x, h.y)
+	qt.nodes[2] = qt.newChild(cx-h.x, cy-h.y, h.x, h.y)
+	qt.nodes[3] = qt.newChild(cx+h.x, cy-h.y, h.x, h.y)
+
+	i := 0
+	if !up {
+		i++
+	}
+	if right {
+		i += 2
+	}
+	qt.nodes[i].recycle()
+	qt.nodes[i] = old
+	old.parent = qt
+
+	s.maxDepth++
+	qt.touch()
+	qt.restock(true)
+}
+
+// deepen moves the node and its children one level down the tree.
+func (qt *QuadTree) deepen() {
+	qt.depth++
+	// the path of the node now starts from the new root
+	qt.hasNodeID = false
+	if qt.nodes[0] != nil {
+		for _, node := range qt.nodes {
+			node.deepen()
+		}
+	}
+}
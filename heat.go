@@ -0,0 +1,59 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// decay returns the contribution of a point to a density query.
+func (q *query) decay(p *Point) float64 {
+	if q.halfLife <= 0 {
+		return 1
+	}
+
+	age := q.now.Sub(p.Updated())
+	if age < 0 {
+		return 1
+	}
+
+	return math.Exp2(-float64(age) / float64(q.halfLife))
+}
+
+// Heat returns the density of points within the axis aligned bounding box.
+// Without options every point contributes 1. Using WithHalfLife each
+// point's contribution decays with the time since it was last inserted or
+// updated, so recent activity dominates.
+func (qt *QuadTree) Heat(a *AABB, opts ...QueryOption) float64 {
+	if noBox(a) {
+		return 0
+	}
+
+	q := qt.newQuery(opts)
+	if q.now.IsZero() {
+		q.now = qt.state.clock()
+	}
+	return qt.heat(a, q)
+}
+
+func (qt *QuadTree) heat(a *AABB, q *query) float64 {
+	var total float64
+
+	if !qt.boundary.Intersect(a) {
+		return total
+	}
+
+	for _, p := range qt.scan(a) {
+		if a.ContainsPoint(p) && q.match(p) {
+			total += q.decay(p)
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return total
+	}
+
+	for _, node := range qt.nodes {
+		total += node.heat(a, q)
+	}
+
+	return total
+}
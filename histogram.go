@@ -0,0 +1,25 @@
+package quadtree
+
+import (
+	"sort"
+)
+
+// Histogram counts the values of the points within the bounding box in a
+// single traversal. Buckets are the ascending upper bounds of each bin,
+// a value falling in the first bin whose bound it does not exceed, with a
+// final bin for values above the last bound, so the result has one more
+// count than there are bounds.
+func (qt *QuadTree) Histogram(a *AABB, value func(*Point) float64, buckets []float64, opts ...QueryOption) []int {
+	counts := make([]int, len(buckets)+1)
+	if noBox(a) {
+		return counts
+	}
+
+	qt.visit(a, qt.newQuery(opts), func(p *Point) bool {
+		v := value(p)
+		counts[sort.SearchFloat64s(buckets, v)]++
+		return true
+	})
+
+	return counts
+}
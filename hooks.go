@@ -0,0 +1,72 @@
+package quadtree
+
+// hooks are the functions called as the tree changes.
+type hooks struct {
+	insert []func(p *Point)
+	remove []func(p *Point)
+	move   []func(p *Point, from *Point)
+	divide []func(node *QuadTree)
+	evict  []func(p *Point)
+}
+
+// OnInsert registers a function called with each point once it has been
+// inserted into the tree. Points moved between nodes as the tree divides,
+// collapses or updates are not reported.
+func (qt *QuadTree) OnInsert(fn func(p *Point)) {
+	qt.state.hooks.insert = append(qt.state.hooks.insert, fn)
+}
+
+// OnRemove registers a function called with each point once it has been
+// removed from the tree, whether by Remove, replacement of its ID,
+// eviction or otherwise.
+func (qt *QuadTree) OnRemove(fn func(p *Point)) {
+	qt.state.hooks.remove = append(qt.state.hooks.remove, fn)
+}
+
+// OnMove registers a function called with each point moved by an update
+// once it is at its new location, along with its previous location.
+func (qt *QuadTree) OnMove(fn func(p *Point, from *Point)) {
+	qt.state.hooks.move = append(qt.state.hooks.move, fn)
+}
+
+// OnDivide registers a function called with each node divided once its
+// points have moved to its children. It is called during the insert
+// causing the division, so it must not modify the tree.
+func (qt *QuadTree) OnDivide(fn func(node *QuadTree)) {
+	qt.state.hooks.divide = append(qt.state.hooks.divide, fn)
+}
+
+func (h *hooks) inserted(p *Point) {
+	for _, fn := range h.insert {
+		fn(p)
+	}
+}
+
+func (h *hooks) removed(p *Point) {
+	for _, fn := range h.remove {
+		fn(p)
+	}
+}
+
+func (h *hooks) moved(p *Point, x, y float64) {
+	if len(h.move) == 0 {
+		return
+	}
+
+	from := &Point{x: x, y: y, id: p.id, data: p.data}
+	for _, fn := range h.move {
+		fn(p, from)
+	}
+}
+
+func (h *hooks) evicted(p *Point) {
+	for _, fn := range h.evict {
+		fn(p)
+	}
+}
+
+func (h *hooks) divided(node *QuadTree) {
+	for _, fn := range h.divide {
+		fn(node)
+	}
+}
@@ -0,0 +1,54 @@
+package quadtree
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// RegionStat describes the activity of a leaf node.
+type RegionStat struct {
+	Boundary *AABB
+	Depth    int
+	// Points held by the node
+	Points int
+	// Hits is the number of queries which visited the node
+	Hits uint64
+	// Writes is the number of updates of points within the node
+	Writes uint64
+}
+
+func (qt *QuadTree) hit() {
+	if qt.state.counters {
+		atomic.AddUint64(&qt.hits, 1)
+	}
+}
+
+// HotRegions returns the n hottest leaves. With WithAccessCounters these
+// are the most frequently queried leaves, otherwise the densest, so
+// operators can decide where to shard or pre-aggregate.
+func (qt *QuadTree) HotRegions(n int) []RegionStat {
+	var stats []RegionStat
+
+	for leaf := range qt.LeavesIntersecting(qt.boundary) {
+		stats = append(stats, RegionStat{
+			Boundary: leaf.node.boundary,
+			Depth:    leaf.node.depth,
+			Points:   len(leaf.node.leaf()),
+			Hits:     atomic.LoadUint64(&leaf.node.hits),
+			Writes:   leaf.node.writes,
+		})
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Hits != stats[j].Hits {
+			return stats[i].Hits > stats[j].Hits
+		}
+		return stats[i].Points > stats[j].Points
+	})
+
+	if n >= 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+
+	return stats
+}
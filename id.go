@@ -0,0 +1,110 @@
+package quadtree
+
+// IDPolicy controls how Insert treats a point whose ID is already in use.
+type IDPolicy int
+
+const (
+	// IDAllow permits several points to share an ID. The ID index refers
+	// to the most recently inserted one.
+	IDAllow IDPolicy = iota
+	// IDReject fails the insert of a point with an ID already in use.
+	IDReject
+	// IDReplace removes the existing point with the ID before inserting.
+	IDReplace
+)
+
+// NewPointID generates a new *Point carrying an ID which is indexed by the
+// tree it is inserted into.
+func NewPointID(id string, x, y float64, data interface{}) *Point {
+	return &Point{x: x, y: y, id: id, data: data}
+}
+
+// ID returns the ID of a point, or an empty string if it has none.
+func (p *Point) ID() string {
+	return p.id
+}
+
+// checkID applies the ID policy to a point about to be inserted. It returns
+// the point to be replaced, if any, or ErrDuplicateID if the insert must
+// not proceed.
+func (qt *QuadTree) checkID(p *Point) (*Point, error) {
+	if p.id == "" {
+		return nil, nil
+	}
+
+	ep, ok := qt.state.ids[p.id]
+	if !ok || ep == p {
+		return nil, nil
+	}
+
+	switch qt.state.policy {
+	case IDReject:
+		return nil, ErrDuplicateID
+	case IDReplace:
+		return ep, nil
+	}
+
+	return nil, nil
+}
+
+// indexID records an inserted point in the ID index.
+func (qt *QuadTree) indexID(p *Point) {
+	if p.id == "" {
+		return
+	}
+
+	qt.state.ids[p.id] = p
+}
+
+func (qt *QuadTree) unindexID(p *Point) {
+	if p.id == "" {
+		return
+	}
+
+	if qt.state.ids[p.id] == p {
+		delete(qt.state.ids, p.id)
+	}
+}
+
+// RebuildIndex rebuilds the ID index from the points in the tree. Where
+// several points share an ID the most recently inserted is indexed, as
+// when they were inserted. Snapshots are indexed as they are loaded, so
+// it is only needed if the index is suspected to be inconsistent.
+func (qt *QuadTree) RebuildIndex() {
+	ids := make(map[string]*Point, len(qt.state.ids))
+
+	qt.walk(&query{removed: true}, func(p *Point) bool {
+		if p.id == "" {
+			return true
+		}
+		if ep, ok := ids[p.id]; !ok || p.seq > ep.seq {
+			ids[p.id] = p
+		}
+		return true
+	})
+
+	qt.state.ids = ids
+}
+
+// Get returns the point with the ID, or nil if there is none.
+func (qt *QuadTree) Get(id string) *Point {
+	return qt.state.index()[id]
+}
+
+// RemoveByID removes the point with the ID.
+func (qt *QuadTree) RemoveByID(id string) bool {
+	p, ok := qt.state.index()[id]
+	if !ok {
+		return false
+	}
+	return qt.Remove(p)
+}
+
+// UpdateByID moves the point with the ID to x, y.
+func (qt *QuadTree) UpdateByID(id string, x, y float64) bool {
+	p, ok := qt.state.index()[id]
+	if !ok {
+		return false
+	}
+	return qt.Update(p, &Point{x: x, y: y})
+}
@@ -0,0 +1,32 @@
+package quadtree
+
+// Depth returns the depth of the node within the tree, 0 for the root.
+func (qt *QuadTree) Depth() int {
+	return qt.depth
+}
+
+// Boundary returns the axis aligned bounding box of the node.
+func (qt *QuadTree) Boundary() *AABB {
+	return qt.boundary
+}
+
+// Parent returns the parent of the node, or nil for the root. A node
+// shared with a Snapshot returns the parent it was last written under,
+// which may belong to an earlier snapshot.
+func (qt *QuadTree) Parent() *QuadTree {
+	return qt.parent
+}
+
+// Children returns the four child nodes of a divided node, or four nils
+// for a leaf. Relative to the center of the node they hold low x and high
+// y, high x and high y, low x and low y, then high x and low y.
+func (qt *QuadTree) Children() [4]*QuadTree {
+	return qt.nodes
+}
+
+// Points returns the points held directly by the node. Only leaves hold
+// points, so it is empty for a node which has been divided. The points
+// must not be modified.
+func (qt *QuadTree) Points() []*Point {
+	return nonNil(qt.leaf())
+}
@@ -0,0 +1,121 @@
+package quadtree
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// inspectorDepth is the number of levels shown below the current node.
+const inspectorDepth = 3
+
+type inspector struct {
+	qt *QuadTree
+	mu sync.Locker
+}
+
+// NewInspector returns an http.Handler rendering a debug view of the tree:
+// its structure, per node occupancy and recent slow queries, with links
+// into each node listing its points. It is meant to be mounted on a
+// private debug listener rather than the public API. If mu is not nil it
+// is held while the tree is read.
+func NewInspector(qt *QuadTree, mu sync.Locker) http.Handler {
+	return &inspector{qt, mu}
+}
+
+// node resolves a path of child indices separated by dots, e.g. "0.3.1".
+func (qt *QuadTree) node(path string) *QuadTree {
+	if path == "" {
+		return qt
+	}
+
+	n := qt
+	for _, part := range strings.Split(path, ".") {
+		i, err := strconv.Atoi(part)
+		if err != nil || i < 0 || i > 3 || n.nodes[0] == nil {
+			return nil
+		}
+		n = n.nodes[i]
+	}
+	return n
+}
+
+func (i *inspector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if i.mu != nil {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+	}
+
+	path := r.URL.Query().Get("node")
+	n := i.qt.node(path)
+	if n == nil {
+		http.Error(w, "node not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	fmt.Fprintf(w, "<html><head><title>quadtree</title></head><body>\n")
+	fmt.Fprintf(w, "<h1>node %s</h1>\n", html.EscapeString(label(path)))
+	if path != "" {
+		parent := path[:strings.LastIndexAny(path, ".")+1]
+		fmt.Fprintf(w, "<p><a href=\"?node=%s\">up</a></p>\n", strings.TrimSuffix(parent, "."))
+	}
+	fmt.Fprintf(w, "<p>depth %d, boundary %s, points %d</p>\n", n.depth, boxString(n.boundary), n.total)
+
+	fmt.Fprintf(w, "<h2>structure</h2>\n")
+	writeNodes(w, n, path, inspectorDepth)
+
+	if n.nodes[0] == nil {
+		fmt.Fprintf(w, "<h2>points</h2>\n<table>\n<tr><th>x</th><th>y</th><th>id</th><th>data</th></tr>\n")
+		for _, p := range n.leaf() {
+			fmt.Fprintf(w, "<tr><td>%v</td><td>%v</td><td>%s</td><td>%s</td></tr>\n",
+				p.x, p.y, html.EscapeString(p.id), html.EscapeString(fmt.Sprint(p.data)))
+		}
+		fmt.Fprintf(w, "</table>\n")
+	}
+
+	fmt.Fprintf(w, "<h2>slow queries</h2>\n<table>\n<tr><th>time</th><th>op</th><th>box</th><th>k</th><th>duration</th></tr>\n")
+	for _, q := range i.qt.SlowQueries() {
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%d</td><td>%s</td></tr>\n",
+			q.Time.Format("15:04:05.000"), q.Op, boxString(q.Box), q.K, q.Duration)
+	}
+	fmt.Fprintf(w, "</table>\n</body></html>\n")
+}
+
+func writeNodes(w io.Writer, n *QuadTree, path string, levels int) {
+	fmt.Fprintf(w, "<ul><li><a href=\"?node=%s\">%s</a> %d points",
+		path, html.EscapeString(label(path)), n.total)
+
+	if n.nodes[0] != nil && levels > 0 {
+		for i, node := range n.nodes {
+			child := strconv.Itoa(i)
+			if path != "" {
+				child = path + "." + child
+			}
+			writeNodes(w, node, child, levels-1)
+		}
+	}
+
+	fmt.Fprintf(w, "</li></ul>\n")
+}
+
+func label(path string) string {
+	if path == "" {
+		return "root"
+	}
+	return path
+}
+
+func boxString(a *AABB) string {
+	if a == nil {
+		return "-"
+	}
+	return fmt.Sprintf("[%v,%v to %v,%v]",
+		a.center.x-a.half.x, a.center.y-a.half.y,
+		a.center.x+a.half.x, a.center.y+a.half.y)
+}
@@ -0,0 +1,50 @@
+package quadtree
+
+import (
+	"iter"
+)
+
+// Iterate calls fn for every point in the tree, stopping early if fn
+// returns false, without collecting the points into a slice.
+func (qt *QuadTree) Iterate(fn func(*Point) bool, opts ...QueryOption) {
+	qt.walk(qt.newQuery(opts), fn)
+}
+
+// All returns an iterator over every point in the tree.
+func (qt *QuadTree) All(opts ...QueryOption) iter.Seq[*Point] {
+	return func(yield func(*Point) bool) {
+		qt.walk(qt.newQuery(opts), yield)
+	}
+}
+
+// SearchSeq returns an iterator over the points within the bounding box,
+// visiting the tree as the iterator is consumed rather than collecting the
+// results like Search.
+func (qt *QuadTree) SearchSeq(a *AABB, opts ...QueryOption) iter.Seq[*Point] {
+	return func(yield func(*Point) bool) {
+		if noBox(a) {
+			return
+		}
+		qt.visit(a, qt.newQuery(opts), yield)
+	}
+}
+
+func (qt *QuadTree) walk(q *query, fn func(*Point) bool) bool {
+	for _, p := range qt.leaf() {
+		if q.match(p) && !fn(p) {
+			return false
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return true
+	}
+
+	for _, node := range qt.nodes {
+		if !node.walk(q, fn) {
+			return false
+		}
+	}
+
+	return true
+}
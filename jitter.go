@@ -0,0 +1,33 @@
+package quadtree
+
+// UpdateNear is Update for high frequency position updates such as GPS
+// jitter. A move of less than thresholdMeters which keeps the point within
+// the node holding it only sets the coordinates in place, skipping the
+// removal and reinsertion of Update. Any other move is a full Update.
+func (qt *QuadTree) UpdateNear(p *Point, np *Point, thresholdMeters float64) bool {
+	if p == nil || np == nil {
+		return false
+	}
+	p = qt.state.current(p)
+	if qt.state.locked(p, np) || !qt.state.allowWrite() {
+		return false
+	}
+
+	if Distance(p, np) < thresholdMeters {
+		// refined leaves keep their points in morton order
+		if node := qt.owner(p); node != nil && node.codes == nil && node.boundary.ContainsPoint(np) {
+			node = qt.root().claim(node)
+			p = node.forkPoint(p)
+			x, y := p.x, p.y
+			p.x = np.x
+			p.y = np.y
+			qt.state.updated(p)
+			p.setMeta().version++
+			node.touch()
+			qt.state.hooks.moved(p, x, y)
+			return true
+		}
+	}
+
+	return qt.update(p, np)
+}
@@ -0,0 +1,151 @@
+package quadtree
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+	"sort"
+)
+
+// closest returns the point of the bounding box closest to p.
+func (a *AABB) closest(p *Point) *Point {
+	return &Point{
+		x: math.Max(a.center.x-a.half.x, math.Min(p.x, a.center.x+a.half.x)),
+		y: math.Max(a.center.y-a.half.y, math.Min(p.y, a.center.y+a.half.y)),
+	}
+}
+
+type scored struct {
+	point *Point
+	score float64
+}
+
+// KBest returns the k points with the lowest score, ordered by score. The
+// score function is given each candidate and its distance in metres from
+// the center, e.g. distance penalised by price or rating. Scores must be no
+// less than the distance, so that nodes further away than the k'th best
+// score found can be pruned.
+func (qt *QuadTree) KBest(center *Point, k int, score func(p *Point, distMeters float64) float64, opts ...QueryOption) []*Point {
+	if center == nil || k <= 0 {
+		return []*Point{}
+	}
+
+	q := qt.newQuery(opts)
+	return nonNil(qt.kbest(center, k, score, nil, nil, q.filter(nil), q))
+}
+
+// WithCost ranks the results of KNearest by a cost, such as approximate
+// travel time over a road network, instead of straight line distance. The
+// cost function is given each candidate and its distance in metres from
+// the center of the query box, and must return no less than the distance
+// so that candidates beyond the k'th best cost found are cut off early.
+func WithCost(cost func(p *Point, distMeters float64) float64) QueryOption {
+	return func(q *query) {
+		q.cost = cost
+	}
+}
+
+// kbest returns the k points matching fn with the lowest score, searching
+// best first from center and restricted to a if not nil. The lowest score
+// beneath a node is bounded by bound given its distance, or by the distance
+// itself if bound is nil. Each candidate point is explained to the
+// WithExplain function of the query if it has one.
+func (qt *QuadTree) kbest(center *Point, k int, score func(p *Point, distMeters float64) float64, bound func(qt *QuadTree, distMeters float64) float64, a *AABB, fn filter, q *query) []*Point {
+	var best []scored
+	var ex *explainer
+	if q.explain != nil {
+		ex = &explainer{}
+		defer func() { ex.flush(best, q.explain) }()
+	}
+
+	if k <= 0 {
+		return []*Point{}
+	}
+
+	lower := func(node *QuadTree) float64 {
+		d := geoMinDist(center, node.boundary)
+		if bound != nil {
+			return bound(node, d)
+		}
+		return d
+	}
+
+	// the keys of the points held when keeping one point per key
+	var held map[string]*Point
+	if q.distinct != nil {
+		held = make(map[string]*Point)
+	}
+
+	queue := &candidates{{node: qt, dist: lower(qt)}}
+
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(candidate)
+
+		if len(best) == k && c.dist >= best[k-1].score || q.stopped() {
+			break
+		}
+
+		for _, p := range c.node.leaf() {
+			if a != nil && !a.ContainsPoint(p) {
+				ex.add(p, 0, 0, ReasonOutside)
+				continue
+			}
+			if !fn(p) {
+				ex.add(p, 0, 0, ReasonFiltered)
+				continue
+			}
+
+			d := Distance(center, p)
+			s := score(p, d)
+			ex.add(p, d, s, ReasonOutranked)
+			if len(best) == k && s >= best[k-1].score {
+				continue
+			}
+
+			before := func(i int) bool {
+				return best[i].score > s || best[i].score == s && best[i].point.seq > p.seq
+			}
+
+			if held != nil {
+				key := q.distinct(p)
+				if h, ok := held[key]; ok {
+					// keep the better scored of the points of the key
+					j := slices.IndexFunc(best, func(b scored) bool {
+						return b.point == h
+					})
+					if !before(j) {
+						continue
+					}
+					best = slices.Delete(best, j, j+1)
+				} else if len(best) == k {
+					delete(held, q.distinct(best[k-1].point))
+				}
+				held[key] = p
+			}
+
+			i := sort.Search(len(best), before)
+			if len(best) < k {
+				best = append(best, scored{})
+			}
+			copy(best[i+1:], best[i:])
+			best[i] = scored{p, s}
+		}
+
+		if c.node.nodes[0] == nil {
+			continue
+		}
+
+		for _, node := range c.node.nodes {
+			if a != nil && !node.boundary.Intersect(a) || !q.tagged(node) {
+				continue
+			}
+			heap.Push(queue, candidate{node: node, dist: lower(node)})
+		}
+	}
+
+	results := make([]*Point, len(best))
+	for i, b := range best {
+		results[i] = b.point
+	}
+	return results
+}
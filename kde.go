@@ -0,0 +1,55 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// kdeCutoff is the number of bandwidths beyond which points are ignored.
+const kdeCutoff = 4
+
+// KDE estimates the density of points per square metre at a location using
+// a Gaussian kernel with the bandwidth in metres. Only points within a few
+// bandwidths are visited.
+func (qt *QuadTree) KDE(at *Point, bandwidthMeters float64, opts ...QueryOption) float64 {
+	if at == nil || bandwidthMeters <= 0 {
+		return 0
+	}
+
+	var sum float64
+
+	h2 := bandwidthMeters * bandwidthMeters
+	a := NewAABB(at, at.HalfPoint(kdeCutoff*bandwidthMeters))
+
+	qt.visit(a, qt.newQuery(opts), func(p *Point) bool {
+		d := Distance(at, p)
+		sum += math.Exp(-d * d / (2 * h2))
+		return true
+	})
+
+	return sum / (2 * math.Pi * h2)
+}
+
+// KDEGrid estimates the density at the center of each cell of a grid of
+// rows by cols cells over the bounding box. Rows run along the x axis and
+// columns along the y axis, both from the minimum.
+func (qt *QuadTree) KDEGrid(a *AABB, rows, cols int, bandwidthMeters float64, opts ...QueryOption) [][]float64 {
+	if noBox(a) || rows <= 0 || cols <= 0 {
+		return [][]float64{}
+	}
+
+	grid := make([][]float64, rows)
+	ch, cw := 2*a.half.x/float64(rows), 2*a.half.y/float64(cols)
+
+	for r := range grid {
+		grid[r] = make([]float64, cols)
+		for c := range grid[r] {
+			center := &Point{
+				x: a.center.x - a.half.x + (float64(r)+0.5)*ch,
+				y: a.center.y - a.half.y + (float64(c)+0.5)*cw,
+			}
+			grid[r][c] = qt.KDE(center, bandwidthMeters, opts...)
+		}
+	}
+
+	return grid
+}
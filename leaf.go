@@ -0,0 +1,120 @@
+package quadtree
+
+import (
+	"iter"
+)
+
+// LeafView is a view of a leaf node, its boundary and the points it
+// holds. The points must not be modified. The zero LeafView has no
+// boundary and no points.
+type LeafView struct {
+	node *QuadTree
+	// node the leaf was found from, as a leaf shared with a snapshot may
+	// have been written under another tree
+	root *QuadTree
+}
+
+// Boundary returns the axis aligned bounding box of the leaf.
+func (l LeafView) Boundary() *AABB {
+	if l.node == nil {
+		return nil
+	}
+	return l.node.boundary
+}
+
+// Depth returns the depth of the leaf within the tree.
+func (l LeafView) Depth() int {
+	if l.node == nil {
+		return 0
+	}
+	return l.node.depth
+}
+
+// Points returns the points held by the leaf.
+func (l LeafView) Points() []*Point {
+	if l.node == nil {
+		return nil
+	}
+	return l.node.leaf()
+}
+
+// Neighbors returns the leaves adjacent to this one, including those
+// touching at a corner.
+func (l LeafView) Neighbors() []LeafView {
+	var results []LeafView
+
+	if l.node == nil {
+		return results
+	}
+
+	root := l.root
+	if root == nil || root.parent != nil {
+		root = l.node.root()
+	}
+
+	for n := range root.LeavesIntersecting(l.node.boundary) {
+		if n.node != l.node {
+			results = append(results, n)
+		}
+	}
+
+	return results
+}
+
+// LeafFor returns the leaf whose boundary contains the location of the
+// point, whether or not the point is stored in the tree. It returns the
+// zero LeafView if the point is nil or outside the tree.
+func (qt *QuadTree) LeafFor(p *Point) LeafView {
+	if p == nil {
+		return LeafView{}
+	}
+	return qt.leafFor(qt, qt.state.current(p))
+}
+
+func (qt *QuadTree) leafFor(root *QuadTree, p *Point) LeafView {
+	if !qt.boundary.ContainsPoint(p) {
+		return LeafView{}
+	}
+
+	if qt.nodes[0] == nil {
+		return LeafView{qt, root}
+	}
+
+	for _, node := range qt.nodes {
+		if node.boundary.ContainsPoint(p) {
+			return node.leafFor(root, p)
+		}
+	}
+
+	return LeafView{}
+}
+
+// LeavesIntersecting returns an iterator over the leaves whose boundary
+// intersects the axis aligned bounding box, for implementing custom per
+// leaf algorithms without reimplementing traversal.
+func (qt *QuadTree) LeavesIntersecting(a *AABB, opts ...QueryOption) iter.Seq[LeafView] {
+	return func(yield func(LeafView) bool) {
+		if noBox(a) {
+			return
+		}
+		qt.leaves(qt, a, qt.newQuery(opts), yield)
+	}
+}
+
+func (qt *QuadTree) leaves(root *QuadTree, a *AABB, q *query, yield func(LeafView) bool) bool {
+	if !qt.boundary.Intersect(a) {
+		return true
+	}
+
+	if qt.nodes[0] == nil {
+		return yield(LeafView{qt, root})
+	}
+
+	for _, node := range q.children(qt, a) {
+		if !node.leaves(root, a, q, yield) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,101 @@
+package quadtree
+
+import (
+	"sort"
+)
+
+// appendPoint adds a point to a leaf, refining the leaf into morton order
+// once it holds more points than the maximum scan.
+func (qt *QuadTree) appendPoint(p *Point) {
+	qt.loadLeaf()
+	qt.counted(1)
+
+	if qt.codes != nil {
+		code := morton(qt.boundary, p)
+		i := sort.Search(len(qt.codes), func(i int) bool {
+			return qt.codes[i] > code
+		})
+		qt.resident = append(qt.resident, nil)
+		qt.codes = append(qt.codes, 0)
+		copy(qt.resident[i+1:], qt.resident[i:])
+		copy(qt.codes[i+1:], qt.codes[i:])
+		qt.resident[i] = p
+		qt.codes[i] = code
+		qt.stored(i, len(qt.resident))
+		return
+	}
+
+	qt.resident = append(qt.resident, p)
+	qt.stored(len(qt.resident)-1, len(qt.resident))
+
+	if limit := qt.state.maxScan; limit > 0 && qt.depth >= qt.state.maxDepth && len(qt.resident) > limit {
+		qt.refine()
+	}
+}
+
+// removeAt removes the i'th point of a leaf.
+func (qt *QuadTree) removeAt(i int) {
+	qt.loadLeaf()
+	qt.counted(-1)
+	last := len(qt.resident) - 1
+
+	if qt.codes != nil {
+		copy(qt.resident[i:], qt.resident[i+1:])
+		copy(qt.codes[i:], qt.codes[i+1:])
+		qt.resident[last] = nil
+		qt.resident = qt.resident[:last]
+		qt.codes = qt.codes[:last]
+		qt.stored(i, last)
+		qt.unstored(last, last+1)
+		return
+	}
+
+	if i != last {
+		qt.resident[i] = qt.resident[last]
+		qt.stored(i, i+1)
+	}
+	qt.resident[last] = nil
+	qt.resident = qt.resident[:last]
+	qt.unstored(last, last+1)
+}
+
+// refine sorts the points of a leaf by morton code.
+func (qt *QuadTree) refine() {
+	qt.loadLeaf()
+	qt.codes = make([]uint64, len(qt.resident))
+	for i, p := range qt.resident {
+		qt.codes[i] = morton(qt.boundary, p)
+	}
+	sort.Sort(byCode{qt.resident, qt.codes})
+	qt.stored(0, len(qt.resident))
+}
+
+type byCode struct {
+	points []*Point
+	codes  []uint64
+}
+
+func (b byCode) Len() int           { return len(b.points) }
+func (b byCode) Less(i, j int) bool { return b.codes[i] < b.codes[j] }
+func (b byCode) Swap(i, j int) {
+	b.points[i], b.points[j] = b.points[j], b.points[i]
+	b.codes[i], b.codes[j] = b.codes[j], b.codes[i]
+}
+
+// scan returns the points of a node which may lie within the bounding box.
+// For a refined leaf this is the range of points whose morton codes lie
+// between those of the box's lower and upper corners.
+func (qt *QuadTree) scan(a *AABB) []*Point {
+	points := qt.leaf()
+	if qt.codes == nil {
+		return points
+	}
+
+	lo := morton(qt.boundary, &Point{x: a.center.x - a.half.x, y: a.center.y - a.half.y})
+	hi := morton(qt.boundary, &Point{x: a.center.x + a.half.x, y: a.center.y + a.half.y})
+
+	i := sort.Search(len(qt.codes), func(i int) bool { return qt.codes[i] >= lo })
+	j := sort.Search(len(qt.codes), func(i int) bool { return qt.codes[i] > hi })
+
+	return points[i:j]
+}
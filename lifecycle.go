@@ -0,0 +1,59 @@
+package quadtree
+
+// State is the lifecycle state of a point, e.g. a vehicle on or off duty.
+// Points in any state keep their location in the tree.
+type State uint8
+
+const (
+	// Active is the state of newly created points.
+	Active State = iota
+	// Inactive points are temporarily out of service.
+	Inactive
+	// Archived points are retained only for their last known location.
+	Archived
+)
+
+func (s State) String() string {
+	switch s {
+	case Active:
+		return "active"
+	case Inactive:
+		return "inactive"
+	case Archived:
+		return "archived"
+	}
+	return "unknown"
+}
+
+// State returns the lifecycle state of the point.
+func (p *Point) State() State {
+	return p.meta().lifecycle
+}
+
+// SetState sets the lifecycle state of the point.
+func (p *Point) SetState(s State) {
+	p.setMeta().lifecycle = s
+}
+
+// SetState moves the point with the ID to the lifecycle state, returning
+// the state it was previously in.
+func (qt *QuadTree) SetState(id string, s State) (State, bool) {
+	p, ok := qt.state.index()[id]
+	if !ok || qt.state.readOnly {
+		return Active, false
+	}
+	p = qt.writable(p)
+	prev := p.meta().lifecycle
+	p.setMeta().lifecycle = s
+	return prev, true
+}
+
+// WithStates restricts the results of a query to points in one of the
+// lifecycle states. Points in every state are returned by default.
+func WithStates(states ...State) QueryOption {
+	return func(q *query) {
+		for _, s := range states {
+			q.states |= 1 << s
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package quadtree
+
+import (
+	"sync"
+	"time"
+)
+
+// limiter caps concurrent queries and the rate of writes.
+type limiter struct {
+	queries chan struct{}
+
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (s *state) limiter() *limiter {
+	if s.limit == nil {
+		s.limit = &limiter{}
+	}
+	return s.limit
+}
+
+// WithMaxConcurrentQueries limits the number of Search and KNearest queries
+// running at once. Further queries wait for a slot, while SearchStrict and
+// KNearestStrict return ErrTooManyQueries instead.
+func WithMaxConcurrentQueries(n int) Option {
+	return func(s *state) {
+		if n > 0 {
+			s.limiter().queries = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithWriteRate limits inserts, removes and updates to perSecond on
+// average with bursts of up to burst writes. Writes beyond the limit fail,
+// with ErrRateLimited where an error is reported.
+func WithWriteRate(perSecond float64, burst int) Option {
+	return func(s *state) {
+		if perSecond > 0 {
+			l := s.limiter()
+			l.rate = perSecond
+			l.burst = float64(max(burst, 1))
+			l.tokens = l.burst
+		}
+	}
+}
+
+func (s *state) acquire() {
+	if s.limit != nil && s.limit.queries != nil {
+		s.limit.queries <- struct{}{}
+	}
+}
+
+func (s *state) tryAcquire() bool {
+	if s.limit == nil || s.limit.queries == nil {
+		return true
+	}
+	select {
+	case s.limit.queries <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *state) release() {
+	if s.limit != nil && s.limit.queries != nil {
+		<-s.limit.queries
+	}
+}
+
+// allowWrite takes a token from the write rate limiter, rejecting writes
+// to a read-only tree.
+func (s *state) allowWrite() bool {
+	if s.readOnly {
+		return false
+	}
+
+	l := s.limit
+	if l == nil || l.rate == 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := s.clock()
+	if l.last.IsZero() {
+		l.last = now
+	}
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+
+	l.tokens--
+	return true
+}
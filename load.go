@@ -0,0 +1,234 @@
+package quadtree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format is the encoding of the records read by Load.
+type Format int
+
+const (
+	// CSV is comma separated values with a header row naming the columns.
+	CSV Format = iota
+	// NDJSON is newline delimited JSON, one object per line.
+	NDJSON
+)
+
+// Record is a record read by Load, a row of a CSV file or a line of an
+// NDJSON file, from which the mapper builds a point.
+type Record struct {
+	// Line is the line number of the record, counting from 1
+	Line int
+
+	fields []string
+	// column of each field name of a CSV file
+	header map[string]int
+
+	raw    json.RawMessage
+	object map[string]json.RawMessage
+}
+
+// Fields returns the fields of a CSV row in order, nil for NDJSON.
+func (r *Record) Fields() []string {
+	return r.fields
+}
+
+// JSON returns the JSON of an NDJSON line, nil for CSV.
+func (r *Record) JSON() json.RawMessage {
+	return r.raw
+}
+
+// Get returns the field with the name, the column named by the header row
+// of a CSV file or a top level field of an NDJSON object, with strings
+// unquoted and other values as JSON. It returns "" if there is no such
+// field.
+func (r *Record) Get(name string) string {
+	if r.raw == nil {
+		i, ok := r.header[name]
+		if !ok || i >= len(r.fields) {
+			return ""
+		}
+		return r.fields[i]
+	}
+
+	if r.object == nil {
+		r.object = make(map[string]json.RawMessage)
+		json.Unmarshal(r.raw, &r.object)
+	}
+
+	v, ok := r.object[name]
+	if !ok {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		return s
+	}
+	return string(v)
+}
+
+// Float returns the field with the name as a number, as for Get.
+func (r *Record) Float(name string) (float64, error) {
+	v := strings.TrimSpace(r.Get(name))
+	if v == "" {
+		return 0, fmt.Errorf("missing field %q", name)
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("field %q: %w", name, err)
+	}
+	return f, nil
+}
+
+// Load builds a tree from a stream of CSV or NDJSON records, calling the
+// mapper to build the point of each record in turn. A mapper returning a
+// nil point skips the record, and one returning an error stops the load
+// with the error and the line of the record. The boundary of the tree is
+// the extent of the points, widened to 1 along an axis on which they all
+// lie at the same coordinate, or the world if there are none. The tree is
+// built in one pass by NewFromPoints once every record is read, and the
+// first point which could not be inserted is returned as an error.
+func Load(r io.Reader, format Format, mapper func(*Record) (*Point, error), opts ...Option) (*QuadTree, error) {
+	return LoadWithin(nil, r, format, mapper, opts...)
+}
+
+// LoadWithin is Load building a tree with the boundary, or the extent of
+// the points if it is nil. Points outside the boundary stop the load with
+// ErrOutOfBounds.
+func LoadWithin(boundary *AABB, r io.Reader, format Format, mapper func(*Record) (*Point, error), opts ...Option) (*QuadTree, error) {
+	if boundary != nil {
+		if err := boundary.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		points []*Point
+		lines  []int
+		lo, hi Point
+	)
+
+	err := readRecords(r, format, func(rec *Record) error {
+		p, err := mapper(rec)
+		if err != nil || p == nil {
+			return err
+		}
+
+		if len(points) == 0 {
+			lo, hi = *p, *p
+		}
+		lo.x, lo.y = min(lo.x, p.x), min(lo.y, p.y)
+		hi.x, hi.y = max(hi.x, p.x), max(hi.y, p.y)
+
+		points = append(points, p)
+		lines = append(lines, rec.Line)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if boundary == nil {
+		boundary = covering(&lo, &hi)
+		if len(points) == 0 {
+			boundary = &AABB{&Point{}, &Point{x: 90, y: 180}}
+		}
+		if err := boundary.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	qt, failures := NewFromPoints(boundary, points, opts...)
+	if len(failures) > 0 {
+		f := failures[0]
+		return nil, fmt.Errorf("quadtree: line %d: %w", lines[f.Index], f.Err)
+	}
+	return qt, nil
+}
+
+// covering returns the box with the corners lo and hi, widened to 1 along
+// an axis on which they meet.
+func covering(lo, hi *Point) *AABB {
+	a := NewAABBFromMinMax(lo, hi)
+	if a.half.x == 0 {
+		a.half.x = 0.5
+	}
+	if a.half.y == 0 {
+		a.half.y = 0.5
+	}
+	return a
+}
+
+// readRecords calls fn with each record read from r, stopping at the
+// first error.
+func readRecords(r io.Reader, format Format, fn func(*Record) error) error {
+	wrap := func(line int, err error) error {
+		return fmt.Errorf("quadtree: line %d: %w", line, err)
+	}
+
+	switch format {
+	case CSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+
+		names, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		header := make(map[string]int, len(names))
+		for i, name := range names {
+			header[strings.TrimSpace(name)] = i
+		}
+
+		for {
+			fields, err := cr.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			line, _ := cr.FieldPos(0)
+			if err := fn(&Record{Line: line, fields: fields, header: header}); err != nil {
+				return wrap(line, err)
+			}
+		}
+
+	case NDJSON:
+		br := bufio.NewReader(r)
+		for line := 1; ; line++ {
+			b, err := br.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+
+			if b := bytes.TrimSpace(b); len(b) > 0 {
+				if !json.Valid(b) {
+					return wrap(line, errors.New("invalid JSON"))
+				}
+				if err := fn(&Record{Line: line, raw: json.RawMessage(b)}); err != nil {
+					return wrap(line, err)
+				}
+			}
+
+			if err == io.EOF {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("quadtree: unknown format %d", format)
+}
@@ -0,0 +1,29 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// WithLocalProjection orders the results of KNearest by distance in a
+// plane tangent to the Earth at the center of the query box. It is metre
+// accurate for small query regions, up to a few tens of kilometres,
+// without evaluating the haversine formula on every comparison.
+func WithLocalProjection() QueryOption {
+	return func(q *query) {
+		q.local = true
+	}
+}
+
+// tangentPlane returns the squared distance in metres between two points
+// projected onto the plane tangent to the Earth at origin.
+func tangentPlane(origin *Point) DistanceFunc {
+	kx := deg2Rad(1) * meanRadius * math.Cos(deg2Rad(origin.x))
+	ky := deg2Rad(1) * meanRadius
+
+	return func(p, q *Point) float64 {
+		dlng := math.Mod(q.y-p.y+540, 360) - 180
+		dx := dlng * kx
+		dy := (q.x - p.x) * ky
+		return dx*dx + dy*dy
+	}
+}
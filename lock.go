@@ -0,0 +1,78 @@
+package quadtree
+
+import (
+	"sync"
+)
+
+// LockRegion rejects writes to points within the region until unlock is
+// called, while queries continue to be served. Inserts fail with
+// ErrRegionLocked where an error is reported, and removes and updates
+// touching the region fail. Migrate is not subject to region locks, so a
+// bulk reload can move the old points of a locked region out and the new
+// points in without interleaving with live writes.
+func (qt *QuadTree) LockRegion(a *AABB) (unlock func()) {
+	if noBox(a) {
+		return func() {}
+	}
+
+	s := qt.state
+	if s.locks == nil {
+		s.locks = make(map[uint64]*AABB)
+	}
+
+	s.lockSeq++
+	id := s.lockSeq
+	s.locks[id] = a
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			delete(s.locks, id)
+		})
+	}
+}
+
+// locked reports whether any of the points lie within a locked region.
+func (s *state) locked(points ...*Point) bool {
+	for _, a := range s.locks {
+		for _, p := range points {
+			if a.ContainsPoint(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LockRegion rejects writes to points within the region in every region
+// of the tree it intersects until unlock is called.
+func (ct *ConcurrentQuadTree) LockRegion(a *AABB) (unlock func()) {
+	if noBox(a) {
+		return func() {}
+	}
+
+	var regions []*region
+	var unlocks []func()
+
+	for _, r := range ct.regions {
+		if !r.tree.boundary.Intersect(a) {
+			continue
+		}
+
+		r.mu.Lock()
+		regions = append(regions, r)
+		unlocks = append(unlocks, r.tree.LockRegion(a))
+		r.mu.Unlock()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			for i, r := range regions {
+				r.mu.Lock()
+				unlocks[i]()
+				r.mu.Unlock()
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package quadtree
+
+import (
+	"time"
+)
+
+// pointMeta holds the optional metadata of a point, allocated when first
+// set so that points without any stay small.
+type pointMeta struct {
+	// incremented by Update and SetData
+	version uint64
+	// hidden from queries by SoftRemove
+	removed bool
+	tenant  string
+	// approximate size in bytes when inserted, zero if that of the point
+	// alone
+	size int64
+	// service radius in metres
+	radius float64
+	// lifecycle state
+	lifecycle State
+	// ranking weight, 1 if not positive
+	weight float64
+	// time to live after the last insert or update, forever if zero
+	ttl time.Duration
+	// tags added by AddTag, and their bitset
+	tags    []string
+	tagBits uint64
+}
+
+// noMeta is the metadata of points without any.
+var noMeta pointMeta
+
+// meta returns the metadata of the point for reading.
+func (p *Point) meta() *pointMeta {
+	if p.md == nil {
+		return &noMeta
+	}
+	return p.md
+}
+
+// setMeta returns the metadata of the point for writing, allocating it
+// the first time.
+func (p *Point) setMeta() *pointMeta {
+	if p.md == nil {
+		p.md = new(pointMeta)
+	}
+	return p.md
+}
+
+// restoreMeta sets the metadata of a point restored from a snapshot or
+// store, leaving it nil if m holds none.
+func (p *Point) restoreMeta(m pointMeta) {
+	if m.version != 0 || m.removed || m.tenant != "" || m.radius != 0 ||
+		m.lifecycle != 0 || m.weight != 0 || m.ttl != 0 {
+		p.md = &m
+	}
+}
+
+// size returns the approximate size of the point in bytes when inserted.
+func (p *Point) size() int64 {
+	if p.md != nil && p.md.size != 0 {
+		return p.md.size
+	}
+	return p.bare()
+}
+
+// setSize sets the size of the point, holding it only if it is not the
+// size of the point alone.
+func (p *Point) setSize(n int64) {
+	if n == p.bare() {
+		if p.md != nil {
+			p.md.size = 0
+		}
+		return
+	}
+	p.setMeta().size = n
+}
+
+// bare returns the size of the point without the size of its data.
+func (p *Point) bare() int64 {
+	return pointOverhead + int64(len(p.id)+len(p.meta().tenant))
+}
+
+// clone returns a copy of the point with a copy of its metadata.
+func (p *Point) clone() *Point {
+	c := *p
+	if p.md != nil {
+		m := *p.md
+		c.md = &m
+	}
+	return &c
+}
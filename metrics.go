@@ -0,0 +1,30 @@
+package quadtree
+
+import (
+	"time"
+)
+
+// Metrics receives measurements of the operations of a tree, e.g. to
+// export them to a monitoring system as package metrics does. Queried is
+// called by concurrent queries so must be safe for concurrent use.
+type Metrics interface {
+	// Inserted is called for each point inserted and Removed for each
+	// point removed, whether by Remove, replacement, eviction or otherwise
+	Inserted()
+	Removed()
+	// Reinserted is called for each point an update moves out of its node,
+	// reinserting it from the nearest node containing its new location
+	Reinserted()
+	// Queried is called with the duration of each timed query, op being
+	// the name of the query such as "search" or "knearest"
+	Queried(op string, d time.Duration)
+}
+
+// WithMetrics reports the inserts, removes, reinsertions and query
+// durations of the tree to m. The size and shape of the tree are read
+// using Len and Stats.
+func WithMetrics(m Metrics) Option {
+	return func(s *state) {
+		s.metrics = m
+	}
+}
@@ -0,0 +1,208 @@
+// Package metrics exports the measurements of a QuadTree to monitoring
+// systems: a Collector records the inserts, removes, reinsertions and query
+// latencies of a tree created using quadtree.WithMetrics, and serves them
+// along with the size and shape of the tree in the Prometheus text format
+// or publishes them with expvar.
+//
+//	c := metrics.New("")
+//	qt := quadtree.New(boundary, 0, nil, quadtree.WithMetrics(c))
+//	http.Handle("/metrics", c.Handler(qt, &mu))
+//
+// Counters are totals since the collector was created, so rates such as
+// inserts per second are computed by the monitoring system, e.g. with
+// rate(quadtree_inserts_total[1m]) in Prometheus.
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/asim/quadtree"
+)
+
+// DefaultNamespace prefixes the metric names unless another is given to New.
+const DefaultNamespace = "quadtree"
+
+// buckets are the upper bounds of the query latency histogram in seconds.
+var buckets = []float64{
+	0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005,
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1,
+}
+
+// Collector implements quadtree.Metrics, counting the writes and timing the
+// queries of a tree. It is safe for concurrent use.
+type Collector struct {
+	namespace string
+
+	inserts   atomic.Uint64
+	removes   atomic.Uint64
+	reinserts atomic.Uint64
+
+	mu      sync.Mutex
+	queries map[string]*histogram
+}
+
+// histogram counts the queries of one type by latency bucket, the last
+// counting those slower than every bucket.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+var _ quadtree.Metrics = (*Collector)(nil)
+
+// New returns a Collector naming its metrics with the namespace, or
+// DefaultNamespace if it is empty.
+func New(namespace string) *Collector {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	return &Collector{
+		namespace: namespace,
+		queries:   make(map[string]*histogram),
+	}
+}
+
+// Inserted counts a point inserted.
+func (c *Collector) Inserted() {
+	c.inserts.Add(1)
+}
+
+// Removed counts a point removed.
+func (c *Collector) Removed() {
+	c.removes.Add(1)
+}
+
+// Reinserted counts a point moved out of its node by an update.
+func (c *Collector) Reinserted() {
+	c.reinserts.Add(1)
+}
+
+// Queried records the latency of a query.
+func (c *Collector) Queried(op string, d time.Duration) {
+	secs := d.Seconds()
+	i := sort.SearchFloat64s(buckets, secs)
+
+	c.mu.Lock()
+	h, ok := c.queries[op]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(buckets)+1)}
+		c.queries[op] = h
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += secs
+	c.mu.Unlock()
+}
+
+// Handler returns an http.Handler serving the metrics of the collector and
+// the size and shape of the tree in the Prometheus text exposition format.
+// If mu is not nil it is held while the tree is read.
+func (c *Collector) Handler(qt *quadtree.QuadTree, mu sync.Locker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Write(w, stats(qt, mu))
+	})
+}
+
+// Write writes the metrics of the collector and the tree statistics in the
+// Prometheus text exposition format.
+func (c *Collector) Write(w io.Writer, s quadtree.Stats) error {
+	bw := bufio.NewWriter(w)
+
+	counter := func(name, help string, v uint64) {
+		name = c.namespace + "_" + name
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+	}
+	gauge := func(name, help string, v int64) {
+		name = c.namespace + "_" + name
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, v)
+	}
+
+	counter("inserts_total", "Points inserted.", c.inserts.Load())
+	counter("removes_total", "Points removed.", c.removes.Load())
+	counter("reinserts_total", "Points moved out of their node by an update.", c.reinserts.Load())
+
+	gauge("points", "Points in the tree.", int64(s.Points))
+	gauge("nodes", "Nodes of the tree.", int64(s.Nodes))
+	gauge("leaves", "Nodes of the tree without children.", int64(s.Leaves))
+	gauge("depth", "Depth of the deepest node below the root.", int64(s.Depth))
+	gauge("bytes", "Estimated memory used by the nodes and points.", s.Bytes)
+
+	name := c.namespace + "_query_duration_seconds"
+	fmt.Fprintf(bw, "# HELP %s Latency of queries by operation.\n# TYPE %s histogram\n", name, name)
+
+	c.mu.Lock()
+	ops := make([]string, 0, len(c.queries))
+	for op := range c.queries {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	for _, op := range ops {
+		h := c.queries[op]
+		label := strconv.Quote(op)
+
+		var n uint64
+		for i, le := range buckets {
+			n += h.counts[i]
+			fmt.Fprintf(bw, "%s_bucket{op=%s,le=\"%g\"} %d\n", name, label, le, n)
+		}
+		fmt.Fprintf(bw, "%s_bucket{op=%s,le=\"+Inf\"} %d\n", name, label, h.count)
+		fmt.Fprintf(bw, "%s_sum{op=%s} %g\n", name, label, h.sum)
+		fmt.Fprintf(bw, "%s_count{op=%s} %d\n", name, label, h.count)
+	}
+	c.mu.Unlock()
+
+	return bw.Flush()
+}
+
+// Publish publishes the metrics of the collector and the tree with expvar
+// as a map under the name, served by expvar's /debug/vars handler. If mu
+// is not nil it is held while the tree is read. Like expvar.Publish it
+// panics if the name is already in use.
+func (c *Collector) Publish(name string, qt *quadtree.QuadTree, mu sync.Locker) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		s := stats(qt, mu)
+
+		c.mu.Lock()
+		queries := make(map[string]interface{}, len(c.queries))
+		for op, h := range c.queries {
+			mean := 0.0
+			if h.count > 0 {
+				mean = h.sum / float64(h.count)
+			}
+			queries[op] = map[string]interface{}{"count": h.count, "mean_seconds": mean}
+		}
+		c.mu.Unlock()
+
+		return map[string]interface{}{
+			"inserts":   c.inserts.Load(),
+			"removes":   c.removes.Load(),
+			"reinserts": c.reinserts.Load(),
+			"points":    s.Points,
+			"nodes":     s.Nodes,
+			"leaves":    s.Leaves,
+			"depth":     s.Depth,
+			"bytes":     s.Bytes,
+			"queries":   queries,
+		}
+	}))
+}
+
+func stats(qt *quadtree.QuadTree, mu sync.Locker) quadtree.Stats {
+	if mu != nil {
+		mu.Lock()
+		defer mu.Unlock()
+	}
+	return qt.Stats()
+}
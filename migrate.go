@@ -0,0 +1,109 @@
+package quadtree
+
+// migrated is a point moved by Migrate, along with the point of dst it
+// replaced and when each was last updated, to undo the move.
+type migrated struct {
+	point, old          *Point
+	updated, oldUpdated int64
+}
+
+// Migrate moves the points of src within the region into dst, e.g. when
+// resharding, returning the number moved. Points are moved one at a time
+// and an error such as ErrOutOfBounds, ErrDuplicateID or ErrQuotaExceeded
+// moves those already moved back to src, returning any points of dst they
+// replaced, so both trees hold the points they did before. Points keep
+// their ID and soft removal, and their identity unless a Snapshot of src
+// still holds them, when they move as copies. The move is not subject to
+// WithWriteRate. It returns ErrRegionLocked, moving nothing, if a point
+// is within a region locked by LockRegion in either tree. Points of a tree
+// capped by WithMaxPoints are evicted once every point has moved.
+//
+// Migrate takes no locks of its own, so is not safe for concurrent use
+// with other writes or queries of either tree; callers serialising access
+// to the trees must hold the write locks of both for the duration.
+func Migrate(src, dst *QuadTree, region *AABB) (int, error) {
+	if src.state.readOnly || dst.state.readOnly {
+		return 0, ErrReadOnly
+	}
+
+	points := src.Search(region, WithRemoved())
+	if len(points) == 0 {
+		return 0, nil
+	}
+
+	// refuse what can be checked up front before moving any point
+	for _, p := range points {
+		if !dst.boundary.ContainsPoint(p) {
+			return 0, ErrOutOfBounds
+		}
+		if src.state.locked(p) || dst.state.locked(p) {
+			return 0, ErrRegionLocked
+		}
+	}
+
+	moved := make([]migrated, 0, len(points))
+
+	for _, p := range points {
+		m := migrated{point: p, updated: p.updated}
+		if !src.remove(p) {
+			continue
+		}
+		src.dropped(p)
+
+		// a point a snapshot of src still holds moves as a copy
+		if src.state.shared(p) {
+			p = p.clone()
+			m.point = p
+		}
+
+		old, err := dst.admit(p)
+		if err == nil && !dst.insert(p) {
+			err = ErrOutOfBounds
+		}
+		if err != nil {
+			src.reinstate(p, m.updated)
+			unmigrate(src, dst, moved)
+			return 0, err
+		}
+
+		if old != nil {
+			m.old, m.oldUpdated = old, old.updated
+		}
+		dst.inserted(p, old)
+		moved = append(moved, m)
+	}
+
+	// evict only once every point has moved, so a failed move has no
+	// evictions to undo
+	dst.trim(nil)
+	return len(moved), nil
+}
+
+// unmigrate moves the points moved by Migrate back from dst to src, the
+// last moved first, returning to dst the points they replaced.
+func unmigrate(src, dst *QuadTree, moved []migrated) {
+	for i := len(moved) - 1; i >= 0; i-- {
+		m := moved[i]
+		if dst.root().remove(m.point) {
+			dst.dropped(m.point)
+		}
+		if m.old != nil {
+			dst.reinstate(m.old, m.oldUpdated)
+		}
+		src.reinstate(m.point, m.updated)
+	}
+}
+
+// reinstate returns a point removed from the tree, as last updated at
+// updated.
+func (qt *QuadTree) reinstate(p *Point, updated int64) {
+	prev := p
+	p = qt.state.reinserting(p)
+	if p != prev {
+		defer qt.state.forwardTo(prev, p)
+	}
+	p.setSize(qt.sizeOf(p))
+	qt.rinsert(p)
+	qt.inserted(p, nil)
+	p.updated = updated
+}
@@ -0,0 +1,91 @@
+package quadtree_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/asim/quadtree"
+	"github.com/asim/quadtree/testdata"
+)
+
+func TestMigrate(t *testing.T) {
+	points := testdata.Uniform(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 500)
+	src := quadtree.New(quadtree.WorldBounds(), 0, nil, quadtree.WithCapacity(4))
+	for _, p := range points {
+		src.Insert(p)
+	}
+	dst := quadtree.New(quadtree.WorldBounds(), 0, nil, quadtree.WithCapacity(4))
+
+	region := quadtree.NewAABB(quadtree.NewPoint(0, 0, nil), quadtree.NewPoint(45, 90, nil))
+	want := testdata.Search(points, region)
+
+	n, err := quadtree.Migrate(src, dst, region)
+	if err != nil || n != len(want) {
+		t.Fatalf("Migrate returned %d, %v, want %d, nil", n, err, len(want))
+	}
+	for _, qt := range []*quadtree.QuadTree{src, dst} {
+		if err := qt.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if src.Count(region) != 0 || dst.Len() != len(want) || src.Len() != len(points)-len(want) {
+		t.Fatalf("after Migrate src holds %d, dst %d, want %d, %d", src.Len(), dst.Len(), len(points)-len(want), len(want))
+	}
+}
+
+func TestMigrateRollback(t *testing.T) {
+	points := testdata.Uniform(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 500)
+	src := quadtree.New(quadtree.WorldBounds(), 0, nil, quadtree.WithCapacity(4))
+	for i, p := range points {
+		// pairs of points share an ID, replacing one another in dst
+		x, y := p.Coordinates()
+		points[i] = quadtree.NewPointID(fmt.Sprint(i/2), x, y, i)
+		src.Insert(points[i])
+	}
+
+	dst := quadtree.New(quadtree.WorldBounds(), 0, nil,
+		quadtree.WithCapacity(4), quadtree.WithUniqueIDs(quadtree.IDReplace), quadtree.WithDuplicates(quadtree.DuplicateReject))
+	held := testdata.Uniform(rand.New(rand.NewSource(2)), quadtree.WorldBounds(), 100)
+	for i, p := range held {
+		x, y := p.Coordinates()
+		held[i] = quadtree.NewPointID(fmt.Sprint(i), x, y, i)
+		dst.Insert(held[i])
+	}
+
+	// a point of dst at the location of the last point of src to move
+	region := quadtree.WorldBounds()
+	moving := src.Search(region)
+	x, y := moving[len(moving)-1].Coordinates()
+	blocker := quadtree.NewPoint(x, y, nil)
+	dst.Insert(blocker)
+	held = append(held, blocker)
+
+	if n, err := quadtree.Migrate(src, dst, region); !errors.Is(err, quadtree.ErrDuplicatePoint) || n != 0 {
+		t.Fatalf("Migrate returned %d, %v, want 0, ErrDuplicatePoint", n, err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		qt     *quadtree.QuadTree
+		points []*quadtree.Point
+	}{{"src", src, points}, {"dst", dst, held}} {
+		if err := tt.qt.Validate(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := tt.qt.Search(region)
+		if len(got) != len(tt.points) {
+			t.Fatalf("%s holds %d points after a failed Migrate, want %d", tt.name, len(got), len(tt.points))
+		}
+		for _, p := range tt.points {
+			if !slices.Contains(got, p) {
+				t.Fatalf("%s lost point %v after a failed Migrate", tt.name, p.Data())
+			}
+			if tt.name == "dst" && p.ID() != "" && tt.qt.Get(p.ID()) != p {
+				t.Fatalf("%s indexes another point for ID %q", tt.name, p.ID())
+			}
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package quadtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// The frozen file format of WriteFile, little endian throughout:
+//
+//	header  magic, version, node count, point count, blob length
+//	nodes   the nodes of the FrozenTree in order, frozenRecord bytes each
+//	points  the points in order, pointRecord bytes each
+//	blob    the IDs and encoded data of the points
+//
+// Points refer to their ID and data by offset into the blob, so a query
+// reads only the records it visits.
+var frozenMagic = [4]byte{'q', 't', 'f', 'z'}
+
+const (
+	frozenVersion = 1
+	frozenHeader  = 4 + 4 + 8 + 8 + 8
+	// bounds, child, start and end
+	frozenRecord = 4*8 + 3*4
+	// x, y, seq, id and data offsets, id and data lengths
+	pointRecord = 5*8 + 2*4
+)
+
+// WriteFile writes the frozen tree to a file in a format which OpenMmap
+// maps into memory to query without loading it. Point data is encoded by
+// the codec of the tree frozen. Only the coordinates, ID and data of
+// points are kept.
+func (ft *FrozenTree) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := ft.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (ft *FrozenTree) write(w io.Writer) error {
+	codec := ft.codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+
+	data := make([][]byte, len(ft.points))
+	blob := uint64(0)
+	for i, p := range ft.points {
+		if p.data != nil {
+			b, err := codec.Marshal(p.data)
+			if err != nil {
+				return err
+			}
+			data[i] = b
+		}
+		blob += uint64(len(p.id) + len(data[i]))
+	}
+
+	bw := bufio.NewWriter(w)
+
+	b := append(frozenMagic[:0:0], frozenMagic[:]...)
+	b = binary.LittleEndian.AppendUint32(b, frozenVersion)
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(ft.nodes)))
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(ft.points)))
+	b = binary.LittleEndian.AppendUint64(b, blob)
+	bw.Write(b)
+
+	for _, n := range ft.nodes {
+		b = b[:0]
+		b = appendFloat(b, n.minX)
+		b = appendFloat(b, n.minY)
+		b = appendFloat(b, n.maxX)
+		b = appendFloat(b, n.maxY)
+		b = binary.LittleEndian.AppendUint32(b, uint32(n.child))
+		b = binary.LittleEndian.AppendUint32(b, uint32(n.start))
+		b = binary.LittleEndian.AppendUint32(b, uint32(n.end))
+		bw.Write(b)
+	}
+
+	off := uint64(0)
+	for i, p := range ft.points {
+		b = b[:0]
+		b = appendFloat(b, p.x)
+		b = appendFloat(b, p.y)
+		b = binary.LittleEndian.AppendUint64(b, p.seq)
+		b = binary.LittleEndian.AppendUint64(b, off)
+		b = binary.LittleEndian.AppendUint64(b, off+uint64(len(p.id)))
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(p.id)))
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(data[i])))
+		bw.Write(b)
+		off += uint64(len(p.id) + len(data[i]))
+	}
+
+	for i, p := range ft.points {
+		bw.WriteString(p.id)
+		bw.Write(data[i])
+	}
+
+	return bw.Flush()
+}
+
+// MmapTree is a FrozenTree queried in place from a file written by
+// WriteFile and mapped into memory, so a tree larger than memory is served
+// with the operating system paging in only the nodes and points queries
+// visit. Points are decoded as they are returned, so each query returns
+// new points. It is safe for concurrent use and must be closed once done.
+type MmapTree struct {
+	b      []byte
+	nodes  []byte
+	points []byte
+	blob   []byte
+
+	distance DistanceFunc
+	codec    Codec
+}
+
+// OpenMmap maps a file written by FrozenTree.WriteFile into memory,
+// returning ErrInvalidSnapshot if it is not one. Of the options of a
+// QuadTree only WithCodec, to decode point data, and the distance of
+// WithDistance or WithCoordinateSystem, to order KNearest, apply. Systems
+// without memory mapping read the file into memory instead.
+func OpenMmap(path string, opts ...Option) (*MmapTree, error) {
+	s := &state{}
+	for _, o := range opts {
+		o(s)
+	}
+
+	b, err := mmapFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	mt := &MmapTree{b: b, distance: s.distance, codec: s.dataCodec()}
+	if mt.distance == nil {
+		mt.distance = planar
+	}
+	if err := mt.parse(); err != nil {
+		munmap(b)
+		return nil, err
+	}
+	return mt, nil
+}
+
+// parse checks the header and that the sections fit the file.
+func (mt *MmapTree) parse() error {
+	b := mt.b
+	if len(b) < frozenHeader || [4]byte(b[:4]) != frozenMagic {
+		return ErrInvalidSnapshot
+	}
+	if binary.LittleEndian.Uint32(b[4:]) > frozenVersion {
+		return ErrSnapshotVersion
+	}
+
+	nodes := binary.LittleEndian.Uint64(b[8:])
+	points := binary.LittleEndian.Uint64(b[16:])
+	blob := binary.LittleEndian.Uint64(b[24:])
+
+	size := uint64(len(b) - frozenHeader)
+	if nodes == 0 || nodes > size/frozenRecord || points > size/pointRecord ||
+		nodes*frozenRecord+points*pointRecord+blob != size {
+		return ErrInvalidSnapshot
+	}
+
+	b = b[frozenHeader:]
+	mt.nodes, b = b[:nodes*frozenRecord], b[nodes*frozenRecord:]
+	mt.points, mt.blob = b[:points*pointRecord], b[points*pointRecord:]
+	return nil
+}
+
+// Close unmaps the file. Points already returned remain valid.
+func (mt *MmapTree) Close() error {
+	b := mt.b
+	mt.b, mt.nodes, mt.points, mt.blob = nil, nil, nil, nil
+	if b == nil {
+		return nil
+	}
+	return munmap(b)
+}
+
+// Len returns the number of points in the tree.
+func (mt *MmapTree) Len() int {
+	return len(mt.points) / pointRecord
+}
+
+// Search returns all the points within the axis aligned bounding box.
+func (mt *MmapTree) Search(a *AABB) []*Point {
+	results := []*Point{}
+	if noBox(a) || len(mt.nodes) == 0 {
+		return results
+	}
+	return mt.search(0, a, nil, results)
+}
+
+// KNearest returns the k points within the axis aligned bounding box
+// nearest to its center which pass the filter, ordered by distance.
+func (mt *MmapTree) KNearest(a *AABB, i int, fn filter) []*Point {
+	results := []*Point{}
+	if noBox(a) || i <= 0 || len(mt.nodes) == 0 {
+		return results
+	}
+
+	results = mt.search(0, a, fn, results)
+	sortPoints(results, a.center, mt.distance)
+	if len(results) > i {
+		results = results[:i]
+	}
+	return results
+}
+
+// node decodes the i'th node.
+func (mt *MmapTree) node(i int) frozenNode {
+	b := mt.nodes[i*frozenRecord:]
+	return frozenNode{
+		minX:  math.Float64frombits(binary.LittleEndian.Uint64(b)),
+		minY:  math.Float64frombits(binary.LittleEndian.Uint64(b[8:])),
+		maxX:  math.Float64frombits(binary.LittleEndian.Uint64(b[16:])),
+		maxY:  math.Float64frombits(binary.LittleEndian.Uint64(b[24:])),
+		child: int32(binary.LittleEndian.Uint32(b[32:])),
+		start: int32(binary.LittleEndian.Uint32(b[36:])),
+		end:   int32(binary.LittleEndian.Uint32(b[40:])),
+	}
+}
+
+// x returns the x coordinate of the i'th point.
+func (mt *MmapTree) x(i int) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(mt.points[i*pointRecord:]))
+}
+
+// point decodes the i'th point, its data left as nil if it cannot be
+// decoded.
+func (mt *MmapTree) point(i int) *Point {
+	b := mt.points[i*pointRecord:]
+	p := &Point{
+		x:   math.Float64frombits(binary.LittleEndian.Uint64(b)),
+		y:   math.Float64frombits(binary.LittleEndian.Uint64(b[8:])),
+		seq: binary.LittleEndian.Uint64(b[16:]),
+	}
+
+	idOff, dataOff := binary.LittleEndian.Uint64(b[24:]), binary.LittleEndian.Uint64(b[32:])
+	idLen, dataLen := uint64(binary.LittleEndian.Uint32(b[40:])), uint64(binary.LittleEndian.Uint32(b[44:]))
+	blob := uint64(len(mt.blob))
+
+	if idOff <= blob && idLen <= blob-idOff {
+		p.id = string(mt.blob[idOff : idOff+idLen])
+	}
+	if dataLen > 0 && dataOff <= blob && dataLen <= blob-dataOff {
+		if data, err := mt.codec.Unmarshal(mt.blob[dataOff : dataOff+dataLen]); err == nil {
+			p.data = data
+		}
+	}
+	return p
+}
+
+func (mt *MmapTree) search(i int, a *AABB, fn filter, results []*Point) []*Point {
+	if i >= len(mt.nodes)/frozenRecord {
+		return results
+	}
+
+	n := mt.node(i)
+	count := int32(mt.Len())
+	if n.start >= n.end || n.start < 0 || n.end > count {
+		return results
+	}
+
+	minX, minY := a.center.x-a.half.x, a.center.y-a.half.y
+	maxX, maxY := a.center.x+a.half.x, a.center.y+a.half.y
+
+	if n.maxX < minX || n.maxY < minY || n.minX > maxX || n.minY > maxY {
+		return results
+	}
+
+	// subtree entirely within the box
+	if fn == nil && n.minX >= minX && n.minY >= minY && n.maxX <= maxX && n.maxY <= maxY {
+		for j := n.start; j < n.end; j++ {
+			results = append(results, mt.point(int(j)))
+		}
+		return results
+	}
+
+	lo, hi := int(n.start), int(n.end)
+	if n.child != 0 {
+		// children follow their parent, so a malformed file cannot loop
+		if n.child <= int32(i) || int(n.child)+4 > len(mt.nodes)/frozenRecord {
+			return results
+		}
+		hi = int(mt.node(int(n.child)).start)
+	} else {
+		// the leaf is sorted by x
+		lo += sort.Search(hi-lo, func(j int) bool { return mt.x(lo+j) >= minX })
+		hi = lo + sort.Search(hi-lo, func(j int) bool { return mt.x(lo+j) > maxX })
+	}
+
+	for j := lo; j < hi && j < int(count); j++ {
+		if p := mt.point(j); a.ContainsPoint(p) && (fn == nil || fn(p)) {
+			results = append(results, p)
+		}
+	}
+
+	if n.child != 0 {
+		for j := int(n.child); j < int(n.child)+4; j++ {
+			results = mt.search(j, a, fn, results)
+		}
+	}
+
+	return results
+}
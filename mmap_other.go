@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package quadtree
+
+import "os"
+
+// mmapFile reads the file into memory where mapping is not supported.
+func mmapFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func munmap(b []byte) error {
+	return nil
+}
@@ -0,0 +1,17 @@
+package quadtree
+
+// MoveTo moves the point to x, y, within the node holding it if it still
+// fits or otherwise reinserting it up the tree. It returns false, leaving
+// the point where it is, if the point is not in the tree or x, y lies
+// outside its boundary.
+func (qt *QuadTree) MoveTo(p *Point, x, y float64) bool {
+	if p == nil {
+		return false
+	}
+	return qt.Update(p, &Point{x: x, y: y})
+}
+
+// MoveToID is MoveTo for the point with the ID.
+func (qt *QuadTree) MoveToID(id string, x, y float64) bool {
+	return qt.MoveTo(qt.state.index()[id], x, y)
+}
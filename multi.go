@@ -0,0 +1,51 @@
+package quadtree
+
+// SearchMulti returns the points within any of the bounding boxes in a
+// single traversal. Each point is returned once even if boxes overlap.
+func (qt *QuadTree) SearchMulti(boxes []*AABB, opts ...QueryOption) []*Point {
+	var valid []*AABB
+	for _, a := range boxes {
+		if !noBox(a) {
+			valid = append(valid, a)
+		}
+	}
+
+	var results []*Point
+	qt.searchMulti(valid, qt.newQuery(opts), &results)
+	return nonNil(results)
+}
+
+func (qt *QuadTree) searchMulti(boxes []*AABB, q *query, results *[]*Point) {
+	var hits []*AABB
+	for _, a := range boxes {
+		if qt.boundary.Intersect(a) {
+			hits = append(hits, a)
+		}
+	}
+
+	if len(hits) == 0 {
+		return
+	}
+
+	qt.hit()
+
+	for _, p := range qt.leaf() {
+		if !q.match(p) {
+			continue
+		}
+		for _, a := range hits {
+			if a.ContainsPoint(p) {
+				*results = append(*results, p)
+				break
+			}
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.searchMulti(hits, q, results)
+	}
+}
@@ -0,0 +1,356 @@
+package quadtree
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+)
+
+// candidate is a node or point queued by the best-first nearest search.
+type candidate struct {
+	node  *QuadTree
+	point *Point
+	dist  float64
+}
+
+type candidates []candidate
+
+func (c candidates) Len() int { return len(c) }
+
+func (c candidates) Less(i, j int) bool {
+	if c[i].dist != c[j].dist {
+		return c[i].dist < c[j].dist
+	}
+	// nodes before points at equal distance, so that every point at that
+	// distance is queued before any is taken, then insertion order
+	if c[i].point == nil || c[j].point == nil {
+		return c[i].point == nil && c[j].point != nil
+	}
+	return c[i].point.seq < c[j].point.seq
+}
+
+func (c candidates) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+
+func (c *candidates) Push(x interface{}) { *c = append(*c, x.(candidate)) }
+
+func (c *candidates) Pop() interface{} {
+	old := *c
+	x := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return x
+}
+
+// push and pop are heap.Push and heap.Pop without boxing the candidate in
+// an interface.
+func (c *candidates) push(e candidate) {
+	*c = append(*c, e)
+	heap.Fix(c, len(*c)-1)
+}
+
+func (c *candidates) pop() candidate {
+	old := *c
+	n := len(old) - 1
+	old.Swap(0, n)
+	*c = old[:n]
+	if n > 0 {
+		heap.Fix(c, 0)
+	}
+	return old[n]
+}
+
+// minDist returns the squared planar distance from p to the nearest point
+// of the bounding box, 0 if p is inside it.
+func (a *AABB) minDist(p *Point) float64 {
+	dx := math.Max(math.Abs(p.x-a.center.x)-a.half.x, 0)
+	dy := math.Max(math.Abs(p.y-a.center.y)-a.half.y, 0)
+	return dx*dx + dy*dy
+}
+
+// Nearest returns the point nearest to p, or nil if the tree holds no
+// points matching the query.
+func (qt *QuadTree) Nearest(p *Point, opts ...QueryOption) *Point {
+	results := qt.NearestN(p, 1, opts...)
+	if len(results) == 0 {
+		return nil
+	}
+	return results[0]
+}
+
+// NearestN returns the k points nearest to p ordered by distance, without
+// bounding the search by a box. Distance is planar unless the tree was
+// created using WithDistance or WithHaversine.
+func (qt *QuadTree) NearestN(p *Point, k int, opts ...QueryOption) []*Point {
+	if p == nil || k <= 0 {
+		return []*Point{}
+	}
+
+	qt.state.acquire()
+	defer qt.state.release()
+
+	t := qt.begin()
+	q := qt.newQuery(opts)
+
+	results := qt.nearest(p, k, q.filter(nil), q.distinct)
+
+	qt.end(t, "nearest", &AABB{p, &Point{}}, k)
+	return nonNil(results)
+}
+
+// nearest returns the k points nearest to p which pass the filter, ordered
+// by distance, and only the nearest of each key if key is not nil. Nodes
+// are visited best first, closest boundary first, and the search stops
+// once k points are closer than every node not yet visited.
+func (qt *QuadTree) nearest(p *Point, k int, fn filter, key func(*Point) string) []*Point {
+	var results []*Point
+
+	if k <= 0 {
+		return results
+	}
+
+	var seen map[string]bool
+	if key != nil {
+		seen = make(map[string]bool)
+	}
+
+	dist := qt.distance()
+	queue := &candidates{{node: qt, dist: qt.bound(p, qt.boundary)}}
+
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(candidate)
+
+		if c.point != nil {
+			if seen != nil {
+				// points are taken nearest first, so the first of a key is
+				// its nearest
+				id := key(c.point)
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+			}
+			results = append(results, c.point)
+			if len(results) == k {
+				break
+			}
+			continue
+		}
+
+		for _, ep := range c.node.leaf() {
+			if fn == nil || fn(ep) {
+				heap.Push(queue, candidate{point: ep, dist: dist(ep, p)})
+			}
+		}
+
+		if c.node.nodes[0] == nil {
+			continue
+		}
+
+		for _, node := range c.node.nodes {
+			heap.Push(queue, candidate{node: node, dist: qt.bound(p, node.boundary)})
+		}
+	}
+
+	return results
+}
+
+// farthest is a max-heap of points by distance, holding the k nearest
+// points found so far with the furthest on top.
+type farthest []candidate
+
+func (f farthest) Len() int { return len(f) }
+
+func (f farthest) Less(i, j int) bool {
+	if f[i].dist != f[j].dist {
+		return f[i].dist > f[j].dist
+	}
+	return f[i].point.seq > f[j].point.seq
+}
+
+func (f farthest) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+
+func (f *farthest) Push(x interface{}) { *f = append(*f, x.(candidate)) }
+
+func (f *farthest) Pop() interface{} {
+	old := *f
+	x := old[len(old)-1]
+	*f = old[:len(old)-1]
+	return x
+}
+
+// push and pop are heap.Push and heap.Pop without boxing the candidate in
+// an interface, so the search allocates only when the heap grows.
+func (f *farthest) push(e candidate) {
+	*f = append(*f, e)
+	heap.Fix(f, len(*f)-1)
+}
+
+func (f *farthest) pop() candidate {
+	old := *f
+	n := len(old) - 1
+	old.Swap(0, n)
+	*f = old[:n]
+	if n > 0 {
+		heap.Fix(f, 0)
+	}
+	return old[n]
+}
+
+// before reports whether c is nearer than d, or as near and inserted
+// earlier.
+func (c candidate) before(d candidate) bool {
+	return c.dist < d.dist || c.dist == d.dist && c.point.seq < d.point.seq
+}
+
+// offer adds the point to the k nearest found unless k are held and it is
+// no nearer than the furthest of them.
+func (f *farthest) offer(e candidate, k int) {
+	if f.Len() < k {
+		f.push(e)
+		return
+	}
+	if e.before((*f)[0]) {
+		(*f)[0] = e
+		heap.Fix(f, 0)
+	}
+}
+
+// offerDistinct is offer holding only the nearest point found for each key,
+// held mapping the keys of the points held to them. A point nearer than the
+// one held for its key replaces it.
+func (f *farthest) offerDistinct(e candidate, k int, key func(*Point) string, held map[string]candidate) {
+	id := key(e.point)
+
+	if h, ok := held[id]; ok {
+		if !e.before(h) {
+			return
+		}
+		i := slices.IndexFunc(*f, func(c candidate) bool {
+			return c.point == h.point
+		})
+		(*f)[i] = e
+		heap.Fix(f, i)
+		held[id] = e
+		return
+	}
+
+	if f.Len() == k {
+		top := (*f)[0]
+		if !e.before(top) {
+			return
+		}
+		delete(held, key(top.point))
+		heap.Pop(f)
+	}
+	heap.Push(f, e)
+	held[id] = e
+}
+
+// knearest appends to dst the k points within a nearest to its center which
+// match the query and pass the filter, ordered by distance. Nodes intersecting a, split where the
+// coordinate system of the tree wraps, are visited best first and the
+// search stops once the k nearest points found are no further away than
+// every node not yet visited. Distances are measured in the plane tangent
+// to the center if local is set.
+func (qt *QuadTree) knearest(dst []*Point, a *AABB, k int, fn filter, q *query) []*Point {
+	boxes, ok := qt.state.wrap(a)
+	if !ok {
+		boxes = []*AABB{a}
+	}
+
+	if k <= 0 || !intersectsAny(boxes, qt.boundary) {
+		return dst
+	}
+
+	center := a.center
+	dist := qt.distance()
+	bound := qt.bound
+	if q.local {
+		dist = tangentPlane(center)
+		bound = func(p *Point, b *AABB) float64 {
+			return dist(p, b.closest(p))
+		}
+	}
+
+	s := getSearch()
+	defer putSearch(s)
+
+	queue, best := &s.queue, &s.best
+	queue.push(candidate{node: qt, dist: bound(center, qt.boundary)})
+
+	var held map[string]candidate
+	if q.distinct != nil {
+		held = make(map[string]candidate)
+	}
+
+	for queue.Len() > 0 {
+		c := queue.pop()
+
+		if best.Len() == k && c.dist > (*best)[0].dist || q.stopped() {
+			break
+		}
+
+		c.node.hit()
+
+		points := c.node.leaf()
+		if len(boxes) == 1 {
+			points = c.node.scan(boxes[0])
+		}
+
+		for _, p := range points {
+			if !containsAny(boxes, p) || !q.match(p) || fn != nil && !fn(p) {
+				continue
+			}
+
+			e := candidate{point: p, dist: dist(p, center)}
+			if held != nil {
+				best.offerDistinct(e, k, q.distinct, held)
+			} else {
+				best.offer(e, k)
+			}
+		}
+
+		if c.node.nodes[0] == nil {
+			continue
+		}
+
+		for _, node := range c.node.nodes {
+			if intersectsAny(boxes, node.boundary) && q.tagged(node) {
+				queue.push(candidate{node: node, dist: bound(center, node.boundary)})
+			}
+		}
+	}
+
+	n := len(dst)
+	dst = append(dst, make([]*Point, best.Len())...)
+	for i := len(dst) - 1; i >= n; i-- {
+		dst[i] = best.pop().point
+	}
+	return dst
+}
+
+// Interpolate estimates a value at the location of the point from the k
+// nearest points using inverse distance weighting, e.g. temperature from a
+// sensor network. A point at the exact location determines the value. It
+// returns NaN if the tree holds no points.
+func (qt *QuadTree) Interpolate(at *Point, k int, value func(*Point) float64) float64 {
+	if at == nil {
+		return math.NaN()
+	}
+
+	var sum, weights float64
+
+	for _, p := range qt.nearest(at, k, qt.newQuery(nil).filter(nil), nil) {
+		d := planar(p, at)
+		if d == 0 {
+			return value(p)
+		}
+		sum += value(p) / d
+		weights += 1 / d
+	}
+
+	if weights == 0 {
+		return math.NaN()
+	}
+
+	return sum / weights
+}
@@ -0,0 +1,354 @@
+package quadtree
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Point3 is a point in three dimensions held by an Octree, e.g. a drone at
+// an altitude. Coordinates share a unit, such as metres in a local frame,
+// as distances between points are straight lines.
+type Point3 struct {
+	x    float64
+	y    float64
+	z    float64
+	data interface{}
+	// insertion sequence used to break ties
+	seq uint64
+}
+
+// NewPoint3 creates a point at x, y, z holding the data.
+func NewPoint3(x, y, z float64, data interface{}) *Point3 {
+	return &Point3{x: x, y: y, z: z, data: data}
+}
+
+// Coordinates returns the x, y and z coordinates of the point.
+func (p *Point3) Coordinates() (float64, float64, float64) {
+	return p.x, p.y, p.z
+}
+
+// Data returns the data stored within the point.
+func (p *Point3) Data() interface{} {
+	return p.data
+}
+
+// AABB3 is an axis aligned bounding box in three dimensions.
+type AABB3 struct {
+	center *Point3
+	half   *Point3
+}
+
+// NewAABB3 creates a box of the half extents along each axis around the
+// center.
+func NewAABB3(center, half *Point3) *AABB3 {
+	return &AABB3{center, half}
+}
+
+// Center returns the center of the box.
+func (a *AABB3) Center() *Point3 {
+	return a.center
+}
+
+// Half returns the half extents of the box.
+func (a *AABB3) Half() *Point3 {
+	return a.half
+}
+
+// ContainsPoint reports whether the point is within the box.
+func (a *AABB3) ContainsPoint(p *Point3) bool {
+	return p.x >= a.center.x-a.half.x && p.x <= a.center.x+a.half.x &&
+		p.y >= a.center.y-a.half.y && p.y <= a.center.y+a.half.y &&
+		p.z >= a.center.z-a.half.z && p.z <= a.center.z+a.half.z
+}
+
+// Intersect reports whether the boxes overlap.
+func (a *AABB3) Intersect(b *AABB3) bool {
+	return b.center.x+b.half.x >= a.center.x-a.half.x && b.center.x-b.half.x <= a.center.x+a.half.x &&
+		b.center.y+b.half.y >= a.center.y-a.half.y && b.center.y-b.half.y <= a.center.y+a.half.y &&
+		b.center.z+b.half.z >= a.center.z-a.half.z && b.center.z-b.half.z <= a.center.z+a.half.z
+}
+
+// minDist returns the squared distance from p to the nearest point of the
+// box, 0 if p is inside it.
+func (a *AABB3) minDist(p *Point3) float64 {
+	dx := math.Max(math.Abs(p.x-a.center.x)-a.half.x, 0)
+	dy := math.Max(math.Abs(p.y-a.center.y)-a.half.y, 0)
+	dz := math.Max(math.Abs(p.z-a.center.z)-a.half.z, 0)
+	return dx*dx + dy*dy + dz*dz
+}
+
+// dist3 returns the squared distance between two points.
+func dist3(p, q *Point3) float64 {
+	dx, dy, dz := p.x-q.x, p.y-q.y, p.z-q.z
+	return dx*dx + dy*dy + dz*dz
+}
+
+// Octree is the three dimensional counterpart of a QuadTree, dividing its
+// boundary into eight octants rather than four quadrants, with Insert,
+// Remove, Update, Search and KNearest measured in straight lines. Like a
+// QuadTree it is not safe for concurrent use.
+type Octree struct {
+	boundary *AABB3
+	depth    int
+	points   []*Point3
+	nodes    [8]*Octree
+	state    *octState
+}
+
+// octState is shared by every node of an octree.
+type octState struct {
+	capacity int
+	maxDepth int
+	seq      uint64
+	count    int
+}
+
+// NewOctree creates an empty *Octree covering the boundary. Of the options
+// of a QuadTree only WithCapacity and WithMaxDepth apply.
+func NewOctree(boundary *AABB3, opts ...Option) *Octree {
+	s := &state{capacity: Capacity, maxDepth: MaxDepth}
+	for _, o := range opts {
+		o(s)
+	}
+
+	return &Octree{
+		boundary: boundary,
+		state:    &octState{capacity: s.capacity, maxDepth: s.maxDepth},
+	}
+}
+
+// Len returns the number of points in the tree.
+func (ot *Octree) Len() int {
+	return ot.state.count
+}
+
+// Boundary returns the boundary of the tree.
+func (ot *Octree) Boundary() *AABB3 {
+	return ot.boundary
+}
+
+// Insert inserts the point, reporting false if it is outside the boundary.
+func (ot *Octree) Insert(p *Point3) bool {
+	if p == nil || !ot.insert(p) {
+		return false
+	}
+
+	if p.seq == 0 {
+		ot.state.seq++
+		p.seq = ot.state.seq
+	}
+	ot.state.count++
+	return true
+}
+
+func (ot *Octree) insert(p *Point3) bool {
+	if !ot.boundary.ContainsPoint(p) {
+		return false
+	}
+
+	if ot.nodes[0] == nil {
+		if len(ot.points) < ot.state.capacity || ot.depth >= ot.state.maxDepth {
+			ot.points = append(ot.points, p)
+			return true
+		}
+		ot.divide()
+	}
+
+	for _, node := range ot.nodes {
+		if node.insert(p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ot *Octree) divide() {
+	c := ot.boundary.center
+	h := &Point3{x: ot.boundary.half.x / 2, y: ot.boundary.half.y / 2, z: ot.boundary.half.z / 2}
+
+	// octant i lies on the positive side of x if bit 0 is set, of y if
+	// bit 1 and of z if bit 2
+	for i := range ot.nodes {
+		center := &Point3{x: c.x - h.x, y: c.y - h.y, z: c.z - h.z}
+		if i&1 != 0 {
+			center.x = c.x + h.x
+		}
+		if i&2 != 0 {
+			center.y = c.y + h.y
+		}
+		if i&4 != 0 {
+			center.z = c.z + h.z
+		}
+		ot.nodes[i] = &Octree{
+			boundary: &AABB3{center, h},
+			depth:    ot.depth + 1,
+			state:    ot.state,
+		}
+	}
+
+	for _, p := range ot.points {
+		for _, node := range ot.nodes {
+			if node.insert(p) {
+				break
+			}
+		}
+	}
+	ot.points = nil
+}
+
+// Remove removes the point, reporting whether it was in the tree.
+func (ot *Octree) Remove(p *Point3) bool {
+	if p == nil || !ot.remove(p) {
+		return false
+	}
+	ot.state.count--
+	return true
+}
+
+func (ot *Octree) remove(p *Point3) bool {
+	if !ot.boundary.ContainsPoint(p) {
+		return false
+	}
+
+	if ot.nodes[0] == nil {
+		for i, ep := range ot.points {
+			if ep == p {
+				last := len(ot.points) - 1
+				ot.points[i] = ot.points[last]
+				ot.points[last] = nil
+				ot.points = ot.points[:last]
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, node := range ot.nodes {
+		if node.remove(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Update moves the point to the location of np, reporting false and
+// leaving it in place if it is not in the tree or np is outside the
+// boundary.
+func (ot *Octree) Update(p *Point3, np *Point3) bool {
+	if p == nil || np == nil || !ot.boundary.ContainsPoint(np) || !ot.remove(p) {
+		return false
+	}
+
+	p.x, p.y, p.z = np.x, np.y, np.z
+	return ot.insert(p)
+}
+
+// Search returns the points within the bounding box.
+func (ot *Octree) Search(a *AABB3) []*Point3 {
+	results := []*Point3{}
+	if a == nil || a.center == nil || a.half == nil {
+		return results
+	}
+	ot.search(a, &results)
+	return results
+}
+
+func (ot *Octree) search(a *AABB3, results *[]*Point3) {
+	if !ot.boundary.Intersect(a) {
+		return
+	}
+
+	for _, p := range ot.points {
+		if a.ContainsPoint(p) {
+			*results = append(*results, p)
+		}
+	}
+
+	if ot.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range ot.nodes {
+		node.search(a, results)
+	}
+}
+
+// KNearest returns the k points within the bounding box nearest to its
+// center which pass the filter, ordered by distance and then by when they
+// were inserted. Nodes are searched best first and those further away
+// than the k'th nearest point found are pruned.
+func (ot *Octree) KNearest(a *AABB3, k int, fn func(*Point3) bool) []*Point3 {
+	results := []*Point3{}
+	if a == nil || a.center == nil || a.half == nil || k <= 0 {
+		return results
+	}
+
+	center := a.center
+	queue := &octCandidates{{node: ot, dist: ot.boundary.minDist(center)}}
+
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(octCandidate)
+
+		if c.point != nil {
+			results = append(results, c.point)
+			if len(results) == k {
+				break
+			}
+			continue
+		}
+
+		for _, p := range c.node.points {
+			if a.ContainsPoint(p) && (fn == nil || fn(p)) {
+				heap.Push(queue, octCandidate{point: p, dist: dist3(p, center)})
+			}
+		}
+
+		if c.node.nodes[0] == nil {
+			continue
+		}
+
+		for _, node := range c.node.nodes {
+			if node.boundary.Intersect(a) {
+				heap.Push(queue, octCandidate{node: node, dist: node.boundary.minDist(center)})
+			}
+		}
+	}
+
+	return results
+}
+
+// octCandidate is a node or point of an Octree queued by KNearest.
+type octCandidate struct {
+	node  *Octree
+	point *Point3
+	dist  float64
+}
+
+// octCandidates is a min-heap of candidates by distance.
+type octCandidates []octCandidate
+
+func (c octCandidates) Len() int { return len(c) }
+
+func (c octCandidates) Less(i, j int) bool {
+	if c[i].dist != c[j].dist {
+		return c[i].dist < c[j].dist
+	}
+	// nodes before points at equal distance, then insertion order, as
+	// for candidates
+	if c[i].point == nil || c[j].point == nil {
+		return c[i].point == nil && c[j].point != nil
+	}
+	return c[i].point.seq < c[j].point.seq
+}
+
+func (c octCandidates) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+
+func (c *octCandidates) Push(x interface{}) { *c = append(*c, x.(octCandidate)) }
+
+func (c *octCandidates) Pop() interface{} {
+	old := *c
+	x := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return x
+}
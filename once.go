@@ -0,0 +1,101 @@
+package quadtree
+
+// DefaultIdempotencyWindow is the number of operation keys remembered by
+// trees created without WithIdempotencyWindow.
+const DefaultIdempotencyWindow = 4096
+
+// opLog remembers the keys of the most recently applied operations.
+type opLog struct {
+	keys map[string]struct{}
+	ring []string
+	next int
+}
+
+// WithIdempotencyWindow sets the number of operation keys remembered by
+// InsertOnce, RemoveOnce and UpdateOnce. A retry arriving after n further
+// operations is applied again.
+func WithIdempotencyWindow(n int) Option {
+	return func(s *state) {
+		if n > 0 {
+			s.ops = &opLog{
+				keys: make(map[string]struct{}, n),
+				ring: make([]string, n),
+			}
+		}
+	}
+}
+
+func (s *state) opLog() *opLog {
+	if s.ops == nil {
+		WithIdempotencyWindow(DefaultIdempotencyWindow)(s)
+	}
+	return s.ops
+}
+
+// seen reports whether the operation key was applied within the window.
+func (l *opLog) seen(key string) bool {
+	_, ok := l.keys[key]
+	return ok
+}
+
+// record adds the key of an applied operation, forgetting the oldest.
+func (l *opLog) record(key string) {
+	if old := l.ring[l.next]; old != "" {
+		delete(l.keys, old)
+	}
+	l.ring[l.next] = key
+	l.keys[key] = struct{}{}
+	l.next = (l.next + 1) % len(l.ring)
+}
+
+// once applies the operation unless its key was applied within the
+// window. Failed operations are not recorded so they may be retried.
+func (qt *QuadTree) once(key string, op func() error) (bool, error) {
+	l := qt.state.opLog()
+	if key != "" && l.seen(key) {
+		return false, nil
+	}
+
+	if err := op(); err != nil {
+		return false, err
+	}
+
+	if key != "" {
+		l.record(key)
+	}
+	return true, nil
+}
+
+// InsertOnce inserts the point unless an operation with the same key was
+// applied within the idempotency window, so messages delivered more than
+// once are applied once. It reports whether the insert was applied, false
+// with a nil error for a duplicate. An empty key is never deduplicated.
+func (qt *QuadTree) InsertOnce(key string, p *Point) (bool, error) {
+	return qt.once(key, func() error {
+		return qt.add(p)
+	})
+}
+
+// RemoveOnce removes the point unless an operation with the same key was
+// applied within the idempotency window, returning ErrNotFound if the
+// point could not be removed.
+func (qt *QuadTree) RemoveOnce(key string, p *Point) (bool, error) {
+	return qt.once(key, func() error {
+		if !qt.Remove(p) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// UpdateOnce moves the point unless an operation with the same key was
+// applied within the idempotency window, returning ErrNotFound if the
+// point could not be moved.
+func (qt *QuadTree) UpdateOnce(key string, p *Point, np *Point) (bool, error) {
+	return qt.once(key, func() error {
+		if !qt.Update(p, np) {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
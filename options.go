@@ -0,0 +1,79 @@
+package quadtree
+
+// Option configures a QuadTree when passed to New.
+type Option func(*state)
+
+// WithUniqueIDs enforces that point IDs are unique within the tree. The
+// policy decides whether inserting a point with an ID already in use is
+// rejected or replaces the existing point.
+func WithUniqueIDs(policy IDPolicy) Option {
+	return func(s *state) {
+		s.policy = policy
+	}
+}
+
+// WithCapacity sets the number of points a leaf holds before it is
+// divided, defaulting to Capacity.
+func WithCapacity(n int) Option {
+	return func(s *state) {
+		if n > 0 {
+			s.capacity = n
+		}
+	}
+}
+
+// WithMaxDepth sets the depth beyond which leaves are no longer divided,
+// defaulting to MaxDepth.
+func WithMaxDepth(d int) Option {
+	return func(s *state) {
+		if d >= 0 {
+			s.maxDepth = d
+		}
+	}
+}
+
+// WithAggregates maintains per node aggregates of the points beneath each
+// node, read via Aggregate and Tiles. The weight function weights the
+// centroid and defaults to 1 when nil. The value function provides the
+// numeric field tracked as Min and Max and may be nil.
+func WithAggregates(weight, value func(*Point) float64) Option {
+	return func(s *state) {
+		s.aggregates = true
+		s.weight = weight
+		s.value = value
+	}
+}
+
+// WithMaxLeafScan bounds the scan of leaves at the maximum depth, which may hold any
+// number of points. Once such a leaf holds more than n points they are
+// kept sorted by Morton code and queries only scan the range of codes
+// covered by the query box.
+func WithMaxLeafScan(n int) Option {
+	return func(s *state) {
+		s.maxScan = n
+	}
+}
+
+// WithAccessCounters counts the queries visiting each node, used by
+// HotRegions to report the most frequently queried regions.
+func WithAccessCounters() Option {
+	return func(s *state) {
+		s.counters = true
+	}
+}
+
+// WithSizer measures the approximate size in bytes of each point's data,
+// counted by BytesUsed along with a fixed overhead per point.
+func WithSizer(sizer func(*Point) int) Option {
+	return func(s *state) {
+		s.sizer = sizer
+	}
+}
+
+// WithMaxBytes limits the bytes used by the tree as reported by BytesUsed.
+// Inserts which would exceed it fail with ErrQuotaExceeded.
+func WithMaxBytes(n int64) Option {
+	return func(s *state) {
+		s.maxBytes = n
+	}
+}
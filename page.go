@@ -0,0 +1,104 @@
+package quadtree
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ErrInvalidCursor is returned when parsing a malformed cursor.
+var ErrInvalidCursor = errors.New("quadtree: invalid cursor")
+
+// Cursor is the position of a page of KNearestPage results. The zero
+// Cursor starts from the nearest point.
+type Cursor struct {
+	// distance and insertion sequence of the last point returned
+	dist float64
+	seq  uint64
+	// started is set once a page has been returned
+	started bool
+	// done is set once every point has been returned
+	done bool
+}
+
+// Done reports whether the pages preceding the cursor held every point.
+func (c Cursor) Done() bool {
+	return c.done
+}
+
+// String encodes the cursor as an opaque URL safe token, for passing to
+// clients of an API. The zero Cursor is the empty string.
+func (c Cursor) String() string {
+	if !c.started {
+		return ""
+	}
+
+	b := make([]byte, 17)
+	binary.BigEndian.PutUint64(b, math.Float64bits(c.dist))
+	binary.BigEndian.PutUint64(b[8:], c.seq)
+	if c.done {
+		b[16] = 1
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCursor decodes a cursor encoded by String.
+func ParseCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) != 17 || b[16] > 1 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{
+		dist:    math.Float64frombits(binary.BigEndian.Uint64(b)),
+		seq:     binary.BigEndian.Uint64(b[8:]),
+		started: true,
+		done:    b[16] == 1,
+	}, nil
+}
+
+// after reports whether a point at distance d comes after the cursor.
+func (c Cursor) after(d float64, p *Point) bool {
+	if !c.started {
+		return true
+	}
+	return d > c.dist || d == c.dist && p.seq > c.seq
+}
+
+// KNearestPage returns the next k points nearest to center after the
+// cursor, ordered by distance as NearestN, and the cursor of the following
+// page. Pages of the same center follow on without overlap, each searching
+// only as far as its own points, so paging deep into a result set costs
+// the points skipped but never a sort of all of them. Points moved between
+// pages may be returned twice or not at all.
+func (qt *QuadTree) KNearestPage(center *Point, k int, cursor Cursor, opts ...QueryOption) ([]*Point, Cursor) {
+	if cursor.done || center == nil || k <= 0 {
+		return []*Point{}, cursor
+	}
+
+	qt.state.acquire()
+	defer qt.state.release()
+
+	t := qt.begin()
+	q := qt.newQuery(opts)
+	dist := qt.distance()
+
+	results := qt.nearest(center, k, func(p *Point) bool {
+		return cursor.after(dist(p, center), p) && q.match(p)
+	}, nil)
+
+	next := Cursor{dist: cursor.dist, seq: cursor.seq, started: true}
+	if n := len(results); n > 0 {
+		last := results[n-1]
+		next.dist, next.seq = dist(last, center), last.seq
+	}
+	next.done = len(results) < k
+
+	qt.end(t, "nearest", &AABB{center, &Point{}}, k)
+	return nonNil(results), next
+}
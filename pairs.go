@@ -0,0 +1,106 @@
+package quadtree
+
+// Pairs calls fn with each pair of points within the distance of each
+// other, in metres or the units of a Cartesian tree, e.g. for the broad
+// phase of collision detection. Each pair is reported once. The leaves of
+// the tree are paired only with those near enough to hold a point within
+// the distance of one of theirs, so the points of distant leaves are never
+// compared. fn must not modify the tree.
+func (qt *QuadTree) Pairs(maxDistMeters float64, fn func(a, b *Point), opts ...QueryOption) {
+	if !(maxDistMeters >= 0) {
+		return
+	}
+
+	q := qt.newQuery(opts)
+	cs := qt.state.coordinates()
+
+	// the points of each node matching the query, indexed in the order of
+	// the nodes
+	var buckets [][]*Point
+	index := make(map[*QuadTree]int)
+	qt.buckets(q, index, &buckets)
+
+	// the last bucket each bucket was paired with
+	paired := make([]int, len(buckets))
+
+	for i, points := range buckets {
+		// a box holding every point within the distance of the bucket
+		lo := &Point{x: points[0].x, y: points[0].y}
+		hi := &Point{x: points[0].x, y: points[0].y}
+		for _, p := range points {
+			h := cs.Half(p, maxDistMeters)
+			lo.x, lo.y = min(lo.x, p.x-h.x), min(lo.y, p.y-h.y)
+			hi.x, hi.y = max(hi.x, p.x+h.x), max(hi.y, p.y+h.y)
+		}
+		near := NewAABBFromMinMax(lo, hi)
+
+		boxes, ok := qt.state.wrap(near)
+		if !ok {
+			boxes = []*AABB{near}
+		}
+
+		for _, b := range boxes {
+			qt.nodesWithin(b, func(node *QuadTree) {
+				j, ok := index[node]
+				if !ok || j < i || paired[j] == i+1 {
+					return
+				}
+				paired[j] = i + 1
+
+				for m, p := range points {
+					others := buckets[j]
+					if j == i {
+						others = points[m+1:]
+					}
+					for _, o := range others {
+						if cs.Distance(p, o) <= maxDistMeters {
+							fn(p, o)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+// buckets indexes the nodes holding points matching the query, appending
+// their points to buckets.
+func (qt *QuadTree) buckets(q *query, index map[*QuadTree]int, buckets *[][]*Point) {
+	var points []*Point
+	for _, p := range qt.leaf() {
+		if q.match(p) {
+			points = append(points, p)
+		}
+	}
+	if len(points) > 0 {
+		index[qt] = len(*buckets)
+		*buckets = append(*buckets, points)
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		if node.total > 0 {
+			node.buckets(q, index, buckets)
+		}
+	}
+}
+
+// nodesWithin calls fn with each node intersecting the bounding box.
+func (qt *QuadTree) nodesWithin(a *AABB, fn func(node *QuadTree)) {
+	if qt.total == 0 || !qt.boundary.Intersect(a) {
+		return
+	}
+
+	fn(qt)
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.nodesWithin(a, fn)
+	}
+}
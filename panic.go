@@ -0,0 +1,38 @@
+package quadtree
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned in place of a panic raised by a user callback,
+// e.g. a filter, cost or distinct function, while running a query.
+type PanicError struct {
+	// Value is the value passed to panic
+	Value interface{}
+	// Stack is the stack trace of the goroutine at the panic
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("quadtree: panic in callback: %v", e.Value)
+}
+
+// recovered converts a panic into a *PanicError stored in err. It must be
+// deferred directly.
+func recovered(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
+
+// readOnly wraps a filter so it is passed a copy of each point, leaving
+// the point held by the tree untouched by anything the filter writes.
+func readOnly(fn filter) filter {
+	if fn == nil {
+		return nil
+	}
+	return func(p *Point) bool {
+		return fn(p.clone())
+	}
+}
@@ -0,0 +1,240 @@
+package quadtree
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// SearchParallel is Search fanning the subtrees intersecting the bounding
+// box out to a pool of workers, GOMAXPROCS if workers is not positive,
+// returning the same points in the same order. Filters and other callbacks
+// of the query run concurrently so must be safe for concurrent use, and as
+// with Search the tree must not be written to during the query.
+func (qt *QuadTree) SearchParallel(a *AABB, workers int, opts ...QueryOption) []*Point {
+	if noBox(a) {
+		return []*Point{}
+	}
+
+	qt.state.acquire()
+	defer qt.state.release()
+
+	t := qt.begin()
+	q := qt.newQuery(opts)
+	// fix the time of the query before it is shared by the workers
+	q.at()
+
+	boxes, ok := qt.state.wrap(a)
+	if !ok {
+		boxes = []*AABB{a}
+	}
+
+	var tasks []func() []*Point
+	for _, b := range boxes {
+		for _, node := range qt.frontier(b, q, workers) {
+			tasks = append(tasks, func() []*Point {
+				// the budget is charged in order once the results are merged
+				wq := *q
+				wq.maxBytes = 0
+				return node.search(b, &wq)
+			})
+		}
+	}
+
+	var results []*Point
+	for _, found := range parallel(tasks, workers) {
+		for _, p := range found {
+			if !q.charge(p) {
+				break
+			}
+			results = append(results, p)
+		}
+	}
+
+	if q.distinct != nil {
+		results = q.dedupe(results, newer)
+	}
+	qt.end(t, "search", a, 0)
+	return nonNil(results)
+}
+
+// KNearestParallel is KNearest fanning the subtrees intersecting the
+// bounding box out to a pool of workers, GOMAXPROCS if workers is not
+// positive, and merging the nearest points of each. Each worker finds up
+// to k points, so it does more work in total than KNearest and pays off
+// for large trees and costly filters. The filter and other callbacks of
+// the query run concurrently so must be safe for concurrent use. Queries
+// using WithExpand or WithExplain run on the calling goroutine.
+func (qt *QuadTree) KNearestParallel(a *AABB, i int, fn filter, workers int, opts ...QueryOption) []*Point {
+	if noBox(a) || i <= 0 {
+		return []*Point{}
+	}
+
+	qt.state.acquire()
+	defer qt.state.release()
+
+	q := qt.newQuery(opts)
+	if q.expand || q.explain != nil {
+		return qt.kNearestQuery(nil, a, i, fn, q)
+	}
+
+	t := qt.begin()
+	q.at()
+
+	rank := func(p *Point) float64 {
+		return qt.distance()(p, a.center)
+	}
+	if q.cost != nil {
+		rank = func(p *Point) float64 {
+			return q.cost(p, Distance(a.center, p))
+		}
+	} else if q.rank != nil {
+		rank = func(p *Point) float64 {
+			return q.rank(Distance(a.center, p), p.Weight())
+		}
+	} else if q.local {
+		local := tangentPlane(a.center)
+		rank = func(p *Point) float64 {
+			return local(p, a.center)
+		}
+	}
+
+	boxes, ok := qt.state.wrap(a)
+	if !ok {
+		boxes = []*AABB{a}
+	}
+
+	// each subtree searches every part of a wrapped box, so is fanned out
+	// once however many parts it intersects
+	var tasks []func() []*Point
+	seen := make(map[*QuadTree]bool)
+	for _, b := range boxes {
+		for _, node := range qt.frontier(b, q, workers) {
+			if seen[node] {
+				continue
+			}
+			seen[node] = true
+			tasks = append(tasks, func() []*Point {
+				wq := *q
+				return node.kNearestIn(nil, a, i, fn, &wq)
+			})
+		}
+	}
+
+	var results []merged
+	for _, found := range parallel(tasks, workers) {
+		for _, p := range found {
+			results = append(results, merged{point: p, rank: rank(p), seq: p.seq})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].rank != results[j].rank {
+			return results[i].rank < results[j].rank
+		}
+		return results[i].seq < results[j].seq
+	})
+
+	if q.distinct != nil {
+		for n := range results {
+			results[n].key = q.distinct(results[n].point)
+		}
+		results = distinct(results, nil)
+	}
+
+	if len(results) > i {
+		results = results[:max(i, 0)]
+	}
+
+	qt.end(t, "knearest", a, i)
+	return points(results)
+}
+
+// frontier returns the nodes intersecting a which the parallel queries fan
+// out, dividing them in the order the query visits children until there
+// are a few for each worker or only leaves remain. Searching the nodes in
+// order visits the points in the same order as searching qt.
+func (qt *QuadTree) frontier(a *AABB, q *query, workers int) []*QuadTree {
+	if !qt.boundary.Intersect(a) || q.excludes(qt.boundary) || !q.tagged(qt) {
+		return nil
+	}
+
+	want := 4 * workerCount(workers)
+	nodes := []*QuadTree{qt}
+
+	for len(nodes) < want {
+		var next []*QuadTree
+		divided := false
+
+		for _, node := range nodes {
+			if node.nodes[0] == nil {
+				next = append(next, node)
+				continue
+			}
+			divided = true
+			node.hit()
+			for _, child := range q.children(node, a) {
+				if child.boundary.Intersect(a) && !q.excludes(child.boundary) && q.tagged(child) {
+					next = append(next, child)
+				}
+			}
+		}
+
+		nodes = next
+		if !divided {
+			break
+		}
+	}
+
+	return nodes
+}
+
+func workerCount(workers int) int {
+	if workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// parallel runs the tasks on a pool of workers, returning the results of
+// each in the order of the tasks. A panic in a task is raised again on the
+// calling goroutine once every worker has stopped.
+func parallel(tasks []func() []*Point, workers int) [][]*Point {
+	results := make([][]*Point, len(tasks))
+	next := make(chan int)
+
+	var (
+		wg      sync.WaitGroup
+		once    sync.Once
+		failure interface{}
+	)
+
+	for w := 0; w < min(workerCount(workers), len(tasks)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					once.Do(func() { failure = r })
+					// drain the remaining tasks so the feeder is not blocked
+					for range next {
+					}
+				}
+			}()
+			for i := range next {
+				results[i] = tasks[i]()
+			}
+		}()
+	}
+
+	for i := range tasks {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	if failure != nil {
+		panic(failure)
+	}
+	return results
+}
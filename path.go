@@ -0,0 +1,59 @@
+package quadtree
+
+import (
+	"strconv"
+)
+
+// NodeInfo describes a node a point descends through.
+type NodeInfo struct {
+	Boundary *AABB
+	Depth    int
+	// Path of child indices from the root separated by dots, e.g.
+	// "0.3.1", empty for the root. It identifies the node's cell.
+	Path string
+	// Leaf reports whether the node has no children.
+	Leaf bool
+}
+
+// PathTo returns the chain of nodes from the root to the leaf whose
+// boundary contains the location of the point, whether or not the point
+// is stored in the tree. It returns an empty slice if the point is nil or
+// outside the tree.
+func (qt *QuadTree) PathTo(p *Point) []NodeInfo {
+	results := []NodeInfo{}
+
+	if p == nil {
+		return results
+	}
+	p = qt.state.current(p)
+	if !qt.boundary.ContainsPoint(p) {
+		return results
+	}
+
+	path := ""
+	node := qt
+
+	for node != nil {
+		leaf := node.nodes[0] == nil
+		results = append(results, NodeInfo{node.boundary, node.depth, path, leaf})
+
+		if leaf {
+			break
+		}
+
+		next := node
+		node = nil
+		for i, child := range next.nodes {
+			if child.boundary.ContainsPoint(p) {
+				if path != "" {
+					path += "."
+				}
+				path += strconv.Itoa(i)
+				node = child
+				break
+			}
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,59 @@
+package quadtree
+
+import (
+	"math"
+	"slices"
+)
+
+// SearchPolygon returns the points inside the polygon, which may be
+// concave, that pass the filter. The polygon is given by its vertices in
+// order and is closed implicitly. Nodes outside the bounding box of the
+// polygon are pruned and the remaining points tested against it.
+func (qt *QuadTree) SearchPolygon(poly []*Point, fn filter, opts ...QueryOption) []*Point {
+	results := []*Point{}
+
+	if len(poly) < 3 || slices.Contains(poly, nil) {
+		return results
+	}
+
+	qt.visit(polygonBounds(poly), qt.newQuery(opts), func(p *Point) bool {
+		if pointInPolygon(p, poly) && (fn == nil || fn(p)) {
+			results = append(results, p)
+		}
+		return true
+	})
+
+	return results
+}
+
+// polygonBounds returns the bounding box of the vertices.
+func polygonBounds(poly []*Point) *AABB {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	for _, v := range poly {
+		minX, maxX = math.Min(minX, v.x), math.Max(maxX, v.x)
+		minY, maxY = math.Min(minY, v.y), math.Max(maxY, v.y)
+	}
+
+	return &AABB{
+		&Point{x: (minX + maxX) / 2, y: (minY + maxY) / 2},
+		&Point{x: (maxX - minX) / 2, y: (maxY - minY) / 2},
+	}
+}
+
+// pointInPolygon reports whether p lies inside the polygon using the even
+// odd rule, casting a ray in the direction of increasing y.
+func pointInPolygon(p *Point, poly []*Point) bool {
+	in := false
+
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a.x > p.x) != (b.x > p.x) &&
+			p.y < (b.y-a.y)*(p.x-a.x)/(b.x-a.x)+a.y {
+			in = !in
+		}
+	}
+
+	return in
+}
@@ -0,0 +1,98 @@
+package quadtree
+
+import (
+	"sync"
+)
+
+// searchPool reuses the heaps of KNearest queries.
+var searchPool = sync.Pool{
+	New: func() interface{} {
+		return new(search)
+	},
+}
+
+// search holds the heaps of a best first search: the nodes queued and the
+// k nearest points found.
+type search struct {
+	queue candidates
+	best  farthest
+}
+
+func getSearch() *search {
+	return searchPool.Get().(*search)
+}
+
+func putSearch(s *search) {
+	// drop the points and nodes held rather than keep them alive
+	clear(s.queue[:cap(s.queue)])
+	clear(s.best[:cap(s.best)])
+	s.queue, s.best = s.queue[:0], s.best[:0]
+	searchPool.Put(s)
+}
+
+// queryPool reuses the queries of KNearestAppend.
+var queryPool = sync.Pool{
+	New: func() interface{} {
+		return new(query)
+	},
+}
+
+// getQuery is qt.newQuery with a pooled query, returned by putQuery once
+// the query is done.
+func (qt *QuadTree) getQuery(opts []QueryOption) *query {
+	q := queryPool.Get().(*query)
+	q.clock = qt.state.now
+	for _, o := range opts {
+		o(q)
+	}
+	return q
+}
+
+func putQuery(q *query) {
+	*q = query{}
+	queryPool.Put(q)
+}
+
+// SearchAppend is Search appending the points to dst and returning the
+// extended slice, so a caller reusing dst across queries allocates only
+// when it must grow.
+func (qt *QuadTree) SearchAppend(dst []*Point, a *AABB, opts ...QueryOption) []*Point {
+	if noBox(a) {
+		return nonNil(dst)
+	}
+
+	qt.state.acquire()
+	defer qt.state.release()
+
+	t := qt.begin()
+	q := qt.newQuery(opts)
+	n := len(dst)
+
+	qt.visit(a, q, func(p *Point) bool {
+		if !q.charge(p) {
+			return false
+		}
+		dst = append(dst, p)
+		return true
+	})
+
+	if q.distinct != nil {
+		dst = append(dst[:n], q.dedupe(dst[n:], newer)...)
+	}
+
+	qt.end(t, "search", a, 0)
+	return nonNil(dst)
+}
+
+// KNearestAppend is KNearest appending the points to dst and returning
+// the extended slice. The search reuses pooled heaps, so a caller reusing
+// dst across queries allocates only when it must grow.
+func (qt *QuadTree) KNearestAppend(dst []*Point, a *AABB, i int, fn filter, opts ...QueryOption) []*Point {
+	qt.state.acquire()
+	defer qt.state.release()
+
+	q := qt.getQuery(opts)
+	defer putQuery(q)
+
+	return qt.kNearestQuery(dst, a, i, fn, q)
+}
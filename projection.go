@@ -0,0 +1,200 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// Projection converts geographic coordinates in degrees to a planar
+// coordinate system and back.
+type Projection interface {
+	Project(lat, lng float64) (x, y float64)
+	Unproject(x, y float64) (lat, lng float64)
+}
+
+// ProjectedTree is a QuadTree which stores points in projected planar
+// coordinates. Points and boxes passed in are geographic (x as latitude,
+// y as longitude) and the points returned are the caller's original
+// geographic points.
+type ProjectedTree struct {
+	proj   Projection
+	tree   *QuadTree
+	points map[*Point]*Point
+}
+
+// WebMercator is the spherical mercator projection (EPSG:3857) used by web
+// map tiles. Projected coordinates are metres, x easting and y northing.
+var WebMercator Projection = webMercator{}
+
+const (
+	// mercatorRadius is the sphere radius used by EPSG:3857 [m]
+	mercatorRadius = 6378137.0
+	// mercatorMaxLat is the latitude at which the projection is square
+	mercatorMaxLat = 85.05112877980659
+)
+
+type webMercator struct{}
+
+func (webMercator) Project(lat, lng float64) (float64, float64) {
+	lat = math.Max(-mercatorMaxLat, math.Min(mercatorMaxLat, lat))
+	x := mercatorRadius * deg2Rad(lng)
+	y := mercatorRadius * math.Log(math.Tan(math.Pi/4+deg2Rad(lat)/2))
+	return x, y
+}
+
+func (webMercator) Unproject(x, y float64) (float64, float64) {
+	lat := rad2Deg(2*math.Atan(math.Exp(y/mercatorRadius)) - math.Pi/2)
+	lng := rad2Deg(x / mercatorRadius)
+	return lat, lng
+}
+
+// WebMercatorTile returns the projected bounding box of the XYZ tile at
+// zoom z, so tile queries line up exactly with tile pixels.
+func WebMercatorTile(z, x, y int) *AABB {
+	extent := math.Pi * mercatorRadius
+	size := 2 * extent / float64(uint(1)<<uint(z))
+
+	minX := -extent + float64(x)*size
+	maxY := extent - float64(y)*size
+
+	return &AABB{
+		&Point{x: minX + size/2, y: maxY - size/2},
+		&Point{x: size / 2, y: size / 2},
+	}
+}
+
+// NewProjected creates a *ProjectedTree covering the geographic boundary
+// provided, storing points in the coordinates of the projection.
+func NewProjected(proj Projection, boundary *AABB, opts ...Option) *ProjectedTree {
+	pt := &ProjectedTree{
+		proj:   proj,
+		points: make(map[*Point]*Point),
+	}
+	pt.tree = New(pt.ProjectAABB(boundary), 0, nil, opts...)
+	return pt
+}
+
+// ProjectAABB converts a geographic bounding box into the smallest
+// projected bounding box containing it.
+func (pt *ProjectedTree) ProjectAABB(a *AABB) *AABB {
+	if noBox(a) {
+		return nil
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	for _, lat := range []float64{a.center.x - a.half.x, a.center.x + a.half.x} {
+		for _, lng := range []float64{a.center.y - a.half.y, a.center.y + a.half.y} {
+			x, y := pt.proj.Project(lat, lng)
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+			minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+		}
+	}
+
+	return &AABB{
+		&Point{x: (minX + maxX) / 2, y: (minY + maxY) / 2},
+		&Point{x: (maxX - minX) / 2, y: (maxY - minY) / 2},
+	}
+}
+
+// Projection returns the projection used by the tree.
+func (pt *ProjectedTree) Projection() Projection {
+	return pt.proj
+}
+
+// Tree returns the underlying QuadTree holding the projected points. The
+// data of each projected point is the original geographic *Point.
+func (pt *ProjectedTree) Tree() *QuadTree {
+	return pt.tree
+}
+
+func (pt *ProjectedTree) project(p *Point, data interface{}) *Point {
+	x, y := pt.proj.Project(p.x, p.y)
+	return &Point{x: x, y: y, id: p.id, data: data}
+}
+
+func (pt *ProjectedTree) unwrap(points []*Point) []*Point {
+	for i, p := range points {
+		points[i] = p.data.(*Point)
+	}
+	return points
+}
+
+// Insert projects and inserts the geographic point into the tree.
+func (pt *ProjectedTree) Insert(p *Point) bool {
+	if p == nil {
+		return false
+	}
+	if _, ok := pt.points[p]; ok {
+		return false
+	}
+
+	old := pt.tree.state.ids[p.id]
+
+	q := pt.project(p, p)
+	if !pt.tree.Insert(q) {
+		return false
+	}
+
+	// drop the point replaced by a unique ID
+	if old != nil && old != pt.tree.state.ids[p.id] {
+		delete(pt.points, old.data.(*Point))
+	}
+
+	pt.points[p] = q
+	return true
+}
+
+// Remove removes a point previously inserted into the tree.
+func (pt *ProjectedTree) Remove(p *Point) bool {
+	q, ok := pt.points[p]
+	if !ok {
+		return false
+	}
+
+	if !pt.tree.Remove(q) {
+		return false
+	}
+
+	delete(pt.points, p)
+	return true
+}
+
+// Update moves a point to the geographic location of np.
+func (pt *ProjectedTree) Update(p *Point, np *Point) bool {
+	q, ok := pt.points[p]
+	if !ok {
+		return false
+	}
+
+	if !pt.tree.Update(q, pt.project(np, nil)) {
+		return false
+	}
+
+	p.x = np.x
+	p.y = np.y
+	return true
+}
+
+// Search returns all the points within the geographic bounding box.
+func (pt *ProjectedTree) Search(a *AABB) []*Point {
+	return pt.SearchProjected(pt.ProjectAABB(a))
+}
+
+// SearchProjected returns all the points within a bounding box given in
+// projected coordinates, e.g. one returned by WebMercatorTile.
+func (pt *ProjectedTree) SearchProjected(a *AABB) []*Point {
+	return pt.unwrap(pt.tree.Search(a))
+}
+
+// KNearest returns the k nearest points within the geographic bounding box.
+// The filter function is evaluated against the original geographic points.
+func (pt *ProjectedTree) KNearest(a *AABB, i int, fn filter) []*Point {
+	var pfn filter
+	if fn != nil {
+		pfn = func(p *Point) bool {
+			return fn(p.data.(*Point))
+		}
+	}
+	return pt.unwrap(pt.tree.KNearest(pt.ProjectAABB(a), i, pfn))
+}
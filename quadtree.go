@@ -2,8 +2,13 @@ package quadtree
 
 import (
 	"math"
+	"math/rand"
+	"sync"
+	"time"
 )
 
+// Capacity and MaxDepth are the defaults for trees created without
+// WithCapacity and WithMaxDepth.
 var (
 	Capacity = 8
 	MaxDepth = 6
@@ -17,15 +22,168 @@ type AABB struct {
 type Point struct {
 	x    float64
 	y    float64
+	id   string
 	data interface{}
+	// last insert or update in unix nanoseconds
+	updated int64
+	// insertion sequence used to break ties
+	seq uint64
+	// optional metadata, nil until set
+	md *pointMeta
 }
 
+// inlinePoints is the number of points a node holds without allocating.
+const inlinePoints = 8
+
 type QuadTree struct {
 	boundary *AABB
 	depth    int
-	points   []*Point
-	parent   *QuadTree
-	nodes    [4]*QuadTree
+	// points of a leaf held in memory, read through leaf
+	resident []*Point
+	// number of points of a released leaf, held only in the point store
+	released int32
+	// storage for the points of sparse leaves
+	inline [inlinePoints]*Point
+	parent *QuadTree
+	nodes  [4]*QuadTree
+	state  *state
+	agg    *Aggregate
+	dirty  bool
+	// morton codes of points in a refined leaf
+	codes []uint64
+	// queries visiting the node
+	hits uint64
+	// updates of points within the node
+	writes uint64
+	// upper bound of the radius of points beneath the node
+	radius float64
+	// upper bound of the weight of points beneath the node
+	weight float64
+	// union of the tag bitsets of points beneath the node
+	tags uint64
+	// number of points beneath the node
+	total int
+	// items with a bounding box not within a single child
+	extents []*Extent
+	// path from the root written to the point store, once known
+	nodeID    NodeID
+	hasNodeID bool
+	// generation of the tree the node was created or copied in, shared
+	// with snapshots once the tree has moved on to a later one
+	gen uint64
+}
+
+// state is shared by every node of a tree.
+type state struct {
+	// generation of the nodes written since the last Snapshot, first so
+	// that it is aligned for atomic access
+	gen    uint64
+	ids    map[string]*Point
+	policy IDPolicy
+	// treatment of points at the same coordinates
+	duplicates DuplicatePolicy
+	seq        uint64
+	// number of points in the tree
+	count int
+	// cap on the number of points
+	capped *capped
+	// nodes removed by Clear, or compacted in an arena, reused as the
+	// tree divides
+	spare []*QuadTree
+	// allocates nodes in slabs
+	arena *arena
+
+	// timestamps and random choices, wall clock and unseeded if nil
+	now func() time.Time
+	rng *rand.Rand
+
+	// keys of recently applied idempotent operations
+	ops *opLog
+
+	// receives the points of each node by slot
+	store    PointStore
+	storeErr error
+	// guards storeErr, written by queries of released leaves
+	storeMu *sync.Mutex
+
+	// copy returned by Snapshot
+	readOnly bool
+	// points inserted or copied since the last Snapshot, nil until one is
+	// taken, and the copies replacing points shared with snapshots
+	fresh   map[*Point]struct{}
+	forward map[*Point]*Point
+	back    map[*Point][2]*Point
+	// builds the ID index of a snapshot on first use
+	lazy *lazyIndex
+
+	// points inserted with a time to live
+	expiring bool
+
+	// regions rejecting writes
+	locks   map[uint64]*AABB
+	lockSeq uint64
+
+	// points per leaf before dividing and maximum depth
+	capacity int
+	maxDepth int
+	// decides when leaves divide, by capacity if nil
+	split SplitPolicy
+
+	// number of points per tenant
+	tenants map[string]int
+
+	// per node aggregation
+	aggregates bool
+	weight     func(*Point) float64
+	value      func(*Point) float64
+
+	slow *slowLog
+
+	// points scanned in a max depth leaf before it is refined
+	maxScan int
+
+	// count queries visiting each node
+	counters bool
+	// updates per point ID, counted if not nil
+	writers map[string]uint64
+
+	// collapse sparse subtrees on removal
+	autoCompact bool
+	// grow the root to take in points outside it
+	grow bool
+
+	// orders nearest results, planar if nil
+	distance DistanceFunc
+	// lower bound of distance to a bounding box
+	bound func(p *Point, a *AABB) float64
+	// measures radius queries, WGS84 if nil
+	coords CoordinateSystem
+
+	hooks hooks
+	// feeds of Changes
+	changes *changes
+
+	// size accounting
+	sizer    func(*Point) int
+	bytes    int64
+	maxBytes int64
+
+	limit    *limiter
+	recorder *recorder
+	metrics  Metrics
+
+	// encodes point data in snapshots
+	codec Codec
+	// compresses the blocks of binary snapshots
+	compressor Compressor
+	// whether binary snapshots write repeated data once
+	dictionary bool
+	// loads point data held outside the tree
+	resolver Resolver
+
+	// tier for points not updated within the window
+	cold       ColdStore
+	coldWindow time.Duration
 }
 
 type filter func(*Point) bool
@@ -50,7 +208,7 @@ func boundaryPoint(x *Point, m float64) *Point {
 	xMax := x2 + m/radius
 	yMax := y2 + m/pradius
 
-	return &Point{rad2Deg(xMax), rad2Deg(yMax), nil}
+	return &Point{x: rad2Deg(xMax), y: rad2Deg(yMax)}
 }
 
 // Earth radius at a given latitude, according to the WGS-84 ellipsoid [m]
@@ -67,13 +225,33 @@ func earthRadius(x float64) float64 {
 
 // New creates a new *QuadTree. It requires a boundary defining the center
 // and half points, depth at which the QuadTree resides and parent node.
-// Depth of 0 and parent as nil implies the root node.
-func New(boundary *AABB, depth int, parent *QuadTree) *QuadTree {
-	return &QuadTree{
+// Depth of 0 and parent as nil implies the root node. Options configure
+// the tree as a whole and are shared with the parent when one is given.
+func New(boundary *AABB, depth int, parent *QuadTree, opts ...Option) *QuadTree {
+	qt := &QuadTree{
 		boundary: boundary,
 		depth:    depth,
 		parent:   parent,
 	}
+	qt.resident = qt.inline[:0]
+
+	if parent != nil {
+		qt.state = parent.state
+		qt.gen = parent.gen
+	} else {
+		qt.state = &state{
+			ids:      make(map[string]*Point),
+			tenants:  make(map[string]int),
+			capacity: Capacity,
+			maxDepth: MaxDepth,
+		}
+	}
+
+	for _, o := range opts {
+		o(qt.state)
+	}
+
+	return qt
 }
 
 // NewAABB creates an axis aligned bounding box. It takes the center and half
@@ -82,9 +260,41 @@ func NewAABB(center, half *Point) *AABB {
 	return &AABB{center, half}
 }
 
+// NewAABBFromMinMax creates an axis aligned bounding box from its minimum
+// and maximum corners, e.g. the south west and north east corners of a
+// dataset's bounds. The box is invalid, as reported by Validate, unless
+// min is below max along both axes.
+func NewAABBFromMinMax(min, max *Point) *AABB {
+	return &AABB{
+		&Point{x: (min.x + max.x) / 2, y: (min.y + max.y) / 2},
+		&Point{x: (max.x - min.x) / 2, y: (max.y - min.y) / 2},
+	}
+}
+
+// Min returns the minimum corner of the bounding box.
+func (a *AABB) Min() *Point {
+	return &Point{x: a.center.x - a.half.x, y: a.center.y - a.half.y}
+}
+
+// Max returns the maximum corner of the bounding box.
+func (a *AABB) Max() *Point {
+	return &Point{x: a.center.x + a.half.x, y: a.center.y + a.half.y}
+}
+
+// Center returns the center point of the bounding box.
+func (a *AABB) Center() *Point {
+	return a.center
+}
+
+// Half returns the half point of the bounding box, its distance from the
+// center to the edges along each axis.
+func (a *AABB) Half() *Point {
+	return a.half
+}
+
 // NewPoint generates a new *Point struct.
 func NewPoint(x, y float64, data interface{}) *Point {
-	return &Point{x, y, data}
+	return &Point{x: x, y: y, data: data}
 }
 
 // ContainsPoint checks whether the point provided resides within the axis
@@ -129,6 +339,11 @@ func (p *Point) Coordinates() (float64, float64) {
 	return p.x, p.y
 }
 
+// Updated returns the time the point was last inserted or updated.
+func (p *Point) Updated() time.Time {
+	return time.Unix(0, p.updated)
+}
+
 // Data returns the data stored within a point.
 func (p *Point) Data() interface{} {
 	return p.data
@@ -139,99 +354,131 @@ func (p *Point) Data() interface{} {
 // argument of metres as float64.
 func (p *Point) HalfPoint(m float64) *Point {
 	p2 := boundaryPoint(p, m)
-	return &Point{p2.x - p.x, p2.y - p.y, nil}
+	return &Point{x: p2.x - p.x, y: p2.y - p.y}
 }
 
 func (qt *QuadTree) divide() {
 	if qt.nodes[0] != nil {
 		return
 	}
+	qt.loadLeaf()
 
-	bb := &AABB{
-		&Point{qt.boundary.center.x - qt.boundary.half.x/2, qt.boundary.center.y + qt.boundary.half.y/2, nil},
-		&Point{qt.boundary.half.x / 2, qt.boundary.half.y / 2, nil},
-	}
+	c := qt.boundary.center
+	hx, hy := qt.boundary.half.x/2, qt.boundary.half.y/2
 
-	qt.nodes[0] = New(bb, qt.depth+1, qt)
+	qt.nodes[0] = qt.newChild(c.x-hx, c.y+hy, hx, hy)
+	qt.nodes[1] = qt.newChild(c.x+hx, c.y+hy, hx, hy)
+	qt.nodes[2] = qt.newChild(c.x-hx, c.y-hy, hx, hy)
+	qt.nodes[3] = qt.newChild(c.x+hx, c.y-hy, hx, hy)
 
-	bb = &AABB{
-		&Point{qt.boundary.center.x + qt.boundary.half.x/2, qt.boundary.center.y + qt.boundary.half.y/2, nil},
-		&Point{qt.boundary.half.x / 2, qt.boundary.half.y / 2, nil},
+	for _, p := range qt.resident {
+		for _, node := range qt.nodes {
+			if node.insert(p) {
+				break
+			}
+		}
 	}
 
-	qt.nodes[1] = New(bb, qt.depth+1, qt)
+	qt.counted(-len(qt.resident))
+	qt.unstored(0, len(qt.resident))
+	qt.resident = nil
+	qt.codes = nil
+	clear(qt.inline[:])
 
-	bb = &AABB{
-		&Point{qt.boundary.center.x - qt.boundary.half.x/2, qt.boundary.center.y - qt.boundary.half.y/2, nil},
-		&Point{qt.boundary.half.x / 2, qt.boundary.half.y / 2, nil},
-	}
+	qt.pushExtents()
 
-	qt.nodes[2] = New(bb, qt.depth+1, qt)
+	qt.state.hooks.divided(qt)
+}
 
-	bb = &AABB{
-		&Point{qt.boundary.center.x + qt.boundary.half.x/2, qt.boundary.center.y - qt.boundary.half.y/2, nil},
-		&Point{qt.boundary.half.x / 2, qt.boundary.half.y / 2, nil},
+func (qt *QuadTree) insert(p *Point) bool {
+	if !qt.boundary.ContainsPoint(p) {
+		return false
 	}
 
-	qt.nodes[3] = New(bb, qt.depth+1, qt)
+	if p.meta().radius > qt.radius {
+		qt.radius = p.meta().radius
+	}
+	if w := p.Weight(); w > qt.weight {
+		qt.weight = w
+	}
+	qt.tags |= p.meta().tagBits
 
-	for _, p := range qt.points {
-		for _, node := range qt.nodes {
-			if node.Insert(p) {
-				break
-			}
+	if qt.nodes[0] == nil {
+		qt.loadLeaf()
+		if !qt.splits(len(qt.leaf()) + 1) {
+			qt.appendPoint(p)
+			qt.touch()
+			return true
 		}
+		qt.divide()
 	}
 
-	qt.points = nil
-}
+	for i, node := range qt.nodes {
+		if node.boundary.ContainsPoint(p) && qt.own(i).insert(p) {
+			return true
+		}
+	}
 
-func (qt *QuadTree) knearest(a *AABB, i int, v map[*QuadTree]bool, fn filter) []*Point {
-	var results []*Point
+	return false
+}
 
-	if _, ok := v[qt]; ok {
-		return results
-	} else {
-		v[qt] = true
+// inserted records the insertion of a point, assigning its sequence the
+// first time it is inserted into the tree, and removes the point it
+// replaces by ID if any.
+func (qt *QuadTree) inserted(p *Point, old *Point) {
+	if p.seq == 0 {
+		qt.state.seq++
+		p.seq = qt.state.seq
 	}
-
-	if !qt.boundary.Intersect(a) {
-		return results
+	p.updated = qt.state.clock().UnixNano()
+	if qt.state.capped != nil {
+		qt.state.capped.add(p)
 	}
 
-	for _, p := range qt.points {
-		if a.ContainsPoint(p) {
-			results = append(results, p)
-		}
+	if old != nil && qt.root().remove(old) {
+		qt.dropped(old)
+	}
 
-		if len(results) >= i {
-			return results[:i]
-		}
+	qt.indexID(p)
+	if qt.state.fresh != nil {
+		qt.state.fresh[p] = struct{}{}
 	}
+	qt.state.tenants[p.meta().tenant]++
+	qt.state.bytes += p.size()
+	qt.state.count++
 
-	if qt.nodes[0] != nil {
-		for _, node := range qt.nodes {
-			results = append(results, node.knearest(a, i, v, fn)...)
+	if qt.state.metrics != nil {
+		qt.state.metrics.Inserted()
+	}
+	qt.state.hooks.inserted(p)
+}
 
-			if len(results) >= i {
-				return results[:i]
-			}
-		}
-		if len(results) >= i {
-			results = results[:i]
-		}
+// dropped records the removal of a point from the tree.
+func (qt *QuadTree) dropped(p *Point) {
+	qt.unindexID(p)
+	qt.state.unshared(p)
+	if qt.state.capped != nil {
+		qt.state.capped.remove(p)
 	}
 
-	if qt.parent == nil {
-		return results
+	if qt.state.tenants[p.meta().tenant]--; qt.state.tenants[p.meta().tenant] <= 0 {
+		delete(qt.state.tenants, p.meta().tenant)
 	}
 
-	results = append(results, qt.parent.knearest(a, i, v, fn)...)
+	qt.state.bytes -= p.size()
+	qt.state.count--
 
-	if len(results) >= i {
-		results = results[:i]
+	if qt.state.metrics != nil {
+		qt.state.metrics.Removed()
 	}
-	return results
+	qt.state.hooks.removed(p)
+}
+
+func (qt *QuadTree) root() *QuadTree {
+	for qt.parent != nil {
+		qt = qt.parent
+	}
+	return qt
 }
 
 // Insert will attempt to insert the point into the QuadTree. It will
@@ -239,104 +486,123 @@ func (qt *QuadTree) knearest(a *AABB, i int, v map[*QuadTree]bool, fn filter) []
 // is at capacity then it will try split the node. If the tree is at
 // max depth then point will be stored in the leaf.
 func (qt *QuadTree) Insert(p *Point) bool {
-	if !qt.boundary.ContainsPoint(p) {
-		return false
+	return qt.add(p) == nil
+}
+
+// add inserts a point, returning the reason it could not be inserted.
+func (qt *QuadTree) add(p *Point) error {
+	return qt.addVia(p, qt.insert)
+}
+
+// addVia is add inserting the point into the tree using insert.
+func (qt *QuadTree) addVia(p *Point, insert func(*Point) bool) error {
+	if qt.state.readOnly {
+		return ErrReadOnly
+	}
+	if p != nil && qt.state.locked(p) {
+		return ErrRegionLocked
+	}
+	if !qt.state.allowWrite() {
+		return ErrRateLimited
 	}
 
-	if qt.nodes[0] == nil {
-		if len(qt.points) < Capacity {
-			qt.points = append(qt.points, p)
-			return true
-		}
+	// a point removed while a snapshot still holds it is inserted as a
+	// copy
+	prev := p
+	p = qt.state.reinserting(p)
 
-		if qt.depth < MaxDepth {
-			qt.divide()
-		} else {
-			qt.points = append(qt.points, p)
-			return true
-		}
+	old, err := qt.admit(p)
+	if err != nil {
+		return err
 	}
 
-	for _, node := range qt.nodes {
-		if node.Insert(p) {
-			return true
-		}
+	if qt.state.grow && qt.parent == nil && !qt.boundary.ContainsPoint(p) && !qt.grow(p) {
+		return ErrOutOfBounds
+	}
+	if !insert(p) {
+		return ErrOutOfBounds
 	}
 
-	return false
+	qt.inserted(p, old)
+	if p != prev {
+		qt.state.forwardTo(prev, p)
+	}
+	qt.trim(p)
+	return nil
 }
 
 // KNearest returns the k nearest points within the QuadTree that fall within
-// the bounds of the axis aligned bounding box. A filter function can be used
-// which is evaluated against each point. The search begins at the leaf and
-// recurses towards the parent until k nearest have been found or root node is
-// hit.
-func (qt *QuadTree) kNearestRoot(a *AABB, i int, v map[*QuadTree]bool, fn filter) []*Point {
-	var results []*Point
+// the bounds of the axis aligned bounding box, ordered by distance from its
+// center. Points at the same distance are ordered by when they were first
+// inserted, so repeated queries and pages of results are reproducible. A
+// filter function can be used which is evaluated against each point. Nodes
+// are searched best first and those further away than the kth nearest
+// point found are pruned.
+func (qt *QuadTree) KNearest(a *AABB, i int, fn filter, opts ...QueryOption) []*Point {
+	qt.state.acquire()
+	defer qt.state.release()
+	return qt.kNearestQuery(nil, a, i, fn, qt.newQuery(opts))
+}
 
-	if !qt.boundary.Intersect(a) {
-		return results
+// kNearestQuery appends the results of a KNearest query to dst.
+func (qt *QuadTree) kNearestQuery(dst []*Point, a *AABB, i int, fn filter, q *query) []*Point {
+	if noBox(a) || i <= 0 {
+		return nonNil(dst)
 	}
 
-	// hit the leaf
-	if qt.nodes[0] == nil {
-		results = append(results, qt.knearest(a, i, v, fn)...)
+	t := qt.begin()
 
-		if len(results) >= i {
-			results = results[:i]
-		}
-
-		return results
+	if q.expand {
+		dst = qt.expanding(dst, a, i, fn, q)
+	} else {
+		dst = qt.kNearestIn(dst, a, i, fn, q)
 	}
 
-	for _, node := range qt.nodes {
-		results = append(results, node.kNearestRoot(a, i, v, fn)...)
+	qt.end(t, "knearest", a, i)
+	return nonNil(dst)
+}
 
-		if len(results) >= i {
-			return results[:i]
-		}
+// kNearestIn appends the k nearest points within a to dst.
+func (qt *QuadTree) kNearestIn(dst []*Point, a *AABB, i int, fn filter, q *query) []*Point {
+	if q.cost != nil {
+		return append(dst, qt.kbest(a.center, i, q.cost, nil, a, q.filter(fn), q)...)
 	}
-
-	if len(results) >= i {
-		results = results[:i]
+	if q.rank != nil {
+		score, bound := ranked(q.rank)
+		return append(dst, qt.kbest(a.center, i, score, bound, a, q.filter(fn), q)...)
 	}
-
-	return results
-}
-
-func (qt *QuadTree) KNearest(a *AABB, i int, fn filter) []*Point {
-	v := make(map[*QuadTree]bool)
-	return qt.kNearestRoot(a, i, v, fn)
+	return qt.knearest(dst, a, i, fn, q)
 }
 
-// Remove attemps to remove a point from the QuadTree. It will recurse until
-// the leaf node is found and then try to remove the point.
-func (qt *QuadTree) Remove(p *Point) bool {
+func (qt *QuadTree) remove(p *Point) bool {
 	if !qt.boundary.ContainsPoint(p) {
 		return false
 	}
 
 	if qt.nodes[0] == nil {
-		for i, ep := range qt.points {
+		qt.loadLeaf()
+		for i, ep := range qt.leaf() {
 			if ep != p {
 				continue
 			}
 
 			// remove point
-			if last := len(qt.points) - 1; i == last {
-				qt.points = qt.points[:last]
-			} else {
-				qt.points[i] = qt.points[last]
-				qt.points = qt.points[:last]
-			}
+			qt.removeAt(i)
+			qt.touch()
 			return true
 		}
 
 		return false
 	}
 
-	for _, node := range qt.nodes {
-		if node.Remove(p) {
+	for i, node := range qt.nodes {
+		if !node.boundary.ContainsPoint(p) {
+			continue
+		}
+		if node = qt.own(i); node.remove(p) {
+			if qt.state.autoCompact && node.nodes[0] == nil && qt.total < qt.state.capacity {
+				qt.collapse()
+			}
 			return true
 		}
 	}
@@ -344,10 +610,28 @@ func (qt *QuadTree) Remove(p *Point) bool {
 	return false
 }
 
-// RInsert is used in conjuction with Update to try reveser insert a point.
-func (qt *QuadTree) RInsert(p *Point) bool {
+// Remove attemps to remove a point from the QuadTree. It will recurse until
+// the leaf node is found and then try to remove the point.
+func (qt *QuadTree) Remove(p *Point) bool {
+	if p == nil {
+		return false
+	}
+	p = qt.state.current(p)
+	if qt.state.locked(p) || !qt.state.allowWrite() {
+		return false
+	}
+
+	if !qt.remove(p) {
+		return false
+	}
+
+	qt.dropped(p)
+	return true
+}
+
+func (qt *QuadTree) rinsert(p *Point) bool {
 	// Try insert down the tree
-	if qt.Insert(p) {
+	if qt.insert(p) {
 		return true
 	}
 
@@ -357,20 +641,83 @@ func (qt *QuadTree) RInsert(p *Point) bool {
 	}
 
 	// try rinsert parent
-	return qt.parent.RInsert(p)
+	return qt.parent.rinsert(p)
+}
+
+// RInsert is used in conjuction with Update to try reveser insert a point.
+func (qt *QuadTree) RInsert(p *Point) bool {
+	if p == nil || qt.state.locked(p) || !qt.state.allowWrite() {
+		return false
+	}
+
+	prev := p
+	p = qt.state.reinserting(p)
+
+	old, err := qt.admit(p)
+	if err != nil {
+		return false
+	}
+
+	if !qt.rinsert(p) {
+		return false
+	}
+
+	qt.inserted(p, old)
+	if p != prev {
+		qt.state.forwardTo(prev, p)
+	}
+	qt.trim(p)
+	return true
 }
 
 // Search will return all the points within the given axis aligned bounding
 // box. It recursively searches downward through the tree.
-func (qt *QuadTree) Search(a *AABB) []*Point {
+func (qt *QuadTree) Search(a *AABB, opts ...QueryOption) []*Point {
+	qt.state.acquire()
+	defer qt.state.release()
+	return qt.searchQuery(a, qt.newQuery(opts))
+}
+
+func (qt *QuadTree) searchQuery(a *AABB, q *query) []*Point {
+	if noBox(a) {
+		return []*Point{}
+	}
+
+	t := qt.begin()
+
+	var results []*Point
+	if boxes, ok := qt.state.wrap(a); ok {
+		for _, b := range boxes {
+			results = append(results, qt.search(b, q)...)
+			if q.truncated || q.cancelled {
+				break
+			}
+		}
+	} else {
+		results = qt.search(a, q)
+	}
+
+	if q.distinct != nil {
+		results = q.dedupe(results, newer)
+	}
+	qt.end(t, "search", a, 0)
+	return nonNil(results)
+}
+
+func (qt *QuadTree) search(a *AABB, q *query) []*Point {
 	var results []*Point
 
-	if !qt.boundary.Intersect(a) {
+	if !qt.boundary.Intersect(a) || q.excludes(qt.boundary) || !q.tagged(qt) || q.stopped() {
 		return results
 	}
 
-	for _, p := range qt.points {
-		if a.ContainsPoint(p) {
+	qt.hit()
+
+	for _, p := range qt.scan(a) {
+		if a.ContainsPoint(p) && q.match(p) {
+			if !q.charge(p) {
+				return results
+			}
 			results = append(results, p)
 		}
 	}
@@ -379,56 +726,180 @@ func (qt *QuadTree) Search(a *AABB) []*Point {
 		return results
 	}
 
-	for _, node := range qt.nodes {
-		results = append(results, node.Search(a)...)
+	for _, node := range q.children(qt, a) {
+		results = append(results, node.search(a, q)...)
+		if q.truncated || q.cancelled {
+			break
+		}
 	}
 
 	return results
 }
 
+// visit calls fn for each point within the bounding box matching the
+// query, stopping early if fn returns false.
+func (qt *QuadTree) visit(a *AABB, q *query, fn func(*Point) bool) bool {
+	boxes, ok := qt.state.wrap(a)
+	if !ok {
+		return qt.visitNode(a, q, fn)
+	}
+
+	for _, b := range boxes {
+		if !qt.visitNode(b, q, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func (qt *QuadTree) visitNode(a *AABB, q *query, fn func(*Point) bool) bool {
+	if !qt.boundary.Intersect(a) || q.excludes(qt.boundary) || !q.tagged(qt) {
+		return true
+	}
+
+	qt.hit()
+
+	for _, p := range qt.scan(a) {
+		if a.ContainsPoint(p) && q.match(p) && !fn(p) {
+			return false
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return true
+	}
+
+	for _, node := range q.children(qt, a) {
+		if !node.visitNode(a, q, fn) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Update will update the location of a point within the tree. It is
 // optimised to attempt reinsertion within the same node and recurse
-// back up the tree until it finds a suitable node.
+// back up the tree until it finds a suitable node. If the new location is
+// outside the boundary of the tree the point is left where it is and
+// Update returns false, unless the tree was created using WithAutoGrow
+// in which case the root grows to take it in. UpdateErr reports why a
+// point was not moved.
 func (qt *QuadTree) Update(p *Point, np *Point) bool {
+	return qt.UpdateErr(p, np) == nil
+}
+
+// UpdateErr is Update returning an error rather than false: ErrNotFound if
+// the point is not in the tree, ErrOutOfBounds if the new location is
+// outside the boundary of a tree created without WithAutoGrow, or
+// ErrReadOnly, ErrRegionLocked or ErrRateLimited as for an insert. The
+// point is left where it was whenever an error is returned.
+func (qt *QuadTree) UpdateErr(p *Point, np *Point) error {
+	if p == nil || np == nil {
+		return ErrNilPoint
+	}
+	if qt.state.readOnly {
+		return ErrReadOnly
+	}
+	p = qt.state.current(p)
+	if qt.state.locked(p, np) {
+		return ErrRegionLocked
+	}
+	if !qt.state.allowWrite() {
+		return ErrRateLimited
+	}
+
+	root := qt.root()
+	if !root.boundary.ContainsPoint(np) {
+		if root.owner(p) == nil {
+			return ErrNotFound
+		}
+		if !qt.state.grow || !root.grow(np) {
+			return ErrOutOfBounds
+		}
+	}
+
+	if !qt.update(p, np) {
+		if root.owner(qt.state.current(p)) == nil {
+			return ErrNotFound
+		}
+		return ErrOutOfBounds
+	}
+	return nil
+}
+
+func (qt *QuadTree) update(p *Point, np *Point) bool {
 	if !qt.boundary.ContainsPoint(p) {
 		return false
 	}
 
 	// At the leaf
 	if qt.nodes[0] == nil {
-		for i, ep := range qt.points {
+		qt.loadLeaf()
+		for i, ep := range qt.leaf() {
 			if ep != p {
 				continue
 			}
 
-			// set new coords
+			// leave the point in place rather than lose it
+			if !qt.boundary.ContainsPoint(np) && !qt.root().boundary.ContainsPoint(np) {
+				return false
+			}
+
+			// set new coords, on a copy of a point held by a snapshot
+			p = qt.fork(i)
+			x, y := p.x, p.y
 			p.x = np.x
 			p.y = np.y
+			qt.touch()
 
 			// now do we move?
 			if qt.boundary.ContainsPoint(np) {
+				if qt.codes != nil {
+					// keep the morton order
+					qt.removeAt(i)
+					qt.appendPoint(p)
+				}
+				qt.moved(p, x, y)
 				return true
 			}
 
 			// remove from current node
-			if last := len(qt.points) - 1; i == last {
-				qt.points = qt.points[:last]
-			} else {
-				qt.points[i] = qt.points[last]
-				qt.points = qt.points[:last]
-			}
+			qt.removeAt(i)
 
 			// well shit now...reinsert
-			return qt.RInsert(p)
+			if qt.state.metrics != nil {
+				qt.state.metrics.Reinserted()
+			}
+			if !qt.rinsert(p) {
+				// put the point back rather than lose it
+				p.x, p.y = x, y
+				if !qt.rinsert(p) {
+					qt.dropped(p)
+				}
+				return false
+			}
+			qt.moved(p, x, y)
+			return true
 		}
 		return false
 	}
 
-	for _, node := range qt.nodes {
-		if node.Update(p, np) {
+	for i, node := range qt.nodes {
+		if node.boundary.ContainsPoint(p) && qt.own(i).update(p, np) {
 			return true
 		}
 	}
 
 	return false
 }
+
+// moved records a point moved from x, y once the move has succeeded, so a
+// failed update leaves its version, update time and write counts as they
+// were.
+func (qt *QuadTree) moved(p *Point, x, y float64) {
+	qt.state.updated(p)
+	p.setMeta().version++
+	qt.wrote(p)
+	qt.state.hooks.moved(p, x, y)
+}
@@ -0,0 +1,412 @@
+package quadtree_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/asim/quadtree"
+	"github.com/asim/quadtree/testdata"
+)
+
+// optionSets are the configurations the operations are applied to, chosen
+// to exercise dividing, collapsing, the arena, ID and duplicate policies,
+// growing, aggregates, point stores and morton ordered leaves.
+var optionSets = []struct {
+	name string
+	opts func() []quadtree.Option
+	grow bool
+	ids  quadtree.IDPolicy
+	dups quadtree.DuplicatePolicy
+	// write through to a point store checked against the tree
+	store bool
+}{
+	{name: "default", opts: func() []quadtree.Option { return nil }},
+	{name: "shallow", opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithCapacity(1), quadtree.WithMaxDepth(3)}
+	}},
+	{name: "arena", opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithArena(), quadtree.WithCapacity(2)}
+	}},
+	{name: "autocompact", opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithAutoCompact(), quadtree.WithCapacity(2)}
+	}},
+	{name: "idreplace", ids: quadtree.IDReplace, opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithUniqueIDs(quadtree.IDReplace)}
+	}},
+	{name: "idreject", ids: quadtree.IDReject, opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithUniqueIDs(quadtree.IDReject)}
+	}},
+	{name: "dupreject", dups: quadtree.DuplicateReject, opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithDuplicates(quadtree.DuplicateReject), quadtree.WithCapacity(2)}
+	}},
+	{name: "dupreplace", dups: quadtree.DuplicateReplace, opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithDuplicates(quadtree.DuplicateReplace), quadtree.WithCapacity(2)}
+	}},
+	{name: "grow", grow: true, opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithAutoGrow(), quadtree.WithCapacity(2)}
+	}},
+	{name: "aggregates", opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithAggregates(nil, nil), quadtree.WithCapacity(2)}
+	}},
+	{name: "store", store: true, opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithCapacity(2)}
+	}},
+	{name: "growstore", grow: true, store: true, opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithAutoGrow(), quadtree.WithCapacity(2)}
+	}},
+	{name: "leafscan", opts: func() []quadtree.Option {
+		return []quadtree.Option{quadtree.WithMaxLeafScan(2), quadtree.WithCapacity(1), quadtree.WithMaxDepth(2)}
+	}},
+}
+
+// ops reads the operations to apply from data.
+type ops struct {
+	data []byte
+}
+
+func (o *ops) more() bool {
+	return len(o.data) > 0
+}
+
+func (o *ops) next() byte {
+	if len(o.data) == 0 {
+		return 0
+	}
+	b := o.data[0]
+	o.data = o.data[1:]
+	return b
+}
+
+// coordinates returns a location of coarse coordinates, so points share
+// them often, outside the world bounds for the highest byte.
+func (o *ops) coordinates() (float64, float64) {
+	x, y := o.next(), o.next()
+	lat, lng := -90+float64(x)*180/254, -180+float64(y)*360/254
+	if x == 255 {
+		lat = 120
+	}
+	if y == 255 {
+		lng = -200
+	}
+	return lat, lng
+}
+
+// box returns a search box within or around the world bounds.
+func (o *ops) box() *quadtree.AABB {
+	lat, lng := o.coordinates()
+	h := quadtree.NewPoint(1+float64(o.next())*90/255, 1+float64(o.next())*180/255, nil)
+	return quadtree.NewAABB(quadtree.NewPoint(lat, lng, nil), h)
+}
+
+// apply runs the operations of data against a tree created with the
+// option set the first byte selects, checking after each that the tree is
+// valid and that queries match a brute force scan of the points it should
+// hold.
+func apply(t *testing.T, data []byte) {
+	o := &ops{data: data}
+	set := optionSets[int(o.next())%len(optionSets)]
+
+	opts := set.opts()
+	store := quadtree.NewMemoryStore()
+	if set.store {
+		opts = append(opts, quadtree.WithPointStore(store))
+	}
+
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil, opts...)
+	var points []*quadtree.Point
+
+	without := func(p *quadtree.Point) {
+		for i, ep := range points {
+			if ep == p {
+				points = append(points[:i], points[i+1:]...)
+				return
+			}
+		}
+	}
+
+	for step := 0; o.more(); step++ {
+		op := o.next() % 7
+
+		switch op {
+		case 0, 1:
+			x, y := o.coordinates()
+			id := ""
+			if n := o.next() % 8; n > 0 {
+				id = fmt.Sprint("id", n)
+			}
+			p := quadtree.NewPointID(id, x, y, len(points))
+
+			var byID *quadtree.Point
+			var byLocation []*quadtree.Point
+			for _, ep := range points {
+				if id != "" && ep.ID() == id {
+					byID = ep
+				}
+				if ex, ey := ep.Coordinates(); ex == x && ey == y {
+					byLocation = append(byLocation, ep)
+				}
+			}
+
+			want := set.grow || quadtree.WorldBounds().ContainsPoint(p)
+			if set.ids == quadtree.IDReject && byID != nil {
+				want = false
+			}
+			if set.dups == quadtree.DuplicateReject && len(byLocation) > 0 {
+				want = false
+			}
+
+			if got := qt.Insert(p); got != want {
+				t.Fatalf("step %d (%s): insert at %v, %v returned %v, want %v", step, set.name, x, y, got, want)
+			}
+			if !want {
+				break
+			}
+			if set.ids == quadtree.IDReplace && byID != nil {
+				without(byID)
+			}
+			if set.dups == quadtree.DuplicateReplace && len(byLocation) > 0 {
+				// points moved onto one another by Update share a location,
+				// and which of them is replaced is not specified
+				replaced := byLocation[0]
+				if len(byLocation) > 1 {
+					held := qt.Search(quadtree.NewAABB(quadtree.NewPoint(x, y, nil), quadtree.NewPoint(0, 0, nil)))
+					for _, ep := range byLocation {
+						if !slices.Contains(held, ep) {
+							replaced = ep
+						}
+					}
+				}
+				without(replaced)
+			}
+			points = append(points, p)
+
+		case 2:
+			if len(points) == 0 {
+				break
+			}
+			p := points[int(o.next())%len(points)]
+			if !qt.Remove(p) {
+				t.Fatalf("step %d (%s): remove of point %v failed", step, set.name, p.Data())
+			}
+			without(p)
+
+		case 3:
+			if len(points) == 0 {
+				break
+			}
+			p := points[int(o.next())%len(points)]
+			x, y := o.coordinates()
+			np := quadtree.NewPoint(x, y, nil)
+
+			want := set.grow || qt.Boundary().ContainsPoint(np)
+			if got := qt.Update(p, np); got != want {
+				t.Fatalf("step %d (%s): update to %v, %v returned %v, want %v", step, set.name, x, y, got, want)
+			}
+			if px, py := p.Coordinates(); want && (px != x || py != y) {
+				t.Fatalf("step %d (%s): update left the point at %v, %v, want %v, %v", step, set.name, px, py, x, y)
+			}
+
+		case 4:
+			qt.Compact()
+
+		case 5:
+			a := o.box()
+			testdata.AssertSearch(t, qt, points, a)
+			testdata.AssertKNearest(t, qt, points, a, 1+int(o.next())%16, nil)
+			if got, want := qt.Count(a), len(testdata.Search(points, a)); got != want {
+				t.Errorf("step %d (%s): count returned %d, want %d", step, set.name, got, want)
+			}
+
+		case 6:
+			// later operations read the released leaves from the store
+			qt.Release()
+		}
+
+		if err := qt.Validate(); err != nil {
+			t.Fatalf("step %d (%s): op %d: %v", step, set.name, op, err)
+		}
+		if qt.Len() != len(points) {
+			t.Fatalf("step %d (%s): tree holds %d points, want %d", step, set.name, qt.Len(), len(points))
+		}
+		if set.store {
+			stored(t, qt, store)
+		}
+		if t.Failed() {
+			t.FailNow()
+		}
+	}
+
+	all := qt.Boundary()
+	testdata.AssertSearch(t, qt, points, all)
+	testdata.AssertKNearest(t, qt, points, all, len(points)+1, nil)
+}
+
+// stored fails the test unless the store holds exactly the points of each
+// leaf of the tree in their slots.
+func stored(t *testing.T, qt *quadtree.QuadTree, store *quadtree.MemoryStore) {
+	t.Helper()
+
+	root := qt.Boundary()
+	n := 0
+	for leaf := range qt.LeavesIntersecting(root) {
+		// the path of the leaf, descending through the quadrants holding
+		// its center in the order of divide
+		var path []string
+		c, h := root.Center(), root.Half()
+		x, y := c.Coordinates()
+		hx, hy := h.Coordinates()
+		lx, ly := leaf.Boundary().Center().Coordinates()
+		for depth := 0; depth < leaf.Depth(); depth++ {
+			hx, hy = hx/2, hy/2
+			i := 0
+			if lx > x {
+				i, x = 1, x+hx
+			} else {
+				x -= hx
+			}
+			if ly > y {
+				y += hy
+			} else {
+				i, y = i+2, y-hy
+			}
+			path = append(path, fmt.Sprint(i))
+		}
+		id := quadtree.NodeID(strings.Join(path, "."))
+
+		for slot, p := range leaf.Points() {
+			if got, _ := store.Get(id, slot); got != p {
+				t.Fatalf("store holds %v in slot %d of node %q, want point %v", got, slot, id, p.Data())
+			}
+			n++
+		}
+	}
+
+	if store.Len() != n {
+		t.Fatalf("store holds %d points, want %d", store.Len(), n)
+	}
+}
+
+// lossyStore is a point store which loses every point once lost is set.
+type lossyStore struct {
+	*quadtree.MemoryStore
+	lost bool
+}
+
+var errLost = errors.New("point lost")
+
+func (s *lossyStore) Get(node quadtree.NodeID, slot int) (*quadtree.Point, error) {
+	if s.lost {
+		return nil, errLost
+	}
+	return s.MemoryStore.Get(node, slot)
+}
+
+func TestRelease(t *testing.T) {
+	store := &lossyStore{MemoryStore: quadtree.NewMemoryStore()}
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil, quadtree.WithPointStore(store), quadtree.WithCapacity(4))
+	points := testdata.Uniform(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 500)
+	for _, p := range points {
+		qt.Insert(p)
+	}
+
+	if n := qt.Release(); n != len(points) {
+		t.Fatalf("released %d points, want %d", n, len(points))
+	}
+	a := quadtree.NewAABB(quadtree.NewPoint(10, 10, nil), quadtree.NewPoint(40, 80, nil))
+	testdata.AssertSearch(t, qt, points, a)
+	testdata.AssertKNearest(t, qt, points, a, 10, nil)
+
+	// writes read the leaves they change back into the tree
+	if !qt.Remove(points[0]) || !qt.Update(points[1], quadtree.NewPoint(-10, -10, nil)) {
+		t.Fatal("write to a released leaf failed")
+	}
+	points = points[1:]
+	testdata.AssertSearch(t, qt, points, qt.Boundary())
+	if err := qt.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	stored(t, qt, store.MemoryStore)
+
+	// queries report points the store loses
+	if n := qt.Release(); n != len(points) {
+		t.Fatalf("released %d points, want %d", n, len(points))
+	}
+	store.lost = true
+	if n := len(qt.Search(a)); n != 0 {
+		t.Fatalf("search found %d points the store lost", n)
+	}
+	if err := qt.StoreErr(); err != errLost {
+		t.Fatalf("StoreErr returned %v, want the error of the store", err)
+	}
+}
+
+func FuzzOps(f *testing.F) {
+	for i := range optionSets {
+		f.Add([]byte{byte(i), 0, 10, 10, 1, 0, 10, 10, 1, 5, 0, 0, 200, 200, 4, 2, 0, 5, 0, 0, 255, 255, 9})
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 16; i++ {
+		b := make([]byte, 64+r.Intn(512))
+		r.Read(b)
+		f.Add(b)
+	}
+
+	f.Fuzz(apply)
+}
+
+func TestOps(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+
+	for i, set := range optionSets {
+		t.Run(set.name, func(t *testing.T) {
+			for n := 0; n < 20; n++ {
+				b := make([]byte, 2000)
+				r.Read(b)
+				// bias towards inserts so trees grow deep
+				for j := 1; j < len(b); j += 7 {
+					b[j] %= 2
+				}
+				b[0] = byte(i)
+				apply(t, b)
+			}
+		})
+	}
+}
+
+func TestPointSize(t *testing.T) {
+	// metadata set by few points, such as tags, a tenant or a time to
+	// live, is held apart so it costs the rest nothing
+	if size := unsafe.Sizeof(quadtree.Point{}); size > 72 {
+		t.Fatalf("Point is %d bytes, want at most 72", size)
+	}
+
+	// a point of its own for each run, as AllocsPerRun runs once more
+	points := make([]*quadtree.Point, 101)
+	for i := range points {
+		points[i] = quadtree.NewPointID("a", 1, 2, nil)
+	}
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		p := points[0]
+		points = points[1:]
+		if !qt.Insert(p) || !qt.Remove(p) {
+			t.Fatal("insert and remove failed")
+		}
+		_, _, _, _ = p.Tenant(), p.Weight(), p.Radius(), p.TTL()
+	})
+	if allocs != 0 {
+		t.Fatalf("inserting and removing a point allocated %v times, want none", allocs)
+	}
+
+	// the metadata is allocated once set
+	p := quadtree.NewPoint(1, 2, nil)
+	p.SetWeight(2)
+	if p.Weight() != 2 || p.Version() != 0 {
+		t.Fatalf("point has weight %v and version %d, want 2 and 0", p.Weight(), p.Version())
+	}
+}
@@ -0,0 +1,271 @@
+package quadtree
+
+import (
+	"time"
+)
+
+// QueryOption configures an individual query.
+type QueryOption func(*query)
+
+type query struct {
+	halfLife time.Duration
+	now      time.Time
+	// clock of the tree, time.Now if nil
+	clock func() time.Time
+
+	// child traversal order
+	order        []int
+	nearestFirst bool
+
+	// rendering
+	boundaries bool
+	highlight  *AABB
+
+	// include soft removed points
+	removed bool
+
+	// key for deduplicating results
+	distinct func(*Point) string
+
+	// restrict results to a tenant
+	tenant   string
+	tenanted bool
+
+	// transform results
+	mapper func(*Point) interface{}
+
+	// bitmask of lifecycle states returned, all if zero
+	states uint8
+
+	// boxes whose points are excluded
+	exclude []*AABB
+
+	// rank nearest results by cost
+	cost func(p *Point, distMeters float64) float64
+	// or by distance and weight
+	rank func(distMeters, weight float64) float64
+
+	// receives the explanation of each ranked candidate
+	explain func(Explanation)
+
+	// order results in a local tangent plane
+	local bool
+
+	// grow the KNearest query box until k points are found
+	expand bool
+
+	// predicates points must pass
+	where []filter
+
+	// thin tiles to one point per cell of a grid this many cells wide
+	resolution int
+
+	// result memory budget
+	maxBytes  int64
+	used      int64
+	truncated bool
+
+	// tags points must have, and their bitset
+	tags    []string
+	tagBits uint64
+
+	// distance from a ray within which Raycast hits points
+	tolerance float64
+
+	// delay before a slow region of a ConcurrentQuadTree is searched again
+	hedge time.Duration
+
+	// closed to cancel the query, stopping its traversal
+	done      <-chan struct{}
+	cancelled bool
+}
+
+func newQuery(opts []QueryOption) *query {
+	q := &query{}
+	for _, o := range opts {
+		o(q)
+	}
+	return q
+}
+
+// newQuery returns the query of the options evaluated by the tree's clock.
+func (qt *QuadTree) newQuery(opts []QueryOption) *query {
+	q := newQuery(opts)
+	q.clock = qt.state.now
+	return q
+}
+
+// at returns the time the query is evaluated at in unix nanoseconds, the
+// time it is first asked for unless set by WithNow.
+func (q *query) at() int64 {
+	if q.now.IsZero() {
+		if q.clock != nil {
+			q.now = q.clock()
+		} else {
+			q.now = time.Now()
+		}
+	}
+	return q.now.UnixNano()
+}
+
+// WithHalfLife decays the contribution of each point by half for every
+// interval d since the point was last inserted or updated.
+func WithHalfLife(d time.Duration) QueryOption {
+	return func(q *query) {
+		q.halfLife = d
+	}
+}
+
+// WithNow sets the time a query is evaluated at, for decay and expiry,
+// defaulting to the time the query is run.
+func WithNow(t time.Time) QueryOption {
+	return func(q *query) {
+		q.now = t
+	}
+}
+
+// WithRemoved includes points hidden by SoftRemove in the results.
+func WithRemoved() QueryOption {
+	return func(q *query) {
+		q.removed = true
+	}
+}
+
+// Where only returns points for which fn returns true, giving Search and
+// the other queries the filter function of KNearest.
+func Where(fn func(*Point) bool) QueryOption {
+	return func(q *query) {
+		q.where = append(q.where, fn)
+	}
+}
+
+// noBox reports whether a query box is nil or missing its center or half
+// extents, so matches no points.
+func noBox(a *AABB) bool {
+	return a == nil || a.center == nil || a.half == nil
+}
+
+// nonNil returns the results of a query, an empty slice if there are none
+// so that results are never nil.
+func nonNil(results []*Point) []*Point {
+	if results == nil {
+		return []*Point{}
+	}
+	return results
+}
+
+// match reports whether a point satisfies the conditions of the query.
+func (q *query) match(p *Point) bool {
+	if p.meta().removed && !q.removed {
+		return false
+	}
+	if p.meta().ttl > 0 && p.expired(q.at()) {
+		return false
+	}
+	if q.tenanted && p.meta().tenant != q.tenant {
+		return false
+	}
+	if q.tagBits != 0 && !q.hasTags(p) {
+		return false
+	}
+	if q.states != 0 && q.states&(1<<p.meta().lifecycle) == 0 {
+		return false
+	}
+	for _, e := range q.exclude {
+		if e.ContainsPoint(p) {
+			return false
+		}
+	}
+	for _, fn := range q.where {
+		if !fn(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// filter combines the conditions of the query with a filter function.
+func (q *query) filter(fn filter) filter {
+	return func(p *Point) bool {
+		return q.match(p) && (fn == nil || fn(p))
+	}
+}
+
+// WithChildOrder sets the order in which the four children of a node are
+// visited, given as child indices 0 to 3 in the order of Children.
+func WithChildOrder(order [4]int) QueryOption {
+	return func(q *query) {
+		q.order = order[:]
+	}
+}
+
+// WithNearestFirst visits the children of a node in order of the distance
+// of their center to the center of the query box, so streamed results are
+// biased towards the most relevant region first.
+func WithNearestFirst() QueryOption {
+	return func(q *query) {
+		q.nearestFirst = true
+	}
+}
+
+// children returns the child nodes of qt in the order they should be
+// visited for the query box a.
+func (q *query) children(qt *QuadTree, a *AABB) [4]*QuadTree {
+	nodes := qt.nodes
+
+	if q.order != nil {
+		for i, o := range q.order {
+			nodes[i] = qt.nodes[o]
+		}
+	}
+
+	if q.nearestFirst && nodes[0] != nil {
+		for i := 1; i < len(nodes); i++ {
+			for j := i; j > 0; j-- {
+				if planar(nodes[j].boundary.center, a.center) >= planar(nodes[j-1].boundary.center, a.center) {
+					break
+				}
+				nodes[j], nodes[j-1] = nodes[j-1], nodes[j]
+			}
+		}
+	}
+
+	return nodes
+}
+
+// DistinctBy returns only one point per key, for trees storing the same
+// logical entity several times. Search keeps the most recently updated
+// point for each key and KNearest the nearest, replacing the point held
+// for a key as nearer ones are found so only k points are ranked.
+func DistinctBy(key func(*Point) string) QueryOption {
+	return func(q *query) {
+		q.distinct = key
+	}
+}
+
+// newer reports whether p was updated more recently than q.
+func newer(p, q *Point) bool {
+	return p.updated > q.updated
+}
+
+// dedupe keeps one point per distinct key, preferring the earliest in
+// order unless better reports a later point is preferable.
+func (q *query) dedupe(points []*Point, better func(p, q *Point) bool) []*Point {
+	seen := make(map[string]int, len(points))
+	results := points[:0]
+
+	for _, p := range points {
+		key := q.distinct(p)
+		i, ok := seen[key]
+		if !ok {
+			seen[key] = len(results)
+			results = append(results, p)
+			continue
+		}
+		if better != nil && better(p, results[i]) {
+			results[i] = p
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,359 @@
+package quadtree_test
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/asim/quadtree"
+	"github.com/asim/quadtree/testdata"
+)
+
+// contractTree returns a tree of clustered points around the world.
+func contractTree() *quadtree.QuadTree {
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil)
+	for _, p := range testdata.Clustered(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 1000, 8, 2) {
+		qt.Insert(p)
+	}
+	return qt
+}
+
+// empty fails the test unless the result is a non-nil empty slice.
+func empty(t *testing.T, name string, result interface{}) {
+	t.Helper()
+
+	v := reflect.ValueOf(result)
+	switch {
+	case v.Kind() != reflect.Slice:
+		t.Errorf("%s returned %T, want a slice", name, result)
+	case v.IsNil():
+		t.Errorf("%s returned nil, want an empty slice", name)
+	case v.Len() != 0:
+		t.Errorf("%s returned %d results, want none", name, v.Len())
+	}
+}
+
+func TestQueryContractBoxes(t *testing.T) {
+	qt := contractTree()
+
+	boxes := map[string]*quadtree.AABB{
+		"nil box":     nil,
+		"nil center":  quadtree.NewAABB(nil, quadtree.NewPoint(1, 1, nil)),
+		"nil half":    quadtree.NewAABB(quadtree.NewPoint(1, 1, nil), nil),
+		"outside box": quadtree.NewAABB(quadtree.NewPoint(500, 500, nil), quadtree.NewPoint(1, 1, nil)),
+	}
+
+	for name, a := range boxes {
+		t.Run(name, func(t *testing.T) {
+			empty(t, "Search", qt.Search(a))
+			empty(t, "KNearest", qt.KNearest(a, 5, nil))
+			empty(t, "SearchValues", qt.SearchValues(a))
+			empty(t, "KNearestValues", qt.KNearestValues(a, 5, nil))
+			empty(t, "SearchResults", qt.SearchResults(a))
+			empty(t, "KNearestResults", qt.KNearestResults(a, 5, nil))
+			empty(t, "KNearestDistance", qt.KNearestDistance(a, 5, nil))
+			empty(t, "SearchAppend", qt.SearchAppend(nil, a))
+			empty(t, "KNearestAppend", qt.KNearestAppend(nil, a, 5, nil))
+			empty(t, "SearchMulti", qt.SearchMulti([]*quadtree.AABB{a}))
+			empty(t, "SearchExtents", qt.SearchExtents(a))
+			empty(t, "Freeze().Search", qt.Freeze().Search(a))
+			empty(t, "Freeze().KNearest", qt.Freeze().KNearest(a, 5, nil))
+
+			for p := range qt.SearchSeq(a) {
+				t.Errorf("SearchSeq yielded %v, want nothing", p)
+			}
+			for l := range qt.LeavesIntersecting(a) {
+				t.Errorf("LeavesIntersecting yielded %v, want nothing", l.Boundary())
+			}
+
+			if p, ok := qt.Any(a, nil); ok || p != nil {
+				t.Errorf("Any returned %v, %v, want nil, false", p, ok)
+			}
+			if n := qt.Count(a); n != 0 {
+				t.Errorf("Count returned %d, want 0", n)
+			}
+			if h := qt.Heat(a); h != 0 {
+				t.Errorf("Heat returned %v, want 0", h)
+			}
+			if n := qt.Warm(a); n != 0 {
+				t.Errorf("Warm returned %d, want 0", n)
+			}
+			if h := qt.Histogram(a, func(*quadtree.Point) float64 { return 0 }, []float64{1}); !reflect.DeepEqual(h, []int{0, 0}) {
+				t.Errorf("Histogram returned %v, want [0 0]", h)
+			}
+		})
+	}
+
+	for name, a := range map[string]*quadtree.AABB{"nil box": nil, "nil center": boxes["nil center"]} {
+		t.Run(name+" grids", func(t *testing.T) {
+			empty(t, "Density", qt.Density(a, 4, 4))
+			empty(t, "KDEGrid", qt.KDEGrid(a, 4, 4, 1000))
+			if s := qt.RenderASCII(a, 4, 4); s != "" {
+				t.Errorf("RenderASCII returned %q, want nothing", s)
+			}
+
+			if _, err := qt.SearchStrict(a); !errors.Is(err, quadtree.ErrInvalidAABB) {
+				t.Errorf("SearchStrict returned %v, want ErrInvalidAABB", err)
+			}
+			if _, err := qt.KNearestStrict(a, 5, nil); !errors.Is(err, quadtree.ErrInvalidAABB) {
+				t.Errorf("KNearestStrict returned %v, want ErrInvalidAABB", err)
+			}
+		})
+	}
+}
+
+func TestQueryContractExcludingNil(t *testing.T) {
+	qt := contractTree()
+	world := quadtree.WorldBounds()
+	want := len(qt.Search(world))
+
+	for name, e := range map[string]*quadtree.AABB{
+		"nil box":    nil,
+		"nil center": quadtree.NewAABB(nil, quadtree.NewPoint(1, 1, nil)),
+	} {
+		if n := len(qt.Search(world, quadtree.Excluding(e))); n != want {
+			t.Errorf("Search excluding %s returned %d points, want %d", name, n, want)
+		}
+		n := 0
+		for range qt.SearchSeq(world, quadtree.Excluding(e, nil)) {
+			n++
+		}
+		if n != want {
+			t.Errorf("SearchSeq excluding %s yielded %d points, want %d", name, n, want)
+		}
+	}
+}
+
+func TestQueryContractK(t *testing.T) {
+	qt := contractTree()
+	a := quadtree.WorldBounds()
+	center := quadtree.NewPoint(0, 0, nil)
+
+	for _, k := range []int{0, -1} {
+		empty(t, "KNearest", qt.KNearest(a, k, nil))
+		empty(t, "KNearestValues", qt.KNearestValues(a, k, nil))
+		empty(t, "KNearestResults", qt.KNearestResults(a, k, nil))
+		empty(t, "KNearestAppend", qt.KNearestAppend(nil, a, k, nil))
+		empty(t, "NearestN", qt.NearestN(center, k))
+		empty(t, "KFarthest", qt.KFarthest(center, k))
+		empty(t, "KNearestWithin", qt.KNearestWithin(center, k, 1e6, nil))
+		empty(t, "Freeze().KNearest", qt.Freeze().KNearest(a, k, nil))
+	}
+}
+
+func TestQueryContractPoints(t *testing.T) {
+	qt := contractTree()
+
+	empty(t, "NearestN", qt.NearestN(nil, 5))
+	empty(t, "KFarthest", qt.KFarthest(nil, 5))
+	empty(t, "KNearestWithin", qt.KNearestWithin(nil, 5, 1e6, nil))
+	empty(t, "WithinRadius", qt.WithinRadius(nil, 1e6, nil))
+	empty(t, "PathTo", qt.PathTo(nil))
+	empty(t, "SearchPolygon", qt.SearchPolygon(nil, nil))
+	empty(t, "SearchPolygon with a nil vertex", qt.SearchPolygon([]*quadtree.Point{
+		quadtree.NewPoint(0, 0, nil), nil, quadtree.NewPoint(1, 1, nil),
+	}, nil))
+	empty(t, "SearchAlong", qt.SearchAlong(nil, 1000))
+
+	if qt.Nearest(nil) != nil {
+		t.Error("Nearest of nil returned a point")
+	}
+	if v := qt.KDE(nil, 1000); v != 0 {
+		t.Errorf("KDE returned %v, want 0", v)
+	}
+	if v := qt.Interpolate(nil, 5, func(*quadtree.Point) float64 { return 1 }); !math.IsNaN(v) {
+		t.Errorf("Interpolate returned %v, want NaN", v)
+	}
+	if l := qt.LeafFor(nil); l != (quadtree.LeafView{}) {
+		t.Errorf("LeafFor returned %v, want the zero LeafView", l)
+	}
+	if qt.RadiusBox(nil, 1000) != nil {
+		t.Error("RadiusBox of nil returned a box")
+	}
+	if m := qt.AssignNearest([]*quadtree.Point{nil}); len(m) != 0 {
+		t.Errorf("AssignNearest returned %d assignments, want none", len(m))
+	}
+
+	n := qt.Len()
+	if qt.Remove(nil) {
+		t.Error("Remove of nil succeeded")
+	}
+	if qt.Update(nil, quadtree.NewPoint(0, 0, nil)) {
+		t.Error("Update of nil succeeded")
+	}
+	if qt.UpdateNear(nil, nil, 10) {
+		t.Error("UpdateNear of nil succeeded")
+	}
+	if err := qt.UpdateIfVersion(nil, nil, 0); !errors.Is(err, quadtree.ErrNilPoint) {
+		t.Errorf("UpdateIfVersion returned %v, want ErrNilPoint", err)
+	}
+	if qt.InsertWithTTL(nil, 0) {
+		t.Error("InsertWithTTL of nil succeeded")
+	}
+	if qt.Len() != n {
+		t.Errorf("tree holds %d points after nil writes, want %d", qt.Len(), n)
+	}
+}
+
+func TestQueryContractNoResults(t *testing.T) {
+	qt := contractTree()
+
+	// a box within the root in which no point lies
+	qt.RemoveWhere(quadtree.NewGeoAABB(0, 0, 100000), nil)
+	a := quadtree.NewGeoAABB(0, 0, 50000)
+
+	empty(t, "Search", qt.Search(a))
+	empty(t, "KNearest", qt.KNearest(a, 5, nil))
+	empty(t, "WithinRadius", qt.WithinRadius(a.Center(), 50000, nil))
+	empty(t, "SearchPolygon", qt.SearchPolygon([]*quadtree.Point{
+		quadtree.NewPoint(-0.1, -0.1, nil), quadtree.NewPoint(0.1, -0.1, nil), quadtree.NewPoint(0, 0.1, nil),
+	}, nil))
+	empty(t, "SearchAlong", qt.SearchAlong([]*quadtree.Point{
+		quadtree.NewPoint(-0.1, 0, nil), quadtree.NewPoint(0.1, 0, nil),
+	}, 1000))
+	empty(t, "Tile", qt.Tile(-1, 5, 5))
+	empty(t, "Tile", qt.Tile(2, 5, 5))
+	empty(t, "KDEGrid", qt.KDEGrid(a, 0, 0, 1000))
+	empty(t, "Density", qt.Density(a, 0, 0))
+
+	empty(t, "empty tree Search", quadtree.New(quadtree.WorldBounds(), 0, nil).Search(quadtree.WorldBounds()))
+	empty(t, "empty tree KNearest", quadtree.New(quadtree.WorldBounds(), 0, nil).KNearest(quadtree.WorldBounds(), 5, nil))
+}
+
+func TestQueryContractConcurrent(t *testing.T) {
+	ct := quadtree.NewConcurrent(quadtree.WorldBounds(), 2)
+	for _, p := range testdata.Uniform(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 1000) {
+		ct.Insert(p)
+	}
+
+	empty(t, "Search", ct.Search(nil))
+	empty(t, "KNearest", ct.KNearest(nil, 5, nil))
+	empty(t, "KNearest", ct.KNearest(quadtree.WorldBounds(), 0, nil))
+
+	if ct.Insert(nil) || ct.Remove(nil) || ct.Update(nil, nil) {
+		t.Error("write of a nil point succeeded")
+	}
+	ct.LockRegion(nil)()
+}
+
+func TestDistinctBy(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	points := testdata.Clustered(r, quadtree.WorldBounds(), 2000, 8, 5)
+
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil, quadtree.WithCapacity(4))
+	for _, p := range points {
+		qt.Insert(p)
+	}
+
+	key := func(p *quadtree.Point) string {
+		return fmt.Sprint(p.Data().(int) % 150)
+	}
+	cost := func(p *quadtree.Point, d float64) float64 {
+		return d * float64(1+p.Data().(int)%3)
+	}
+
+	// distinct keeps the first of each key of the points, ranked best first
+	distinct := func(ranked []*quadtree.Point, k int) []*quadtree.Point {
+		seen := make(map[string]bool)
+		var results []*quadtree.Point
+		for _, p := range ranked {
+			if !seen[key(p)] && len(results) < k {
+				seen[key(p)] = true
+				results = append(results, p)
+			}
+		}
+		return results
+	}
+
+	same := func(name string, got, want []*quadtree.Point) {
+		t.Helper()
+		if !slices.Equal(got, want) {
+			t.Errorf("%s returned %d points differing from the %d nearest of each key", name, len(got), len(want))
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		center := points[r.Intn(len(points))]
+		a := quadtree.NewAABB(center, quadtree.NewPoint(10+r.Float64()*40, 10+r.Float64()*80, nil))
+		all := quadtree.NewAABB(center, quadtree.NewPoint(360, 720, nil))
+		k := 1 + r.Intn(40)
+
+		want := distinct(testdata.KNearest(points, a, len(points), nil, nil), k)
+		same("KNearest", qt.KNearest(a, k, nil, quadtree.DistinctBy(key)), want)
+		same("KNearestParallel", qt.KNearestParallel(a, k, nil, 4, quadtree.DistinctBy(key)), want)
+
+		want = distinct(testdata.KNearest(points, all, len(points), nil, nil), k)
+		same("NearestN", qt.NearestN(center, k, quadtree.DistinctBy(key)), want)
+		same("Query", qt.Query().OrderByDistance(center).Limit(k).With(quadtree.DistinctBy(key)).Run(), want)
+
+		ranked := testdata.Search(points, a)
+		slices.SortStableFunc(ranked, func(p, q *quadtree.Point) int {
+			return cmp.Compare(cost(p, quadtree.Distance(center, p)), cost(q, quadtree.Distance(center, q)))
+		})
+		same("KNearest WithCost", qt.KNearest(a, k, nil, quadtree.WithCost(cost), quadtree.DistinctBy(key)), distinct(ranked, k))
+	}
+}
+
+func TestDistanceMatrix(t *testing.T) {
+	boundary := quadtree.NewAABB(quadtree.NewPoint(0, 0, nil), quadtree.NewPoint(100, 100, nil))
+	planar := func(p, q *quadtree.Point) float64 {
+		px, py := p.Coordinates()
+		qx, qy := q.Coordinates()
+		return math.Abs(px-qx) + math.Abs(py-qy)
+	}
+
+	trees := []struct {
+		name string
+		opts []quadtree.Option
+		dist quadtree.DistanceFunc
+	}{
+		{"default", nil, quadtree.Distance},
+		{"cartesian", []quadtree.Option{quadtree.WithCoordinateSystem(quadtree.Cartesian)}, quadtree.Cartesian.Distance},
+		{"distance", []quadtree.Option{quadtree.WithDistance(planar)}, planar},
+	}
+
+	for _, tt := range trees {
+		qt := quadtree.New(boundary, 0, nil, tt.opts...)
+		for _, p := range testdata.Uniform(rand.New(rand.NewSource(1)), boundary, 20) {
+			qt.Insert(p)
+		}
+
+		matrix, points := qt.DistanceMatrix(boundary, 0)
+		for i := range matrix {
+			for j := range matrix[i] {
+				if want := tt.dist(points[i], points[j]); i != j && matrix[i][j] != want {
+					t.Fatalf("%s: distance %d, %d is %v, want %v", tt.name, i, j, matrix[i][j], want)
+				}
+			}
+		}
+	}
+}
+
+func TestKNearestAppendAllocs(t *testing.T) {
+	qt := contractTree()
+	a := quadtree.NewAABB(quadtree.NewPoint(10, 10, nil), quadtree.NewPoint(40, 40, nil))
+
+	want := qt.KNearest(a, 10, nil)
+	if len(want) != 10 {
+		t.Fatalf("KNearest returned %d points, want 10", len(want))
+	}
+	dst := make([]*quadtree.Point, 0, 10)
+	if got := qt.KNearestAppend(dst, a, 10, nil); !slices.Equal(got, want) {
+		t.Fatalf("KNearestAppend returned %d points, want those of KNearest", len(got))
+	}
+
+	if raceEnabled {
+		t.Skip("pooled heaps are dropped at random under the race detector")
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		dst = qt.KNearestAppend(dst[:0], a, 10, nil)
+	})
+	if allocs != 0 {
+		t.Fatalf("KNearestAppend allocated %v times, want none", allocs)
+	}
+}
@@ -0,0 +1,55 @@
+package quadtree
+
+import (
+	"unsafe"
+)
+
+// pointOverhead is the fixed size of a point within a leaf.
+const pointOverhead = int64(unsafe.Sizeof(Point{})) + int64(unsafe.Sizeof((*Point)(nil)))
+
+// sizeOf returns the approximate size of a point in bytes.
+func (qt *QuadTree) sizeOf(p *Point) int64 {
+	n := p.bare()
+	if qt.state.sizer != nil {
+		n += int64(qt.state.sizer(p))
+	}
+	return n
+}
+
+// admit checks a point may be inserted, returning the point it replaces by
+// ID if any.
+func (qt *QuadTree) admit(p *Point) (*Point, error) {
+	if p == nil {
+		return nil, ErrNilPoint
+	}
+
+	old, err := qt.checkID(p)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err = qt.checkDuplicate(p, old)
+	if err != nil {
+		return nil, err
+	}
+
+	p.setSize(qt.sizeOf(p))
+
+	if limit := qt.state.maxBytes; limit > 0 {
+		used := qt.state.bytes + p.size()
+		if old != nil {
+			used -= old.size()
+		}
+		if used > limit {
+			return nil, ErrQuotaExceeded
+		}
+	}
+
+	return old, nil
+}
+
+// BytesUsed returns the approximate number of bytes used by the points in
+// the tree, measured when they were inserted.
+func (qt *QuadTree) BytesUsed() int64 {
+	return qt.state.bytes
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package quadtree_test
+
+// raceEnabled reports whether the tests are built with the race detector,
+// under which sync.Pool drops items at random.
+const raceEnabled = true
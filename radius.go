@@ -0,0 +1,43 @@
+package quadtree
+
+// Radius returns the service radius of the point in metres.
+func (p *Point) Radius() float64 {
+	return p.meta().radius
+}
+
+// SetRadius sets the service radius of the point in metres, e.g. a store's
+// delivery range. It must be set before the point is inserted.
+func (p *Point) SetRadius(m float64) {
+	p.setMeta().radius = m
+}
+
+// Covering returns the points whose service radius covers the location.
+// Each node tracks the largest radius of the points beneath it so nodes
+// further away than it are pruned.
+func (qt *QuadTree) Covering(at *Point, opts ...QueryOption) []*Point {
+	var results []*Point
+	if at != nil {
+		qt.covering(at, qt.newQuery(opts), &results)
+	}
+	return nonNil(results)
+}
+
+func (qt *QuadTree) covering(at *Point, q *query, results *[]*Point) {
+	if qt.radius <= 0 || geoMinDist(at, qt.boundary) > qt.radius {
+		return
+	}
+
+	for _, p := range qt.leaf() {
+		if p.meta().radius > 0 && q.match(p) && Distance(at, p) <= p.meta().radius {
+			*results = append(*results, p)
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.covering(at, q, results)
+	}
+}
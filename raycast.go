@@ -0,0 +1,134 @@
+package quadtree
+
+import (
+	"container/heap"
+	"math"
+)
+
+// WithTolerance sets the distance either side of the ray of Raycast within
+// which a point is hit, in the units of the coordinates. Without it only
+// points lying exactly on the ray are hit.
+func WithTolerance(d float64) QueryOption {
+	return func(q *query) {
+		q.tolerance = d
+	}
+}
+
+// Raycast returns the first point hit by a ray from the origin in the
+// direction dirX, dirY, up to maxDist along it, and the distance along the
+// ray to the point, e.g. for line of sight or picking in a simulation. A
+// point is hit if it lies within the WithTolerance distance of the ray.
+// Nodes are visited in the order the ray enters them, stopping once the
+// ray enters no node before the nearest hit. Distances are planar, in the
+// units of the coordinates, and points at the same distance are hit in the
+// order they were inserted.
+func (qt *QuadTree) Raycast(origin *Point, dirX, dirY float64, maxDist float64, opts ...QueryOption) (*Point, float64, bool) {
+	l := math.Hypot(dirX, dirY)
+	if origin == nil || l == 0 || math.IsNaN(l) || !(maxDist >= 0) {
+		return nil, 0, false
+	}
+
+	q := qt.newQuery(opts)
+	r := ray{x: origin.x, y: origin.y, dx: dirX / l, dy: dirY / l, max: maxDist, tol: math.Max(q.tolerance, 0)}
+
+	var hit *Point
+	best := math.Inf(1)
+
+	queue := &candidates{}
+	if t, ok := r.enter(qt.boundary); ok {
+		heap.Push(queue, candidate{node: qt, dist: t})
+	}
+
+	for queue.Len() > 0 {
+		c := heap.Pop(queue).(candidate)
+		if c.dist > best {
+			break
+		}
+
+		c.node.hit()
+
+		for _, p := range c.node.leaf() {
+			t, ok := r.along(p)
+			if !ok || t > best || !q.match(p) {
+				continue
+			}
+			if t == best && hit.seq < p.seq {
+				continue
+			}
+			hit, best = p, t
+		}
+
+		if c.node.nodes[0] == nil {
+			continue
+		}
+
+		for _, node := range c.node.nodes {
+			if t, ok := r.enter(node.boundary); ok && t <= best {
+				heap.Push(queue, candidate{node: node, dist: t})
+			}
+		}
+	}
+
+	if hit == nil {
+		return nil, 0, false
+	}
+	return hit, best, true
+}
+
+// ray is a ray with a unit direction, hitting points within tol of it up
+// to max along it.
+type ray struct {
+	x, y   float64
+	dx, dy float64
+	max    float64
+	tol    float64
+}
+
+// along returns the distance along the ray to the point if it is hit.
+func (r *ray) along(p *Point) (float64, bool) {
+	px, py := p.x-r.x, p.y-r.y
+	t := px*r.dx + py*r.dy
+	if t < 0 || t > r.max {
+		return 0, false
+	}
+	if math.Abs(px*r.dy-py*r.dx) > r.tol {
+		return 0, false
+	}
+	return t, true
+}
+
+// enter returns the distance along the ray at which it enters the box
+// widened by the tolerance, 0 if the origin is within it, reporting false
+// if the ray misses the box before max.
+func (r *ray) enter(a *AABB) (float64, bool) {
+	t0, t1 := 0.0, r.max
+
+	slabs := [2][3]float64{
+		{r.x, r.dx, a.half.x},
+		{r.y, r.dy, a.half.y},
+	}
+	centers := [2]float64{a.center.x, a.center.y}
+
+	for i, s := range slabs {
+		lo := centers[i] - s[2] - r.tol
+		hi := centers[i] + s[2] + r.tol
+
+		if s[1] == 0 {
+			if s[0] < lo || s[0] > hi {
+				return 0, false
+			}
+			continue
+		}
+
+		ta, tb := (lo-s[0])/s[1], (hi-s[0])/s[1]
+		if ta > tb {
+			ta, tb = tb, ta
+		}
+		t0, t1 = math.Max(t0, ta), math.Min(t1, tb)
+		if t0 > t1 {
+			return 0, false
+		}
+	}
+
+	return t0, true
+}
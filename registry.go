@@ -0,0 +1,163 @@
+package quadtree
+
+import (
+	"sort"
+)
+
+// Registry holds named trees, layers, over the same boundary and with the
+// same options, e.g. "drivers", "restaurants" and "incidents", queried
+// one at a time or across several layers at once. Like a QuadTree it is
+// not safe for concurrent use.
+type Registry struct {
+	boundary *AABB
+	opts     []Option
+	layers   map[string]*QuadTree
+}
+
+// LayerPoint is a point returned by a query across the layers of a
+// Registry, along with the name of its layer.
+type LayerPoint struct {
+	Layer string
+	Point *Point
+}
+
+// NewRegistry creates a *Registry whose layers cover the boundary and are
+// created with the options.
+func NewRegistry(boundary *AABB, opts ...Option) *Registry {
+	return &Registry{
+		boundary: boundary,
+		opts:     opts,
+		layers:   make(map[string]*QuadTree),
+	}
+}
+
+// Layer returns the tree of the layer with the name, creating it if there
+// is none.
+func (r *Registry) Layer(name string) *QuadTree {
+	qt, ok := r.layers[name]
+	if !ok {
+		qt = New(r.boundary, 0, nil, r.opts...)
+		r.layers[name] = qt
+	}
+	return qt
+}
+
+// Lookup returns the tree of the layer with the name, if there is one.
+func (r *Registry) Lookup(name string) (*QuadTree, bool) {
+	qt, ok := r.layers[name]
+	return qt, ok
+}
+
+// Drop removes the layer with the name, reporting whether there was one.
+func (r *Registry) Drop(name string) bool {
+	_, ok := r.layers[name]
+	delete(r.layers, name)
+	return ok
+}
+
+// Names returns the names of the layers in order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.layers))
+	for name := range r.layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Search returns the points of the layers within the bounding box, layer
+// by layer in the order given. Layers which do not exist are skipped, and
+// every layer is searched if layers is nil.
+func (r *Registry) Search(layers []string, a *AABB, opts ...QueryOption) []LayerPoint {
+	results := []LayerPoint{}
+
+	for _, name := range r.named(layers) {
+		for _, p := range r.layers[name].Search(a, opts...) {
+			results = append(results, LayerPoint{name, p})
+		}
+	}
+
+	return results
+}
+
+// KNearest returns the k points of the layers nearest to the center of the
+// bounding box which pass the filter, merging the nearest of each layer as
+// ordered by KNearest. Points at the same distance are ordered by the
+// order of their layers, then the order they were inserted. Layers which
+// do not exist are skipped, and every layer is searched if layers is nil.
+func (r *Registry) KNearest(layers []string, a *AABB, i int, fn filter, opts ...QueryOption) []LayerPoint {
+	q := newQuery(opts)
+
+	type ranked struct {
+		merged
+		layer int
+	}
+
+	var results []ranked
+	names := r.named(layers)
+
+	for n, name := range names {
+		qt := r.layers[name]
+
+		dist := qt.distance()
+		rank := func(p *Point) float64 {
+			return dist(p, a.center)
+		}
+		if q.cost != nil {
+			rank = func(p *Point) float64 {
+				return q.cost(p, Distance(a.center, p))
+			}
+		} else if q.rank != nil {
+			rank = func(p *Point) float64 {
+				return q.rank(Distance(a.center, p), p.Weight())
+			}
+		} else if q.local {
+			local := tangentPlane(a.center)
+			rank = func(p *Point) float64 {
+				return local(p, a.center)
+			}
+		}
+
+		for _, p := range qt.KNearest(a, i, fn, opts...) {
+			results = append(results, ranked{merged{point: p, rank: rank(p), seq: p.seq}, n})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].rank != results[j].rank {
+			return results[i].rank < results[j].rank
+		}
+		if results[i].layer != results[j].layer {
+			return results[i].layer < results[j].layer
+		}
+		return results[i].seq < results[j].seq
+	})
+
+	if len(results) > i {
+		results = results[:max(i, 0)]
+	}
+
+	points := make([]LayerPoint, len(results))
+	for n, m := range results {
+		points[n] = LayerPoint{names[m.layer], m.point}
+	}
+	return points
+}
+
+// named returns the layers which exist, each once, or every layer if
+// layers is nil.
+func (r *Registry) named(layers []string) []string {
+	if layers == nil {
+		return r.Names()
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range layers {
+		if _, ok := r.layers[name]; ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,114 @@
+package quadtree
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// QueryRecord is a recorded query, one JSON object per line of the log.
+type QueryRecord struct {
+	Op string `json:"op"`
+	// Box is the query box as center x, center y, half x, half y
+	Box [4]float64 `json:"box"`
+	K   int        `json:"k,omitempty"`
+	// Duration in nanoseconds
+	Duration int64 `json:"ns"`
+	// Time in unix nanoseconds
+	Time int64 `json:"t"`
+}
+
+type recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// WithQueryRecorder records every Search and KNearest query, with its box,
+// k and duration, to w as newline delimited JSON for use with Replay.
+func WithQueryRecorder(w io.Writer) Option {
+	return func(s *state) {
+		if w == nil {
+			s.recorder = nil
+			return
+		}
+		s.recorder = &recorder{enc: json.NewEncoder(w)}
+	}
+}
+
+func (r *recorder) record(op string, a *AABB, k int, d time.Duration, t time.Time) {
+	if r == nil {
+		return
+	}
+
+	rec := QueryRecord{
+		Op:       op,
+		Box:      [4]float64{a.center.x, a.center.y, a.half.x, a.half.y},
+		K:        k,
+		Duration: int64(d),
+		Time:     t.UnixNano(),
+	}
+
+	r.mu.Lock()
+	r.enc.Encode(rec)
+	r.mu.Unlock()
+}
+
+// ReplayStats compares the recorded and replayed durations of the queries
+// of one type.
+type ReplayStats struct {
+	Queries  int
+	Recorded time.Duration
+	Replayed time.Duration
+}
+
+// Replay re-executes the queries in a log written by WithQueryRecorder
+// against the tree, returning the statistics for each type of query. It is
+// used to benchmark alternative configurations against a real query mix.
+func Replay(log io.Reader, qt *QuadTree) (map[string]*ReplayStats, error) {
+	stats := make(map[string]*ReplayStats)
+
+	sc := bufio.NewScanner(log)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+
+		var rec QueryRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return stats, err
+		}
+
+		a := NewAABB(NewPoint(rec.Box[0], rec.Box[1], nil), NewPoint(rec.Box[2], rec.Box[3], nil))
+
+		t := time.Now()
+		switch rec.Op {
+		case "search":
+			qt.Search(a)
+		case "knearest":
+			qt.KNearest(a, rec.K, nil)
+		case "nearest":
+			qt.NearestN(a.center, rec.K)
+		case "count":
+			qt.Count(a)
+		case "query":
+			qt.Query().Within(a).Limit(rec.K).Run()
+		default:
+			return stats, errors.New("quadtree: unknown query " + rec.Op)
+		}
+		d := time.Since(t)
+
+		s, ok := stats[rec.Op]
+		if !ok {
+			s = &ReplayStats{}
+			stats[rec.Op] = s
+		}
+		s.Queries++
+		s.Recorded += time.Duration(rec.Duration)
+		s.Replayed += d
+	}
+
+	return stats, sc.Err()
+}
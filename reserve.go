@@ -0,0 +1,46 @@
+package quadtree
+
+// Reserve prepares the tree for n points spread uniformly over its
+// boundary. Leaves are subdivided down to the depth at which n points fit
+// within the capacity of a leaf, or the maximum depth, and their point slices pre-allocated, so
+// loading the points avoids repeated divides and slice growth.
+func (qt *QuadTree) Reserve(n int) {
+	if n <= 0 {
+		return
+	}
+
+	depth := qt.depth
+	leaves := 1
+	capacity, maxDepth := qt.state.capacity, qt.state.maxDepth
+	for depth < maxDepth && leaves*capacity < n {
+		depth++
+		leaves *= 4
+	}
+
+	per := (n + leaves - 1) / leaves
+	if depth < maxDepth && per > capacity {
+		per = capacity
+	}
+
+	qt.reserve(depth, per)
+}
+
+func (qt *QuadTree) reserve(depth, per int) {
+	if qt.nodes[0] == nil {
+		if qt.depth < depth {
+			qt.divide()
+		} else {
+			qt.loadLeaf()
+			if cap(qt.resident) < per {
+				points := make([]*Point, len(qt.resident), per)
+				copy(points, qt.resident)
+				qt.resident = points
+			}
+			return
+		}
+	}
+
+	for i := range qt.nodes {
+		qt.own(i).reserve(depth, per)
+	}
+}
@@ -0,0 +1,81 @@
+package quadtree
+
+// Resolver loads the data of points held outside the tree, e.g. rows of a
+// SQL table or values in Redis, so the tree holds only the ID and
+// coordinates of each point rather than a second copy of its data.
+type Resolver interface {
+	// Resolve returns the data of the points with the IDs in the order
+	// given, nil for an ID without data.
+	Resolve(ids []string) ([]interface{}, error)
+}
+
+// ResolverFunc adapts a function to a Resolver.
+type ResolverFunc func(ids []string) ([]interface{}, error)
+
+// Resolve calls f.
+func (f ResolverFunc) Resolve(ids []string) ([]interface{}, error) {
+	return f(ids)
+}
+
+// WithResolver loads the data of points by their ID through the resolver
+// when queried results are passed to Resolve. Points are inserted by
+// NewPointID without data, which the tree never loads into the points.
+func WithResolver(r Resolver) Option {
+	return func(s *state) {
+		s.resolver = r
+	}
+}
+
+// Resolve returns the data of the points in order, e.g. of the results of
+// a query, loading the data of every point with an ID in one call to the
+// resolver of the tree, each ID once. Points without an ID, or of a tree
+// created without WithResolver, give the data they hold. The error of the
+// resolver is returned as is, or ErrResolverMismatch if it returns the
+// wrong number of values.
+func (qt *QuadTree) Resolve(points []*Point) ([]interface{}, error) {
+	values := make([]interface{}, len(points))
+
+	r := qt.state.resolver
+	if r == nil {
+		for i, p := range points {
+			if p != nil {
+				values[i] = p.data
+			}
+		}
+		return values, nil
+	}
+
+	var ids []string
+	index := make(map[string]int)
+	for _, p := range points {
+		if p == nil || p.id == "" {
+			continue
+		}
+		if _, ok := index[p.id]; !ok {
+			index[p.id] = len(ids)
+			ids = append(ids, p.id)
+		}
+	}
+
+	var data []interface{}
+	if len(ids) > 0 {
+		var err error
+		if data, err = r.Resolve(ids); err != nil {
+			return nil, err
+		}
+		if len(data) != len(ids) {
+			return nil, ErrResolverMismatch
+		}
+	}
+
+	for i, p := range points {
+		switch {
+		case p == nil:
+		case p.id == "":
+			values[i] = p.data
+		default:
+			values[i] = data[index[p.id]]
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,243 @@
+package quadtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// blockReader reads the blocks of a snapshot in turn.
+type blockReader interface {
+	next() ([]byte, error)
+	// end returns an error unless every block has been read
+	end() error
+}
+
+// ReadFrom replaces the contents of the tree with a snapshot written by
+// MarshalBinary, read from r until EOF, returning the number of bytes
+// read. As each block of the snapshot is read it is decompressed, decoded
+// and built into a subtree by one of GOMAXPROCS workers, so restoring
+// large snapshots is neither bound to one core nor needs the whole
+// snapshot in memory. The codec and sizer of the tree are called
+// concurrently so must be safe for concurrent use. Options are kept and
+// errors returned as for UnmarshalBinary.
+func (qt *QuadTree) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: bufio.NewReader(r)}
+
+	var magic [4]byte
+	if _, err := io.ReadFull(cr, magic[:]); err != nil {
+		return cr.n, truncated(err)
+	}
+	if magic != snapshotMagic {
+		return cr.n, ErrInvalidSnapshot
+	}
+
+	version, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return cr.n, truncated(err)
+	}
+	if version != snapshotVersion {
+		return cr.n, ErrSnapshotVersion
+	}
+
+	flags, err := cr.ReadByte()
+	if err != nil {
+		return cr.n, truncated(err)
+	}
+	return cr.n, qt.restoreBlocks(flags, streamBlocks{cr})
+}
+
+// restoreBlocks restores the tree from the blocks of a snapshot, building
+// the subtrees of the blocks following the top block in parallel.
+func (qt *QuadTree) restoreBlocks(flags byte, br blockReader) error {
+	if flags&^(snapshotCompressed|snapshotDictionary) != 0 {
+		return ErrInvalidSnapshot
+	}
+
+	return qt.restore(func(s *state) (*QuadTree, error) {
+		var c Compressor
+		if flags&snapshotCompressed != 0 {
+			c = s.snapshotCompressor()
+		}
+
+		base := snapshotReader{refs: flags&snapshotDictionary != 0}
+		block := func(b []byte) (*snapshotReader, error) {
+			if c != nil {
+				var err error
+				if b, err = c.Decompress(b); err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+				}
+			}
+			r := base
+			r.b = b
+			return &r, nil
+		}
+		next := func() (*snapshotReader, error) {
+			b, err := br.next()
+			if err != nil {
+				return nil, err
+			}
+			return block(b)
+		}
+
+		if base.refs {
+			r, err := next()
+			if err != nil {
+				return nil, err
+			}
+			base.dict = r.dictionary()
+			if err := r.done(); err != nil {
+				return nil, err
+			}
+		}
+
+		r, err := next()
+		if err != nil {
+			return nil, err
+		}
+		var root *snapshotNode
+		var slots []**snapshotNode
+		r.top(&root, &slots)
+		if err := r.done(); err != nil {
+			return nil, err
+		}
+
+		built := make([]*QuadTree, len(slots))
+		totals := make([]loaded, len(slots))
+		errs := make([]error, len(slots))
+		var failed atomic.Bool
+
+		// build decodes the block of the i'th deferred subtree
+		build := func(i int, b []byte) {
+			r, err := block(b)
+			if err == nil {
+				n := r.node()
+				if err = r.done(); err == nil {
+					node := &QuadTree{state: s}
+					err = node.load(n, s.dataCodec(), &totals[i])
+					built[i] = node
+				}
+			}
+			if err != nil {
+				errs[i] = err
+				failed.Store(true)
+			}
+		}
+
+		type job struct {
+			i int
+			b []byte
+		}
+		workers := runtime.GOMAXPROCS(0)
+		jobs := make(chan job, workers)
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range jobs {
+					build(j.i, j.b)
+				}
+			}()
+		}
+
+		// read the blocks in turn, stopping once one fails to build
+		for i := 0; i < len(slots) && err == nil && !failed.Load(); i++ {
+			var b []byte
+			if b, err = br.next(); err == nil {
+				jobs <- job{i, b}
+			}
+		}
+		close(jobs)
+		wg.Wait()
+
+		if err == nil {
+			err = errors.Join(errs...)
+		}
+		if err == nil {
+			err = br.end()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i, slot := range slots {
+			*slot = &snapshotNode{built: built[i]}
+			s.addLoaded(&totals[i])
+		}
+		return loadRoot(root, s)
+	})
+}
+
+// streamBlocks reads the blocks of a snapshot from a stream.
+type streamBlocks struct {
+	r *countingReader
+}
+
+func (s streamBlocks) next() ([]byte, error) {
+	n, err := binary.ReadUvarint(s.r)
+	if err != nil {
+		return nil, truncated(err)
+	}
+	if n > math.MaxInt64 {
+		return nil, ErrInvalidSnapshot
+	}
+
+	// read no more than is there, rather than trusting the length
+	b, err := io.ReadAll(io.LimitReader(s.r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(b)) != n {
+		return nil, ErrInvalidSnapshot
+	}
+	return b, nil
+}
+
+func (s streamBlocks) end() error {
+	_, err := s.r.ReadByte()
+	switch {
+	case err == io.EOF:
+		return nil
+	case err == nil:
+		return ErrInvalidSnapshot
+	default:
+		return err
+	}
+}
+
+// truncated returns ErrInvalidSnapshot for a snapshot cut short, or else
+// the error reading it.
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrInvalidSnapshot
+	}
+	return err
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
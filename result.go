@@ -0,0 +1,44 @@
+package quadtree
+
+// Result is a point returned by a query, with a stable JSON shape for
+// APIs serving query results.
+type Result struct {
+	ID  string  `json:"id,omitempty"`
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+	// Distance is the great-circle distance in metres from the point the
+	// query was measured from, omitted when there is none
+	Distance float64     `json:"distance,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+// Results converts points to results, measuring their distance from the
+// point from unless it is nil.
+func Results(points []*Point, from *Point) []Result {
+	results := make([]Result, len(points))
+	for i, p := range points {
+		results[i] = Result{ID: p.id, Lat: p.x, Lng: p.y, Data: p.data}
+		if from != nil {
+			results[i].Distance = Distance(from, p)
+		}
+	}
+	return results
+}
+
+// SearchResults is Search returning results measured from the center of
+// the bounding box.
+func (qt *QuadTree) SearchResults(a *AABB, opts ...QueryOption) []Result {
+	if noBox(a) {
+		return []Result{}
+	}
+	return Results(qt.Search(a, opts...), a.center)
+}
+
+// KNearestResults is KNearest returning results measured from the center
+// of the bounding box.
+func (qt *QuadTree) KNearestResults(a *AABB, i int, fn filter, opts ...QueryOption) []Result {
+	if noBox(a) {
+		return []Result{}
+	}
+	return Results(qt.KNearest(a, i, fn, opts...), a.center)
+}
@@ -0,0 +1,107 @@
+package quadtree
+
+import (
+	"math"
+	"sort"
+)
+
+// Aggregator reduces the points of a region to a single value.
+type Aggregator interface {
+	// Value returns the value of a single point
+	Value(p *Point) float64
+	// Merge combines the values of two sets of points
+	Merge(a, b float64) float64
+}
+
+// RegionValue is the aggregated value of the points within a node.
+type RegionValue struct {
+	Boundary *AABB
+	Depth    int
+	Count    int
+	Value    float64
+}
+
+type aggregator struct {
+	value func(*Point) float64
+	merge func(a, b float64) float64
+}
+
+func (a aggregator) Value(p *Point) float64     { return a.value(p) }
+func (a aggregator) Merge(x, y float64) float64 { return a.merge(x, y) }
+
+func sum(a, b float64) float64 { return a + b }
+
+// CountPoints aggregates the number of points in each region.
+var CountPoints Aggregator = aggregator{func(*Point) float64 { return 1 }, sum}
+
+// SumOf aggregates the sum of the value of the points in each region.
+func SumOf(value func(*Point) float64) Aggregator {
+	return aggregator{value, sum}
+}
+
+// MinOf aggregates the minimum value of the points in each region.
+func MinOf(value func(*Point) float64) Aggregator {
+	return aggregator{value, math.Min}
+}
+
+// MaxOf aggregates the maximum value of the points in each region.
+func MaxOf(value func(*Point) float64) Aggregator {
+	return aggregator{value, math.Max}
+}
+
+// Rollup aggregates the points of the tree at each of the depths in a
+// single traversal, e.g. to build the levels of a zoom pyramid. Regions
+// holding no points are left out and a leaf above a depth stands for
+// itself at that depth, so each depth covers every point.
+func (qt *QuadTree) Rollup(depths []int, agg Aggregator) map[int][]RegionValue {
+	depths = append([]int(nil), depths...)
+	sort.Ints(depths)
+
+	results := make(map[int][]RegionValue, len(depths))
+	for _, d := range depths {
+		results[d] = []RegionValue{}
+	}
+
+	qt.rollup(depths, agg, results)
+	return results
+}
+
+func (qt *QuadTree) rollup(depths []int, agg Aggregator, results map[int][]RegionValue) (float64, int) {
+	var value float64
+	var count int
+
+	merge := func(v float64, n int) {
+		if n == 0 {
+			return
+		}
+		if count == 0 {
+			value = v
+		} else {
+			value = agg.Merge(value, v)
+		}
+		count += n
+	}
+
+	for _, p := range qt.leaf() {
+		merge(agg.Value(p), 1)
+	}
+
+	leaf := qt.nodes[0] == nil
+	if !leaf {
+		for _, node := range qt.nodes {
+			merge(node.rollup(depths, agg, results))
+		}
+	}
+
+	if count == 0 {
+		return value, count
+	}
+
+	for _, d := range depths {
+		if d == qt.depth || (leaf && d > qt.depth) {
+			results[d] = append(results[d], RegionValue{qt.boundary, d, count, value})
+		}
+	}
+
+	return value, count
+}
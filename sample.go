@@ -0,0 +1,94 @@
+package quadtree
+
+import (
+	"math/rand"
+)
+
+// Sample returns about n of the points within the bounding box, spread
+// like the points themselves, e.g. so a zoomed out map renders thousands
+// of markers rather than millions. The share of each node is in proportion
+// to the points beneath it, counted from the totals nodes keep rather than
+// by visiting them, so only the points of the nodes sampled are read. Points
+// are chosen at random, repeatably by a tree created using
+// WithDeterministic. Fewer points are returned when filtered out by the
+// query options.
+func (qt *QuadTree) Sample(a *AABB, n int, opts ...QueryOption) []*Point {
+	results := []*Point{}
+	if noBox(a) || n <= 0 {
+		return results
+	}
+
+	q := qt.newQuery(opts)
+	rng := qt.state.random()
+
+	boxes, ok := qt.state.wrap(a)
+	if !ok {
+		boxes = []*AABB{a}
+	}
+
+	counts := make([]int, len(boxes))
+	total := 0
+	for i, b := range boxes {
+		counts[i] = qt.countIn(b)
+		total += counts[i]
+	}
+
+	for i, b := range boxes {
+		if counts[i] > 0 {
+			qt.sample(b, q, float64(n)*float64(counts[i])/float64(total), rng, &results)
+		}
+	}
+	return results
+}
+
+// sample appends about budget points within the bounding box to results.
+func (qt *QuadTree) sample(a *AABB, q *query, budget float64, rng *rand.Rand, results *[]*Point) {
+	if qt.total == 0 || !qt.boundary.Intersect(a) {
+		return
+	}
+
+	if qt.nodes[0] == nil || float64(qt.total) <= budget {
+		var points []*Point
+		qt.visitNode(a, q, func(p *Point) bool {
+			points = append(points, p)
+			return true
+		})
+
+		// round the budget at random so small shares are kept in
+		// proportion across nodes
+		k := int(budget)
+		if rng.Float64() < budget-float64(k) {
+			k++
+		}
+		if k >= len(points) {
+			*results = append(*results, points...)
+			return
+		}
+
+		for i := 0; i < k; i++ {
+			j := i + rng.Intn(len(points)-i)
+			points[i], points[j] = points[j], points[i]
+		}
+		*results = append(*results, points[:k]...)
+		return
+	}
+
+	var counts [4]int
+	total := 0
+	for i, node := range qt.nodes {
+		if a.contains(node.boundary) {
+			counts[i] = node.total
+		} else {
+			counts[i] = node.countIn(a)
+		}
+		total += counts[i]
+	}
+
+	qt.hit()
+
+	for i, node := range qt.nodes {
+		if counts[i] > 0 {
+			node.sample(a, q, budget*float64(counts[i])/float64(total), rng, results)
+		}
+	}
+}
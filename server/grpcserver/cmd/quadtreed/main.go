@@ -0,0 +1,275 @@
+// Command quadtreed serves a quadtree over HTTP, and optionally gRPC, as a
+// standalone spatial index service. Both serve the same tree, so a point
+// written over one is queried and notified over the other. Every write is made through a write-ahead log in a data
+// directory, which is compacted into a snapshot periodically and on
+// shutdown, and the tree is restored from the snapshot and log on start.
+// Metrics of the tree are served in the Prometheus text format at
+// /metrics alongside the API.
+//
+// Configuration is read from an optional JSON file given by -config, then
+// from the environment, then from flags, each overriding the last:
+//
+//	{"address": ":8080", "grpcAddress": ":9090", "dir": "quadtree.wal",
+//	 "snapshotInterval": "1m", "sync": true, "commitLatency": "10ms",
+//	 "capacity": 8, "maxDepth": 6}
+//
+//	QUADTREED_ADDRESS, QUADTREED_GRPC_ADDRESS, QUADTREED_DIR,
+//	QUADTREED_SNAPSHOT_INTERVAL, QUADTREED_SYNC, QUADTREED_COMMIT_LATENCY,
+//	QUADTREED_CAPACITY, QUADTREED_MAX_DEPTH
+//
+// The gRPC service of package grpcserver is served only given an address
+// to listen on. The daemon is part of the module of package grpcserver so
+// the quadtree module depends only on the standard library.
+//
+// A commit latency syncs the writes made within it together, rather than
+// each as it is made, each write waiting up to the latency to be synced.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/asim/quadtree"
+	"github.com/asim/quadtree/metrics"
+	"github.com/asim/quadtree/server"
+	"github.com/asim/quadtree/server/grpcserver"
+	"github.com/asim/quadtree/server/grpcserver/quadtreepb"
+	"github.com/asim/quadtree/wal"
+	"google.golang.org/grpc"
+)
+
+// config is the configuration of the daemon.
+type config struct {
+	Address string `json:"address"`
+	// address of the gRPC service, empty to serve only HTTP
+	GRPCAddress string `json:"grpcAddress"`
+	// directory of the write-ahead log and its snapshot
+	Dir              string   `json:"dir"`
+	SnapshotInterval duration `json:"snapshotInterval"`
+	// sync the log to disk after every write
+	Sync bool `json:"sync"`
+	// longest a write waits to be synced with others, 0 to sync each
+	CommitLatency duration `json:"commitLatency"`
+	Capacity      int      `json:"capacity"`
+	MaxDepth      int      `json:"maxDepth"`
+}
+
+// duration is a time.Duration read from JSON as a string such as "30s".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+func defaults() config {
+	return config{
+		Address:          server.DefaultAddress,
+		Dir:              "quadtree.wal",
+		SnapshotInterval: duration(time.Minute),
+		Sync:             true,
+		Capacity:         quadtree.Capacity,
+		MaxDepth:         quadtree.MaxDepth,
+	}
+}
+
+// load reads the configuration file, if any, and the environment.
+func load(path string) (config, error) {
+	c := defaults()
+
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return c, err
+		}
+		if err := json.Unmarshal(b, &c); err != nil {
+			return c, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if v, ok := os.LookupEnv("QUADTREED_ADDRESS"); ok {
+		c.Address = v
+	}
+	if v, ok := os.LookupEnv("QUADTREED_GRPC_ADDRESS"); ok {
+		c.GRPCAddress = v
+	}
+	if v, ok := os.LookupEnv("QUADTREED_DIR"); ok {
+		c.Dir = v
+	}
+	for name, dst := range map[string]*duration{
+		"QUADTREED_SNAPSHOT_INTERVAL": &c.SnapshotInterval,
+		"QUADTREED_COMMIT_LATENCY":    &c.CommitLatency,
+	} {
+		if v, ok := os.LookupEnv(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return c, fmt.Errorf("%s: %w", name, err)
+			}
+			*dst = duration(d)
+		}
+	}
+	if v, ok := os.LookupEnv("QUADTREED_SYNC"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return c, fmt.Errorf("QUADTREED_SYNC: %w", err)
+		}
+		c.Sync = b
+	}
+	for name, dst := range map[string]*int{
+		"QUADTREED_CAPACITY":  &c.Capacity,
+		"QUADTREED_MAX_DEPTH": &c.MaxDepth,
+	} {
+		if v, ok := os.LookupEnv(name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return c, fmt.Errorf("%s: %w", name, err)
+			}
+			*dst = n
+		}
+	}
+
+	return c, nil
+}
+
+func main() {
+	path := flag.String("config", "", "JSON configuration file")
+	addr := flag.String("address", "", "address to listen on")
+	grpcAddr := flag.String("grpc-address", "", "address to serve gRPC on, empty to use the configured address")
+	dir := flag.String("dir", "", "data directory, empty to use the configured directory")
+	interval := flag.Duration("snapshot-interval", 0, "interval between snapshots, 0 to use the configured interval")
+	flag.Parse()
+
+	c, err := load(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *addr != "" {
+		c.Address = *addr
+	}
+	if *grpcAddr != "" {
+		c.GRPCAddress = *grpcAddr
+	}
+	if *dir != "" {
+		c.Dir = *dir
+	}
+	if *interval > 0 {
+		c.SnapshotInterval = duration(*interval)
+	}
+
+	collector := metrics.New("")
+	tree := quadtree.New(quadtree.WorldBounds(), 0, nil,
+		quadtree.WithCapacity(c.Capacity),
+		quadtree.WithMaxDepth(c.MaxDepth),
+		quadtree.WithUniqueIDs(quadtree.IDReplace),
+		quadtree.WithCodec(server.Codec),
+		quadtree.WithMetrics(collector),
+	)
+
+	wl, err := wal.Open(c.Dir, tree, wal.WithCodec(server.Codec), wal.WithSync(c.Sync),
+		wal.WithGroupCommit(time.Duration(c.CommitLatency)))
+	if err != nil {
+		log.Fatalf("opening %s: %v", c.Dir, err)
+	}
+	log.Printf("restored %d points from %s", tree.Len(), c.Dir)
+
+	srv := server.New(tree, server.WithAddress(c.Address), server.WithLog(wl))
+	srv.Handle("GET /metrics", collector.Handler(tree, srv.RLocker()))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if c.SnapshotInterval <= 0 {
+			return
+		}
+		t := time.NewTicker(time.Duration(c.SnapshotInterval))
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := wl.Compact(); err != nil {
+					log.Printf("snapshot: %v", err)
+				}
+			}
+		}
+	}()
+
+	errc := make(chan error, 2)
+	go func() {
+		log.Printf("listening on %s", c.Address)
+		errc <- srv.ListenAndServe()
+	}()
+
+	// the gRPC service writes through the same server, so through the
+	// same log
+	var gs *grpc.Server
+	gsrv := grpcserver.New(srv)
+	if c.GRPCAddress != "" {
+		lis, err := net.Listen("tcp", c.GRPCAddress)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gs = grpc.NewServer()
+		quadtreepb.RegisterQuadTreeServer(gs, gsrv)
+		go func() {
+			log.Printf("serving gRPC on %s", c.GRPCAddress)
+			errc <- gs.Serve(lis)
+		}()
+	}
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			log.Fatal(err)
+		}
+	case <-ctx.Done():
+	}
+
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdown); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+	if gs != nil {
+		gsrv.Close()
+		stopped := make(chan struct{})
+		go func() {
+			gs.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdown.Done():
+			gs.Stop()
+		}
+	}
+
+	// every write is already in the log, so compacting only saves
+	// replaying it on the next start
+	if err := wl.Compact(); err != nil {
+		log.Printf("snapshot: %v", err)
+	}
+	if err := wl.Close(); err != nil {
+		log.Fatalf("closing %s: %v", c.Dir, err)
+	}
+	log.Printf("saved %d points to %s", tree.Len(), c.Dir)
+}
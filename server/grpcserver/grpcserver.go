@@ -0,0 +1,242 @@
+// Package grpcserver serves a server.Server over gRPC, implementing the
+// QuadTree service of quadtree.proto alongside the JSON API. Writes go
+// through the server, so they are applied, logged and notified to
+// subscribers as those of the JSON API are.
+//
+//	srv := server.New(tree)
+//	gs := grpc.NewServer()
+//	quadtreepb.RegisterQuadTreeServer(gs, grpcserver.New(srv))
+//
+// It is a module of its own so the quadtree module depends only on the
+// standard library. Command quadtreed, in this module, serves both over a
+// tree written through a write-ahead log.
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/asim/quadtree"
+	"github.com/asim/quadtree/server"
+	"github.com/asim/quadtree/server/grpcserver/quadtreepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements quadtreepb.QuadTreeServer with a server.Server. It is
+// safe for concurrent use.
+type Server struct {
+	quadtreepb.UnimplementedQuadTreeServer
+
+	srv  *server.Server
+	done chan struct{}
+	once sync.Once
+}
+
+// New creates a *Server serving the points of srv.
+func New(srv *server.Server) *Server {
+	return &Server{
+		srv:  srv,
+		done: make(chan struct{}),
+	}
+}
+
+// Close ends the Subscribe streams being served, so that a
+// grpc.Server can stop gracefully.
+func (s *Server) Close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// Insert inserts a point, or moves the point with the same id.
+func (s *Server) Insert(ctx context.Context, in *quadtreepb.Point) (*quadtreepb.Point, error) {
+	if err := unlayered(in.GetLayer()); err != nil {
+		return nil, err
+	}
+	if _, err := s.srv.Insert(fromProto(in)); err != nil {
+		return nil, toStatus(err)
+	}
+	return in, nil
+}
+
+// Remove removes the point with the id.
+func (s *Server) Remove(ctx context.Context, in *quadtreepb.RemoveRequest) (*quadtreepb.RemoveResponse, error) {
+	if err := unlayered(in.GetLayer()); err != nil {
+		return nil, err
+	}
+	if err := s.srv.Remove(in.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &quadtreepb.RemoveResponse{}, nil
+}
+
+// Update moves the point with the id, replacing its data unless none is
+// given.
+func (s *Server) Update(ctx context.Context, in *quadtreepb.Point) (*quadtreepb.Point, error) {
+	if err := unlayered(in.GetLayer()); err != nil {
+		return nil, err
+	}
+	if err := s.srv.Update(fromProto(in)); err != nil {
+		return nil, toStatus(err)
+	}
+	return in, nil
+}
+
+// Search returns the points within a bounding box.
+func (s *Server) Search(ctx context.Context, in *quadtreepb.SearchRequest) (*quadtreepb.Results, error) {
+	if err := unlayered(in.GetLayers()...); err != nil {
+		return nil, err
+	}
+	if in.GetBbox() == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing bbox")
+	}
+
+	results, err := s.srv.Search(fromBBox(in.GetBbox()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toResults(results), nil
+}
+
+// KNearest returns the k nearest points to a location within a radius.
+func (s *Server) KNearest(ctx context.Context, in *quadtreepb.KNearestRequest) (*quadtreepb.Results, error) {
+	if err := unlayered(in.GetLayers()...); err != nil {
+		return nil, err
+	}
+	if in.GetK() < 0 || in.GetRadius() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid k or radius")
+	}
+
+	results := s.srv.KNearest(in.GetLat(), in.GetLng(), int(in.GetK()), in.GetRadius())
+	return toResults(results), nil
+}
+
+// Subscribe streams an Event for each point within the watched area, then
+// for each point which enters, moves within or leaves it, until the
+// client cancels, the server shuts down or Close is called. A subscriber
+// which falls behind is resynced as by server.Server.Subscribe.
+func (s *Server) Subscribe(in *quadtreepb.Watch, stream grpc.ServerStreamingServer[quadtreepb.Event]) error {
+	if err := unlayered(in.GetLayer()); err != nil {
+		return err
+	}
+
+	var wt server.Watch
+	switch {
+	case in.GetBbox() != nil:
+		box := fromBBox(in.GetBbox())
+		wt.BBox = &box
+	case in.GetCircle().GetRadius() > 0:
+		c := in.GetCircle()
+		wt.Lat, wt.Lng, wt.Radius = c.GetLat(), c.GetLng(), c.GetRadius()
+	default:
+		return status.Error(codes.InvalidArgument, "watch needs a bbox or a circle of positive radius")
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := s.srv.Subscribe(ctx, wt, func(ev server.Event) error {
+		return stream.Send(&quadtreepb.Event{Type: ev.Type, Point: toProto(ev.Point)})
+	})
+
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return toStatus(err)
+}
+
+// unlayered refuses requests naming a layer, as the server serves a single
+// tree.
+func unlayered(layers ...string) error {
+	for _, l := range layers {
+		if l != "" {
+			return status.Error(codes.Unimplemented, "layers are not served")
+		}
+	}
+	return nil
+}
+
+// toStatus converts an error of the server to a status with its code.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, quadtree.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, server.ErrMissingID),
+		errors.Is(err, quadtree.ErrInvalidAABB),
+		errors.Is(err, quadtree.ErrOutOfBounds):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, quadtree.ErrRateLimited):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, server.ErrNotInserted),
+		errors.Is(err, server.ErrNotMoved),
+		errors.Is(err, quadtree.ErrDuplicateID),
+		errors.Is(err, quadtree.ErrDuplicatePoint),
+		errors.Is(err, quadtree.ErrQuotaExceeded),
+		errors.Is(err, quadtree.ErrRegionLocked),
+		errors.Is(err, quadtree.ErrReadOnly):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func fromProto(p *quadtreepb.Point) server.Point {
+	var data json.RawMessage
+	if len(p.GetData()) > 0 {
+		data = json.RawMessage(p.GetData())
+	}
+	return server.Point{ID: p.GetId(), Lat: p.GetLat(), Lng: p.GetLng(), Data: data}
+}
+
+func toProto(p server.Point) *quadtreepb.Point {
+	return &quadtreepb.Point{Id: p.ID, Lat: p.Lat, Lng: p.Lng, Data: p.Data}
+}
+
+func fromBBox(b *quadtreepb.BBox) [4]float64 {
+	return [4]float64{b.GetMinLat(), b.GetMinLng(), b.GetMaxLat(), b.GetMaxLng()}
+}
+
+func toResults(results []quadtree.Result) *quadtreepb.Results {
+	out := &quadtreepb.Results{Results: make([]*quadtreepb.Result, len(results))}
+	for i, r := range results {
+		out.Results[i] = &quadtreepb.Result{
+			Point:    &quadtreepb.Point{Id: r.ID, Lat: r.Lat, Lng: r.Lng, Data: toData(r.Data)},
+			Distance: r.Distance,
+		}
+	}
+	return out
+}
+
+// toData returns point data as JSON, unchanged if written as JSON.
+func toData(data interface{}) []byte {
+	switch d := data.(type) {
+	case nil:
+		return nil
+	case json.RawMessage:
+		return d
+	default:
+		b, _ := json.Marshal(d)
+		return b
+	}
+}
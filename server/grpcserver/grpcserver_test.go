@@ -0,0 +1,196 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/asim/quadtree"
+	"github.com/asim/quadtree/server"
+	"github.com/asim/quadtree/server/grpcserver/quadtreepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serve serves a tree over an in-memory connection, returning a client of
+// it and the gRPC server.
+func serve(t *testing.T) (quadtreepb.QuadTreeClient, *Server) {
+	t.Helper()
+
+	tree := quadtree.New(quadtree.WorldBounds(), 0, nil)
+	gs := New(server.New(tree))
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	quadtreepb.RegisterQuadTreeServer(srv, gs)
+	go srv.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		gs.Close()
+		srv.GracefulStop()
+	})
+
+	return quadtreepb.NewQuadTreeClient(conn), gs
+}
+
+func code(err error) codes.Code {
+	return status.Code(err)
+}
+
+func TestWrites(t *testing.T) {
+	c, _ := serve(t)
+	ctx := context.Background()
+
+	if _, err := c.Insert(ctx, &quadtreepb.Point{Id: "a", Lat: 51.5, Lng: -0.12, Data: []byte(`{"n":1}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Insert(ctx, &quadtreepb.Point{Lat: 1, Lng: 1}); code(err) != codes.InvalidArgument {
+		t.Fatalf("insert without id: got %v, want InvalidArgument", err)
+	}
+	if _, err := c.Insert(ctx, &quadtreepb.Point{Id: "b", Lat: 100, Lng: 1}); code(err) != codes.InvalidArgument {
+		t.Fatalf("insert out of bounds: got %v, want InvalidArgument", err)
+	}
+	if _, err := c.Update(ctx, &quadtreepb.Point{Id: "b", Lat: 1, Lng: 1}); code(err) != codes.NotFound {
+		t.Fatalf("update of missing point: got %v, want NotFound", err)
+	}
+	if _, err := c.Update(ctx, &quadtreepb.Point{Id: "a", Lat: 100, Lng: 1}); code(err) != codes.InvalidArgument {
+		t.Fatalf("update out of bounds: got %v, want InvalidArgument", err)
+	}
+
+	// an update without data keeps it
+	if _, err := c.Update(ctx, &quadtreepb.Point{Id: "a", Lat: 48.85, Lng: 2.35}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.KNearest(ctx, &quadtreepb.KNearestRequest{Lat: 48.85, Lng: 2.35})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Results) != 1 {
+		t.Fatalf("got %d results, want 1", len(res.Results))
+	}
+	if p := res.Results[0].Point; p.Id != "a" || p.Lat != 48.85 || string(p.Data) != `{"n":1}` {
+		t.Fatalf("got %v, want a moved keeping its data", p)
+	}
+
+	if _, err := c.Remove(ctx, &quadtreepb.RemoveRequest{Id: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Remove(ctx, &quadtreepb.RemoveRequest{Id: "a"}); code(err) != codes.NotFound {
+		t.Fatalf("remove of missing point: got %v, want NotFound", err)
+	}
+	if _, err := c.Remove(ctx, &quadtreepb.RemoveRequest{Id: "a", Layer: "x"}); code(err) != codes.Unimplemented {
+		t.Fatalf("remove from a layer: got %v, want Unimplemented", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c, _ := serve(t)
+	ctx := context.Background()
+
+	for _, p := range []*quadtreepb.Point{
+		{Id: "london", Lat: 51.5, Lng: -0.12},
+		{Id: "paris", Lat: 48.85, Lng: 2.35},
+		{Id: "tokyo", Lat: 35.68, Lng: 139.69},
+	} {
+		if _, err := c.Insert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := c.Search(ctx, &quadtreepb.SearchRequest{Bbox: &quadtreepb.BBox{MinLat: 40, MinLng: -10, MaxLat: 60, MaxLng: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, r := range res.Results {
+		got[r.Point.Id] = true
+	}
+	if len(got) != 2 || !got["london"] || !got["paris"] {
+		t.Fatalf("got %v, want london and paris", got)
+	}
+
+	if _, err := c.Search(ctx, &quadtreepb.SearchRequest{Bbox: &quadtreepb.BBox{MinLat: 60, MaxLat: 40}}); code(err) != codes.InvalidArgument {
+		t.Fatalf("inverted bbox: got %v, want InvalidArgument", err)
+	}
+	if _, err := c.Search(ctx, &quadtreepb.SearchRequest{}); code(err) != codes.InvalidArgument {
+		t.Fatalf("missing bbox: got %v, want InvalidArgument", err)
+	}
+
+	res, err = c.KNearest(ctx, &quadtreepb.KNearestRequest{Lat: 51.5, Lng: -0.12, K: 1, Radius: 1e6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Results) != 1 || res.Results[0].Point.Id != "london" {
+		t.Fatalf("got %v, want london", res.Results)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	c, gs := serve(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := c.Insert(ctx, &quadtreepb.Point{Id: "a", Lat: 51.5, Lng: -0.12}); err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := c.Subscribe(ctx, &quadtreepb.Watch{Area: &quadtreepb.Watch_Circle{
+		Circle: &quadtreepb.Circle{Lat: 51.5, Lng: -0.12, Radius: 1000},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(typ, id string) {
+		t.Helper()
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Type != typ || ev.Point.Id != id {
+			t.Fatalf("got %s of %s, want %s of %s", ev.Type, ev.Point.Id, typ, id)
+		}
+	}
+
+	next("enter", "a")
+
+	if _, err := c.Insert(ctx, &quadtreepb.Point{Id: "b", Lat: 51.501, Lng: -0.12}); err != nil {
+		t.Fatal(err)
+	}
+	next("enter", "b")
+
+	if _, err := c.Update(ctx, &quadtreepb.Point{Id: "a", Lat: 48.85, Lng: 2.35}); err != nil {
+		t.Fatal(err)
+	}
+	next("leave", "a")
+
+	// closing ends the stream cleanly
+	gs.Close()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("got %v after close, want EOF", err)
+	}
+
+	s, err := c.Subscribe(ctx, &quadtreepb.Watch{})
+	if err == nil {
+		_, err = s.Recv()
+	}
+	if code(err) != codes.InvalidArgument {
+		t.Fatalf("watch without an area: got %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,848 @@
+// Service definition of the point store served over JSON by package
+// server, and over gRPC by package grpcserver. Messages mirror the JSON
+// types of the server: Point for server.Point, Event for server.Event and
+// Watch for server.Watch. The code of package quadtreepb is generated with
+//
+//	protoc --go_out=quadtreepb --go_opt=paths=source_relative \
+//		--go-grpc_out=quadtreepb --go-grpc_opt=paths=source_relative quadtree.proto
+//
+// Services serving the layers of a Registry, as server.Layers does, name
+// the layer of each request. Package grpcserver serves a single tree and
+// refuses requests naming a layer.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: quadtree.proto
+
+package quadtreepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Point struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Lat   float64                `protobuf:"fixed64,2,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng   float64                `protobuf:"fixed64,3,opt,name=lng,proto3" json:"lng,omitempty"`
+	// data of the point as JSON
+	Data          []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Layer         string `protobuf:"bytes,5,opt,name=layer,proto3" json:"layer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Point) Reset() {
+	*x = Point{}
+	mi := &file_quadtree_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Point) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Point) ProtoMessage() {}
+
+func (x *Point) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Point.ProtoReflect.Descriptor instead.
+func (*Point) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Point) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Point) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *Point) GetLng() float64 {
+	if x != nil {
+		return x.Lng
+	}
+	return 0
+}
+
+func (x *Point) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Point) GetLayer() string {
+	if x != nil {
+		return x.Layer
+	}
+	return ""
+}
+
+type RemoveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Layer         string                 `protobuf:"bytes,2,opt,name=layer,proto3" json:"layer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveRequest) Reset() {
+	*x = RemoveRequest{}
+	mi := &file_quadtree_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveRequest) ProtoMessage() {}
+
+func (x *RemoveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveRequest.ProtoReflect.Descriptor instead.
+func (*RemoveRequest) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RemoveRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RemoveRequest) GetLayer() string {
+	if x != nil {
+		return x.Layer
+	}
+	return ""
+}
+
+type RemoveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveResponse) Reset() {
+	*x = RemoveResponse{}
+	mi := &file_quadtree_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveResponse) ProtoMessage() {}
+
+func (x *RemoveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveResponse.ProtoReflect.Descriptor instead.
+func (*RemoveResponse) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{2}
+}
+
+// BBox is a box given by its corners.
+type BBox struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinLat        float64                `protobuf:"fixed64,1,opt,name=min_lat,json=minLat,proto3" json:"min_lat,omitempty"`
+	MinLng        float64                `protobuf:"fixed64,2,opt,name=min_lng,json=minLng,proto3" json:"min_lng,omitempty"`
+	MaxLat        float64                `protobuf:"fixed64,3,opt,name=max_lat,json=maxLat,proto3" json:"max_lat,omitempty"`
+	MaxLng        float64                `protobuf:"fixed64,4,opt,name=max_lng,json=maxLng,proto3" json:"max_lng,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BBox) Reset() {
+	*x = BBox{}
+	mi := &file_quadtree_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BBox) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BBox) ProtoMessage() {}
+
+func (x *BBox) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BBox.ProtoReflect.Descriptor instead.
+func (*BBox) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BBox) GetMinLat() float64 {
+	if x != nil {
+		return x.MinLat
+	}
+	return 0
+}
+
+func (x *BBox) GetMinLng() float64 {
+	if x != nil {
+		return x.MinLng
+	}
+	return 0
+}
+
+func (x *BBox) GetMaxLat() float64 {
+	if x != nil {
+		return x.MaxLat
+	}
+	return 0
+}
+
+func (x *BBox) GetMaxLng() float64 {
+	if x != nil {
+		return x.MaxLng
+	}
+	return 0
+}
+
+type SearchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Bbox  *BBox                  `protobuf:"bytes,1,opt,name=bbox,proto3" json:"bbox,omitempty"`
+	// layers searched, every layer if empty
+	Layers        []string `protobuf:"bytes,2,rep,name=layers,proto3" json:"layers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_quadtree_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SearchRequest) GetBbox() *BBox {
+	if x != nil {
+		return x.Bbox
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetLayers() []string {
+	if x != nil {
+		return x.Layers
+	}
+	return nil
+}
+
+type KNearestRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Lat   float64                `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng   float64                `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	// number of points, 10 if zero
+	K int32 `protobuf:"varint,3,opt,name=k,proto3" json:"k,omitempty"`
+	// search radius in metres, 10000 if zero
+	Radius float64 `protobuf:"fixed64,4,opt,name=radius,proto3" json:"radius,omitempty"`
+	// layers searched, every layer if empty
+	Layers        []string `protobuf:"bytes,5,rep,name=layers,proto3" json:"layers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KNearestRequest) Reset() {
+	*x = KNearestRequest{}
+	mi := &file_quadtree_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KNearestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KNearestRequest) ProtoMessage() {}
+
+func (x *KNearestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KNearestRequest.ProtoReflect.Descriptor instead.
+func (*KNearestRequest) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *KNearestRequest) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *KNearestRequest) GetLng() float64 {
+	if x != nil {
+		return x.Lng
+	}
+	return 0
+}
+
+func (x *KNearestRequest) GetK() int32 {
+	if x != nil {
+		return x.K
+	}
+	return 0
+}
+
+func (x *KNearestRequest) GetRadius() float64 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+func (x *KNearestRequest) GetLayers() []string {
+	if x != nil {
+		return x.Layers
+	}
+	return nil
+}
+
+// Result is a point along with its distance in metres from the center of
+// the query.
+type Result struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Point         *Point                 `protobuf:"bytes,1,opt,name=point,proto3" json:"point,omitempty"`
+	Distance      float64                `protobuf:"fixed64,2,opt,name=distance,proto3" json:"distance,omitempty"`
+	Layer         string                 `protobuf:"bytes,3,opt,name=layer,proto3" json:"layer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Result) Reset() {
+	*x = Result{}
+	mi := &file_quadtree_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Result) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result) ProtoMessage() {}
+
+func (x *Result) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result.ProtoReflect.Descriptor instead.
+func (*Result) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Result) GetPoint() *Point {
+	if x != nil {
+		return x.Point
+	}
+	return nil
+}
+
+func (x *Result) GetDistance() float64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+func (x *Result) GetLayer() string {
+	if x != nil {
+		return x.Layer
+	}
+	return ""
+}
+
+type Results struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*Result              `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Results) Reset() {
+	*x = Results{}
+	mi := &file_quadtree_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Results) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Results) ProtoMessage() {}
+
+func (x *Results) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Results.ProtoReflect.Descriptor instead.
+func (*Results) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Results) GetResults() []*Result {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// Watch is the area of a subscription, either a box or a radius in metres
+// around a location.
+type Watch struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Area:
+	//
+	//	*Watch_Bbox
+	//	*Watch_Circle
+	Area          isWatch_Area `protobuf_oneof:"area"`
+	Layer         string       `protobuf:"bytes,3,opt,name=layer,proto3" json:"layer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Watch) Reset() {
+	*x = Watch{}
+	mi := &file_quadtree_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Watch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Watch) ProtoMessage() {}
+
+func (x *Watch) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Watch.ProtoReflect.Descriptor instead.
+func (*Watch) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Watch) GetArea() isWatch_Area {
+	if x != nil {
+		return x.Area
+	}
+	return nil
+}
+
+func (x *Watch) GetBbox() *BBox {
+	if x != nil {
+		if x, ok := x.Area.(*Watch_Bbox); ok {
+			return x.Bbox
+		}
+	}
+	return nil
+}
+
+func (x *Watch) GetCircle() *Circle {
+	if x != nil {
+		if x, ok := x.Area.(*Watch_Circle); ok {
+			return x.Circle
+		}
+	}
+	return nil
+}
+
+func (x *Watch) GetLayer() string {
+	if x != nil {
+		return x.Layer
+	}
+	return ""
+}
+
+type isWatch_Area interface {
+	isWatch_Area()
+}
+
+type Watch_Bbox struct {
+	Bbox *BBox `protobuf:"bytes,1,opt,name=bbox,proto3,oneof"`
+}
+
+type Watch_Circle struct {
+	Circle *Circle `protobuf:"bytes,2,opt,name=circle,proto3,oneof"`
+}
+
+func (*Watch_Bbox) isWatch_Area() {}
+
+func (*Watch_Circle) isWatch_Area() {}
+
+type Circle struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Lat           float64                `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng           float64                `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	Radius        float64                `protobuf:"fixed64,3,opt,name=radius,proto3" json:"radius,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Circle) Reset() {
+	*x = Circle{}
+	mi := &file_quadtree_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Circle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Circle) ProtoMessage() {}
+
+func (x *Circle) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Circle.ProtoReflect.Descriptor instead.
+func (*Circle) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Circle) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *Circle) GetLng() float64 {
+	if x != nil {
+		return x.Lng
+	}
+	return 0
+}
+
+func (x *Circle) GetRadius() float64 {
+	if x != nil {
+		return x.Radius
+	}
+	return 0
+}
+
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// enter, move or leave, or resync without a point when the subscriber
+	// fell behind, followed by an enter event for each point then within
+	// the area in place of the events missed
+	Type          string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Point         *Point `protobuf:"bytes,2,opt,name=point,proto3" json:"point,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_quadtree_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_quadtree_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_quadtree_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetPoint() *Point {
+	if x != nil {
+		return x.Point
+	}
+	return nil
+}
+
+var File_quadtree_proto protoreflect.FileDescriptor
+
+const file_quadtree_proto_rawDesc = "" +
+	"\n" +
+	"\x0equadtree.proto\x12\bquadtree\"e\n" +
+	"\x05Point\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03lat\x18\x02 \x01(\x01R\x03lat\x12\x10\n" +
+	"\x03lng\x18\x03 \x01(\x01R\x03lng\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12\x14\n" +
+	"\x05layer\x18\x05 \x01(\tR\x05layer\"5\n" +
+	"\rRemoveRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05layer\x18\x02 \x01(\tR\x05layer\"\x10\n" +
+	"\x0eRemoveResponse\"j\n" +
+	"\x04BBox\x12\x17\n" +
+	"\amin_lat\x18\x01 \x01(\x01R\x06minLat\x12\x17\n" +
+	"\amin_lng\x18\x02 \x01(\x01R\x06minLng\x12\x17\n" +
+	"\amax_lat\x18\x03 \x01(\x01R\x06maxLat\x12\x17\n" +
+	"\amax_lng\x18\x04 \x01(\x01R\x06maxLng\"K\n" +
+	"\rSearchRequest\x12\"\n" +
+	"\x04bbox\x18\x01 \x01(\v2\x0e.quadtree.BBoxR\x04bbox\x12\x16\n" +
+	"\x06layers\x18\x02 \x03(\tR\x06layers\"s\n" +
+	"\x0fKNearestRequest\x12\x10\n" +
+	"\x03lat\x18\x01 \x01(\x01R\x03lat\x12\x10\n" +
+	"\x03lng\x18\x02 \x01(\x01R\x03lng\x12\f\n" +
+	"\x01k\x18\x03 \x01(\x05R\x01k\x12\x16\n" +
+	"\x06radius\x18\x04 \x01(\x01R\x06radius\x12\x16\n" +
+	"\x06layers\x18\x05 \x03(\tR\x06layers\"a\n" +
+	"\x06Result\x12%\n" +
+	"\x05point\x18\x01 \x01(\v2\x0f.quadtree.PointR\x05point\x12\x1a\n" +
+	"\bdistance\x18\x02 \x01(\x01R\bdistance\x12\x14\n" +
+	"\x05layer\x18\x03 \x01(\tR\x05layer\"5\n" +
+	"\aResults\x12*\n" +
+	"\aresults\x18\x01 \x03(\v2\x10.quadtree.ResultR\aresults\"w\n" +
+	"\x05Watch\x12$\n" +
+	"\x04bbox\x18\x01 \x01(\v2\x0e.quadtree.BBoxH\x00R\x04bbox\x12*\n" +
+	"\x06circle\x18\x02 \x01(\v2\x10.quadtree.CircleH\x00R\x06circle\x12\x14\n" +
+	"\x05layer\x18\x03 \x01(\tR\x05layerB\x06\n" +
+	"\x04area\"D\n" +
+	"\x06Circle\x12\x10\n" +
+	"\x03lat\x18\x01 \x01(\x01R\x03lat\x12\x10\n" +
+	"\x03lng\x18\x02 \x01(\x01R\x03lng\x12\x16\n" +
+	"\x06radius\x18\x03 \x01(\x01R\x06radius\"B\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12%\n" +
+	"\x05point\x18\x02 \x01(\v2\x0f.quadtree.PointR\x05point2\xc0\x02\n" +
+	"\bQuadTree\x12*\n" +
+	"\x06Insert\x12\x0f.quadtree.Point\x1a\x0f.quadtree.Point\x12;\n" +
+	"\x06Remove\x12\x17.quadtree.RemoveRequest\x1a\x18.quadtree.RemoveResponse\x12*\n" +
+	"\x06Update\x12\x0f.quadtree.Point\x1a\x0f.quadtree.Point\x124\n" +
+	"\x06Search\x12\x17.quadtree.SearchRequest\x1a\x11.quadtree.Results\x128\n" +
+	"\bKNearest\x12\x19.quadtree.KNearestRequest\x1a\x11.quadtree.Results\x12/\n" +
+	"\tSubscribe\x12\x0f.quadtree.Watch\x1a\x0f.quadtree.Event0\x01B7Z5github.com/asim/quadtree/server/grpcserver/quadtreepbb\x06proto3"
+
+var (
+	file_quadtree_proto_rawDescOnce sync.Once
+	file_quadtree_proto_rawDescData []byte
+)
+
+func file_quadtree_proto_rawDescGZIP() []byte {
+	file_quadtree_proto_rawDescOnce.Do(func() {
+		file_quadtree_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_quadtree_proto_rawDesc), len(file_quadtree_proto_rawDesc)))
+	})
+	return file_quadtree_proto_rawDescData
+}
+
+var file_quadtree_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_quadtree_proto_goTypes = []any{
+	(*Point)(nil),           // 0: quadtree.Point
+	(*RemoveRequest)(nil),   // 1: quadtree.RemoveRequest
+	(*RemoveResponse)(nil),  // 2: quadtree.RemoveResponse
+	(*BBox)(nil),            // 3: quadtree.BBox
+	(*SearchRequest)(nil),   // 4: quadtree.SearchRequest
+	(*KNearestRequest)(nil), // 5: quadtree.KNearestRequest
+	(*Result)(nil),          // 6: quadtree.Result
+	(*Results)(nil),         // 7: quadtree.Results
+	(*Watch)(nil),           // 8: quadtree.Watch
+	(*Circle)(nil),          // 9: quadtree.Circle
+	(*Event)(nil),           // 10: quadtree.Event
+}
+var file_quadtree_proto_depIdxs = []int32{
+	3,  // 0: quadtree.SearchRequest.bbox:type_name -> quadtree.BBox
+	0,  // 1: quadtree.Result.point:type_name -> quadtree.Point
+	6,  // 2: quadtree.Results.results:type_name -> quadtree.Result
+	3,  // 3: quadtree.Watch.bbox:type_name -> quadtree.BBox
+	9,  // 4: quadtree.Watch.circle:type_name -> quadtree.Circle
+	0,  // 5: quadtree.Event.point:type_name -> quadtree.Point
+	0,  // 6: quadtree.QuadTree.Insert:input_type -> quadtree.Point
+	1,  // 7: quadtree.QuadTree.Remove:input_type -> quadtree.RemoveRequest
+	0,  // 8: quadtree.QuadTree.Update:input_type -> quadtree.Point
+	4,  // 9: quadtree.QuadTree.Search:input_type -> quadtree.SearchRequest
+	5,  // 10: quadtree.QuadTree.KNearest:input_type -> quadtree.KNearestRequest
+	8,  // 11: quadtree.QuadTree.Subscribe:input_type -> quadtree.Watch
+	0,  // 12: quadtree.QuadTree.Insert:output_type -> quadtree.Point
+	2,  // 13: quadtree.QuadTree.Remove:output_type -> quadtree.RemoveResponse
+	0,  // 14: quadtree.QuadTree.Update:output_type -> quadtree.Point
+	7,  // 15: quadtree.QuadTree.Search:output_type -> quadtree.Results
+	7,  // 16: quadtree.QuadTree.KNearest:output_type -> quadtree.Results
+	10, // 17: quadtree.QuadTree.Subscribe:output_type -> quadtree.Event
+	12, // [12:18] is the sub-list for method output_type
+	6,  // [6:12] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_quadtree_proto_init() }
+func file_quadtree_proto_init() {
+	if File_quadtree_proto != nil {
+		return
+	}
+	file_quadtree_proto_msgTypes[8].OneofWrappers = []any{
+		(*Watch_Bbox)(nil),
+		(*Watch_Circle)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_quadtree_proto_rawDesc), len(file_quadtree_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_quadtree_proto_goTypes,
+		DependencyIndexes: file_quadtree_proto_depIdxs,
+		MessageInfos:      file_quadtree_proto_msgTypes,
+	}.Build()
+	File_quadtree_proto = out.File
+	file_quadtree_proto_goTypes = nil
+	file_quadtree_proto_depIdxs = nil
+}
@@ -0,0 +1,341 @@
+// Service definition of the point store served over JSON by package
+// server, and over gRPC by package grpcserver. Messages mirror the JSON
+// types of the server: Point for server.Point, Event for server.Event and
+// Watch for server.Watch. The code of package quadtreepb is generated with
+//
+//	protoc --go_out=quadtreepb --go_opt=paths=source_relative \
+//		--go-grpc_out=quadtreepb --go-grpc_opt=paths=source_relative quadtree.proto
+//
+// Services serving the layers of a Registry, as server.Layers does, name
+// the layer of each request. Package grpcserver serves a single tree and
+// refuses requests naming a layer.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: quadtree.proto
+
+package quadtreepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	QuadTree_Insert_FullMethodName    = "/quadtree.QuadTree/Insert"
+	QuadTree_Remove_FullMethodName    = "/quadtree.QuadTree/Remove"
+	QuadTree_Update_FullMethodName    = "/quadtree.QuadTree/Update"
+	QuadTree_Search_FullMethodName    = "/quadtree.QuadTree/Search"
+	QuadTree_KNearest_FullMethodName  = "/quadtree.QuadTree/KNearest"
+	QuadTree_Subscribe_FullMethodName = "/quadtree.QuadTree/Subscribe"
+)
+
+// QuadTreeClient is the client API for QuadTree service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type QuadTreeClient interface {
+	// Insert inserts a point, or moves the point with the same id.
+	Insert(ctx context.Context, in *Point, opts ...grpc.CallOption) (*Point, error)
+	// Remove removes the point with the id.
+	Remove(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*RemoveResponse, error)
+	// Update moves the point with the id, failing if there is none.
+	Update(ctx context.Context, in *Point, opts ...grpc.CallOption) (*Point, error)
+	// Search returns the points within a bounding box.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*Results, error)
+	// KNearest returns the k nearest points to a location within a radius.
+	KNearest(ctx context.Context, in *KNearestRequest, opts ...grpc.CallOption) (*Results, error)
+	// Subscribe streams an Event for each point within the watched area,
+	// then for each point which enters, moves within or leaves it.
+	Subscribe(ctx context.Context, in *Watch, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type quadTreeClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQuadTreeClient(cc grpc.ClientConnInterface) QuadTreeClient {
+	return &quadTreeClient{cc}
+}
+
+func (c *quadTreeClient) Insert(ctx context.Context, in *Point, opts ...grpc.CallOption) (*Point, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Point)
+	err := c.cc.Invoke(ctx, QuadTree_Insert_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quadTreeClient) Remove(ctx context.Context, in *RemoveRequest, opts ...grpc.CallOption) (*RemoveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveResponse)
+	err := c.cc.Invoke(ctx, QuadTree_Remove_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quadTreeClient) Update(ctx context.Context, in *Point, opts ...grpc.CallOption) (*Point, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Point)
+	err := c.cc.Invoke(ctx, QuadTree_Update_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quadTreeClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*Results, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Results)
+	err := c.cc.Invoke(ctx, QuadTree_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quadTreeClient) KNearest(ctx context.Context, in *KNearestRequest, opts ...grpc.CallOption) (*Results, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Results)
+	err := c.cc.Invoke(ctx, QuadTree_KNearest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *quadTreeClient) Subscribe(ctx context.Context, in *Watch, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &QuadTree_ServiceDesc.Streams[0], QuadTree_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Watch, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type QuadTree_SubscribeClient = grpc.ServerStreamingClient[Event]
+
+// QuadTreeServer is the server API for QuadTree service.
+// All implementations must embed UnimplementedQuadTreeServer
+// for forward compatibility.
+type QuadTreeServer interface {
+	// Insert inserts a point, or moves the point with the same id.
+	Insert(context.Context, *Point) (*Point, error)
+	// Remove removes the point with the id.
+	Remove(context.Context, *RemoveRequest) (*RemoveResponse, error)
+	// Update moves the point with the id, failing if there is none.
+	Update(context.Context, *Point) (*Point, error)
+	// Search returns the points within a bounding box.
+	Search(context.Context, *SearchRequest) (*Results, error)
+	// KNearest returns the k nearest points to a location within a radius.
+	KNearest(context.Context, *KNearestRequest) (*Results, error)
+	// Subscribe streams an Event for each point within the watched area,
+	// then for each point which enters, moves within or leaves it.
+	Subscribe(*Watch, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedQuadTreeServer()
+}
+
+// UnimplementedQuadTreeServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedQuadTreeServer struct{}
+
+func (UnimplementedQuadTreeServer) Insert(context.Context, *Point) (*Point, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Insert not implemented")
+}
+func (UnimplementedQuadTreeServer) Remove(context.Context, *RemoveRequest) (*RemoveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Remove not implemented")
+}
+func (UnimplementedQuadTreeServer) Update(context.Context, *Point) (*Point, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedQuadTreeServer) Search(context.Context, *SearchRequest) (*Results, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedQuadTreeServer) KNearest(context.Context, *KNearestRequest) (*Results, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KNearest not implemented")
+}
+func (UnimplementedQuadTreeServer) Subscribe(*Watch, grpc.ServerStreamingServer[Event]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedQuadTreeServer) mustEmbedUnimplementedQuadTreeServer() {}
+func (UnimplementedQuadTreeServer) testEmbeddedByValue()                  {}
+
+// UnsafeQuadTreeServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to QuadTreeServer will
+// result in compilation errors.
+type UnsafeQuadTreeServer interface {
+	mustEmbedUnimplementedQuadTreeServer()
+}
+
+func RegisterQuadTreeServer(s grpc.ServiceRegistrar, srv QuadTreeServer) {
+	// If the following call pancis, it indicates UnimplementedQuadTreeServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&QuadTree_ServiceDesc, srv)
+}
+
+func _QuadTree_Insert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Point)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuadTreeServer).Insert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuadTree_Insert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuadTreeServer).Insert(ctx, req.(*Point))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuadTree_Remove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuadTreeServer).Remove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuadTree_Remove_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuadTreeServer).Remove(ctx, req.(*RemoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuadTree_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Point)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuadTreeServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuadTree_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuadTreeServer).Update(ctx, req.(*Point))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuadTree_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuadTreeServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuadTree_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuadTreeServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuadTree_KNearest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KNearestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuadTreeServer).KNearest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: QuadTree_KNearest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuadTreeServer).KNearest(ctx, req.(*KNearestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QuadTree_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Watch)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QuadTreeServer).Subscribe(m, &grpc.GenericServerStream[Watch, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type QuadTree_SubscribeServer = grpc.ServerStreamingServer[Event]
+
+// QuadTree_ServiceDesc is the grpc.ServiceDesc for QuadTree service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var QuadTree_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "quadtree.QuadTree",
+	HandlerType: (*QuadTreeServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Insert",
+			Handler:    _QuadTree_Insert_Handler,
+		},
+		{
+			MethodName: "Remove",
+			Handler:    _QuadTree_Remove_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _QuadTree_Update_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _QuadTree_Search_Handler,
+		},
+		{
+			MethodName: "KNearest",
+			Handler:    _QuadTree_KNearest_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _QuadTree_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "quadtree.proto",
+}
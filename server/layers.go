@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/asim/quadtree"
+)
+
+// Layers serves the layers of a quadtree.Registry, each with the API of a
+// Server under /layers/{layer}, along with queries across layers.
+//
+//	GET  /layers                    list the names of the layers
+//	POST /layers/{layer}/points     insert into a layer, creating it if there is none
+//	GET  /layers/{layer}/...        any other route of a Server, for one layer
+//	GET  /search?bbox=&layers=      points of the layers within a box
+//	GET  /knearest?lat=&lng=&layers=  k nearest points across the layers
+//
+// The layers queried are given as a comma separated list, every layer if
+// there is none, and results are LayerResult.
+type Layers struct {
+	mu     sync.Mutex
+	reg    *quadtree.Registry
+	opts   []Option
+	layers map[string]*Server
+
+	srv *http.Server
+	mux *http.ServeMux
+}
+
+// LayerResult is a result of a query across layers, with its layer.
+type LayerResult struct {
+	Layer string `json:"layer"`
+	quadtree.Result
+}
+
+// NewLayers creates a *Layers serving the layers of the registry, each by
+// a Server created with the options. The registry must not be modified
+// other than through the server while it is running.
+func NewLayers(reg *quadtree.Registry, opts ...Option) *Layers {
+	l := &Layers{
+		reg:    reg,
+		opts:   opts,
+		layers: make(map[string]*Server),
+		mux:    http.NewServeMux(),
+	}
+
+	for _, name := range reg.Names() {
+		l.layers[name] = New(reg.Layer(name), opts...)
+	}
+
+	l.mux.HandleFunc("GET /layers", l.list)
+	l.mux.HandleFunc("/layers/{layer}/", l.route)
+	l.mux.HandleFunc("GET /search", l.search)
+	l.mux.HandleFunc("GET /knearest", l.knearest)
+
+	cfg := &Server{addr: DefaultAddress}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	l.srv = &http.Server{Addr: cfg.addr, Handler: l.mux}
+	l.srv.RegisterOnShutdown(func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, s := range l.layers {
+			s.closeSubscriptions()
+		}
+	})
+	return l
+}
+
+// Handler returns the HTTP handler of the API, for mounting in an
+// existing server.
+func (l *Layers) Handler() http.Handler {
+	return l.mux
+}
+
+// ListenAndServe listens on the configured address and serves requests
+// until Shutdown is called, returning nil after a graceful shutdown.
+func (l *Layers) ListenAndServe() error {
+	if err := l.srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the server, waiting for active requests to
+// complete until the context is done.
+func (l *Layers) Shutdown(ctx context.Context) error {
+	return l.srv.Shutdown(ctx)
+}
+
+func (l *Layers) list(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, l.reg.Names())
+}
+
+// route serves a request for a layer by the Server of the layer, creating
+// the layer for an insert.
+func (l *Layers) route(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("layer")
+
+	l.mu.Lock()
+	s, ok := l.layers[name]
+	if !ok && r.Method == http.MethodPost {
+		s = New(l.reg.Layer(name), l.opts...)
+		l.layers[name] = s
+		ok = true
+	}
+	l.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "layer not found")
+		return
+	}
+
+	http.StripPrefix("/layers/"+name, s.Handler()).ServeHTTP(w, r)
+}
+
+func (l *Layers) search(w http.ResponseWriter, r *http.Request) {
+	bbox, err := parseBBox(r.URL.Query().Get("bbox"))
+	var box *quadtree.AABB
+	if err == nil {
+		box, err = toAABB(bbox)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	l.query(w, r, box, func(names []string) []quadtree.LayerPoint {
+		return l.reg.Search(names, box)
+	})
+}
+
+func (l *Layers) knearest(w http.ResponseWriter, r *http.Request) {
+	box, k, err := parseNearest(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	l.query(w, r, box, func(names []string) []quadtree.LayerPoint {
+		return l.reg.KNearest(names, box, k, nil)
+	})
+}
+
+// query runs a query across the layers of the request with each layer
+// read locked, writing the results measured from the center of the box.
+func (l *Layers) query(w http.ResponseWriter, r *http.Request, box *quadtree.AABB, fn func(names []string) []quadtree.LayerPoint) {
+	var names []string
+	if v := r.URL.Query().Get("layers"); v != "" {
+		names = strings.Split(v, ",")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// lock the layers in order so concurrent queries cannot deadlock
+	locked := names
+	if locked == nil {
+		locked = l.reg.Names()
+	}
+	locked = append([]string(nil), locked...)
+	sort.Strings(locked)
+
+	for i, name := range locked {
+		if i > 0 && name == locked[i-1] {
+			continue
+		}
+		if s, ok := l.layers[name]; ok {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+		}
+	}
+
+	found := fn(names)
+	results := make([]LayerResult, len(found))
+	for i, lp := range found {
+		results[i] = LayerResult{lp.Layer, quadtree.Results([]*quadtree.Point{lp.Point}, box.Center())[0]}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
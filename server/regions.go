@@ -0,0 +1,287 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/asim/quadtree"
+)
+
+// subscriberBuffer is the number of events buffered per subscriber. A
+// subscriber to a region which falls further behind is sent an overflow
+// event and disconnected, and a watch is resynced.
+const subscriberBuffer = 64
+
+// Region is the JSON representation of a named region, a union of boxes
+// each given as minLat, minLng, maxLat, maxLng.
+type Region struct {
+	Boxes [][4]float64 `json:"boxes"`
+}
+
+// Event is sent to the subscribers of a region as a point enters, moves
+// within or leaves it.
+type Event struct {
+	Type  string `json:"type"`
+	Point Point  `json:"point"`
+}
+
+type region struct {
+	def   Region
+	boxes []*quadtree.AABB
+	subs  map[chan Event]struct{}
+}
+
+func (r *region) contains(p *Point) bool {
+	if p == nil {
+		return false
+	}
+	at := quadtree.NewPoint(p.Lat, p.Lng, nil)
+	for _, b := range r.boxes {
+		if b.ContainsPoint(at) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRegion saves a named region, replacing any region of the same name
+// while keeping its subscribers.
+func (s *Server) SetRegion(name string, def Region) error {
+	if len(def.Boxes) == 0 {
+		return fmt.Errorf("region %q has no boxes", name)
+	}
+
+	boxes := make([]*quadtree.AABB, len(def.Boxes))
+	for i, c := range def.Boxes {
+		b, err := toAABB(c)
+		if err != nil {
+			return err
+		}
+		boxes[i] = b
+	}
+
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	r, ok := s.regions[name]
+	if !ok {
+		r = &region{subs: make(map[chan Event]struct{})}
+		s.regions[name] = r
+	}
+	r.def = def
+	r.boxes = boxes
+	return nil
+}
+
+// DeleteRegion deletes a named region, ending its subscriptions.
+func (s *Server) DeleteRegion(name string) bool {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	r, ok := s.regions[name]
+	if !ok {
+		return false
+	}
+	r.close()
+	delete(s.regions, name)
+	return true
+}
+
+// close ends the subscriptions of the region.
+func (r *region) close() {
+	for ch := range r.subs {
+		delete(r.subs, ch)
+		close(ch)
+	}
+}
+
+// closeSubscriptions ends every subscription, so streams don't hold up a
+// graceful shutdown.
+func (s *Server) closeSubscriptions() {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	for _, r := range s.regions {
+		r.close()
+	}
+	s.closeWatches()
+}
+
+func (s *Server) region(name string) (*region, bool) {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+	r, ok := s.regions[name]
+	return r, ok
+}
+
+// notify sends events to the subscribers of the regions and the websocket
+// clients watching areas a point was in before or after a change, either
+// of which is nil for an insert or a removal.
+func (s *Server) notify(old, new *Point) {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	for _, r := range s.regions {
+		if len(r.subs) == 0 {
+			continue
+		}
+
+		ev, ok := change(r.contains, old, new)
+		if !ok {
+			continue
+		}
+
+		for ch := range r.subs {
+			if len(ch) < subscriberBuffer {
+				ch <- ev
+				continue
+			}
+
+			// rather than miss events unknowingly, the subscriber is told
+			// to fetch the points of the region again
+			ch <- Event{Type: "overflow"}
+			delete(r.subs, ch)
+			close(ch)
+		}
+	}
+
+	s.watchEvents(old, new)
+}
+
+// change returns the event of a point changing from old to new for an
+// area, or false if the point was in it neither before nor after.
+func change(contains func(p *Point) bool, old, new *Point) (Event, bool) {
+	was, is := old != nil && contains(old), new != nil && contains(new)
+
+	switch {
+	case was && is:
+		return Event{"move", *new}, true
+	case is:
+		return Event{"enter", *new}, true
+	case was:
+		return Event{"leave", *old}, true
+	}
+	return Event{}, false
+}
+
+func (s *Server) putRegion(w http.ResponseWriter, r *http.Request) {
+	var def Region
+	if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid region: "+err.Error())
+		return
+	}
+
+	if err := s.SetRegion(r.PathValue("name"), def); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (s *Server) listRegions(w http.ResponseWriter, r *http.Request) {
+	s.rmu.Lock()
+	names := make([]string, 0, len(s.regions))
+	for name := range s.regions {
+		names = append(names, name)
+	}
+	s.rmu.Unlock()
+
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, names)
+}
+
+func (s *Server) getRegion(w http.ResponseWriter, r *http.Request) {
+	reg, ok := s.region(r.PathValue("name"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "region not found")
+		return
+	}
+
+	s.rmu.Lock()
+	def := reg.def
+	s.rmu.Unlock()
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (s *Server) deleteRegion(w http.ResponseWriter, r *http.Request) {
+	if !s.DeleteRegion(r.PathValue("name")) {
+		writeError(w, http.StatusNotFound, "region not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) regionPoints(w http.ResponseWriter, r *http.Request) {
+	reg, ok := s.region(r.PathValue("name"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "region not found")
+		return
+	}
+
+	s.rmu.Lock()
+	boxes := reg.boxes
+	s.rmu.Unlock()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, quadtree.Results(s.tree.SearchMulti(boxes), nil))
+}
+
+func (s *Server) subscribeRegion(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	// with room for the overflow event
+	ch := make(chan Event, subscriberBuffer+1)
+
+	s.rmu.Lock()
+	reg, ok := s.regions[r.PathValue("name")]
+	if ok {
+		reg.subs[ch] = struct{}{}
+	}
+	s.rmu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "region not found")
+		return
+	}
+
+	defer func() {
+		s.rmu.Lock()
+		if _, ok := reg.subs[ch]; ok {
+			delete(reg.subs, ch)
+			close(ch)
+		}
+		s.rmu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			b, _ := json.Marshal(ev.Point)
+			if ev.Type == "overflow" {
+				b = []byte("{}")
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, b)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,574 @@
+// Package server exposes a QuadTree over a JSON REST API.
+//
+//	POST   /points                  insert or move a point {"id", "lat", "lng", "data"}
+//	DELETE /points/{id}             remove a point
+//	GET    /search?bbox=            points within minLat,minLng,maxLat,maxLng
+//	GET    /knearest?lat=&lng=      k nearest points, with optional k and radius
+//	GET    /stats                   point count and node statistics of the tree
+//	GET    /tiles/{z}/{x}/{y}.json  points within a web map tile, thinned by an optional resolution
+//
+// Named regions are saved on the server and queried or subscribed to by
+// name.
+//
+//	PUT    /regions/{name}            save a region {"boxes": [[minLat, minLng, maxLat, maxLng], ...]}
+//	GET    /regions                   list the names of saved regions
+//	GET    /regions/{name}            the boxes of a region
+//	DELETE /regions/{name}            delete a region
+//	GET    /regions/{name}/points     points within a region
+//	GET    /regions/{name}/subscribe  server-sent events as points enter, move within and leave a region
+//
+// A subscriber to a region which falls behind is sent an overflow event
+// and disconnected, so it fetches the points of the region again rather
+// than miss events.
+//
+// Live queries need no saved region. A websocket client of /subscribe
+// sends a Watch, such as {"bbox": [minLat, minLng, maxLat, maxLng]} or
+// {"lat": 51.5, "lng": -0.12, "radius": 500}, and receives an Event as
+// JSON text for each point within the area, then for each point which
+// enters, moves within or leaves it. A client which falls behind is sent
+// a resync Event in place of the events it missed, followed by an enter
+// Event for each point then within the area.
+//
+// Layers serves the named trees of a quadtree.Registry, each with the
+// routes above under /layers/{layer}, and queries across layers at /search
+// and /knearest given a comma separated list of layers.
+//
+// Queries respond with a JSON array of quadtree.Result, each holding the
+// id, lat, lng and data of a point, and for /knearest and /search its
+// distance in metres from the query center.
+//
+// The methods of Server writing and querying points are those of the API,
+// shared with package grpcserver, which serves them over gRPC in a module
+// of its own.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/asim/quadtree"
+)
+
+const (
+	// DefaultAddress is the address listened on unless WithAddress is used.
+	DefaultAddress = ":8080"
+	// DefaultK is the number of points returned by /knearest without k.
+	DefaultK = 10
+	// DefaultRadius is the search radius of /knearest in metres without
+	// radius.
+	DefaultRadius = 10000.0
+)
+
+var (
+	// ErrMissingID is returned writing a point without an ID.
+	ErrMissingID = errors.New("missing id")
+	// ErrNotInserted is returned when the tree refuses a point inserted.
+	ErrNotInserted = errors.New("point could not be inserted")
+	// ErrNotMoved is returned when the tree refuses to move a point.
+	ErrNotMoved = errors.New("point could not be moved")
+)
+
+// Point is the JSON representation of a point written to the server.
+type Point struct {
+	ID   string          `json:"id"`
+	Lat  float64         `json:"lat"`
+	Lng  float64         `json:"lng"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// data returns the data of the point, nil rather than empty JSON if it has
+// none so none is logged or snapshotted.
+func (p Point) data() interface{} {
+	if p.Data == nil {
+		return nil
+	}
+	return p.Data
+}
+
+// Server serves a QuadTree over HTTP. It is safe for concurrent use.
+type Server struct {
+	mu   sync.RWMutex
+	tree *quadtree.QuadTree
+
+	addr string
+	srv  *http.Server
+	mux  *http.ServeMux
+	log  Log
+
+	rmu     sync.Mutex
+	regions map[string]*region
+	watches map[*watch]struct{}
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithAddress sets the address the server listens on.
+func WithAddress(addr string) Option {
+	return func(s *Server) {
+		s.addr = addr
+	}
+}
+
+// Log is a write-ahead log applying writes to the tree served, such as a
+// *wal.Log opened on it.
+type Log interface {
+	// Insert inserts the point, replacing any point with its ID.
+	Insert(p *quadtree.Point) error
+	// UpdateData moves the point with the ID to x, y and replaces its
+	// data.
+	UpdateData(id string, x, y float64, data interface{}) error
+	// Remove removes the point with the ID.
+	Remove(id string) error
+}
+
+// WithLog makes the writes of the server through the log, so they are
+// durable once acknowledged. The tree served must be the one the log
+// writes to. Errors of the log are returned by the server unchanged.
+func WithLog(l Log) Option {
+	return func(s *Server) {
+		s.log = l
+	}
+}
+
+// Codec encodes point data as the raw JSON written to the server. Trees
+// served which are restored from snapshots should be created using
+// quadtree.WithCodec(Codec) so point data is served unchanged.
+var Codec quadtree.Codec = rawCodec{}
+
+type rawCodec struct{}
+
+func (rawCodec) Marshal(data interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+func (rawCodec) Unmarshal(b []byte) (interface{}, error) {
+	return json.RawMessage(append([]byte(nil), b...)), nil
+}
+
+// New creates a *Server backed by the tree, serving the points it already
+// holds by their ID in the tree. The tree must not be modified other than through the
+// server while it is running.
+func New(tree *quadtree.QuadTree, opts ...Option) *Server {
+	s := &Server{
+		tree:    tree,
+		addr:    DefaultAddress,
+		mux:     http.NewServeMux(),
+		regions: make(map[string]*region),
+		watches: make(map[*watch]struct{}),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	tree.OnInsert(func(p *quadtree.Point) {
+		now := toPoint(p)
+		s.notify(nil, &now)
+	})
+	tree.OnRemove(func(p *quadtree.Point) {
+		old := toPoint(p)
+		s.notify(&old, nil)
+	})
+	tree.OnMove(func(p, from *quadtree.Point) {
+		old, now := toPoint(from), toPoint(p)
+		s.notify(&old, &now)
+	})
+
+	s.mux.HandleFunc("POST /points", s.postPoint)
+	s.mux.HandleFunc("DELETE /points/{id}", s.deletePoint)
+	s.mux.HandleFunc("GET /search", s.searchPoints)
+	s.mux.HandleFunc("GET /knearest", s.nearestPoints)
+	s.mux.HandleFunc("GET /stats", s.stats)
+	s.mux.HandleFunc("GET /tiles/{z}/{x}/{y}", s.tile)
+	s.mux.HandleFunc("PUT /regions/{name}", s.putRegion)
+	s.mux.HandleFunc("GET /regions", s.listRegions)
+	s.mux.HandleFunc("GET /regions/{name}", s.getRegion)
+	s.mux.HandleFunc("DELETE /regions/{name}", s.deleteRegion)
+	s.mux.HandleFunc("GET /regions/{name}/points", s.regionPoints)
+	s.mux.HandleFunc("GET /regions/{name}/subscribe", s.subscribeRegion)
+	s.mux.HandleFunc("GET /subscribe", s.watch)
+
+	s.srv = &http.Server{Addr: s.addr, Handler: s.mux}
+	s.srv.RegisterOnShutdown(s.closeSubscriptions)
+	return s
+}
+
+// Handler returns the HTTP handler of the API, for mounting in an
+// existing server.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Handle registers a handler alongside the API for the pattern, e.g. to
+// serve metrics of the tree.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// RLocker returns a Locker holding back writes through the server while
+// locked, for reading the tree alongside it, e.g. by the handler of a
+// metrics.Collector.
+func (s *Server) RLocker() sync.Locker {
+	return s.mu.RLocker()
+}
+
+// ListenAndServe listens on the configured address and serves requests
+// until Shutdown is called, returning nil after a graceful shutdown.
+func (s *Server) ListenAndServe() error {
+	if err := s.srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Snapshot writes a binary snapshot of the tree to w, taken while writes
+// through the server are held back.
+func (s *Server) Snapshot(w io.Writer) error {
+	s.mu.RLock()
+	b, err := s.tree.MarshalBinary()
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// Shutdown gracefully stops the server, waiting for active requests to
+// complete until the context is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// Insert inserts the point, or moves the point with its ID and replaces
+// its data, reporting whether it was inserted. It returns ErrMissingID
+// without an ID, quadtree.ErrOutOfBounds for a point outside the tree, and
+// ErrNotInserted or ErrNotMoved if the tree refuses the write, or the
+// error of the log.
+func (s *Server) Insert(in Point) (bool, error) {
+	if in.ID == "" {
+		return false, ErrMissingID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.tree.Get(in.ID); p != nil {
+		return false, s.move(p, in)
+	}
+
+	if err := s.add(quadtree.NewPointID(in.ID, in.Lat, in.Lng, in.data())); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Update moves the point with the ID of in and replaces its data, keeping
+// its data if in has none. It returns quadtree.ErrNotFound if the server
+// holds no point with the ID, and otherwise the errors of Insert.
+func (s *Server) Update(in Point) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.tree.Get(in.ID)
+	if p == nil {
+		return quadtree.ErrNotFound
+	}
+	if in.Data == nil {
+		in.Data, _ = p.Data().(json.RawMessage)
+	}
+	return s.move(p, in)
+}
+
+// Remove removes the point with the ID, returning quadtree.ErrNotFound if
+// the server holds no such point, or the error of the log.
+func (s *Server) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.tree.Get(id)
+	if p == nil {
+		return quadtree.ErrNotFound
+	}
+	return s.drop(p)
+}
+
+// Search returns the points within a box given as minLat, minLng, maxLat,
+// maxLng, returning quadtree.ErrInvalidAABB if the minimum exceeds the
+// maximum.
+func (s *Server) Search(bbox [4]float64) ([]quadtree.Result, error) {
+	box, err := toAABB(bbox)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.tree.SearchResults(box), nil
+}
+
+// KNearest returns the k points nearest lat, lng within the radius in
+// metres, nearest first. A k or radius of 0 or less is DefaultK or
+// DefaultRadius.
+func (s *Server) KNearest(lat, lng float64, k int, radius float64) []quadtree.Result {
+	if k <= 0 {
+		k = DefaultK
+	}
+	if radius <= 0 {
+		radius = DefaultRadius
+	}
+
+	center := quadtree.NewPoint(lat, lng, nil)
+	box := quadtree.NewAABB(center, center.HalfPoint(radius))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.tree.KNearestResults(box, k, nil)
+}
+
+func (s *Server) postPoint(w http.ResponseWriter, r *http.Request) {
+	var in Point
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid point: "+err.Error())
+		return
+	}
+
+	created, err := s.Insert(in)
+	switch {
+	case err != nil:
+		writeError(w, errorStatus(err), err.Error())
+	case created:
+		writeJSON(w, http.StatusCreated, in)
+	default:
+		writeJSON(w, http.StatusOK, in)
+	}
+}
+
+func (s *Server) deletePoint(w http.ResponseWriter, r *http.Request) {
+	if err := s.Remove(r.PathValue("id")); err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errorStatus returns the HTTP status of an error writing a point, 500
+// for those other than invalid or refused writes, such as failing to
+// write the log.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrMissingID):
+		return http.StatusBadRequest
+	case errors.Is(err, quadtree.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, quadtree.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, quadtree.ErrOutOfBounds),
+		errors.Is(err, quadtree.ErrDuplicateID),
+		errors.Is(err, quadtree.ErrDuplicatePoint),
+		errors.Is(err, quadtree.ErrQuotaExceeded),
+		errors.Is(err, quadtree.ErrRegionLocked),
+		errors.Is(err, quadtree.ErrReadOnly),
+		errors.Is(err, ErrNotInserted),
+		errors.Is(err, ErrNotMoved):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// add inserts the point, through the log if the server has one.
+func (s *Server) add(p *quadtree.Point) error {
+	if !s.tree.Boundary().ContainsPoint(p) {
+		return quadtree.ErrOutOfBounds
+	}
+
+	if s.log != nil {
+		return s.log.Insert(p)
+	}
+	if !s.tree.Insert(p) {
+		return ErrNotInserted
+	}
+	return nil
+}
+
+// move moves the point and replaces its data, through the log if the
+// server has one.
+func (s *Server) move(p *quadtree.Point, in Point) error {
+	if !s.tree.Boundary().ContainsPoint(quadtree.NewPoint(in.Lat, in.Lng, nil)) {
+		return quadtree.ErrOutOfBounds
+	}
+
+	if s.log != nil {
+		return s.log.UpdateData(in.ID, in.Lat, in.Lng, in.data())
+	}
+
+	// set the data first so the move is notified with it
+	p.SetData(in.data())
+	if !s.tree.MoveTo(p, in.Lat, in.Lng) {
+		return ErrNotMoved
+	}
+	return nil
+}
+
+// drop removes the point, through the log if the server has one.
+func (s *Server) drop(p *quadtree.Point) error {
+	if s.log != nil {
+		return s.log.Remove(p.ID())
+	}
+	if !s.tree.Remove(p) {
+		return quadtree.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Server) searchPoints(w http.ResponseWriter, r *http.Request) {
+	bbox, err := parseBBox(r.URL.Query().Get("bbox"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := s.Search(bbox)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (s *Server) nearestPoints(w http.ResponseWriter, r *http.Request) {
+	box, k, err := parseNearest(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, s.tree.KNearestResults(box, k, nil))
+}
+
+func (s *Server) tile(w http.ResponseWriter, r *http.Request) {
+	var c [3]int
+	for i, v := range []string{r.PathValue("z"), r.PathValue("x"), strings.TrimSuffix(r.PathValue("y"), ".json")} {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "tile not found")
+			return
+		}
+		c[i] = n
+	}
+
+	var opts []quadtree.QueryOption
+	if v := r.URL.Query().Get("resolution"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid resolution")
+			return
+		}
+		opts = append(opts, quadtree.WithTileResolution(n))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, quadtree.Results(s.tree.Tile(c[0], c[1], c[2], opts...), nil))
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, s.tree.Stats())
+}
+
+// parseNearest parses the lat, lng, k and radius of a k nearest query into
+// the query box and k.
+func parseNearest(q url.Values) (*quadtree.AABB, int, error) {
+	lat, err := strconv.ParseFloat(q.Get("lat"), 64)
+	if err != nil {
+		return nil, 0, errors.New("invalid lat")
+	}
+	lng, err := strconv.ParseFloat(q.Get("lng"), 64)
+	if err != nil {
+		return nil, 0, errors.New("invalid lng")
+	}
+
+	k := DefaultK
+	if v := q.Get("k"); v != "" {
+		if k, err = strconv.Atoi(v); err != nil || k <= 0 {
+			return nil, 0, errors.New("invalid k")
+		}
+	}
+
+	radius := DefaultRadius
+	if v := q.Get("radius"); v != "" {
+		if radius, err = strconv.ParseFloat(v, 64); err != nil || radius <= 0 {
+			return nil, 0, errors.New("invalid radius")
+		}
+	}
+
+	center := quadtree.NewPoint(lat, lng, nil)
+	return quadtree.NewAABB(center, center.HalfPoint(radius)), k, nil
+}
+
+// parseBBox parses a box given as minLat,minLng,maxLat,maxLng.
+func parseBBox(v string) ([4]float64, error) {
+	var c [4]float64
+
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return c, errors.New("bbox must be minLat,minLng,maxLat,maxLng")
+	}
+
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return c, errors.New("invalid bbox coordinate " + part)
+		}
+		c[i] = f
+	}
+
+	return c, nil
+}
+
+// toAABB converts a box given as minLat, minLng, maxLat, maxLng.
+func toAABB(c [4]float64) (*quadtree.AABB, error) {
+	if c[2] < c[0] || c[3] < c[1] {
+		return nil, quadtree.ErrInvalidAABB
+	}
+
+	return quadtree.NewAABBFromMinMax(
+		quadtree.NewPoint(c[0], c[1], nil),
+		quadtree.NewPoint(c[2], c[3], nil),
+	), nil
+}
+
+func toPoint(p *quadtree.Point) Point {
+	lat, lng := p.Coordinates()
+	data, _ := p.Data().(json.RawMessage)
+	return Point{p.ID(), lat, lng, data}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/asim/quadtree"
+)
+
+// Watch is the area watched by Subscribe or by a /subscribe client, which
+// sends it as a JSON text message: either a box given as minLat, minLng,
+// maxLat, maxLng, or a radius in metres around lat and lng. A client
+// sending another replaces it.
+type Watch struct {
+	BBox   *[4]float64 `json:"bbox,omitempty"`
+	Lat    float64     `json:"lat,omitempty"`
+	Lng    float64     `json:"lng,omitempty"`
+	Radius float64     `json:"radius,omitempty"`
+}
+
+// watch is a live query of a websocket client.
+type watch struct {
+	// the area watched, and whether a point is within it, nil until one
+	// is sent
+	area     Watch
+	contains func(p *Point) bool
+	// messages waiting to be written, guarded by the server's rmu
+	pending []interface{}
+	closed  bool
+	// the client fell behind, so events are dropped until it is resynced
+	behind bool
+	wake   chan struct{}
+}
+
+// area parses the watched area, returning the contains function and the
+// points of the tree within it.
+func (wt Watch) area(tree *quadtree.QuadTree) (func(p *Point) bool, []*quadtree.Point, error) {
+	if wt.BBox != nil {
+		box, err := toAABB(*wt.BBox)
+		if err != nil {
+			return nil, nil, err
+		}
+		contains := func(p *Point) bool {
+			return box.ContainsPoint(quadtree.NewPoint(p.Lat, p.Lng, nil))
+		}
+		return contains, tree.Search(box), nil
+	}
+
+	if wt.Radius <= 0 {
+		return nil, nil, errors.New("watch needs a bbox or a positive radius")
+	}
+
+	center := quadtree.NewPoint(wt.Lat, wt.Lng, nil)
+	contains := func(p *Point) bool {
+		return quadtree.Distance(center, quadtree.NewPoint(p.Lat, p.Lng, nil)) <= wt.Radius
+	}
+
+	var points []*quadtree.Point
+	for _, pd := range tree.WithinRadius(center, wt.Radius, nil) {
+		points = append(points, pd.Point)
+	}
+	return contains, points, nil
+}
+
+// queue adds messages for the client and wakes its writer. It must be
+// called holding rmu.
+func (w *watch) queue(msgs ...interface{}) {
+	w.pending = append(w.pending, msgs...)
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// setWatch replaces the watched area, queueing an enter event for each point
+// already within it.
+func (s *Server) setWatch(w *watch, wt Watch) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	contains, points, err := wt.area(s.tree)
+	if err != nil {
+		return err
+	}
+
+	s.rmu.Lock()
+	w.area, w.contains = wt, contains
+	w.queue(entered(points)...)
+	s.rmu.Unlock()
+	return nil
+}
+
+// entered returns an enter event for each point.
+func entered(points []*quadtree.Point) []interface{} {
+	events := make([]interface{}, len(points))
+	for i, p := range points {
+		events[i] = Event{"enter", toPoint(p)}
+	}
+	return events
+}
+
+// resync replaces the events dropped for a client which fell behind with a
+// resync event, followed by an enter event for each point within its area.
+func (s *Server) resync(w *watch) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	if !w.behind {
+		return
+	}
+	// the area was valid when set
+	_, points, _ := w.area.area(s.tree)
+
+	w.behind = false
+	w.queue(Event{Type: "resync"})
+	w.queue(entered(points)...)
+}
+
+// watchEvents sends the events of a change to the websocket clients whose
+// area the point was in before or after it, dropping those waiting for a
+// client which falls behind to resync it. It must be called holding rmu.
+func (s *Server) watchEvents(old, new *Point) {
+	for w := range s.watches {
+		if w.contains == nil || w.behind {
+			continue
+		}
+
+		ev, ok := change(w.contains, old, new)
+		if !ok {
+			continue
+		}
+		if len(w.pending) >= subscriberBuffer {
+			w.pending, w.behind = nil, true
+			w.queue()
+			continue
+		}
+		w.queue(ev)
+	}
+}
+
+// closeWatches ends every websocket subscription.
+func (s *Server) closeWatches() {
+	for w := range s.watches {
+		w.closed = true
+		w.queue()
+	}
+}
+
+// newWatch registers a live query, returning it and a function
+// unregistering it.
+func (s *Server) newWatch() (*watch, func()) {
+	w := &watch{wake: make(chan struct{}, 1)}
+
+	s.rmu.Lock()
+	s.watches[w] = struct{}{}
+	s.rmu.Unlock()
+
+	return w, func() {
+		s.rmu.Lock()
+		delete(s.watches, w)
+		s.rmu.Unlock()
+	}
+}
+
+// take returns the messages waiting to be written to the client and
+// whether the server has closed its subscription, first resyncing a client
+// which fell behind.
+func (s *Server) take(w *watch) ([]interface{}, bool) {
+	s.rmu.Lock()
+	behind := w.behind
+	s.rmu.Unlock()
+
+	if behind {
+		s.resync(w)
+	}
+
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+
+	msgs := w.pending
+	w.pending = nil
+	return msgs, w.closed
+}
+
+// Subscribe calls send with an enter Event for each point within the
+// watched area, then with an Event for each point which enters, moves
+// within or leaves it, until the context is done, send returns an error or
+// the server shuts down. It returns the error of the watch, send or
+// context, or nil once the server shuts down. A subscriber which falls
+// behind is resynced as for /subscribe.
+func (s *Server) Subscribe(ctx context.Context, wt Watch, send func(Event) error) error {
+	w, unwatch := s.newWatch()
+	defer unwatch()
+
+	if err := s.setWatch(w, wt); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.wake:
+		}
+
+		msgs, closed := s.take(w)
+		for _, m := range msgs {
+			if err := send(m.(Event)); err != nil {
+				return err
+			}
+		}
+
+		if closed {
+			return nil
+		}
+	}
+}
+
+func (s *Server) watch(rw http.ResponseWriter, r *http.Request) {
+	conn, brw, ok := upgrade(rw, r)
+	if !ok {
+		return
+	}
+	defer conn.Close()
+
+	w, unwatch := s.newWatch()
+	defer unwatch()
+
+	var wmu sync.Mutex
+	write := func(op byte, payload []byte) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		return writeFrame(brw.Writer, op, payload)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.readWatches(w, brw.Reader, write)
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-w.wake:
+		}
+
+		msgs, closed := s.take(w)
+		for _, m := range msgs {
+			b, _ := json.Marshal(m)
+			if write(opText, b) != nil {
+				return
+			}
+		}
+
+		if closed {
+			write(opClose, nil)
+			return
+		}
+	}
+}
+
+// readWatches reads the areas sent by a client until it disconnects.
+func (s *Server) readWatches(w *watch, r *bufio.Reader, write func(byte, []byte) error) {
+	for {
+		msg, err := readMessage(r, write)
+		if err != nil {
+			return
+		}
+
+		var wt Watch
+		err = json.Unmarshal(msg, &wt)
+		if err == nil {
+			err = s.setWatch(w, wt)
+		}
+		if err != nil {
+			s.rmu.Lock()
+			w.queue(map[string]string{"error": err.Error()})
+			s.rmu.Unlock()
+		}
+	}
+}
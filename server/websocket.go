@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// The subset of RFC 6455 needed to push JSON messages to a client and read
+// its small text messages: unfragmented or fragmented text frames, ping,
+// pong and close, without extensions.
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xa
+
+	// maxMessage is the largest message accepted from a client
+	maxMessage = 1 << 16
+
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+var errMessageTooLarge = errors.New("websocket: message too large")
+
+// upgrade completes the websocket handshake and takes over the
+// connection. It writes an HTTP error and returns false if the request is
+// not a websocket upgrade.
+func upgrade(w http.ResponseWriter, r *http.Request) (net.Conn, *bufio.ReadWriter, bool) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeError(w, http.StatusUpgradeRequired, "websocket upgrade required")
+		return nil, nil, false
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "websocket unsupported")
+		return nil, nil, false
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, nil, false
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, false
+	}
+
+	return conn, rw, true
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readFrame reads a frame sent by a client, unmasking its payload.
+func readFrame(r *bufio.Reader) (fin bool, op byte, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(r, h[:]); err != nil {
+		return
+	}
+
+	fin = h[0]&0x80 != 0
+	op = h[0] & 0x0f
+	masked := h[1]&0x80 != 0
+	n := uint64(h[1] & 0x7f)
+
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(r, b[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(r, b[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+
+	if n > maxMessage {
+		err = errMessageTooLarge
+		return
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, mask[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return
+}
+
+// readMessage reads the next text message, answering pings with w. It
+// returns io.EOF once the client closes the connection.
+func readMessage(r *bufio.Reader, w func(op byte, payload []byte) error) ([]byte, error) {
+	var msg []byte
+
+	for {
+		fin, op, payload, err := readFrame(r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := w(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			w(opClose, nil)
+			return nil, io.EOF
+		}
+
+		msg = append(msg, payload...)
+		if len(msg) > maxMessage {
+			return nil, errMessageTooLarge
+		}
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// writeFrame writes an unmasked frame as sent by a server.
+func writeFrame(w *bufio.Writer, op byte, payload []byte) error {
+	w.WriteByte(0x80 | op)
+
+	switch n := len(payload); {
+	case n < 126:
+		w.WriteByte(byte(n))
+	case n <= 0xffff:
+		w.WriteByte(126)
+		binary.Write(w, binary.BigEndian, uint16(n))
+	default:
+		w.WriteByte(127)
+		binary.Write(w, binary.BigEndian, uint64(n))
+	}
+
+	w.Write(payload)
+	return w.Flush()
+}
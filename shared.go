@@ -0,0 +1,257 @@
+package quadtree
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Snapshots share the nodes and points of the tree rather than copying
+// them. Each node carries the generation of the tree it was created or
+// copied in, and Snapshot moves the tree on to the next generation, so
+// every node reachable from the root then belongs to the snapshot as
+// well. Writers copy each shared node on the path to the nodes they
+// write, and each shared point before it is changed, leaving those held
+// by snapshots as they were. Nodes of the current generation are only
+// ever reached through nodes of the current generation.
+
+// lazyIndex builds the ID index of a snapshot the first time it is used.
+type lazyIndex struct {
+	once sync.Once
+	root *QuadTree
+}
+
+// index returns the ID index of the tree.
+func (s *state) index() map[string]*Point {
+	if l := s.lazy; l != nil {
+		l.once.Do(func() {
+			l.root.RebuildIndex()
+		})
+	}
+	return s.ids
+}
+
+// owned reports whether the node belongs to the current generation of the
+// tree, so may be written in place rather than being shared with a
+// snapshot.
+func (qt *QuadTree) owned() bool {
+	return qt.gen == atomic.LoadUint64(&qt.state.gen)
+}
+
+// own returns the i'th child of an owned node, first replacing it with a
+// copy if it is shared with a snapshot.
+func (qt *QuadTree) own(i int) *QuadTree {
+	node := qt.nodes[i]
+	if node.owned() {
+		return node
+	}
+
+	var c *QuadTree
+	if a := qt.state.arena; a != nil {
+		c = a.node()
+	} else {
+		c = new(QuadTree)
+	}
+	c.shallow(node)
+	c.parent = qt
+	c.gen = qt.gen
+
+	qt.nodes[i] = c
+	return c
+}
+
+// shallow sets the node to a copy of n sharing its children and points,
+// with slices of its own.
+func (qt *QuadTree) shallow(n *QuadTree) {
+	*qt = QuadTree{
+		boundary:  n.boundary,
+		depth:     n.depth,
+		released:  n.released,
+		parent:    n.parent,
+		nodes:     n.nodes,
+		state:     n.state,
+		agg:       n.agg,
+		dirty:     n.dirty,
+		codes:     slices.Clone(n.codes),
+		hits:      atomic.LoadUint64(&n.hits),
+		writes:    n.writes,
+		radius:    n.radius,
+		weight:    n.weight,
+		tags:      n.tags,
+		total:     n.total,
+		extents:   slices.Clone(n.extents),
+		nodeID:    n.nodeID,
+		hasNodeID: n.hasNodeID,
+		gen:       n.gen,
+	}
+
+	if n.resident == nil {
+		return
+	}
+	qt.resident = append(qt.inline[:0], n.resident...)
+	if len(n.resident) > inlinePoints {
+		qt.resident = slices.Clone(n.resident)
+	}
+}
+
+// unshare owns every node of the subtree, for writes which move nodes
+// within the tree.
+func (qt *QuadTree) unshare() {
+	if qt.nodes[0] == nil {
+		return
+	}
+	for i := range qt.nodes {
+		qt.own(i).unshare()
+	}
+}
+
+// claim returns the owned node at the position of n, copying the shared
+// nodes on the path to it from the root.
+func (qt *QuadTree) claim(n *QuadTree) *QuadTree {
+	if n.owned() {
+		return n
+	}
+
+	node := qt
+	c := n.boundary.center
+	for node.depth < n.depth && node.nodes[0] != nil {
+		i := 0
+		if c.x >= node.boundary.center.x {
+			i++
+		}
+		if c.y < node.boundary.center.y {
+			i += 2
+		}
+		node = node.own(i)
+	}
+	return node
+}
+
+// writable returns the point to change in place of p, first copying it,
+// and the nodes on the path to it, if a snapshot holds it.
+func (qt *QuadTree) writable(p *Point) *Point {
+	if !qt.state.shared(p) {
+		return p
+	}
+
+	root := qt.root()
+	node := root.owner(p)
+	if node == nil {
+		return p
+	}
+	return root.claim(node).forkPoint(p)
+}
+
+// forkPoint is fork for the point p of an owned leaf.
+func (qt *QuadTree) forkPoint(p *Point) *Point {
+	qt.loadLeaf()
+	for i, ep := range qt.resident {
+		if ep == p {
+			return qt.fork(i)
+		}
+	}
+	return p
+}
+
+// shared reports whether the point may be held by a snapshot, so must be
+// copied before it is changed.
+func (s *state) shared(p *Point) bool {
+	if s.fresh == nil {
+		return false
+	}
+	_, ok := s.fresh[p]
+	return !ok
+}
+
+// fork returns the i'th point of an owned leaf to be changed, first
+// replacing it with a copy if it is shared with a snapshot.
+func (qt *QuadTree) fork(i int) *Point {
+	p := qt.resident[i]
+	if !qt.state.shared(p) {
+		return p
+	}
+
+	cp := p.clone()
+	qt.resident[i] = cp
+	qt.stored(i, i+1)
+	qt.state.forked(p, cp)
+	return cp
+}
+
+// forked records the copy of a point replacing it in the tree.
+func (s *state) forked(p, cp *Point) {
+	s.fresh[cp] = struct{}{}
+	if p.id != "" && s.ids[p.id] == p {
+		s.ids[p.id] = cp
+	}
+	if s.capped != nil {
+		s.capped.replace(p, cp)
+	}
+	s.forwardTo(p, cp)
+}
+
+// forwardTo forwards writes through p, and through the point first
+// inserted of which it is a copy, to cp. The copies in between are
+// forgotten so the points forwarded are bounded by those in the tree.
+func (s *state) forwardTo(p, cp *Point) {
+	if s.forward == nil {
+		s.forward = make(map[*Point]*Point)
+		s.back = make(map[*Point][2]*Point)
+	}
+
+	e, ok := s.back[p]
+	delete(s.back, p)
+	if !ok {
+		e[0] = p
+	} else if e[1] != e[0] {
+		delete(s.forward, e[1])
+	}
+	e[1] = p
+
+	s.forward[e[0]] = cp
+	s.forward[p] = cp
+	s.back[cp] = e
+}
+
+// current returns the point the tree holds in place of p, p itself unless
+// it was copied on write since being returned.
+func (s *state) current(p *Point) *Point {
+	if cp, ok := s.forward[p]; ok {
+		return cp
+	}
+	return p
+}
+
+// reinserting returns the point to insert in place of p, a copy if p was
+// removed from the tree while still held by a snapshot.
+func (s *state) reinserting(p *Point) *Point {
+	if p == nil || p.seq == 0 || !s.shared(p) {
+		return p
+	}
+	cp := p.clone()
+	s.fresh[cp] = struct{}{}
+	return cp
+}
+
+// unshared forgets a point removed from the tree.
+func (s *state) unshared(p *Point) {
+	if s.fresh == nil {
+		return
+	}
+	delete(s.fresh, p)
+	if e, ok := s.back[p]; ok {
+		delete(s.forward, e[0])
+		delete(s.forward, e[1])
+		delete(s.back, p)
+	}
+}
+
+// detach starts a copy of the state for a tree of its own, whose nodes
+// are all owned.
+func (s *state) detach() {
+	s.gen = 0
+	s.fresh = nil
+	s.forward = nil
+	s.back = nil
+	s.lazy = nil
+}
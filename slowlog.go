@@ -0,0 +1,100 @@
+package quadtree
+
+import (
+	"sync"
+	"time"
+)
+
+// SlowQuery describes a query which took longer than the slow query
+// threshold.
+type SlowQuery struct {
+	Op       string
+	Box      *AABB
+	K        int
+	Duration time.Duration
+	Time     time.Time
+}
+
+// slowLog is a ring of the most recent slow queries.
+type slowLog struct {
+	mu        sync.Mutex
+	threshold time.Duration
+	entries   []SlowQuery
+	next      int
+	full      bool
+}
+
+// WithSlowQueryLog records the n most recent Search and KNearest queries
+// taking longer than threshold, read via SlowQueries.
+func WithSlowQueryLog(threshold time.Duration, n int) Option {
+	return func(s *state) {
+		if n <= 0 {
+			s.slow = nil
+			return
+		}
+		s.slow = &slowLog{
+			threshold: threshold,
+			entries:   make([]SlowQuery, n),
+		}
+	}
+}
+
+// begin returns the start time of a query when queries are timed.
+func (qt *QuadTree) begin() time.Time {
+	if qt.state.slow == nil && qt.state.recorder == nil && qt.state.metrics == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// end logs the query started at t if it was slow, and records it.
+func (qt *QuadTree) end(t time.Time, op string, a *AABB, k int) {
+	if t.IsZero() {
+		return
+	}
+
+	d := time.Since(t)
+	qt.state.recorder.record(op, a, k, d, t)
+	if qt.state.metrics != nil {
+		qt.state.metrics.Queried(op, d)
+	}
+
+	l := qt.state.slow
+	if l == nil || d < l.threshold {
+		return
+	}
+
+	l.mu.Lock()
+	l.entries[l.next] = SlowQuery{op, a, k, d, t}
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+	l.mu.Unlock()
+}
+
+// SlowQueries returns the most recent slow queries, newest first. The
+// tree must be created using WithSlowQueryLog.
+func (qt *QuadTree) SlowQueries() []SlowQuery {
+	var results []SlowQuery
+
+	l := qt.state.slow
+	if l == nil {
+		return results
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := l.next
+	if l.full {
+		n = len(l.entries)
+	}
+
+	for i := 1; i <= n; i++ {
+		idx := (l.next - i + len(l.entries)) % len(l.entries)
+		results = append(results, l.entries[idx])
+	}
+
+	return results
+}
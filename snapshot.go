@@ -0,0 +1,731 @@
+package quadtree
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// snapshotVersion is the version of the snapshot formats, written so
+// snapshots of a later layout are refused rather than misread.
+const snapshotVersion = 1
+
+// Flags of a binary snapshot.
+const (
+	// the blocks are compressed
+	snapshotCompressed = 1 << iota
+	// point data may refer to the dictionary block
+	snapshotDictionary
+)
+
+// snapshotBlockPoints is the most points of a subtree written to a block
+// of its own; larger subtrees are split between their children.
+const snapshotBlockPoints = 4096
+
+// snapshotMagic prefixes binary snapshots.
+var snapshotMagic = [4]byte{'Q', 'T', 'R', 'E'}
+
+// Codec encodes and decodes point data in snapshots.
+type Codec interface {
+	Marshal(data interface{}) ([]byte, error)
+	Unmarshal(b []byte) (interface{}, error)
+}
+
+// JSONCodec encodes point data as JSON, decoding it into interface{}
+// values. It is used unless WithCodec is given.
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(data interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+func (jsonCodec) Unmarshal(b []byte) (interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+// WithCodec sets the codec used for point data by MarshalBinary,
+// UnmarshalBinary, MarshalJSON and UnmarshalJSON. MarshalJSON embeds the
+// encoded data directly, so the codec must produce JSON for it.
+func WithCodec(c Codec) Option {
+	return func(s *state) {
+		s.codec = c
+	}
+}
+
+func (s *state) dataCodec() Codec {
+	if s.codec == nil {
+		return JSONCodec
+	}
+	return s.codec
+}
+
+// snapshotNode is a node of a snapshot, shared by the binary and JSON
+// formats.
+type snapshotNode struct {
+	Center   [2]float64      `json:"center"`
+	Half     [2]float64      `json:"half"`
+	Depth    int             `json:"depth"`
+	Points   []snapshotPoint `json:"points,omitempty"`
+	Children []*snapshotNode `json:"children,omitempty"`
+	// points beneath the node, to split binary snapshots into blocks
+	count int
+	// the subtree, if already built from a block of its own
+	built *QuadTree
+}
+
+type snapshotPoint struct {
+	X       float64         `json:"x"`
+	Y       float64         `json:"y"`
+	ID      string          `json:"id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Updated int64           `json:"updated"`
+	Seq     uint64          `json:"seq"`
+	Version uint64          `json:"version,omitempty"`
+	Removed bool            `json:"removed,omitempty"`
+	Tenant  string          `json:"tenant,omitempty"`
+	Radius  float64         `json:"radius,omitempty"`
+	State   State           `json:"state,omitempty"`
+	Weight  float64         `json:"weight,omitempty"`
+	TTL     int64           `json:"ttl,omitempty"`
+	Tags    []string        `json:"tags,omitempty"`
+}
+
+type snapshotJSON struct {
+	Version int           `json:"version"`
+	Root    *snapshotNode `json:"root"`
+}
+
+func (qt *QuadTree) snapshot(c Codec) (*snapshotNode, error) {
+	n := &snapshotNode{
+		Center: [2]float64{qt.boundary.center.x, qt.boundary.center.y},
+		Half:   [2]float64{qt.boundary.half.x, qt.boundary.half.y},
+		Depth:  qt.depth,
+	}
+
+	for _, p := range qt.leaf() {
+		m := p.meta()
+		sp := snapshotPoint{
+			X: p.x, Y: p.y, ID: p.id, Updated: p.updated, Seq: p.seq,
+			Version: m.version, Removed: m.removed, Tenant: m.tenant,
+			Radius: m.radius, State: m.lifecycle, Weight: m.weight,
+			TTL: int64(m.ttl), Tags: m.tags,
+		}
+		if p.data != nil {
+			b, err := c.Marshal(p.data)
+			if err != nil {
+				return nil, err
+			}
+			sp.Data = b
+		}
+		n.Points = append(n.Points, sp)
+	}
+	n.count = len(n.Points)
+
+	if qt.nodes[0] != nil {
+		for _, node := range qt.nodes {
+			c, err := node.snapshot(c)
+			if err != nil {
+				return nil, err
+			}
+			n.Children = append(n.Children, c)
+			n.count += c.count
+		}
+	}
+
+	return n, nil
+}
+
+// restore replaces the contents of the tree with the root built by build
+// from a snapshot, given the state of the new tree, keeping the options of
+// the tree. The tree is unchanged if the snapshot cannot be loaded or its
+// nodes do not form a valid tree.
+func (qt *QuadTree) restore(build func(s *state) (*QuadTree, error)) error {
+	if qt.state == nil {
+		qt.state = New(nil, 0, nil).state
+	}
+	if qt.state.readOnly {
+		return ErrReadOnly
+	}
+
+	s := *qt.state
+	s.detach()
+	s.ids = make(map[string]*Point)
+	s.tenants = make(map[string]int)
+	s.seq = 0
+	s.bytes = 0
+	s.count = 0
+
+	// the store is written once the tree is complete
+	s.store = nil
+
+	root, err := build(&s)
+	if err != nil {
+		return err
+	}
+	s.store = qt.state.store
+	root.RebuildIndex()
+	if s.capped != nil {
+		s.capped = s.capped.rebuild(root)
+	}
+
+	// a snapshot decodes whatever its nodes hold, so check they form a
+	// tree searches can rely on before replacing this one
+	if err := root.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+
+	if qt.boundary != nil {
+		qt.restock(false)
+	}
+
+	*qt = *root
+	if len(qt.resident) > 0 && &qt.resident[0] == &root.inline[0] {
+		qt.resident = qt.inline[:len(qt.resident)]
+	}
+	for _, node := range qt.nodes {
+		if node != nil {
+			node.parent = qt
+		}
+	}
+	qt.restock(true)
+	return nil
+}
+
+// loaded totals the points loaded into a subtree, added to the state of
+// the tree once it is built so that subtrees can be loaded concurrently.
+type loaded struct {
+	tenants  map[string]int
+	bytes    int64
+	count    int
+	seq      uint64
+	expiring bool
+}
+
+// addLoaded adds the totals of points loaded into the tree.
+func (s *state) addLoaded(t *loaded) {
+	for tenant, n := range t.tenants {
+		s.tenants[tenant] += n
+	}
+	s.bytes += t.bytes
+	s.count += t.count
+	s.seq = max(s.seq, t.seq)
+	s.expiring = s.expiring || t.expiring
+}
+
+// loadRoot returns the root of a tree with the state loaded from the
+// snapshot.
+func loadRoot(n *snapshotNode, s *state) (*QuadTree, error) {
+	if n != nil && n.built != nil {
+		return n.built, nil
+	}
+
+	root := &QuadTree{state: s}
+	var t loaded
+	err := root.load(n, s.dataCodec(), &t)
+	s.addLoaded(&t)
+	return root, err
+}
+
+// load loads the snapshot into the node, adding its points to t. Subtrees
+// already built are attached as they are.
+func (qt *QuadTree) load(n *snapshotNode, c Codec, t *loaded) error {
+	if n == nil || n.Half[0] <= 0 || n.Half[1] <= 0 || (len(n.Children) != 0 && len(n.Children) != 4) {
+		return ErrInvalidSnapshot
+	}
+
+	qt.boundary = &AABB{
+		&Point{x: n.Center[0], y: n.Center[1]},
+		&Point{x: n.Half[0], y: n.Half[1]},
+	}
+	qt.depth = n.Depth
+	qt.resident = qt.inline[:0]
+	qt.nodes = [4]*QuadTree{}
+	qt.codes = nil
+	qt.agg = nil
+	qt.dirty = true
+	qt.hits = 0
+	qt.radius = 0
+	qt.weight = 0
+	qt.tags = 0
+	qt.total = len(n.Points)
+
+	if t.tenants == nil {
+		t.tenants = make(map[string]int)
+	}
+
+	s := qt.state
+	for _, sp := range n.Points {
+		p := &Point{x: sp.X, y: sp.Y, id: sp.ID, updated: sp.Updated, seq: sp.Seq}
+		p.restoreMeta(pointMeta{
+			version: sp.Version, removed: sp.Removed, tenant: sp.Tenant,
+			radius: sp.Radius, lifecycle: sp.State, weight: sp.Weight,
+			ttl: time.Duration(sp.TTL),
+		})
+		for _, t := range sp.Tags {
+			p.AddTag(t)
+		}
+		if len(sp.Data) > 0 {
+			data, err := c.Unmarshal(sp.Data)
+			if err != nil {
+				return err
+			}
+			p.data = data
+		}
+
+		p.setSize(qt.sizeOf(p))
+		qt.resident = append(qt.resident, p)
+		qt.radius = math.Max(qt.radius, p.meta().radius)
+		qt.weight = math.Max(qt.weight, p.Weight())
+		qt.tags |= p.meta().tagBits
+
+		t.tenants[p.meta().tenant]++
+		t.bytes += p.size()
+		t.count++
+		t.seq = max(t.seq, p.seq)
+		t.expiring = t.expiring || p.meta().ttl > 0
+	}
+
+	if limit := s.maxScan; limit > 0 && qt.depth >= s.maxDepth && len(qt.resident) > limit {
+		qt.refine()
+	}
+
+	for i, cn := range n.Children {
+		node := &QuadTree{state: s}
+		if cn != nil && cn.built != nil {
+			node = cn.built
+		} else if err := node.load(cn, c, t); err != nil {
+			return err
+		}
+		node.parent = qt
+		qt.nodes[i] = node
+		qt.radius = math.Max(qt.radius, node.radius)
+		qt.weight = math.Max(qt.weight, node.weight)
+		qt.tags |= node.tags
+		qt.total += node.total
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the tree, its node structure and points as JSON.
+// Point data is encoded by the tree's codec. Options are not included.
+func (qt *QuadTree) MarshalJSON() ([]byte, error) {
+	root, err := qt.snapshot(qt.state.dataCodec())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(snapshotJSON{snapshotVersion, root})
+}
+
+// UnmarshalJSON replaces the contents of the tree with one encoded by
+// MarshalJSON, without reinserting the points. The options of the tree
+// are kept, so a tree created by New with the same options should be
+// used; a zero QuadTree uses the defaults. It returns ErrInvalidSnapshot
+// if the nodes of the snapshot fail Validate, e.g. holding points outside
+// their boundary.
+func (qt *QuadTree) UnmarshalJSON(b []byte) error {
+	var s snapshotJSON
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if s.Version != snapshotVersion {
+		return ErrSnapshotVersion
+	}
+	return qt.restore(func(st *state) (*QuadTree, error) {
+		return loadRoot(s.Root, st)
+	})
+}
+
+// MarshalBinary encodes the tree, its node structure and points in a
+// compact versioned binary format. Point data is encoded by the tree's
+// codec. Subtrees are written in blocks of their own, compressed by
+// WithSnapshotCompression, and WithSnapshotDictionary writes repeated
+// point data once. Options are not included.
+func (qt *QuadTree) MarshalBinary() ([]byte, error) {
+	root, err := qt.snapshot(qt.state.dataCodec())
+	if err != nil {
+		return nil, err
+	}
+
+	e := &snapshotEncoder{compressor: qt.state.compressor}
+	var dict [][]byte
+	var flags byte
+	if e.compressor != nil {
+		flags |= snapshotCompressed
+	}
+	if qt.state.dictionary {
+		flags |= snapshotDictionary
+		dict, e.index = newDictionary(root)
+	}
+
+	b := append(snapshotMagic[:0:0], snapshotMagic[:]...)
+	b = binary.AppendUvarint(b, snapshotVersion)
+	b = append(b, flags)
+
+	if e.index != nil {
+		d := binary.AppendUvarint(nil, uint64(len(dict)))
+		for _, data := range dict {
+			d = appendBytes(d, data)
+		}
+		if b, err = e.block(b, d); err != nil {
+			return nil, err
+		}
+	}
+
+	if b, err = e.block(b, e.appendTop(nil, root)); err != nil {
+		return nil, err
+	}
+	for _, n := range e.deferred {
+		if b, err = e.block(b, e.appendNode(nil, n)); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary replaces the contents of the tree with one encoded by
+// MarshalBinary, without reinserting the points. Options are kept as for
+// UnmarshalJSON, and compressed snapshots are decompressed by the
+// compressor given by WithSnapshotCompression, or else by FlateCompressor.
+// The blocks of the snapshot are decoded in parallel as by ReadFrom.
+func (qt *QuadTree) UnmarshalBinary(b []byte) error {
+	if len(b) < len(snapshotMagic) || [4]byte(b[:4]) != snapshotMagic {
+		return ErrInvalidSnapshot
+	}
+
+	r := &snapshotReader{b: b[4:]}
+	if version := r.uvarint(); r.err == nil && version != snapshotVersion {
+		return ErrSnapshotVersion
+	}
+
+	flags := r.flag()
+	if r.err != nil {
+		return r.err
+	}
+	return qt.restoreBlocks(flags, r)
+}
+
+func appendFloat(b []byte, f float64) []byte {
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+}
+
+func appendBytes(b []byte, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// snapshotEncoder writes the blocks of a binary snapshot.
+type snapshotEncoder struct {
+	compressor Compressor
+	// index of repeated data in the dictionary, nil without one
+	index map[string]uint64
+	// subtrees written to blocks of their own after the top block
+	deferred []*snapshotNode
+}
+
+// block appends a block, compressed if the snapshot is.
+func (e *snapshotEncoder) block(b, blk []byte) ([]byte, error) {
+	if e.compressor != nil {
+		var err error
+		if blk, err = e.compressor.Compress(blk); err != nil {
+			return nil, err
+		}
+	}
+	return appendBytes(b, blk), nil
+}
+
+// appendTop appends the nodes of the top block, deferring each subtree of
+// at most snapshotBlockPoints points, or without children, to a block of
+// its own.
+func (e *snapshotEncoder) appendTop(b []byte, n *snapshotNode) []byte {
+	if n.count <= snapshotBlockPoints || len(n.Children) == 0 {
+		e.deferred = append(e.deferred, n)
+		return append(b, 1)
+	}
+
+	b = append(b, 0)
+	b = e.appendPoints(b, n)
+	b = binary.AppendUvarint(b, uint64(len(n.Children)))
+	for _, c := range n.Children {
+		b = e.appendTop(b, c)
+	}
+	return b
+}
+
+// appendNode appends the subtree.
+func (e *snapshotEncoder) appendNode(b []byte, n *snapshotNode) []byte {
+	b = e.appendPoints(b, n)
+	b = binary.AppendUvarint(b, uint64(len(n.Children)))
+	for _, c := range n.Children {
+		b = e.appendNode(b, c)
+	}
+	return b
+}
+
+// appendPoints appends the boundary, depth and points of the node.
+func (e *snapshotEncoder) appendPoints(b []byte, n *snapshotNode) []byte {
+	b = appendFloat(b, n.Center[0])
+	b = appendFloat(b, n.Center[1])
+	b = appendFloat(b, n.Half[0])
+	b = appendFloat(b, n.Half[1])
+	b = binary.AppendVarint(b, int64(n.Depth))
+
+	b = binary.AppendUvarint(b, uint64(len(n.Points)))
+	for _, p := range n.Points {
+		b = appendFloat(b, p.X)
+		b = appendFloat(b, p.Y)
+		b = appendBytes(b, []byte(p.ID))
+		b = e.appendData(b, p.Data)
+		b = binary.AppendVarint(b, p.Updated)
+		b = binary.AppendUvarint(b, p.Seq)
+		b = binary.AppendUvarint(b, p.Version)
+		b = appendBytes(b, []byte(p.Tenant))
+		b = appendFloat(b, p.Radius)
+
+		flags := byte(0)
+		if p.Removed {
+			flags |= 1
+		}
+		b = append(b, flags, byte(p.State))
+		b = appendFloat(b, p.Weight)
+		b = binary.AppendVarint(b, p.TTL)
+
+		b = binary.AppendUvarint(b, uint64(len(p.Tags)))
+		for _, t := range p.Tags {
+			b = appendBytes(b, []byte(t))
+		}
+	}
+
+	return b
+}
+
+// appendData appends point data, as a reference to the dictionary if it
+// holds the data, or else inline following a reference of 0.
+func (e *snapshotEncoder) appendData(b []byte, data []byte) []byte {
+	if e.index == nil {
+		return appendBytes(b, data)
+	}
+	if i, ok := e.index[string(data)]; ok {
+		return binary.AppendUvarint(b, i+1)
+	}
+	b = binary.AppendUvarint(b, 0)
+	return appendBytes(b, data)
+}
+
+// snapshotReader decodes a binary snapshot, recording the first error.
+type snapshotReader struct {
+	b   []byte
+	err error
+	// whether point data may refer to the dictionary
+	refs bool
+	dict [][]byte
+}
+
+// next returns the next block of the snapshot.
+func (r *snapshotReader) next() ([]byte, error) {
+	b := r.bytes()
+	return b, r.err
+}
+
+// end returns ErrInvalidSnapshot unless every block has been read.
+func (r *snapshotReader) end() error {
+	if len(r.b) != 0 {
+		return ErrInvalidSnapshot
+	}
+	return nil
+}
+
+// done returns the error of reading a block, or ErrInvalidSnapshot if it
+// was not read to its end.
+func (r *snapshotReader) done() error {
+	if r.err == nil && len(r.b) != 0 {
+		r.fail()
+	}
+	return r.err
+}
+
+// dictionary reads the dictionary block.
+func (r *snapshotReader) dictionary() [][]byte {
+	count := r.uvarint()
+	if count > uint64(len(r.b)) {
+		r.fail()
+		return nil
+	}
+
+	dict := make([][]byte, 0, count)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		dict = append(dict, r.bytes())
+	}
+	return dict
+}
+
+// top reads the top block into slot, adding the slots of the subtrees
+// deferred to blocks of their own in the order the blocks follow.
+func (r *snapshotReader) top(slot **snapshotNode, slots *[]**snapshotNode) {
+	switch r.flag() {
+	case 0:
+	case 1:
+		*slots = append(*slots, slot)
+		return
+	default:
+		r.fail()
+		return
+	}
+
+	n := r.points()
+	*slot = n
+
+	n.Children = make([]*snapshotNode, r.children())
+	for i := range n.Children {
+		if r.err != nil {
+			return
+		}
+		r.top(&n.Children[i], slots)
+	}
+}
+
+func (r *snapshotReader) fail() {
+	if r.err == nil {
+		r.err = ErrInvalidSnapshot
+	}
+	r.b = nil
+}
+
+func (r *snapshotReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *snapshotReader) varint() int64 {
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *snapshotReader) float() float64 {
+	if len(r.b) < 8 {
+		r.fail()
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(r.b))
+	r.b = r.b[8:]
+	return v
+}
+
+func (r *snapshotReader) bytes() []byte {
+	n := r.uvarint()
+	if n > uint64(len(r.b)) {
+		r.fail()
+		return nil
+	}
+	v := r.b[:n:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *snapshotReader) flag() byte {
+	if len(r.b) < 1 {
+		r.fail()
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+// data reads point data, inline or from the dictionary.
+func (r *snapshotReader) data() []byte {
+	if !r.refs {
+		return r.bytes()
+	}
+
+	ref := r.uvarint()
+	if ref == 0 {
+		return r.bytes()
+	}
+	if ref > uint64(len(r.dict)) {
+		r.fail()
+		return nil
+	}
+	return r.dict[ref-1]
+}
+
+// children reads the number of children of a node, 0 or 4.
+func (r *snapshotReader) children() int {
+	children := r.uvarint()
+	if children != 0 && children != 4 {
+		r.fail()
+		return 0
+	}
+	return int(children)
+}
+
+func (r *snapshotReader) node() *snapshotNode {
+	n := r.points()
+
+	children := r.children()
+	for i := 0; i < children && r.err == nil; i++ {
+		n.Children = append(n.Children, r.node())
+	}
+
+	return n
+}
+
+// points reads the boundary, depth and points of a node.
+func (r *snapshotReader) points() *snapshotNode {
+	n := &snapshotNode{}
+	n.Center = [2]float64{r.float(), r.float()}
+	n.Half = [2]float64{r.float(), r.float()}
+	n.Depth = int(r.varint())
+
+	count := r.uvarint()
+	if count > uint64(len(r.b)) {
+		r.fail()
+		return n
+	}
+
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		p := snapshotPoint{X: r.float(), Y: r.float()}
+		p.ID = string(r.bytes())
+		if data := r.data(); len(data) > 0 {
+			p.Data = data
+		}
+		p.Updated = r.varint()
+		p.Seq = r.uvarint()
+		p.Version = r.uvarint()
+		p.Tenant = string(r.bytes())
+		p.Radius = r.float()
+		p.Removed = r.flag()&1 != 0
+		p.State = State(r.flag())
+		p.Weight = r.float()
+		p.TTL = r.varint()
+
+		tags := r.uvarint()
+		if tags > uint64(len(r.b)) {
+			r.fail()
+			break
+		}
+		for j := uint64(0); j < tags; j++ {
+			p.Tags = append(p.Tags, string(r.bytes()))
+		}
+		n.Points = append(n.Points, p)
+	}
+
+	return n
+}
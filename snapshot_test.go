@@ -0,0 +1,446 @@
+package quadtree_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/asim/quadtree"
+	"github.com/asim/quadtree/testdata"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	points := testdata.Clustered(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 2000, 8, 2)
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil, quadtree.WithCapacity(4))
+	for _, p := range points {
+		qt.Insert(p)
+	}
+
+	b, err := qt.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := quadtree.New(quadtree.WorldBounds(), 0, nil, quadtree.WithCapacity(4))
+	if err := restored.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != len(points) {
+		t.Fatalf("restored %d points, want %d", restored.Len(), len(points))
+	}
+}
+
+func TestSnapshotInvalid(t *testing.T) {
+	snapshots := map[string]string{
+		"point outside its node": `{"version":1,"root":{"center":[0,0],"half":[90,180],"depth":0,
+			"points":[{"x":120,"y":0,"seq":1}]}}`,
+		"child not a quadrant": `{"version":1,"root":{"center":[0,0],"half":[90,180],"depth":0,"children":[
+			{"center":[-45,90],"half":[45,90],"depth":1},
+			{"center":[45,90],"half":[45,90],"depth":1},
+			{"center":[-45,-90],"half":[45,90],"depth":1},
+			{"center":[10,-90],"half":[45,90],"depth":1}]}}`,
+		"child of the wrong depth": `{"version":1,"root":{"center":[0,0],"half":[90,180],"depth":0,"children":[
+			{"center":[-45,90],"half":[45,90],"depth":1},
+			{"center":[45,90],"half":[45,90],"depth":1},
+			{"center":[-45,-90],"half":[45,90],"depth":1},
+			{"center":[45,-90],"half":[45,90],"depth":3}]}}`,
+	}
+
+	for name, s := range snapshots {
+		t.Run(name, func(t *testing.T) {
+			qt := quadtree.New(quadtree.WorldBounds(), 0, nil)
+			p := quadtree.NewPoint(1, 1, nil)
+			qt.Insert(p)
+
+			err := qt.UnmarshalJSON([]byte(strings.Join(strings.Fields(s), "")))
+			if !errors.Is(err, quadtree.ErrInvalidSnapshot) || !errors.Is(err, quadtree.ErrInvalidTree) {
+				t.Fatalf("restore returned %v, want ErrInvalidSnapshot", err)
+			}
+			// the tree is left as it was
+			if qt.Len() != 1 || len(qt.Search(quadtree.WorldBounds())) != 1 {
+				t.Fatal("a failed restore changed the tree")
+			}
+		})
+	}
+}
+
+func TestSnapshotStable(t *testing.T) {
+	points := testdata.Uniform(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 500)
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil, quadtree.WithCapacity(4))
+	for _, p := range points {
+		qt.Insert(p)
+	}
+
+	snap := qt.Snapshot()
+	a := quadtree.NewAABB(quadtree.NewPoint(10, 10, nil), quadtree.NewPoint(40, 80, nil))
+	want := len(snap.Search(a))
+
+	// moving and removing every point of the tree leaves the snapshot as
+	// it was
+	for i, p := range points {
+		if i%2 == 0 {
+			qt.Update(p, quadtree.NewPoint(-80, -170, nil))
+		} else {
+			qt.Remove(p)
+		}
+	}
+
+	if got := len(snap.Search(a)); got != want || snap.Len() != len(points) {
+		t.Fatalf("snapshot holds %d points, %d within the box, want %d, %d", snap.Len(), got, len(points), want)
+	}
+	if err := snap.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Insert(quadtree.NewPoint(0, 0, nil)) {
+		t.Fatal("snapshot accepted a write")
+	}
+
+	// the tree holds copies of the points it moved, still written through
+	// the points inserted
+	if err := qt.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	corner := quadtree.NewAABB(quadtree.NewPoint(-80, -170, nil), quadtree.NewPoint(0, 0, nil))
+	if n := len(qt.Search(corner)); n != len(points)/2 || qt.Len() != n {
+		t.Fatalf("tree holds %d points, %d moved, want %d", qt.Len(), n, len(points)/2)
+	}
+	for i := 0; i < len(points); i += 2 {
+		if !qt.Contains(points[i]) || !qt.Remove(points[i]) {
+			t.Fatalf("point %d not written through its handle", i)
+		}
+	}
+	if qt.Len() != 0 || snap.Len() != len(points) {
+		t.Fatalf("tree holds %d points and snapshot %d, want 0 and %d", qt.Len(), snap.Len(), len(points))
+	}
+}
+
+func TestSnapshotAllocs(t *testing.T) {
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil)
+	for _, p := range testdata.Uniform(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 20000) {
+		qt.Insert(p)
+	}
+
+	// a snapshot shares the nodes and points of the tree
+	if n := testing.AllocsPerRun(10, func() { qt.Snapshot() }); n > 8 {
+		t.Fatalf("snapshot of %d points made %v allocations", qt.Len(), n)
+	}
+}
+
+// entry is a point as a tree or snapshot should hold it.
+type entry struct {
+	x, y float64
+	data interface{}
+}
+
+// entries returns the points held by the tree by ID.
+func entries(qt *quadtree.QuadTree) map[string]entry {
+	m := make(map[string]entry)
+	for _, p := range qt.Search(qt.Boundary(), quadtree.WithRemoved()) {
+		x, y := p.Coordinates()
+		m[p.ID()] = entry{x, y, p.Data()}
+	}
+	return m
+}
+
+func TestSnapshotCopyOnWrite(t *testing.T) {
+	sets := []struct {
+		name  string
+		opts  []quadtree.Option
+		store bool
+	}{
+		{"default", nil, false},
+		{"arena", []quadtree.Option{quadtree.WithArena()}, false},
+		{"compact", []quadtree.Option{quadtree.WithAutoCompact(), quadtree.WithAggregates(nil, nil)}, false},
+		{"grow", []quadtree.Option{quadtree.WithAutoGrow()}, false},
+		{"store", nil, true},
+	}
+
+	for _, set := range sets {
+		t.Run(set.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			opts := append([]quadtree.Option{quadtree.WithCapacity(4)}, set.opts...)
+			if set.store {
+				opts = append(opts, quadtree.WithPointStore(quadtree.NewMemoryStore()))
+			}
+			qt := quadtree.New(quadtree.WorldBounds(), 0, nil, opts...)
+
+			// points by ID as inserted, and as the tree should hold them
+			handles := make(map[string]*quadtree.Point)
+			live := make(map[string]entry)
+			var snaps []*quadtree.QuadTree
+			var held []map[string]entry
+
+			coordinates := func() (float64, float64) {
+				return r.Float64()*180 - 90, r.Float64()*360 - 180
+			}
+
+			for step := 0; step < 3000; step++ {
+				var id string
+				for id = range handles {
+					break
+				}
+
+				switch op := r.Intn(10); {
+				case op < 3 || id == "":
+					x, y := coordinates()
+					id := fmt.Sprint("id", step)
+					p := quadtree.NewPointID(id, x, y, step)
+					if !qt.Insert(p) {
+						t.Fatalf("step %d: insert failed", step)
+					}
+					handles[id] = p
+					live[id] = entry{x, y, step}
+
+				case op < 5:
+					x, y := coordinates()
+					if set.name == "grow" && r.Intn(20) == 0 {
+						x *= 3
+					}
+					if !qt.Update(handles[id], quadtree.NewPoint(x, y, nil)) {
+						t.Fatalf("step %d: update of %s failed", step, id)
+					}
+					live[id] = entry{x, y, live[id].data}
+
+				case op < 6:
+					if !qt.Remove(handles[id]) {
+						t.Fatalf("step %d: remove of %s failed", step, id)
+					}
+					delete(handles, id)
+					delete(live, id)
+
+				case op < 7:
+					if err := qt.SetData(id, -step); err != nil {
+						t.Fatalf("step %d: set data of %s: %v", step, id, err)
+					}
+					e := live[id]
+					e.data = -step
+					live[id] = e
+
+				case op < 8:
+					x, y := coordinates()
+					a := quadtree.NewAABB(quadtree.NewPoint(x, y, nil), quadtree.NewPoint(5, 10, nil))
+					qt.RemoveRange(a)
+					for id, e := range live {
+						if a.ContainsPoint(quadtree.NewPoint(e.x, e.y, nil)) {
+							delete(handles, id)
+							delete(live, id)
+						}
+					}
+
+				case op < 9:
+					qt.Compact()
+					qt.Release()
+
+				default:
+					snaps = append(snaps, qt.Snapshot())
+					held = append(held, maps.Clone(live))
+				}
+
+				if err := qt.Validate(); err != nil {
+					t.Fatalf("step %d: %v", step, err)
+				}
+			}
+
+			if got := entries(qt); !maps.Equal(got, live) {
+				t.Fatalf("tree holds %d points, not the %d written", len(got), len(live))
+			}
+			for i, snap := range snaps {
+				if err := snap.Validate(); err != nil {
+					t.Fatalf("snapshot %d: %v", i, err)
+				}
+				if got := entries(snap); !maps.Equal(got, held[i]) || snap.Len() != len(held[i]) {
+					t.Fatalf("snapshot %d holds %d points, not the %d it was taken with", i, len(got), len(held[i]))
+				}
+				for id, e := range held[i] {
+					if p := snap.Get(id); p == nil || p.Data() != e.data {
+						t.Fatalf("snapshot %d indexes %v for %s", i, p, id)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshotConcurrent(t *testing.T) {
+	points := testdata.Uniform(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 2000)
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil, quadtree.WithCapacity(4), quadtree.WithAggregates(nil, nil))
+	for _, p := range points {
+		qt.Insert(p)
+	}
+
+	snap := qt.Snapshot()
+	a := quadtree.NewAABB(quadtree.NewPoint(10, 10, nil), quadtree.NewPoint(40, 80, nil))
+	want := len(snap.Search(a))
+	nearest := snap.KNearest(a, 10, nil)
+
+	// readers scan the snapshot without locks while the tree is written
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if n := len(snap.Search(a)); n != want {
+					errs <- fmt.Errorf("search found %d points, want %d", n, want)
+					return
+				}
+				if got := snap.KNearest(a, 10, nil); !slices.Equal(got, nearest) {
+					errs <- errors.New("nearest points changed")
+					return
+				}
+				snap.Aggregate()
+			}
+		}()
+	}
+
+	r := rand.New(rand.NewSource(2))
+	for i, p := range points {
+		switch i % 3 {
+		case 0:
+			qt.Update(p, quadtree.NewPoint(r.Float64()*180-90, r.Float64()*360-180, nil))
+		case 1:
+			qt.Remove(p)
+		default:
+			qt.Insert(quadtree.NewPoint(r.Float64()*180-90, r.Float64()*360-180, nil))
+		}
+		qt.Aggregate()
+	}
+	qt.Compact()
+
+	close(done)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotCompression(t *testing.T) {
+	kinds := []map[string]interface{}{
+		{"kind": "store", "brand": "north", "open": "08:00-20:00"},
+		{"kind": "store", "brand": "south", "open": "09:00-21:00"},
+		{"kind": "depot", "brand": "north", "open": "00:00-24:00"},
+	}
+
+	// enough points that subtrees are written to blocks of their own
+	located := testdata.Clustered(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 20000, 8, 2)
+
+	options := map[string][]quadtree.Option{
+		"plain":      nil,
+		"dictionary": {quadtree.WithSnapshotDictionary()},
+		"compressed": {quadtree.WithSnapshotCompression(quadtree.FlateCompressor)},
+		"both":       {quadtree.WithSnapshotDictionary(), quadtree.WithSnapshotCompression(quadtree.FlateCompressor)},
+	}
+
+	sizes := make(map[string]int)
+	for name, opts := range options {
+		t.Run(name, func(t *testing.T) {
+			qt := quadtree.New(quadtree.WorldBounds(), 0, nil, opts...)
+			for i, p := range located {
+				x, y := p.Coordinates()
+				qt.Insert(quadtree.NewPointID(fmt.Sprint(i), x, y, kinds[i%len(kinds)]))
+			}
+			// points without data are left inline
+			qt.Insert(quadtree.NewPointID("bare", 1, 1, nil))
+
+			b, err := qt.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			sizes[name] = len(b)
+
+			// compressed snapshots are decompressed with FlateCompressor
+			// by default
+			restored := quadtree.New(quadtree.WorldBounds(), 0, nil)
+			if err := restored.UnmarshalBinary(b); err != nil {
+				t.Fatal(err)
+			}
+
+			want, _ := qt.MarshalJSON()
+			got, _ := restored.MarshalJSON()
+			if string(got) != string(want) {
+				t.Fatal("restored tree differs from the tree snapshotted")
+			}
+
+			// a block cut short is refused
+			if err := restored.UnmarshalBinary(b[:len(b)-1]); !errors.Is(err, quadtree.ErrInvalidSnapshot) {
+				t.Fatalf("truncated snapshot returned %v, want ErrInvalidSnapshot", err)
+			}
+		})
+	}
+
+	for _, name := range []string{"dictionary", "compressed"} {
+		if sizes[name] >= sizes["plain"] {
+			t.Errorf("%s snapshot of %d bytes is no smaller than %d", name, sizes[name], sizes["plain"])
+		}
+	}
+	if sizes["both"] >= sizes["compressed"] {
+		t.Errorf("dictionary and compression of %d bytes is no smaller than compression of %d", sizes["both"], sizes["compressed"])
+	}
+}
+
+func TestSnapshotReadFrom(t *testing.T) {
+	// enough points for many blocks, built in parallel
+	points := testdata.Clustered(rand.New(rand.NewSource(1)), quadtree.WorldBounds(), 50000, 16, 2)
+	opts := []quadtree.Option{quadtree.WithSnapshotCompression(quadtree.FlateCompressor)}
+
+	qt := quadtree.New(quadtree.WorldBounds(), 0, nil, opts...)
+	for i, p := range points {
+		x, y := p.Coordinates()
+		qt.Insert(quadtree.NewPointID(fmt.Sprint(i), x, y, i%7))
+	}
+	b, err := qt.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := qt.MarshalJSON()
+
+	restored := quadtree.New(quadtree.WorldBounds(), 0, nil, opts...)
+	n, err := restored.ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(b)) {
+		t.Fatalf("read %d bytes, want %d", n, len(b))
+	}
+	if got, _ := restored.MarshalJSON(); string(got) != string(want) {
+		t.Fatal("restored tree differs from the tree snapshotted")
+	}
+	if restored.Get("123") == nil || restored.Len() != len(points) {
+		t.Fatalf("restored %d points, want %d indexed by ID", restored.Len(), len(points))
+	}
+
+	// snapshots cut short, followed by more, or with a corrupt block are
+	// refused, leaving the tree as it was
+	corrupt := bytes.Clone(b)
+	for i := len(b) / 2; i < len(b)/2+64; i++ {
+		corrupt[i] ^= 0xff
+	}
+	for name, b := range map[string][]byte{
+		"truncated": b[:len(b)-10],
+		"trailing":  append(bytes.Clone(b), 0),
+		"corrupt":   corrupt,
+	} {
+		if _, err := restored.ReadFrom(bytes.NewReader(b)); !errors.Is(err, quadtree.ErrInvalidSnapshot) {
+			t.Fatalf("%s snapshot returned %v, want ErrInvalidSnapshot", name, err)
+		}
+		if restored.Len() != len(points) {
+			t.Fatalf("%s snapshot changed the tree", name)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package quadtree
+
+// SoftRemove hides the point with the ID from queries without removing it
+// from the tree. Queries using WithRemoved still return it.
+func (qt *QuadTree) SoftRemove(id string) bool {
+	p, ok := qt.state.index()[id]
+	if !ok || qt.state.readOnly || qt.state.locked(p) {
+		return false
+	}
+	qt.writable(p).setMeta().removed = true
+	return true
+}
+
+// Restore makes a point hidden by SoftRemove visible to queries again.
+func (qt *QuadTree) Restore(id string) bool {
+	p, ok := qt.state.index()[id]
+	if !ok || !p.meta().removed || qt.state.readOnly || qt.state.locked(p) {
+		return false
+	}
+	qt.writable(p).setMeta().removed = false
+	return true
+}
+
+// Removed reports whether the point is hidden by SoftRemove.
+func (p *Point) Removed() bool {
+	return p.meta().removed
+}
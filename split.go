@@ -0,0 +1,95 @@
+package quadtree
+
+// SplitPolicy decides when a leaf divides into four, e.g. so that dense
+// city centres divide finely while sparse regions keep large leaves.
+// Leaves at the max depth never divide, whatever the policy.
+type SplitPolicy interface {
+	// Split reports whether the leaf divides rather than hold n points.
+	// The leaf is viewed as it is before holding them.
+	Split(leaf LeafView, n int) bool
+}
+
+// SplitFunc adapts a function to a SplitPolicy.
+type SplitFunc func(leaf LeafView, n int) bool
+
+// Split calls f.
+func (f SplitFunc) Split(leaf LeafView, n int) bool {
+	return f(leaf, n)
+}
+
+// WithSplitPolicy sets the policy deciding when leaves divide, replacing
+// the capacity set by WithCapacity. Collapsing by Compact and
+// WithAutoCompact still uses the capacity.
+func WithSplitPolicy(policy SplitPolicy) Option {
+	return func(s *state) {
+		s.split = policy
+	}
+}
+
+// SplitByCapacity divides leaves holding more than n points, the policy of
+// a tree created using WithCapacity(n).
+func SplitByCapacity(n int) SplitPolicy {
+	return SplitFunc(func(leaf LeafView, count int) bool {
+		return count > n
+	})
+}
+
+// SplitBySize divides leaves larger than the size along either axis, in
+// metres or the units of a Cartesian tree, so every point is held by a
+// leaf no larger than it whatever the number of points.
+func SplitBySize(maxMeters float64) SplitPolicy {
+	return SplitFunc(func(leaf LeafView, n int) bool {
+		x, y := leaf.Size()
+		return x > maxMeters || y > maxMeters
+	})
+}
+
+// SplitByDensity divides leaves which would hold more than the number of
+// points per square kilometre, or per square unit of a Cartesian tree, and
+// more than one point.
+func SplitByDensity(maxPerKm2 float64) SplitPolicy {
+	return SplitFunc(func(leaf LeafView, n int) bool {
+		x, y := leaf.Size()
+		area := x * y
+		if leaf.node.state.coords != Cartesian {
+			area /= 1e6
+		}
+		return n > 1 && float64(n) > maxPerKm2*area
+	})
+}
+
+// MinCellSize divides leaves by the policy, but never into leaves smaller
+// than the size along either axis, in metres or the units of a Cartesian
+// tree, e.g. to stop GPS noise dividing a region down to the max depth.
+func MinCellSize(minMeters float64, policy SplitPolicy) SplitPolicy {
+	return SplitFunc(func(leaf LeafView, n int) bool {
+		x, y := leaf.Size()
+		return x/2 >= minMeters && y/2 >= minMeters && policy.Split(leaf, n)
+	})
+}
+
+// Size returns the extent of the leaf along x and y, in metres or the
+// units of a Cartesian tree, measured through its center as twice the
+// distance to the midpoints of its edges.
+func (l LeafView) Size() (x, y float64) {
+	if l.node == nil {
+		return 0, 0
+	}
+
+	cs := l.node.state.coordinates()
+	c, h := l.node.boundary.center, l.node.boundary.half
+	x = 2 * cs.Distance(c, &Point{x: c.x + h.x, y: c.y})
+	y = 2 * cs.Distance(c, &Point{x: c.x, y: c.y + h.y})
+	return x, y
+}
+
+// splits reports whether the leaf divides rather than hold n points.
+func (qt *QuadTree) splits(n int) bool {
+	if qt.depth >= qt.state.maxDepth {
+		return false
+	}
+	if qt.state.split == nil {
+		return n > qt.state.capacity
+	}
+	return qt.state.split.Split(LeafView{node: qt}, n)
+}
@@ -0,0 +1,85 @@
+package quadtree
+
+import (
+	"unsafe"
+)
+
+// nodeOverhead is the fixed size of a node.
+const nodeOverhead = int64(unsafe.Sizeof(QuadTree{}))
+
+// Stats describes the shape of a tree, used to tune the capacity and
+// maximum depth of a tree and to monitor it.
+type Stats struct {
+	// Points is the number of points in the tree
+	Points int
+	// Nodes is the number of nodes, Leaves those without children
+	Nodes  int
+	Leaves int
+	// Depth is the depth of the deepest node below the root
+	Depth int
+	// Levels holds the nodes, leaves and points at each depth below
+	// the root, indexed by depth
+	Levels []LevelStats
+	// LeafPoints is a histogram of points per leaf, LeafPoints[n] being
+	// the number of leaves holding n points
+	LeafPoints []int
+	// Bytes is an estimate of the memory used by the nodes and points
+	Bytes int64
+}
+
+// LevelStats counts the nodes, leaves and points at a depth of the tree.
+type LevelStats struct {
+	Nodes  int
+	Leaves int
+	Points int
+}
+
+// Len returns the number of points in the tree, including soft removed
+// points, without traversing it.
+func (qt *QuadTree) Len() int {
+	return qt.state.count
+}
+
+// Stats walks the tree returning the distribution of its nodes and
+// points.
+func (qt *QuadTree) Stats() Stats {
+	var s Stats
+	qt.stats(0, &s)
+
+	// points are measured when inserted and include their own overhead
+	s.Bytes += qt.state.bytes
+	return s
+}
+
+func (qt *QuadTree) stats(depth int, s *Stats) {
+	if depth == len(s.Levels) {
+		s.Levels = append(s.Levels, LevelStats{})
+	}
+	level := &s.Levels[depth]
+
+	s.Nodes++
+	level.Nodes++
+	level.Points += qt.slots()
+	s.Points += qt.slots()
+	s.Depth = max(s.Depth, depth)
+
+	s.Bytes += nodeOverhead
+	if cap(qt.resident) > inlinePoints {
+		s.Bytes += int64(cap(qt.resident)) * int64(unsafe.Sizeof((*Point)(nil)))
+	}
+	s.Bytes += int64(cap(qt.codes)) * int64(unsafe.Sizeof(uint64(0)))
+
+	if qt.nodes[0] == nil {
+		s.Leaves++
+		level.Leaves++
+		for len(s.LeafPoints) <= qt.slots() {
+			s.LeafPoints = append(s.LeafPoints, 0)
+		}
+		s.LeafPoints[qt.slots()]++
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.stats(depth+1, s)
+	}
+}
@@ -0,0 +1,107 @@
+package quadtree
+
+// stepped is a point moved by Step along with where it was.
+type stepped struct {
+	point *Point
+	x, y  float64
+}
+
+// Step advances every point by its velocity over dt in a single pass of
+// the tree, e.g. each frame of a game or agent based simulation, rather
+// than an Update per point. Points remaining within their leaf are moved
+// in place, and only those crossing into another node are reinserted,
+// which are returned. Points with no velocity are left alone, as are
+// those whose new location is within a locked region or outside the
+// boundary of a tree created without WithAutoGrow. OnMove hooks are called
+// once every point has moved.
+func (qt *QuadTree) Step(dt float64, vel func(*Point) (vx, vy float64)) []*Point {
+	if vel == nil || !qt.state.allowWrite() {
+		return nil
+	}
+
+	var moved, crossing []stepped
+	qt.step(dt, vel, &moved, &crossing)
+
+	root := qt.root()
+	var crossed []*Point
+
+	for _, c := range crossing {
+		p := c.point
+		if qt.state.grow && !root.boundary.ContainsPoint(p) {
+			root.grow(p)
+		}
+		if !root.insert(p) {
+			// put the point back rather than lose it
+			p.x, p.y = c.x, c.y
+			root.insert(p)
+			continue
+		}
+
+		if qt.state.metrics != nil {
+			qt.state.metrics.Reinserted()
+		}
+		moved = append(moved, c)
+		crossed = append(crossed, p)
+	}
+
+	for _, m := range moved {
+		qt.state.updated(m.point)
+		m.point.setMeta().version++
+	}
+	for _, m := range moved {
+		qt.state.hooks.moved(m.point, m.x, m.y)
+	}
+
+	return crossed
+}
+
+// step moves the points of the node and its children staying within their
+// leaf, appending them to moved, and removes and appends to crossing those
+// leaving it, at their new location.
+func (qt *QuadTree) step(dt float64, vel func(*Point) (vx, vy float64), moved, crossing *[]stepped) {
+	changed := false
+	qt.loadLeaf()
+
+	for i := len(qt.resident) - 1; i >= 0; i-- {
+		p := qt.resident[i]
+		vx, vy := vel(p)
+		if vx == 0 && vy == 0 {
+			continue
+		}
+
+		np := &Point{x: p.x + vx*dt, y: p.y + vy*dt}
+		if qt.state.locked(p, np) {
+			continue
+		}
+
+		p = qt.fork(i)
+		s := stepped{p, p.x, p.y}
+		p.x, p.y = np.x, np.y
+		changed = true
+		qt.wrote(p)
+
+		if qt.nodes[0] == nil && qt.boundary.ContainsPoint(p) {
+			*moved = append(*moved, s)
+			continue
+		}
+
+		qt.removeAt(i)
+		*crossing = append(*crossing, s)
+	}
+
+	if changed {
+		if qt.codes != nil {
+			// keep the morton order
+			qt.refine()
+		}
+		qt.touch()
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for i := range qt.nodes {
+		qt.own(i).step(dt, vel, moved, crossing)
+	}
+}
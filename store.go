@@ -0,0 +1,300 @@
+package quadtree
+
+import (
+	"strconv"
+	"sync"
+)
+
+// NodeID identifies a node by its path of child indices from the root
+// separated by dots, e.g. "0.3.1", the root being "".
+type NodeID string
+
+// PointStore holds the points of each node by slot, the index of a point
+// within its node. The tree writes every change to the slots of a node
+// through to the store, so a backend such as a file or remote service
+// holds the exact layout of the tree, e.g. for replication or inspection.
+//
+// Leaves emptied by Release hold only the number of their slots, the
+// points in them read back through Get whenever the leaf is queried, so
+// the store rather than the tree holds them. Points are identified by
+// pointer, so Get must return the point Put in a slot while it is still
+// in use, e.g. by keeping it through a weak.Pointer and decoding it
+// afresh only once it has been collected.
+type PointStore interface {
+	// Put stores the point in the slot of a node, replacing any point
+	// held in the slot.
+	Put(node NodeID, slot int, p *Point) error
+	// Delete empties the slot of a node.
+	Delete(node NodeID, slot int) error
+	// Get returns the point held in the slot of a node.
+	Get(node NodeID, slot int) (*Point, error)
+}
+
+// WithPointStore writes the points of each node through to the store.
+// The first error returned by the store is reported by StoreErr.
+func WithPointStore(store PointStore) Option {
+	return func(s *state) {
+		s.store = store
+		s.storeMu = new(sync.Mutex)
+	}
+}
+
+// StoreErr returns the first error returned by the point store of the
+// tree, if any.
+func (qt *QuadTree) StoreErr() error {
+	s := qt.state
+	if s.storeMu == nil {
+		return nil
+	}
+
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	return s.storeErr
+}
+
+// Release empties each leaf of a tree created using WithPointStore of its
+// points, leaving them held only by the store, and returns the number of
+// points the store alone holds. Queries read the points of a released leaf through the
+// store, and writes to the leaf read them back into the tree. Nothing is
+// released while StoreErr reports the store missed a write, nor from a
+// Snapshot.
+func (qt *QuadTree) Release() int {
+	if qt.state.store == nil || qt.state.readOnly || qt.StoreErr() != nil {
+		return 0
+	}
+	return qt.release()
+}
+
+func (qt *QuadTree) release() int {
+	if qt.nodes[0] != nil {
+		n := 0
+		for i := range qt.nodes {
+			n += qt.own(i).release()
+		}
+		return n
+	}
+
+	n := len(qt.resident)
+	if n == 0 {
+		return int(qt.released)
+	}
+
+	// points changed in place since they were put are put again, and the
+	// ID is built now so queries reading the leaf do not write it
+	qt.stored(0, n)
+	if qt.state.storeErr != nil {
+		return 0
+	}
+	qt.released = int32(n)
+	clear(qt.resident)
+	qt.resident = qt.inline[:0]
+	return n
+}
+
+// slots returns the number of points of a leaf, without reading those of
+// a released leaf from the point store.
+func (qt *QuadTree) slots() int {
+	return len(qt.resident) + int(qt.released)
+}
+
+// leaf returns the points of a leaf, read from the point store if the
+// leaf was released.
+func (qt *QuadTree) leaf() []*Point {
+	if qt.released == 0 {
+		return qt.resident
+	}
+	return qt.fetch()
+}
+
+// fetch reads the points of a released leaf from the point store, leaving
+// the leaf as it is so concurrent queries may read it.
+func (qt *QuadTree) fetch() []*Point {
+	s := qt.state
+	id := qt.id()
+
+	points := make([]*Point, 0, qt.released)
+	for i := 0; i < int(qt.released); i++ {
+		p, err := s.store.Get(id, i)
+		if err != nil || p == nil {
+			s.storeFailed(err)
+			continue
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+// loadLeaf reads the points of a released leaf back into the tree before
+// it is written.
+func (qt *QuadTree) loadLeaf() {
+	if qt.released == 0 {
+		return
+	}
+
+	points := qt.fetch()
+	qt.resident = append(qt.inline[:0], points...)
+	if len(points) > inlinePoints {
+		qt.resident = points
+	}
+	qt.released = 0
+}
+
+// id returns the path of the node from the root, built once from that of
+// its parent and kept until the tree grows above it. That of a node shared
+// with a snapshot is built afresh each time as snapshots read it
+// concurrently.
+func (qt *QuadTree) id() NodeID {
+	if qt.hasNodeID || qt.parent == nil {
+		return qt.nodeID
+	}
+
+	for i, node := range qt.parent.nodes {
+		if node != qt {
+			continue
+		}
+		id := NodeID(strconv.Itoa(i))
+		if parent := qt.parent.id(); parent != "" {
+			id = parent + "." + id
+		}
+		if qt.owned() {
+			qt.nodeID = id
+			qt.hasNodeID = true
+		}
+		return id
+	}
+	return qt.nodeID
+}
+
+func (s *state) storeFailed(err error) {
+	if err == nil {
+		return
+	}
+
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	if s.storeErr == nil {
+		s.storeErr = err
+	}
+}
+
+// stored writes the slots of the node from i up to n to the store.
+func (qt *QuadTree) stored(i, n int) {
+	s := qt.state
+	if s.store == nil {
+		return
+	}
+
+	id := qt.id()
+	for ; i < n; i++ {
+		s.storeFailed(s.store.Put(id, i, qt.resident[i]))
+	}
+}
+
+// unstored deletes the slots of the node from i up to n from the store.
+func (qt *QuadTree) unstored(i, n int) {
+	s := qt.state
+	if s.store == nil {
+		return
+	}
+
+	id := qt.id()
+	for ; i < n; i++ {
+		s.storeFailed(s.store.Delete(id, i))
+	}
+}
+
+// restock writes every slot of the tree to the store, or deletes them.
+func (qt *QuadTree) restock(put bool) {
+	if qt.state.store == nil {
+		return
+	}
+
+	// the slots of a released leaf are all that hold its points
+	qt.loadLeaf()
+	if put {
+		qt.stored(0, len(qt.resident))
+	} else {
+		qt.unstored(0, len(qt.resident))
+	}
+
+	if qt.nodes[0] != nil {
+		for _, node := range qt.nodes {
+			node.restock(put)
+		}
+	}
+}
+
+// MemoryStore is an in-memory PointStore, safe for concurrent use.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	nodes map[NodeID][]*Point
+}
+
+// NewMemoryStore returns an empty *MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nodes: make(map[NodeID][]*Point)}
+}
+
+// Get returns the point written to the slot of a node, or ErrNotFound if
+// the slot is empty.
+func (m *MemoryStore) Get(node NodeID, slot int) (*Point, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	points := m.nodes[node]
+	if slot < 0 || slot >= len(points) || points[slot] == nil {
+		return nil, ErrNotFound
+	}
+	return points[slot], nil
+}
+
+func (m *MemoryStore) Put(node NodeID, slot int, p *Point) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	points := m.nodes[node]
+	for len(points) <= slot {
+		points = append(points, nil)
+	}
+	points[slot] = p
+	m.nodes[node] = points
+	return nil
+}
+
+func (m *MemoryStore) Delete(node NodeID, slot int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	points := m.nodes[node]
+	if slot < 0 || slot >= len(points) {
+		return nil
+	}
+	points[slot] = nil
+
+	// trim empty trailing slots
+	for len(points) > 0 && points[len(points)-1] == nil {
+		points = points[:len(points)-1]
+	}
+	if len(points) == 0 {
+		delete(m.nodes, node)
+		return nil
+	}
+	m.nodes[node] = points
+	return nil
+}
+
+// Len returns the number of points held by the store.
+func (m *MemoryStore) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n := 0
+	for _, points := range m.nodes {
+		for _, p := range points {
+			if p != nil {
+				n++
+			}
+		}
+	}
+	return n
+}
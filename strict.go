@@ -0,0 +1,86 @@
+package quadtree
+
+import (
+	"math"
+)
+
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// Validate checks the axis aligned bounding box has a finite center and
+// positive finite half extents.
+func (a *AABB) Validate() error {
+	if a == nil || a.center == nil || a.half == nil {
+		return ErrInvalidAABB
+	}
+	if !finite(a.center.x) || !finite(a.center.y) {
+		return ErrInvalidAABB
+	}
+	if !finite(a.half.x) || !finite(a.half.y) || a.half.x <= 0 || a.half.y <= 0 {
+		return ErrInvalidAABB
+	}
+	return nil
+}
+
+// NewAABBStrict creates an axis aligned bounding box like NewAABB but
+// returns ErrInvalidAABB rather than a box which can never match.
+func NewAABBStrict(center, half *Point) (*AABB, error) {
+	a := &AABB{center, half}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// NewStrict creates a new root *QuadTree like New, returning an error if
+// the boundary is nil or invalid.
+func NewStrict(boundary *AABB, opts ...Option) (*QuadTree, error) {
+	if boundary == nil {
+		return nil, ErrNilBoundary
+	}
+	if err := boundary.Validate(); err != nil {
+		return nil, err
+	}
+	return New(boundary, 0, nil, opts...), nil
+}
+
+// SearchStrict is Search returning ErrInvalidAABB for an invalid query box
+// instead of silently returning nothing. It returns ErrTooManyQueries
+// rather than waiting when the concurrent query limit is reached, and
+// ErrTruncated with the partial results of a query exceeding its
+// WithMaxResultsBytes budget. A panic in a callback of the query is
+// returned as a *PanicError.
+func (qt *QuadTree) SearchStrict(a *AABB, opts ...QueryOption) (_ []*Point, err error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	if !qt.state.tryAcquire() {
+		return nil, ErrTooManyQueries
+	}
+	defer qt.state.release()
+	defer recovered(&err)
+
+	q := qt.newQuery(opts)
+	results := qt.searchQuery(a, q)
+	if q.truncated {
+		return results, ErrTruncated
+	}
+	return results, nil
+}
+
+// KNearestStrict is KNearest returning ErrInvalidAABB for an invalid query
+// box instead of silently returning nothing. It returns ErrTooManyQueries
+// rather than waiting when the concurrent query limit is reached, and a
+// *PanicError if the filter or another callback panics.
+func (qt *QuadTree) KNearestStrict(a *AABB, i int, fn filter, opts ...QueryOption) (_ []*Point, err error) {
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	if !qt.state.tryAcquire() {
+		return nil, ErrTooManyQueries
+	}
+	defer qt.state.release()
+	defer recovered(&err)
+	return qt.kNearestQuery(nil, a, i, fn, qt.newQuery(opts)), nil
+}
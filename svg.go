@@ -0,0 +1,70 @@
+package quadtree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WithHighlight outlines the bounding box in renderings and highlights
+// the points within it, e.g. to check the results of a query.
+func WithHighlight(a *AABB) QueryOption {
+	return func(q *query) {
+		q.highlight = a
+	}
+}
+
+// RenderSVG draws the points within the axis aligned bounding box as an
+// SVG image of width by height pixels, oriented as for RenderASCII with x
+// increasing upwards. Using WithNodeBoundaries the boundaries of the leaf
+// nodes are drawn, and using WithHighlight a query box is outlined and the
+// points within it drawn in red.
+func (qt *QuadTree) RenderSVG(w io.Writer, a *AABB, width, height int, opts ...QueryOption) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	q := qt.newQuery(opts)
+	bw := bufio.NewWriter(w)
+
+	minX, minY := a.center.x-a.half.x, a.center.y-a.half.y
+	sx, sy := float64(width)/(2*a.half.y), float64(height)/(2*a.half.x)
+
+	// rect returns the pixel rectangle of a box
+	rect := func(b *AABB) (x, y, w, h float64) {
+		x = (b.center.y - b.half.y - minY) * sx
+		y = float64(height) - (b.center.x+b.half.x-minX)*sy
+		return x, y, 2 * b.half.y * sx, 2 * b.half.x * sy
+	}
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+
+	if q.boundaries {
+		fmt.Fprintln(bw, `<g fill="none" stroke="#999" stroke-width="0.5">`)
+		for leaf := range qt.LeavesIntersecting(a) {
+			x, y, w, h := rect(leaf.Boundary())
+			fmt.Fprintf(bw, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f"/>`+"\n", x, y, w, h)
+		}
+		fmt.Fprintln(bw, `</g>`)
+	}
+
+	fmt.Fprintln(bw, `<g>`)
+	for _, p := range qt.search(a, q) {
+		fill, r := "black", 1.5
+		if q.highlight != nil && q.highlight.ContainsPoint(p) {
+			fill, r = "red", 2.5
+		}
+		fmt.Fprintf(bw, `<circle cx="%.2f" cy="%.2f" r="%.1f" fill="%s"/>`+"\n",
+			(p.y-minY)*sx, float64(height)-(p.x-minX)*sy, r, fill)
+	}
+	fmt.Fprintln(bw, `</g>`)
+
+	if q.highlight != nil {
+		x, y, w, h := rect(q.highlight)
+		fmt.Fprintf(bw, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="none" stroke="red" stroke-dasharray="4 2"/>`+"\n", x, y, w, h)
+	}
+
+	fmt.Fprintln(bw, `</svg>`)
+	return bw.Flush()
+}
@@ -0,0 +1,75 @@
+package quadtree
+
+import (
+	"hash/fnv"
+)
+
+// tagBit returns the bit of a tag in the tag bitsets of points and nodes.
+// Tags share the 64 bits by hash, so a bitset may claim a tag none of its
+// points has but never misses one they have.
+func tagBit(tag string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(tag))
+	return 1 << (h.Sum64() % 64)
+}
+
+// AddTag tags the point, e.g. "type:cafe", for queries restricted by
+// WithTags. Tags must be added before the point is inserted.
+func (p *Point) AddTag(tag string) {
+	if p.HasTag(tag) {
+		return
+	}
+	m := p.setMeta()
+	m.tags = append(m.tags, tag)
+	m.tagBits |= tagBit(tag)
+}
+
+// HasTag reports whether the point has the tag.
+func (p *Point) HasTag(tag string) bool {
+	if p.meta().tagBits&tagBit(tag) == 0 {
+		return false
+	}
+	for _, t := range p.meta().tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Tags returns the tags of the point in the order they were added.
+func (p *Point) Tags() []string {
+	return append([]string(nil), p.meta().tags...)
+}
+
+// WithTags restricts the results of a query to points with every one of
+// the tags. Each node tracks the tags of the points beneath it, so Search,
+// KNearest and the queries built on them skip subtrees without the tags
+// rather than filtering each of their points.
+func WithTags(tags ...string) QueryOption {
+	return func(q *query) {
+		for _, t := range tags {
+			q.tags = append(q.tags, t)
+			q.tagBits |= tagBit(t)
+		}
+	}
+}
+
+// tagged reports whether the node may hold points with the tags of the
+// query.
+func (q *query) tagged(qt *QuadTree) bool {
+	return qt.tags&q.tagBits == q.tagBits
+}
+
+// hasTags reports whether the point has every tag of the query.
+func (q *query) hasTags(p *Point) bool {
+	if p.meta().tagBits&q.tagBits != q.tagBits {
+		return false
+	}
+	for _, t := range q.tags {
+		if !p.HasTag(t) {
+			return false
+		}
+	}
+	return true
+}
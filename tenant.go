@@ -0,0 +1,74 @@
+package quadtree
+
+// Tenant returns the tenant the point belongs to.
+func (p *Point) Tenant() string {
+	return p.meta().tenant
+}
+
+// SetTenant sets the tenant the point belongs to. It must be set before
+// the point is inserted.
+func (p *Point) SetTenant(tenant string) {
+	p.setMeta().tenant = tenant
+}
+
+// WithTenant restricts the results of a query to points of the tenant.
+func WithTenant(tenant string) QueryOption {
+	return func(q *query) {
+		q.tenant = tenant
+		q.tenanted = true
+	}
+}
+
+// TenantCount returns the number of points in the tree belonging to the
+// tenant.
+func (qt *QuadTree) TenantCount(tenant string) int {
+	return qt.state.tenants[tenant]
+}
+
+// Tenants returns the number of points in the tree for each tenant.
+func (qt *QuadTree) Tenants() map[string]int {
+	counts := make(map[string]int, len(qt.state.tenants))
+	for t, n := range qt.state.tenants {
+		counts[t] = n
+	}
+	return counts
+}
+
+// RemoveTenant removes every point of the tenant in a single traversal,
+// returning the number removed.
+func (qt *QuadTree) RemoveTenant(tenant string) int {
+	if qt.state.tenants[tenant] == 0 || qt.state.readOnly {
+		return 0
+	}
+
+	var removed []*Point
+	qt.removeTenant(tenant, &removed)
+
+	for _, p := range removed {
+		qt.dropped(p)
+	}
+
+	return len(removed)
+}
+
+func (qt *QuadTree) removeTenant(tenant string, removed *[]*Point) {
+	if qt.nodes[0] != nil {
+		for i := range qt.nodes {
+			qt.own(i).removeTenant(tenant, removed)
+		}
+		return
+	}
+
+	n := len(*removed)
+	qt.loadLeaf()
+	for i := len(qt.resident) - 1; i >= 0; i-- {
+		if p := qt.resident[i]; p.meta().tenant == tenant {
+			*removed = append(*removed, p)
+			qt.removeAt(i)
+		}
+	}
+
+	if len(*removed) > n {
+		qt.touch()
+	}
+}
@@ -0,0 +1,230 @@
+// Package testdata generates points for the tests of code built on a
+// quadtree and checks the results of queries against a brute force scan
+// of the points, so each user of the package need not write their own.
+//
+//	func TestNearest(t *testing.T) {
+//		r := rand.New(rand.NewSource(1))
+//		points := testdata.Clustered(r, quadtree.WorldBounds(), 10000, 8, 0.5)
+//		tree := quadtree.New(quadtree.WorldBounds(), 0, nil)
+//		for _, p := range points {
+//			tree.Insert(p)
+//		}
+//		testdata.AssertKNearest(t, tree, points, quadtree.NewGeoAABB(51.5, -0.1, 50000), 10, nil)
+//	}
+//
+// Generators take the *rand.Rand they draw from so a fixed seed generates
+// the same points on every run. Each point holds its index as its data.
+//
+// The go tool leaves directories named testdata out of patterns such as
+// ./..., so the package is built only where it is imported.
+package testdata
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/asim/quadtree"
+)
+
+// Uniform returns n points spread evenly over the boundary.
+func Uniform(r *rand.Rand, bounds *quadtree.AABB, n int) []*quadtree.Point {
+	min, max := corners(bounds)
+	points := make([]*quadtree.Point, n)
+	for i := range points {
+		x := min[0] + r.Float64()*(max[0]-min[0])
+		y := min[1] + r.Float64()*(max[1]-min[1])
+		points[i] = quadtree.NewPoint(x, y, i)
+	}
+	return points
+}
+
+// Clustered returns n points gathered normally around a number of centers
+// within the boundary, as with users or stores around cities. The spread
+// is the standard deviation of each cluster in the units of the
+// coordinates.
+func Clustered(r *rand.Rand, bounds *quadtree.AABB, n, clusters int, spread float64) []*quadtree.Point {
+	centers := Uniform(r, bounds, max(clusters, 1))
+	points := make([]*quadtree.Point, n)
+	for i := range points {
+		cx, cy := centers[r.Intn(len(centers))].Coordinates()
+		x, y := clamp(bounds, cx+r.NormFloat64()*spread, cy+r.NormFloat64()*spread)
+		points[i] = quadtree.NewPoint(x, y, i)
+	}
+	return points
+}
+
+// Grid returns a point at the center of each cell of a grid of cols by
+// rows cells over the boundary, in rows of increasing x.
+func Grid(bounds *quadtree.AABB, cols, rows int) []*quadtree.Point {
+	min, max := corners(bounds)
+	w, h := (max[0]-min[0])/float64(rows), (max[1]-min[1])/float64(cols)
+
+	points := make([]*quadtree.Point, 0, cols*rows)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			x, y := min[0]+(float64(i)+0.5)*w, min[1]+(float64(j)+0.5)*h
+			points = append(points, quadtree.NewPoint(x, y, len(points)))
+		}
+	}
+	return points
+}
+
+// Traces returns n points along random walks within the boundary, as with
+// recorded GPS tracks. Each walk is fixes points long, each a step on from
+// the last in the units of the coordinates, its heading drifting as it
+// goes. Walks start near a few centers so they overlap.
+func Traces(r *rand.Rand, bounds *quadtree.AABB, n, fixes int, step float64) []*quadtree.Point {
+	centers := Uniform(r, bounds, 16)
+	fixes = max(fixes, 1)
+
+	points := make([]*quadtree.Point, 0, n)
+	for len(points) < n {
+		x, y := centers[r.Intn(len(centers))].Coordinates()
+		x, y = clamp(bounds, x+r.NormFloat64()*step*100, y+r.NormFloat64()*step*100)
+		heading := r.Float64() * 2 * math.Pi
+
+		for i := 0; i < fixes && len(points) < n; i++ {
+			heading += r.NormFloat64() * 0.1
+			x, y = clamp(bounds, x+math.Cos(heading)*step, y+math.Sin(heading)*step)
+			points = append(points, quadtree.NewPoint(x, y, len(points)))
+		}
+	}
+	return points
+}
+
+// Search returns the points within the bounding box by scanning every
+// point, in the order given. Unlike a tree of WGS84 coordinates it does not
+// wrap boxes around the antimeridian.
+func Search(points []*quadtree.Point, a *quadtree.AABB) []*quadtree.Point {
+	results := []*quadtree.Point{}
+	if a == nil || a.Center() == nil || a.Half() == nil {
+		return results
+	}
+
+	for _, p := range points {
+		if a.ContainsPoint(p) {
+			results = append(results, p)
+		}
+	}
+	return results
+}
+
+// KNearest returns the k points within the bounding box nearest to its
+// center which pass the filter, by scanning every point. Points are
+// ordered by the distance function, or by planar distance if it is nil,
+// and points at the same distance in the order given.
+func KNearest(points []*quadtree.Point, a *quadtree.AABB, k int, fn func(*quadtree.Point) bool, dist quadtree.DistanceFunc) []*quadtree.Point {
+	results := []*quadtree.Point{}
+	if k <= 0 {
+		return results
+	}
+	if dist == nil {
+		dist = planar
+	}
+
+	for _, p := range Search(points, a) {
+		if fn == nil || fn(p) {
+			results = append(results, p)
+		}
+	}
+
+	c := a.Center()
+	sort.SliceStable(results, func(i, j int) bool {
+		return dist(results[i], c) < dist(results[j], c)
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// AssertSearch fails the test unless a search of the tree for the box
+// returns exactly the points of Search, in any order. The points are
+// those inserted into the tree.
+func AssertSearch(t testing.TB, tree *quadtree.QuadTree, points []*quadtree.Point, a *quadtree.AABB) {
+	t.Helper()
+
+	got, want := tree.Search(a), Search(points, a)
+
+	count := make(map[*quadtree.Point]int, len(want))
+	for _, p := range want {
+		count[p]++
+	}
+	for _, p := range got {
+		count[p]--
+	}
+
+	// report in the order of the points rather than of the map
+	for _, p := range append(want, got...) {
+		x, y := p.Coordinates()
+		if count[p] > 0 {
+			t.Errorf("search of %v missing point %v at %v, %v", box(a), p.Data(), x, y)
+		} else if count[p] < 0 {
+			t.Errorf("search of %v returned unexpected point %v at %v, %v", box(a), p.Data(), x, y)
+		}
+		count[p] = 0
+	}
+}
+
+// AssertKNearest fails the test unless the k nearest points of the tree to
+// the center of the box are at the distances of those of KNearest, in
+// order. Distances rather than points are compared so points tied at the
+// same distance may be returned in either order. The distance function
+// is that of the tree, or nil for planar distance.
+func AssertKNearest(t testing.TB, tree *quadtree.QuadTree, points []*quadtree.Point, a *quadtree.AABB, k int, dist quadtree.DistanceFunc) {
+	t.Helper()
+
+	if dist == nil {
+		dist = planar
+	}
+
+	got, want := tree.KNearest(a, k, nil), KNearest(points, a, k, nil, dist)
+	if len(got) != len(want) {
+		t.Errorf("knearest %d of %v returned %d points, want %d", k, box(a), len(got), len(want))
+		return
+	}
+
+	c := a.Center()
+	for i := range got {
+		if g, w := dist(got[i], c), dist(want[i], c); g != w {
+			t.Errorf("knearest %d of %v returned point %d at distance %v, want %v", k, box(a), i, g, w)
+			return
+		}
+	}
+}
+
+// planar returns the square of the straight line distance between two
+// points, computed as the tree does so points within a rounding error of
+// each other are ordered alike.
+func planar(p, q *quadtree.Point) float64 {
+	px, py := p.Coordinates()
+	qx, qy := q.Coordinates()
+	dx, dy := px-qx, py-qy
+	return dx*dx + dy*dy
+}
+
+// corners returns the minimum and maximum coordinates of the box.
+func corners(a *quadtree.AABB) (min, max [2]float64) {
+	lo, hi := a.Min(), a.Max()
+	min[0], min[1] = lo.Coordinates()
+	max[0], max[1] = hi.Coordinates()
+	return min, max
+}
+
+// clamp returns x, y moved onto the boundary if outside it.
+func clamp(a *quadtree.AABB, x, y float64) (float64, float64) {
+	min, max := corners(a)
+	return math.Max(min[0], math.Min(x, max[0])), math.Max(min[1], math.Min(y, max[1]))
+}
+
+// box formats the box by its corners.
+func box(a *quadtree.AABB) string {
+	if a == nil || a.Center() == nil || a.Half() == nil {
+		return "<nil>"
+	}
+	min, max := corners(a)
+	return fmt.Sprintf("[%v, %v to %v, %v]", min[0], min[1], max[0], max[1])
+}
@@ -0,0 +1,158 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// maxTileZoom is the deepest zoom of Tile, beyond that of any web map.
+const maxTileZoom = 30
+
+// WithTileResolution thins the points returned by Tile to at most one in
+// each cell of an n by n grid over the tile, the first found, so tiles at
+// low zooms stay small without enumerating every point beneath them. A
+// resolution of 256 keeps one point per pixel of a standard tile.
+func WithTileResolution(n int) QueryOption {
+	return func(q *query) {
+		q.resolution = n
+	}
+}
+
+// Tile returns the points within the XYZ web map tile at zoom z, column x
+// and row y, as requested by Leaflet or OpenLayers. A point on the edge
+// between two tiles belongs to the one east or south of it, so the tiles
+// of a zoom never return the same point twice. Trees using the Mercator
+// coordinate system are queried in the projected coordinates of the tile,
+// other trees as latitude and longitude. Tiles outside the zoom are empty.
+func (qt *QuadTree) Tile(z, x, y int, opts ...QueryOption) []*Point {
+	if z < 0 || z > maxTileZoom || x < 0 || y < 0 || x >= 1<<z || y >= 1<<z {
+		return []*Point{}
+	}
+
+	qt.state.acquire()
+	defer qt.state.release()
+
+	tl := newTile(z, x, y, qt.state.coords != Mercator)
+	q := qt.newQuery(opts)
+
+	if q.resolution <= 0 {
+		q.where = append(q.where, tl.holds)
+		return qt.searchQuery(tl.box, q)
+	}
+
+	t := qt.begin()
+
+	tl.grid = newGrid(tl.proj, q.resolution, q.resolution)
+	tl.taken = make([]bool, q.resolution*q.resolution)
+
+	var results []*Point
+	qt.tile(tl, q, &results)
+
+	qt.end(t, "search", tl.box, 0)
+	return nonNil(results)
+}
+
+// tile is a web map tile being queried.
+type tile struct {
+	z, x, y int
+
+	// bounding box in projected and tree coordinates
+	proj *AABB
+	box  *AABB
+
+	// whether the tree holds latitude and longitude
+	geo bool
+
+	// cells of the tile, and whether each holds a point, when thinning
+	grid  *grid
+	taken []bool
+}
+
+func newTile(z, x, y int, geo bool) *tile {
+	tl := &tile{z: z, x: x, y: y, geo: geo}
+	tl.proj = WebMercatorTile(z, x, y)
+	tl.box = tl.proj
+
+	if geo {
+		p := tl.proj
+		minLat, minLng := WebMercator.Unproject(p.center.x-p.half.x, p.center.y-p.half.y)
+		maxLat, maxLng := WebMercator.Unproject(p.center.x+p.half.x, p.center.y+p.half.y)
+		tl.box = &AABB{
+			&Point{x: (minLat + maxLat) / 2, y: (minLng + maxLng) / 2},
+			&Point{x: (maxLat - minLat) / 2, y: (maxLng - minLng) / 2},
+		}
+	}
+
+	// widen the box searched so that rounding never loses the points on
+	// its edges, leaving holds to decide which tile they belong to
+	h := tl.box.half
+	tl.box = &AABB{tl.box.center, &Point{x: h.x * (1 + 1e-9), y: h.y * (1 + 1e-9)}}
+
+	return tl
+}
+
+// project returns the projected location of a point of the tree.
+func (tl *tile) project(p *Point) *Point {
+	if !tl.geo {
+		return p
+	}
+	x, y := WebMercator.Project(p.x, p.y)
+	return &Point{x: x, y: y}
+}
+
+// projectAABB returns the projected bounding box of a box of the tree.
+func (tl *tile) projectAABB(a *AABB) *AABB {
+	if !tl.geo {
+		return a
+	}
+	lo := tl.project(&Point{x: a.center.x - a.half.x, y: a.center.y - a.half.y})
+	hi := tl.project(&Point{x: a.center.x + a.half.x, y: a.center.y + a.half.y})
+	return &AABB{
+		&Point{x: (lo.x + hi.x) / 2, y: (lo.y + hi.y) / 2},
+		&Point{x: (hi.x - lo.x) / 2, y: (hi.y - lo.y) / 2},
+	}
+}
+
+// holds reports whether a point within the box of the tile belongs to it
+// rather than to a neighbour sharing its edge.
+func (tl *tile) holds(p *Point) bool {
+	m := tl.project(p)
+
+	extent := math.Pi * mercatorRadius
+	n := 1 << tl.z
+	size := 2 * extent / float64(n)
+
+	x := max(0, min(n-1, int(math.Floor((m.x+extent)/size))))
+	y := max(0, min(n-1, int(math.Floor((extent-m.y)/size))))
+	return x == tl.x && y == tl.y
+}
+
+// tile collects the first point found in each cell of the tile, skipping
+// nodes lying within a single cell which already holds one.
+func (qt *QuadTree) tile(tl *tile, q *query, results *[]*Point) {
+	if !qt.boundary.Intersect(tl.box) {
+		return
+	}
+
+	if r, c, ok := tl.grid.within(tl.proj, tl.projectAABB(qt.boundary)); ok && tl.taken[r*tl.grid.cols+c] {
+		return
+	}
+
+	for _, p := range qt.leaf() {
+		if !tl.box.ContainsPoint(p) || !tl.holds(p) || !q.match(p) {
+			continue
+		}
+		r, c := tl.grid.cell(tl.project(p))
+		if i := r*tl.grid.cols + c; !tl.taken[i] {
+			tl.taken[i] = true
+			*results = append(*results, p)
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.tile(tl, q, results)
+	}
+}
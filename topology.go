@@ -0,0 +1,102 @@
+package quadtree
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Topology is the node structure of a tree without its points, so a tuned
+// subdivision can be shipped and filled with points at runtime. It
+// marshals to JSON.
+type Topology struct {
+	Capacity int           `json:"capacity"`
+	MaxDepth int           `json:"max_depth"`
+	Root     *TopologyNode `json:"root"`
+}
+
+// TopologyNode is the boundary and depth of a node and its children.
+type TopologyNode struct {
+	Center   [2]float64      `json:"center"`
+	Half     [2]float64      `json:"half"`
+	Depth    int             `json:"depth"`
+	Children []*TopologyNode `json:"children,omitempty"`
+}
+
+// Topology returns the node structure of the tree.
+func (qt *QuadTree) Topology() *Topology {
+	return &Topology{
+		Capacity: qt.state.capacity,
+		MaxDepth: qt.state.maxDepth,
+		Root:     qt.topology(),
+	}
+}
+
+func (qt *QuadTree) topology() *TopologyNode {
+	n := &TopologyNode{
+		Center: [2]float64{qt.boundary.center.x, qt.boundary.center.y},
+		Half:   [2]float64{qt.boundary.half.x, qt.boundary.half.y},
+		Depth:  qt.depth,
+	}
+
+	if qt.nodes[0] != nil {
+		for _, node := range qt.nodes {
+			n.Children = append(n.Children, node.topology())
+		}
+	}
+
+	return n
+}
+
+// NewFromTopology creates an empty tree with the node structure provided,
+// using the capacity and maximum depth of the topology unless overridden
+// by the options. Nodes with other than four children are treated as
+// leaves.
+func NewFromTopology(t *Topology, opts ...Option) *QuadTree {
+	if t == nil || t.Root == nil {
+		return nil
+	}
+
+	if t.Capacity > 0 && t.MaxDepth > 0 {
+		opts = append([]Option{WithCapacity(t.Capacity), WithMaxDepth(t.MaxDepth)}, opts...)
+	}
+
+	qt := New(t.Root.aabb(), t.Root.Depth, nil, opts...)
+	qt.build(t.Root)
+	return qt
+}
+
+func (n *TopologyNode) aabb() *AABB {
+	return &AABB{
+		&Point{x: n.Center[0], y: n.Center[1]},
+		&Point{x: n.Half[0], y: n.Half[1]},
+	}
+}
+
+func (qt *QuadTree) build(n *TopologyNode) {
+	if len(n.Children) != 4 {
+		return
+	}
+
+	for i, c := range n.Children {
+		qt.nodes[i] = New(c.aabb(), c.Depth, qt)
+		qt.nodes[i].build(c)
+	}
+}
+
+// WriteTopology writes the node structure of the tree as JSON.
+func (qt *QuadTree) WriteTopology(w io.Writer) error {
+	return json.NewEncoder(w).Encode(qt.Topology())
+}
+
+// ReadTopology reads a JSON node structure written by WriteTopology and
+// creates an empty tree from it.
+func ReadTopology(r io.Reader, opts ...Option) (*QuadTree, error) {
+	var t Topology
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, err
+	}
+	if t.Root == nil {
+		return nil, ErrNilBoundary
+	}
+	return NewFromTopology(&t, opts...), nil
+}
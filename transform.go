@@ -0,0 +1,53 @@
+package quadtree
+
+// Map transforms each result of SearchValues and KNearestValues, e.g.
+// into DTOs or IDs, so the points themselves don't escape to the caller.
+func Map(fn func(*Point) interface{}) QueryOption {
+	return func(q *query) {
+		q.mapper = fn
+	}
+}
+
+func (q *query) value(p *Point) interface{} {
+	if q.mapper == nil {
+		return p
+	}
+	return q.mapper(p)
+}
+
+// SearchValues returns the points within the bounding box transformed by
+// the Map option as they are found, or the points themselves without it.
+func (qt *QuadTree) SearchValues(a *AABB, opts ...QueryOption) []interface{} {
+	results := []interface{}{}
+	if noBox(a) {
+		return results
+	}
+
+	q := qt.newQuery(opts)
+	if q.distinct != nil {
+		for _, p := range qt.Search(a, opts...) {
+			results = append(results, q.value(p))
+		}
+		return results
+	}
+
+	qt.visit(a, q, func(p *Point) bool {
+		results = append(results, q.value(p))
+		return true
+	})
+
+	return results
+}
+
+// KNearestValues returns the results of KNearest transformed by the Map
+// option, or the points themselves without it.
+func (qt *QuadTree) KNearestValues(a *AABB, i int, fn filter, opts ...QueryOption) []interface{} {
+	results := []interface{}{}
+
+	q := qt.newQuery(opts)
+	for _, p := range qt.KNearest(a, i, fn, opts...) {
+		results = append(results, q.value(p))
+	}
+
+	return results
+}
@@ -0,0 +1,43 @@
+package quadtree
+
+// TransformFunc converts a pair of coordinates.
+type TransformFunc func(x, y float64) (float64, float64)
+
+// Transform is a Projection built from a pair of functions converting
+// source coordinates into the coordinates stored by the tree and back,
+// e.g. a datum shift or unit conversion.
+type Transform struct {
+	In  TransformFunc
+	Out TransformFunc
+}
+
+// Project applies the In transform.
+func (t Transform) Project(x, y float64) (float64, float64) {
+	if t.In == nil {
+		return x, y
+	}
+	return t.In(x, y)
+}
+
+// Unproject applies the Out transform.
+func (t Transform) Unproject(x, y float64) (float64, float64) {
+	if t.Out == nil {
+		return x, y
+	}
+	return t.Out(x, y)
+}
+
+// NewTransformed creates a *ProjectedTree which applies the in transform to
+// every point and query box passed in, storing the results, while the
+// points returned remain in source coordinates. The boundary is given in
+// source coordinates. Query boxes are transformed by their corners so in
+// should preserve axis alignment.
+func NewTransformed(in, out TransformFunc, boundary *AABB, opts ...Option) *ProjectedTree {
+	return NewProjected(Transform{in, out}, boundary, opts...)
+}
+
+// Unproject returns the source coordinates of a location in the stored
+// coordinates of the tree, e.g. the center of a node boundary.
+func (pt *ProjectedTree) Unproject(x, y float64) (float64, float64) {
+	return pt.proj.Unproject(x, y)
+}
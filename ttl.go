@@ -0,0 +1,123 @@
+package quadtree
+
+import (
+	"sync"
+	"time"
+)
+
+// InsertWithTTL inserts a point which expires d after it was last inserted
+// or updated, so devices which stop reporting their location disappear.
+// Expired points are hidden from queries at once, as of WithNow if given,
+// and removed from the tree by Expire or in the background by ExpireEvery,
+// until which they are still counted by Len. A d of zero never expires.
+func (qt *QuadTree) InsertWithTTL(p *Point, d time.Duration) bool {
+	if p == nil {
+		return false
+	}
+
+	// a point removed while a snapshot still holds it is inserted as a
+	// copy
+	cp := qt.state.reinserting(p)
+	cp.setMeta().ttl = d
+	if d > 0 {
+		qt.state.expiring = true
+	}
+	if !qt.Insert(cp) {
+		return false
+	}
+	if cp != p {
+		qt.state.forwardTo(p, cp)
+	}
+	return true
+}
+
+// TTL returns the time the point lives after its last insert or update,
+// zero if it never expires.
+func (p *Point) TTL() time.Duration {
+	return p.meta().ttl
+}
+
+// expired reports whether the point has expired by the time in unix
+// nanoseconds.
+func (p *Point) expired(now int64) bool {
+	ttl := p.meta().ttl
+	return ttl > 0 && p.updated+int64(ttl) <= now
+}
+
+// Expire removes the expired points from the tree, returning the number
+// removed.
+func (qt *QuadTree) Expire() int {
+	if !qt.state.expiring || qt.state.readOnly {
+		return 0
+	}
+
+	var expired []*Point
+	qt.collectExpired(qt.state.clock().UnixNano(), &expired)
+
+	n := 0
+	for _, p := range expired {
+		if qt.remove(p) {
+			qt.dropped(p)
+			n++
+		}
+	}
+	return n
+}
+
+func (qt *QuadTree) collectExpired(now int64, expired *[]*Point) {
+	for _, p := range qt.leaf() {
+		if p.expired(now) {
+			*expired = append(*expired, p)
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.collectExpired(now, expired)
+	}
+}
+
+// ExpireEvery calls Expire every interval in the background until stop is
+// called. A QuadTree is not safe for concurrent use, so each sweep holds
+// mu, the lock guarding the tree's other uses.
+func (qt *QuadTree) ExpireEvery(interval time.Duration, mu sync.Locker) (stop func()) {
+	return every(interval, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		qt.Expire()
+	})
+}
+
+// every calls fn every interval in a goroutine until stop is called, stop
+// waiting for a call in progress to return.
+func every(interval time.Duration, fn func()) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}
+}
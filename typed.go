@@ -0,0 +1,85 @@
+package quadtree
+
+// TypedTree is a QuadTree whose points all carry data of type T, so data
+// is read back without type assertions.
+type TypedTree[T any] struct {
+	tree *QuadTree
+}
+
+// NewTyped creates a *TypedTree covering the boundary.
+func NewTyped[T any](boundary *AABB, opts ...Option) *TypedTree[T] {
+	return &TypedTree[T]{tree: New(boundary, 0, nil, opts...)}
+}
+
+// DataOf returns the data of a point as type T, reporting whether it
+// holds a T.
+func DataOf[T any](p *Point) (T, bool) {
+	v, ok := p.data.(T)
+	return v, ok
+}
+
+// Tree returns the underlying QuadTree.
+func (t *TypedTree[T]) Tree() *QuadTree {
+	return t.tree
+}
+
+// Insert inserts a point carrying the data at x, y, returning the point
+// or nil if it could not be inserted.
+func (t *TypedTree[T]) Insert(x, y float64, data T) *Point {
+	p := NewPoint(x, y, data)
+	if !t.tree.Insert(p) {
+		return nil
+	}
+	return p
+}
+
+// InsertID inserts a point carrying the data and an ID at x, y, returning
+// the point or nil if it could not be inserted.
+func (t *TypedTree[T]) InsertID(id string, x, y float64, data T) *Point {
+	p := NewPointID(id, x, y, data)
+	if !t.tree.Insert(p) {
+		return nil
+	}
+	return p
+}
+
+// Remove removes a point returned by Insert.
+func (t *TypedTree[T]) Remove(p *Point) bool {
+	return t.tree.Remove(p)
+}
+
+// Update moves a point returned by Insert to the location of np.
+func (t *TypedTree[T]) Update(p *Point, np *Point) bool {
+	return t.tree.Update(p, np)
+}
+
+// Data returns the data of a point returned by Insert.
+func (t *TypedTree[T]) Data(p *Point) T {
+	v, _ := DataOf[T](p)
+	return v
+}
+
+// Search returns the data of the points within the bounding box.
+func (t *TypedTree[T]) Search(a *AABB, opts ...QueryOption) []T {
+	return t.values(t.tree.Search(a, opts...))
+}
+
+// KNearest returns the data of the k nearest points within the bounding
+// box whose data matches fn, if not nil.
+func (t *TypedTree[T]) KNearest(a *AABB, k int, fn func(T) bool, opts ...QueryOption) []T {
+	var pfn filter
+	if fn != nil {
+		pfn = func(p *Point) bool {
+			return fn(t.Data(p))
+		}
+	}
+	return t.values(t.tree.KNearest(a, k, pfn, opts...))
+}
+
+func (t *TypedTree[T]) values(points []*Point) []T {
+	values := make([]T, len(points))
+	for i, p := range points {
+		values[i] = t.Data(p)
+	}
+	return values
+}
@@ -0,0 +1,150 @@
+package quadtree
+
+import (
+	"math"
+)
+
+const (
+	// WGS-84 ellipsoid major semiaxis [m] and flattening
+	wgs84Major      = 6378137.0
+	wgs84Flattening = 1 / 298.257223563
+	// UTM central meridian scale factor
+	utmScale = 0.9996
+	// UTM false easting and southern hemisphere false northing [m]
+	utmEasting  = 500000.0
+	utmNorthing = 10000000.0
+)
+
+type utm struct {
+	zone  int
+	north bool
+}
+
+// UTM returns the Universal Transverse Mercator projection for the zone
+// (1-60) and hemisphere provided. Projected coordinates are metres, x
+// easting and y northing, accurate to well below a centimetre within
+// the zone.
+func UTM(zone int, north bool) Projection {
+	return utm{zone, north}
+}
+
+// UTMZone returns the UTM zone number and hemisphere for a latitude and
+// longitude, including the Norway and Svalbard exceptions.
+func UTMZone(lat, lng float64) (int, bool) {
+	lng = math.Mod(lng+180, 360)
+	if lng < 0 {
+		lng += 360
+	}
+	zone := int(lng/6) + 1
+	lng -= 180
+
+	switch {
+	case lat >= 56 && lat < 64 && lng >= 3 && lng < 12:
+		zone = 32
+	case lat >= 72 && lat < 84:
+		switch {
+		case lng >= 0 && lng < 9:
+			zone = 31
+		case lng >= 9 && lng < 21:
+			zone = 33
+		case lng >= 21 && lng < 33:
+			zone = 35
+		case lng >= 33 && lng < 42:
+			zone = 37
+		}
+	}
+
+	return zone, lat >= 0
+}
+
+// NewUTM creates a *ProjectedTree in the UTM zone provided covering the
+// geographic boundary. Distances between the stored points are planar
+// metres consistent within the zone.
+func NewUTM(zone int, north bool, boundary *AABB, opts ...Option) *ProjectedTree {
+	return NewProjected(UTM(zone, north), boundary, opts...)
+}
+
+func (u utm) meridian() float64 {
+	return deg2Rad(float64(u.zone-1)*6 - 180 + 3)
+}
+
+func (u utm) Project(lat, lng float64) (float64, float64) {
+	a := wgs84Major
+	e2 := wgs84Flattening * (2 - wgs84Flattening)
+	ep2 := e2 / (1 - e2)
+
+	phi := deg2Rad(lat)
+	sin, cos, tan := math.Sin(phi), math.Cos(phi), math.Tan(phi)
+
+	n := a / math.Sqrt(1-e2*sin*sin)
+	t := tan * tan
+	c := ep2 * cos * cos
+	A := cos * (deg2Rad(lng) - u.meridian())
+	m := utmArc(phi, e2)
+
+	x := utmScale*n*(A+(1-t+c)*math.Pow(A, 3)/6+
+		(5-18*t+t*t+72*c-58*ep2)*math.Pow(A, 5)/120) + utmEasting
+
+	y := utmScale * (m + n*tan*(A*A/2+
+		(5-t+9*c+4*c*c)*math.Pow(A, 4)/24+
+		(61-58*t+t*t+600*c-330*ep2)*math.Pow(A, 6)/720))
+
+	if !u.north {
+		y += utmNorthing
+	}
+
+	return x, y
+}
+
+func (u utm) Unproject(x, y float64) (float64, float64) {
+	a := wgs84Major
+	e2 := wgs84Flattening * (2 - wgs84Flattening)
+	ep2 := e2 / (1 - e2)
+
+	if !u.north {
+		y -= utmNorthing
+	}
+	x -= utmEasting
+
+	mu := y / utmScale / (a * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+
+	phi1 := mu + (3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+		(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+		(151*math.Pow(e1, 3)/96)*math.Sin(6*mu) +
+		(1097*math.Pow(e1, 4)/512)*math.Sin(8*mu)
+
+	sin, cos, tan := math.Sin(phi1), math.Cos(phi1), math.Tan(phi1)
+
+	n1 := a / math.Sqrt(1-e2*sin*sin)
+	t1 := tan * tan
+	c1 := ep2 * cos * cos
+	r1 := a * (1 - e2) / math.Pow(1-e2*sin*sin, 1.5)
+	d := x / (n1 * utmScale)
+
+	phi := phi1 - (n1*tan/r1)*(d*d/2-
+		(5+3*t1+10*c1-4*c1*c1-9*ep2)*math.Pow(d, 4)/24+
+		(61+90*t1+298*c1+45*t1*t1-252*ep2-3*c1*c1)*math.Pow(d, 6)/720)
+
+	lng := u.meridian() + (d-(1+2*t1+c1)*math.Pow(d, 3)/6+
+		(5-2*c1+28*t1-3*c1*c1+8*ep2+24*t1*t1)*math.Pow(d, 5)/120)/cos
+
+	return rad2Deg(phi), rad2Deg(lng)
+}
+
+// utmArc returns the meridian arc length from the equator to latitude phi.
+func utmArc(phi, e2 float64) float64 {
+	e4, e6 := e2*e2, e2*e2*e2
+	return wgs84Major * ((1-e2/4-3*e4/64-5*e6/256)*phi -
+		(3*e2/8+3*e4/32+45*e6/1024)*math.Sin(2*phi) +
+		(15*e4/256+45*e6/1024)*math.Sin(4*phi) -
+		(35*e6/3072)*math.Sin(6*phi))
+}
+
+// Distance returns the planar distance between two geographic points in
+// projected units, metres for UTM and Web Mercator.
+func (pt *ProjectedTree) Distance(p, q *Point) float64 {
+	x1, y1 := pt.proj.Project(p.x, p.y)
+	x2, y2 := pt.proj.Project(q.x, q.y)
+	return math.Hypot(x2-x1, y2-y1)
+}
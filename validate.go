@@ -0,0 +1,187 @@
+package quadtree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Validate checks the structural invariants of the tree, returning an
+// error wrapping ErrInvalidTree which describes the first broken: that
+// children divide their parent into quadrants one level deeper, only
+// leaves hold points, each point lies within its leaf and appears once,
+// the counters, bounds and morton order of each node match the points
+// beneath it, and the ID index and tenant counts match the points of the
+// tree. It walks every node, so is meant for tests and debugging rather
+// than for use in production.
+func (qt *QuadTree) Validate() error {
+	v := &validator{
+		seen:     make(map[*Point]bool),
+		tenants:  make(map[string]int),
+		maxDepth: qt.state.maxDepth,
+	}
+
+	if err := qt.validate("", v); err != nil {
+		return err
+	}
+
+	if qt.parent != nil {
+		return nil
+	}
+
+	s := qt.state
+	if s.count != qt.total {
+		return invalid("", "tree counts %d points but holds %d", s.count, qt.total)
+	}
+
+	for id, p := range s.index() {
+		if !v.seen[p] {
+			return invalid("", "id %q indexes a point not in the tree", id)
+		}
+		if p.id != id {
+			return invalid("", "id %q indexes a point with id %q", id, p.id)
+		}
+	}
+	if s.policy != IDAllow {
+		for p := range v.seen {
+			if p.id != "" && s.index()[p.id] != p {
+				return invalid("", "point with id %q is not indexed", p.id)
+			}
+		}
+	}
+
+	for tenant, n := range v.tenants {
+		if s.tenants[tenant] != n {
+			return invalid("", "tenant %q counts %d points but holds %d", tenant, s.tenants[tenant], n)
+		}
+	}
+	for tenant, n := range s.tenants {
+		if n != 0 && v.tenants[tenant] == 0 {
+			return invalid("", "tenant %q counts %d points but holds none", tenant, n)
+		}
+	}
+
+	return nil
+}
+
+// validator collects the points of the tree while validating its nodes.
+type validator struct {
+	seen     map[*Point]bool
+	tenants  map[string]int
+	maxDepth int
+}
+
+func invalid(path, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s: %s", ErrInvalidTree, label(path), fmt.Sprintf(format, args...))
+}
+
+func (qt *QuadTree) validate(path string, v *validator) error {
+	if qt.boundary == nil || qt.boundary.center == nil || qt.boundary.half == nil {
+		return invalid(path, "missing boundary")
+	}
+
+	points := qt.leaf()
+	total := len(points)
+
+	for _, p := range points {
+		if p == nil {
+			return invalid(path, "nil point")
+		}
+		if !qt.boundary.ContainsPoint(p) {
+			return invalid(path, "point (%v, %v) outside boundary %s", p.x, p.y, boxString(qt.boundary))
+		}
+		if v.seen[p] {
+			return invalid(path, "point (%v, %v) held twice", p.x, p.y)
+		}
+		v.seen[p] = true
+		v.tenants[p.meta().tenant]++
+
+		if p.meta().radius > qt.radius {
+			return invalid(path, "point radius %v exceeds node bound %v", p.meta().radius, qt.radius)
+		}
+		if p.Weight() > qt.weight {
+			return invalid(path, "point weight %v exceeds node bound %v", p.Weight(), qt.weight)
+		}
+		if p.meta().tagBits&^qt.tags != 0 {
+			return invalid(path, "point tags %v missing from node", p.meta().tags)
+		}
+	}
+
+	if qt.codes != nil {
+		if len(qt.codes) != len(points) {
+			return invalid(path, "%d morton codes for %d points", len(qt.codes), len(points))
+		}
+		for i, p := range points {
+			if qt.codes[i] != morton(qt.boundary, p) {
+				return invalid(path, "stale morton code of point (%v, %v)", p.x, p.y)
+			}
+		}
+		if !sort.SliceIsSorted(qt.codes, func(i, j int) bool { return qt.codes[i] < qt.codes[j] }) {
+			return invalid(path, "points out of morton order")
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		for _, node := range qt.nodes {
+			if node != nil {
+				return invalid(path, "partially divided")
+			}
+		}
+	} else {
+		if len(points) > 0 {
+			return invalid(path, "divided node holds %d points", len(points))
+		}
+
+		b := qt.boundary
+		half := &Point{x: b.half.x / 2, y: b.half.y / 2}
+		centers := [4]*Point{
+			{x: b.center.x - half.x, y: b.center.y + half.y},
+			{x: b.center.x + half.x, y: b.center.y + half.y},
+			{x: b.center.x - half.x, y: b.center.y - half.y},
+			{x: b.center.x + half.x, y: b.center.y - half.y},
+		}
+
+		for i, node := range qt.nodes {
+			cpath := fmt.Sprintf("%d", i)
+			if path != "" {
+				cpath = path + "." + cpath
+			}
+
+			if node == nil {
+				return invalid(path, "partially divided")
+			}
+			// nodes shared with a snapshot keep the parent they were
+			// written under
+			if node.owned() && node.parent != qt {
+				return invalid(cpath, "parent is not the node divided")
+			}
+			// the children of a snapshot are those of the tree
+			if node.state != qt.state && !qt.state.readOnly {
+				return invalid(cpath, "state not shared with parent")
+			}
+			if node.depth != qt.depth+1 {
+				return invalid(cpath, "depth %d under a node of depth %d", node.depth, qt.depth)
+			}
+			c := node.boundary.center
+			if c.x != centers[i].x || c.y != centers[i].y || node.boundary.half.x != half.x || node.boundary.half.y != half.y {
+				return invalid(cpath, "boundary %s is not quadrant %d of %s", boxString(node.boundary), i, boxString(b))
+			}
+			if node.radius > qt.radius || node.weight > qt.weight || node.tags&^qt.tags != 0 {
+				return invalid(cpath, "bounds exceed those of the parent")
+			}
+
+			if err := node.validate(cpath, v); err != nil {
+				return err
+			}
+			total += node.total
+		}
+	}
+
+	if qt.depth > v.maxDepth {
+		return invalid(path, "depth %d beyond max depth %d", qt.depth, v.maxDepth)
+	}
+	if qt.total != total {
+		return invalid(path, "counts %d points but holds %d", qt.total, total)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package quadtree
+
+// Version returns the version of a point, incremented each time it is
+// moved by Update or its data replaced by SetData.
+func (p *Point) Version() uint64 {
+	return p.meta().version
+}
+
+// SetData replaces the data stored within a point, incrementing its
+// version. Use QuadTree.SetData for a point within a tree so its size and
+// aggregates follow the new data.
+func (p *Point) SetData(data interface{}) {
+	p.data = data
+	p.setMeta().version++
+}
+
+// SetData replaces the data of the point with the ID in place, without
+// moving it through the tree, incrementing its version and timestamping
+// it as updated. Its size is measured again and the aggregates above it
+// recomputed. It returns ErrNotFound if no point has the ID, ErrReadOnly
+// for a snapshot, ErrRegionLocked if the point is within a locked region,
+// ErrRateLimited beyond the WithWriteRate limit and ErrQuotaExceeded if
+// the new data would take the tree over its WithMaxBytes limit.
+func (qt *QuadTree) SetData(id string, data interface{}) error {
+	s := qt.state
+
+	p, ok := s.index()[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	if s.locked(p) {
+		return ErrRegionLocked
+	}
+
+	p = qt.writable(p)
+	prev := p.data
+	p.data = data
+	size := qt.sizeOf(p)
+	if s.maxBytes > 0 && s.bytes-p.size()+size > s.maxBytes {
+		p.data = prev
+		return ErrQuotaExceeded
+	}
+	if !s.allowWrite() {
+		p.data = prev
+		return ErrRateLimited
+	}
+
+	s.bytes += size - p.size()
+	p.setSize(size)
+	p.setMeta().version++
+	s.updated(p)
+	s.changes.send(OpUpdate, p)
+
+	if s.aggregates {
+		if node := qt.root().owner(p); node != nil {
+			node.touch()
+		}
+	}
+	return nil
+}
+
+// UpdateIfVersion moves a point like Update only if it is still at the
+// version provided, returning ErrVersionConflict otherwise, or the errors
+// of UpdateErr if it cannot be moved. Writers applying updates out of
+// order use it to detect conflicting writes.
+func (qt *QuadTree) UpdateIfVersion(p *Point, np *Point, version uint64) error {
+	if p == nil || np == nil {
+		return ErrNilPoint
+	}
+	p = qt.state.current(p)
+	if p.meta().version != version {
+		return ErrVersionConflict
+	}
+	return qt.UpdateErr(p, np)
+}
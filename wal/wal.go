@@ -0,0 +1,579 @@
+// Package wal makes a QuadTree durable by appending every insert, remove
+// and update to a write-ahead log, replayed on top of the last snapshot
+// when the log is opened again after a restart or crash.
+//
+// A log directory holds two files: snapshot, the tree as written by
+// MarshalBinary, and log, one JSON record per line of each write applied
+// since. Compact writes a new snapshot and truncates the log.
+//
+// Points are identified by ID, so every point written through the log must
+// have one and IDs must be unique within the tree.
+//
+// Each write is applied to the tree, then logged and synced to disk before
+// it returns, and undone if it cannot be logged. WithGroupCommit syncs the
+// writes made within a window together, for frequent writes such as
+// position updates.
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/asim/quadtree"
+)
+
+const (
+	snapshotFile = "snapshot"
+	logFile      = "log"
+)
+
+var (
+	// ErrNoID is returned when writing a point without an ID.
+	ErrNoID = errors.New("wal: point has no id")
+	// ErrNotApplied is returned when the tree rejects a write, which is
+	// then not logged.
+	ErrNotApplied = errors.New("wal: write not applied")
+	// ErrClosed is returned when writing to a closed log.
+	ErrClosed = errors.New("wal: log closed")
+	// ErrCorrupt is returned opening a log holding a record which cannot
+	// be read or replayed, other than a final record left incomplete by a
+	// crash.
+	ErrCorrupt = errors.New("wal: log corrupt")
+)
+
+// record is a write as stored in the log.
+type record struct {
+	Op   string  `json:"op"`
+	ID   string  `json:"id"`
+	X    float64 `json:"x,omitempty"`
+	Y    float64 `json:"y,omitempty"`
+	Data []byte  `json:"data,omitempty"`
+}
+
+// Log applies writes to a tree and records them in a write-ahead log. It
+// is safe for concurrent use, but the tree must not be modified other than
+// through the log, and queries running alongside writes must be
+// synchronised by the caller.
+type Log struct {
+	mu   sync.Mutex
+	tree *quadtree.QuadTree
+	dir  string
+	f    *os.File
+	// bytes of the log file holding committed records
+	size int64
+
+	codec        quadtree.Codec
+	sync         bool
+	compactEvery int
+	// records written since the last snapshot
+	records int
+
+	// longest a write waits to be synced, 0 to sync every write
+	latency time.Duration
+	// the writes waiting to be committed, nil if there are none
+	batch *batch
+	// error leaving the log file unusable, returned by every write since
+	err error
+}
+
+// batch is the writes committed to the log file together.
+type batch struct {
+	// records waiting to be written
+	buf []byte
+	// undo the writes to the tree, in the order made
+	undo []func()
+	// commits the batch once the latency has passed
+	timer *time.Timer
+	// closed once committed, after which err is its error
+	done chan struct{}
+	err  error
+}
+
+// Option configures a Log.
+type Option func(*Log)
+
+// WithCodec sets the codec used to encode point data in the log, JSON by
+// default. It should match the codec the tree uses for snapshots.
+func WithCodec(c quadtree.Codec) Option {
+	return func(l *Log) {
+		l.codec = c
+	}
+}
+
+// WithSync sets whether the log is synced to disk after every write,
+// enabled by default. Without it a crash may lose the most recent writes.
+func WithSync(sync bool) Option {
+	return func(l *Log) {
+		l.sync = sync
+	}
+}
+
+// WithGroupCommit syncs writes to disk together, at most the latency after
+// the first of them, rather than each as it is made. Writes are applied to
+// the tree as made, then wait for their batch to be committed, or for Sync,
+// and return its error, so concurrent writers share a sync at the cost of
+// waiting up to the latency. If the batch cannot be written each of its
+// writes is undone. Without WithSync the writes are only flushed.
+func WithGroupCommit(latency time.Duration) Option {
+	return func(l *Log) {
+		l.latency = latency
+	}
+}
+
+// WithCompactEvery compacts the log once n records have been written
+// since the last snapshot. Logs are only compacted by Compact if n is 0.
+func WithCompactEvery(n int) Option {
+	return func(l *Log) {
+		l.compactEvery = n
+	}
+}
+
+// Open restores the tree from the snapshot and log in dir, creating the
+// directory if it does not exist, and returns a Log appending to it. The
+// tree should be empty and created with the same options as when the log
+// was written. A final record left incomplete by a crash is discarded,
+// but ErrCorrupt is returned for any other record which cannot be read or
+// replayed.
+func Open(dir string, tree *quadtree.QuadTree, opts ...Option) (*Log, error) {
+	l := &Log{
+		tree:  tree,
+		dir:   dir,
+		codec: quadtree.JSONCodec,
+		sync:  true,
+	}
+
+	for _, o := range opts {
+		o(l)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	if err := restore(filepath.Join(dir, snapshotFile), tree); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := l.replay(f)
+	if err == nil {
+		// drop a partial record and append after the last complete one
+		err = f.Truncate(n)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	l.f = f
+	l.size = n
+	return l, nil
+}
+
+// restore restores the tree from the snapshot, if there is one, decoding
+// it in parallel as it is read.
+func restore(path string, tree *quadtree.QuadTree) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = tree.ReadFrom(f)
+	return err
+}
+
+// replay applies the complete records of the log to the tree, returning
+// the offset following the last of them. Only the final record may be
+// incomplete, having no newline, as when a crash interrupts its write.
+func (l *Log) replay(r io.Reader) (int64, error) {
+	var n int64
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return n, fmt.Errorf("%w: record at offset %d: %w", ErrCorrupt, n, err)
+		}
+		if err := l.apply(rec); err != nil {
+			return n, fmt.Errorf("%w: %s of %q at offset %d: %w", ErrCorrupt, rec.Op, rec.ID, n, err)
+		}
+
+		n += int64(len(line))
+		l.records++
+	}
+}
+
+// apply replays a record. Records are applied so that replaying those
+// already included in the snapshot, after a crash during Compact, leaves
+// the tree unchanged, so the point a record names may have been removed.
+// It returns ErrNotApplied if the tree refuses a record.
+func (l *Log) apply(rec record) error {
+	switch rec.Op {
+	case "insert":
+		data, err := l.data(rec)
+		if err != nil {
+			return err
+		}
+		if p := l.tree.Get(rec.ID); p != nil && !l.tree.Remove(p) {
+			return ErrNotApplied
+		}
+		if !l.tree.Insert(quadtree.NewPointID(rec.ID, rec.X, rec.Y, data)) {
+			return ErrNotApplied
+		}
+	case "remove":
+		if p := l.tree.Get(rec.ID); p != nil && !l.tree.Remove(p) {
+			return ErrNotApplied
+		}
+	case "update", "set":
+		data, err := l.data(rec)
+		if err != nil {
+			return err
+		}
+		p := l.tree.Get(rec.ID)
+		if p == nil {
+			return nil
+		}
+		if rec.Op == "set" {
+			p.SetData(data)
+		}
+		if !l.tree.MoveTo(p, rec.X, rec.Y) {
+			return ErrNotApplied
+		}
+	default:
+		return fmt.Errorf("unknown op %q", rec.Op)
+	}
+	return nil
+}
+
+// data decodes the point data of a record.
+func (l *Log) data(rec record) (interface{}, error) {
+	if rec.Data == nil {
+		return nil, nil
+	}
+	return l.codec.Unmarshal(rec.Data)
+}
+
+// Tree returns the tree written to by the log.
+func (l *Log) Tree() *quadtree.QuadTree {
+	return l.tree
+}
+
+// Insert inserts the point into the tree and logs it.
+func (l *Log) Insert(p *quadtree.Point) error {
+	if p == nil {
+		return quadtree.ErrNilPoint
+	}
+	if p.ID() == "" {
+		return ErrNoID
+	}
+
+	rec := record{Op: "insert", ID: p.ID()}
+	rec.X, rec.Y = p.Coordinates()
+
+	if d := p.Data(); d != nil {
+		b, err := l.codec.Marshal(d)
+		if err != nil {
+			return err
+		}
+		rec.Data = b
+	}
+
+	return l.write(rec, func() (func(), error) {
+		old := l.tree.Get(p.ID())
+		if err := l.tree.InsertAll([]*quadtree.Point{p})[0]; err != nil {
+			return nil, err
+		}
+		return func() {
+			l.tree.Remove(p)
+			// put back the point replaced
+			if old != nil && !l.tree.Contains(old) {
+				l.tree.Insert(old)
+			}
+		}, nil
+	})
+}
+
+// Remove removes the point with the ID from the tree and logs it.
+func (l *Log) Remove(id string) error {
+	return l.write(record{Op: "remove", ID: id}, func() (func(), error) {
+		p := l.tree.Get(id)
+		if p == nil {
+			return nil, quadtree.ErrNotFound
+		}
+		if !l.tree.Remove(p) {
+			return nil, ErrNotApplied
+		}
+		return func() { l.tree.Insert(p) }, nil
+	})
+}
+
+// Update moves the point with the ID to x, y and logs it. A point is not
+// moved out of the bounds of the tree.
+func (l *Log) Update(id string, x, y float64) error {
+	return l.write(record{Op: "update", ID: id, X: x, Y: y}, func() (func(), error) {
+		return l.move(id, x, y, nil, false)
+	})
+}
+
+// UpdateData is Update also replacing the data of the point, which is set
+// before the point moves so hooks of the move see it.
+func (l *Log) UpdateData(id string, x, y float64, data interface{}) error {
+	rec := record{Op: "set", ID: id, X: x, Y: y}
+	if data != nil {
+		b, err := l.codec.Marshal(data)
+		if err != nil {
+			return err
+		}
+		rec.Data = b
+	}
+
+	return l.write(rec, func() (func(), error) {
+		return l.move(id, x, y, data, true)
+	})
+}
+
+// move moves the point with the ID to x, y, replacing its data if set,
+// and returns a function moving it back.
+func (l *Log) move(id string, x, y float64, data interface{}, set bool) (func(), error) {
+	p := l.tree.Get(id)
+	if p == nil {
+		return nil, quadtree.ErrNotFound
+	}
+	if !l.tree.Boundary().ContainsPoint(quadtree.NewPoint(x, y, nil)) {
+		return nil, quadtree.ErrOutOfBounds
+	}
+
+	ox, oy := p.Coordinates()
+	old := p.Data()
+	if set {
+		p.SetData(data)
+	}
+	if !l.tree.MoveTo(p, x, y) {
+		p.SetData(old)
+		return nil, ErrNotApplied
+	}
+
+	return func() {
+		p.SetData(old)
+		l.tree.MoveTo(p, ox, oy)
+	}, nil
+}
+
+// write applies a write to the tree with apply, which returns a function
+// undoing it, then logs its record, returning once the record is
+// committed. The write is undone if the record cannot be committed, so the
+// tree holds only the writes logged.
+func (l *Log) write(rec record, apply func() (func(), error)) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+
+	if err := l.writable(); err != nil {
+		l.mu.Unlock()
+		return err
+	}
+
+	undo, err := apply()
+	if err != nil {
+		l.mu.Unlock()
+		return err
+	}
+
+	if l.batch == nil {
+		l.batch = &batch{done: make(chan struct{})}
+		if l.latency > 0 {
+			bt := l.batch
+			bt.timer = time.AfterFunc(l.latency, func() { l.groupCommit(bt) })
+		}
+	}
+	bt := l.batch
+	bt.buf = append(append(bt.buf, b...), '\n')
+	bt.undo = append(bt.undo, undo)
+
+	if l.latency <= 0 {
+		err := l.commit()
+		l.mu.Unlock()
+		return err
+	}
+
+	l.mu.Unlock()
+	<-bt.done
+	return bt.err
+}
+
+// writable returns the error refusing writes to the log, first compacting
+// it if due.
+func (l *Log) writable() error {
+	if l.f == nil {
+		return ErrClosed
+	}
+	if l.err != nil {
+		return l.err
+	}
+	if l.compactEvery > 0 && l.records >= l.compactEvery {
+		return l.compact()
+	}
+	return nil
+}
+
+// commit writes the records of the batch waiting to the log file, syncing
+// it unless WithSync is disabled, and ends the batch with its error. If
+// the records cannot be written the writes of the batch are undone, latest
+// first, and the records dropped from the log file.
+func (l *Log) commit() error {
+	bt := l.batch
+	if bt == nil {
+		return nil
+	}
+	l.batch = nil
+	if bt.timer != nil {
+		bt.timer.Stop()
+	}
+
+	_, err := l.f.WriteAt(bt.buf, l.size)
+	if err == nil && l.sync {
+		err = l.f.Sync()
+	}
+
+	if err == nil {
+		l.size += int64(len(bt.buf))
+		l.records += len(bt.undo)
+	} else {
+		for i := len(bt.undo) - 1; i >= 0; i-- {
+			bt.undo[i]()
+		}
+		// records written in part would be replayed
+		if terr := l.f.Truncate(l.size); terr != nil {
+			l.err = fmt.Errorf("wal: log unusable after %w: %w", err, terr)
+		}
+	}
+
+	bt.err = err
+	close(bt.done)
+	return err
+}
+
+// groupCommit commits the batch once its latency has passed, unless Sync,
+// Compact or Close have committed it since.
+func (l *Log) groupCommit(bt *batch) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.batch == bt {
+		l.commit()
+	}
+}
+
+// Sync commits the writes waiting for a group commit now, returning the
+// error of their batch.
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return ErrClosed
+	}
+	return l.commit()
+}
+
+// Compact writes a snapshot of the tree and truncates the log.
+func (l *Log) Compact() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return ErrClosed
+	}
+	return l.compact()
+}
+
+func (l *Log) compact() error {
+	// the snapshot must not hold writes which may yet be undone
+	if err := l.commit(); err != nil {
+		return err
+	}
+
+	b, err := l.tree.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	if err := writeFile(filepath.Join(l.dir, snapshotFile), b); err != nil {
+		return err
+	}
+
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	l.size = 0
+	l.records = 0
+
+	return l.f.Sync()
+}
+
+// writeFile replaces the file with b only once b is completely written.
+func writeFile(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, bytes.NewReader(b)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Close commits any writes waiting for a group commit, returning the error
+// of their batch, and closes the log file. The tree remains usable.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return ErrClosed
+	}
+
+	err := l.commit()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
@@ -0,0 +1,268 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asim/quadtree"
+)
+
+func newTree() *quadtree.QuadTree {
+	return quadtree.New(quadtree.WorldBounds(), 0, nil, quadtree.WithUniqueIDs(quadtree.IDReplace))
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := Open(dir, newTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{
+		l.Insert(quadtree.NewPointID("a", 1, 2, "one")),
+		l.Insert(quadtree.NewPointID("b", 3, 4, nil)),
+		l.Insert(quadtree.NewPointID("c", 5, 6, nil)),
+		l.UpdateData("a", 7, 8, "two"),
+		l.Update("b", 9, 10),
+		l.Remove("c"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.UpdateData("a", 100, 0, "three"); err != quadtree.ErrOutOfBounds {
+		t.Fatalf("UpdateData out of bounds returned %v, want ErrOutOfBounds", err)
+	}
+	if d := l.Tree().Get("a").Data(); d != "two" {
+		t.Fatalf("a refused update left data %v, want two", d)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// replayed from the log alone, compacted, then from the snapshot
+	for _, compact := range []bool{true, false} {
+		l, err := Open(dir, newTree())
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree := l.Tree()
+
+		if tree.Len() != 2 || tree.Get("c") != nil {
+			t.Fatalf("replayed tree holds %d points, want a and b", tree.Len())
+		}
+		a, b := tree.Get("a"), tree.Get("b")
+		if x, y := a.Coordinates(); x != 7 || y != 8 || a.Data() != "two" {
+			t.Fatalf("a replayed at %v, %v with %v, want 7, 8 with two", x, y, a.Data())
+		}
+		if x, y := b.Coordinates(); x != 9 || y != 10 {
+			t.Fatalf("b replayed at %v, %v, want 9, 10", x, y)
+		}
+
+		if compact {
+			if err := l.Compact(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReplayCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, logFile)
+
+	l, err := Open(dir, newTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := l.Insert(quadtree.NewPointID(id, 1, 2, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	logged, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a record torn by a crash is dropped
+	torn := append(append([]byte(nil), logged...), `{"op":"insert","id":"c"`...)
+	if err := os.WriteFile(path, torn, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err = Open(dir, newTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := l.Tree().Len(); n != 2 {
+		t.Fatalf("replayed %d points past a torn record, want 2", n)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); len(b) != len(logged) {
+		t.Fatalf("log holds %d bytes after dropping the torn record, want %d", len(b), len(logged))
+	}
+
+	// any other record which cannot be replayed fails
+	for name, rec := range map[string]string{
+		"invalid":       `{"op":"insert",` + "\n",
+		"unknown op":    `{"op":"rename","id":"a"}` + "\n",
+		"out of bounds": `{"op":"insert","id":"c","x":100,"y":0}` + "\n",
+	} {
+		corrupt := append([]byte(rec), logged...)
+		if err := os.WriteFile(path, corrupt, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(dir, newTree()); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("Open of a log with an %s record returned %v, want ErrCorrupt", name, err)
+		}
+	}
+}
+
+func TestGroupCommit(t *testing.T) {
+	dir := t.TempDir()
+	size := func() int64 {
+		fi, err := os.Stat(filepath.Join(dir, logFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Size()
+	}
+
+	// writes wait for Sync, or Close, rather than syncing as made
+	l, err := Open(dir, newTree(), WithGroupCommit(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error)
+	for _, id := range []string{"a", "b"} {
+		go func() {
+			errs <- l.Insert(quadtree.NewPointID(id, 1, 2, nil))
+		}()
+	}
+	waiting(t, l, 2)
+	if n := size(); n != 0 {
+		t.Fatalf("log holds %d bytes before the group commit, want 0", n)
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	synced := size()
+	if synced == 0 {
+		t.Fatal("Sync left the writes waiting")
+	}
+
+	go func() {
+		errs <- l.Remove("a")
+	}()
+	waiting(t, l, 1)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if size() <= synced {
+		t.Fatal("Close left the writes waiting")
+	}
+
+	// writes are committed once the latency has passed
+	l, err = Open(dir, newTree(), WithGroupCommit(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree := l.Tree(); tree.Len() != 1 || tree.Get("b") == nil {
+		t.Fatalf("replayed tree holds %d points, want b", tree.Len())
+	}
+
+	closed := size()
+	if err := l.Update("b", 3, 4); err != nil {
+		t.Fatal(err)
+	}
+	if size() == closed {
+		t.Fatal("write returned before it was committed")
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waiting waits until n writes are waiting for a group commit.
+func waiting(t *testing.T, l *Log, n int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		l.mu.Lock()
+		bt := l.batch
+		done := bt != nil && len(bt.undo) == n
+		l.mu.Unlock()
+
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d writes not waiting for the group commit", n)
+		}
+	}
+}
+
+func TestUndo(t *testing.T) {
+	writes := map[string]func(l *Log) error{
+		"insert":  func(l *Log) error { return l.Insert(quadtree.NewPointID("c", 5, 6, nil)) },
+		"replace": func(l *Log) error { return l.Insert(quadtree.NewPointID("b", 5, 6, nil)) },
+		"remove":  func(l *Log) error { return l.Remove("b") },
+		"update":  func(l *Log) error { return l.UpdateData("a", 7, 8, "two") },
+	}
+
+	for _, opts := range [][]Option{nil, {WithGroupCommit(time.Millisecond)}} {
+		for op, write := range writes {
+			l, err := Open(t.TempDir(), newTree(), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tree := l.Tree()
+
+			for _, err := range []error{
+				l.Insert(quadtree.NewPointID("a", 1, 2, "one")),
+				l.Insert(quadtree.NewPointID("b", 3, 4, nil)),
+			} {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// the log file can no longer be written
+			l.f.Close()
+
+			if err := write(l); err == nil {
+				t.Fatalf("%s to an unwritable log returned no error", op)
+			}
+
+			if tree.Len() != 2 || tree.Get("c") != nil {
+				t.Fatalf("tree holds %d points after a failed %s, want a and b", tree.Len(), op)
+			}
+			a, b := tree.Get("a"), tree.Get("b")
+			if x, y := a.Coordinates(); x != 1 || y != 2 || a.Data() != "one" {
+				t.Fatalf("a left at %v, %v with %v after a failed %s, want 1, 2 with one", x, y, a.Data(), op)
+			}
+			if x, y := b.Coordinates(); x != 3 || y != 4 {
+				t.Fatalf("b left at %v, %v after a failed %s, want 3, 4", x, y, op)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package quadtree
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// warmSink keeps the reads made by Warm from being optimised away.
+var warmSink atomic.Uint64
+
+// Warm touches every node and point within the bounding box so the memory
+// backing them is faulted in and cached before traffic arrives, and brings
+// node aggregates up to date. It returns the number of points touched.
+func (qt *QuadTree) Warm(a *AABB) int {
+	if noBox(a) {
+		return 0
+	}
+
+	if qt.state.aggregates {
+		qt.aggregate()
+	}
+
+	var sum float64
+	n := qt.warm(a, &sum)
+	warmSink.Store(math.Float64bits(sum))
+	return n
+}
+
+func (qt *QuadTree) warm(a *AABB, sum *float64) int {
+	if !qt.boundary.Intersect(a) {
+		return 0
+	}
+
+	points := qt.leaf()
+	for _, p := range points {
+		*sum += p.x + p.y
+	}
+	n := len(points)
+
+	if qt.nodes[0] == nil {
+		return n
+	}
+
+	for _, node := range qt.nodes {
+		n += node.warm(a, sum)
+	}
+
+	return n
+}
@@ -0,0 +1,53 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// Weight returns the weight of the point, 1 unless set by SetWeight.
+func (p *Point) Weight() float64 {
+	if p.meta().weight <= 0 {
+		return 1
+	}
+	return p.meta().weight
+}
+
+// SetWeight sets the positive weight of the point ranked by WithRank,
+// e.g. a driver's rating. It must be set before the point is inserted.
+func (p *Point) SetWeight(w float64) {
+	p.setMeta().weight = w
+}
+
+// WeightedDistance ranks a point by its distance divided by its weight, so
+// a point of weight 2 ranks alongside one of weight 1 half as far away.
+func WeightedDistance(distMeters, weight float64) float64 {
+	return distMeters / weight
+}
+
+// WithRank ranks the results of KNearest by a score of each candidate's
+// distance in metres from the center of the query box and its weight,
+// such as WeightedDistance, rather than by distance alone. Scores must not
+// decrease with distance nor increase with weight. Each node tracks the
+// largest weight beneath it, so heavier points further away are found
+// rather than cut off by the k nearest, and nodes which cannot beat the
+// k'th best score found are pruned.
+func WithRank(rank func(distMeters, weight float64) float64) QueryOption {
+	return func(q *query) {
+		q.rank = rank
+	}
+}
+
+// ranked returns the score and node bound of kbest for a rank.
+func ranked(rank func(distMeters, weight float64) float64) (func(*Point, float64) float64, func(*QuadTree, float64) float64) {
+	score := func(p *Point, d float64) float64 {
+		return rank(d, p.Weight())
+	}
+	bound := func(qt *QuadTree, d float64) float64 {
+		if qt.weight <= 0 {
+			// nothing was ever inserted beneath the node
+			return math.Inf(1)
+		}
+		return rank(d, qt.weight)
+	}
+	return score, bound
+}
@@ -0,0 +1,159 @@
+package quadtree
+
+// RemoveWhere removes the points within the bounding box passing the
+// filter, or every point within it if the filter is nil, in a single
+// traversal of the tree, returning the number removed. Points hidden by
+// SoftRemove are removed too, while points within a locked region are
+// kept. OnRemove hooks are called once every point has been removed, so
+// they may write to the tree.
+func (qt *QuadTree) RemoveWhere(a *AABB, fn filter) int {
+	if noBox(a) || !qt.state.allowWrite() {
+		return 0
+	}
+
+	boxes, ok := qt.state.wrap(a)
+	if !ok {
+		boxes = []*AABB{a}
+	}
+
+	var removed []*Point
+	for _, b := range boxes {
+		qt.removeWhere(b, fn, &removed)
+	}
+
+	for _, p := range removed {
+		qt.dropped(p)
+	}
+	return len(removed)
+}
+
+func (qt *QuadTree) removeWhere(a *AABB, fn filter, removed *[]*Point) {
+	if !qt.boundary.Intersect(a) {
+		return
+	}
+
+	if fn == nil && qt.nodes[0] != nil && a.contains(qt.boundary) && qt.clearable() {
+		qt.empty(removed)
+		return
+	}
+
+	n := len(*removed)
+	qt.loadLeaf()
+	for i := len(qt.resident) - 1; i >= 0; i-- {
+		p := qt.resident[i]
+		if !a.ContainsPoint(p) || fn != nil && !fn(p) || qt.state.locked(p) {
+			continue
+		}
+		qt.removeAt(i)
+		*removed = append(*removed, p)
+	}
+	if len(*removed) > n {
+		qt.touch()
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for i, node := range qt.nodes {
+		if node.boundary.Intersect(a) {
+			qt.own(i).removeWhere(a, fn, removed)
+		}
+	}
+
+	if qt.state.autoCompact && qt.total < qt.state.capacity {
+		qt.collapse()
+	}
+}
+
+// RemoveRange removes every point within the bounding box, returning the
+// number removed, e.g. to clear a decommissioned region. Subtrees wholly
+// within the box are emptied at once rather than point by point, their
+// nodes kept to be reused as the tree divides again. It is RemoveWhere
+// without a filter, so points within a locked region are kept and
+// OnRemove hooks are called once every point has been removed.
+func (qt *QuadTree) RemoveRange(a *AABB) int {
+	return qt.RemoveWhere(a, nil)
+}
+
+// clearable reports whether the subtree may be emptied at once, holding no
+// extents and intersecting no locked region.
+func (qt *QuadTree) clearable() bool {
+	for _, l := range qt.state.locks {
+		if l.Intersect(qt.boundary) {
+			return false
+		}
+	}
+
+	extents := false
+	qt.walkExtents(func(e *Extent) {
+		extents = true
+	})
+	return !extents
+}
+
+// empty removes every point of the subtree, appending them to removed,
+// and collapses it into an empty leaf.
+func (qt *QuadTree) empty(removed *[]*Point) {
+	qt.gather(removed)
+	qt.restock(false)
+
+	n := qt.total
+	for _, node := range qt.nodes {
+		node.recycle()
+	}
+	qt.reset()
+
+	if qt.parent != nil {
+		qt.parent.counted(-n)
+	}
+	qt.touch()
+}
+
+// gather appends every point of the subtree to points.
+func (qt *QuadTree) gather(points *[]*Point) {
+	*points = append(*points, qt.leaf()...)
+	if qt.nodes[0] != nil {
+		for _, node := range qt.nodes {
+			node.gather(points)
+		}
+	}
+}
+
+// Retain keeps the points passing the filter and removes the rest in a
+// single traversal of the tree, returning the number of points kept, e.g.
+// to prune stale entries without rebuilding the tree. Subtrees left
+// without points are collapsed. Points within a locked region are kept,
+// and OnRemove hooks are called as for RemoveWhere.
+func (qt *QuadTree) Retain(fn filter) int {
+	if fn == nil || qt.state.readOnly || !qt.state.allowWrite() {
+		return qt.Len()
+	}
+
+	var removed []*Point
+	qt.removeWhere(qt.boundary, func(p *Point) bool {
+		return !fn(p)
+	}, &removed)
+	qt.collapseEmpty()
+
+	for _, p := range removed {
+		qt.dropped(p)
+	}
+	return qt.Len()
+}
+
+// collapseEmpty collapses every subtree without points.
+func (qt *QuadTree) collapseEmpty() {
+	if qt.nodes[0] == nil {
+		return
+	}
+	if qt.total == 0 {
+		qt.collapse()
+		return
+	}
+	for i, node := range qt.nodes {
+		if node.nodes[0] != nil {
+			qt.own(i).collapseEmpty()
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package quadtree
+
+import (
+	"sort"
+)
+
+// PointDistance is a point and its distance in metres from a location.
+type PointDistance struct {
+	Point    *Point
+	Distance float64
+}
+
+// WithinRadius returns the points within a great-circle radius in metres
+// of the center which pass the filter, along with their distance, ordered
+// by distance. The radius and distances are in the units of the tree's
+// coordinate system if it was created using WithCoordinateSystem.
+func (qt *QuadTree) WithinRadius(center *Point, meters float64, fn filter, opts ...QueryOption) []PointDistance {
+	results := []PointDistance{}
+	if center == nil {
+		return results
+	}
+
+	qt.within(qt.state.coordinates(), center, meters, qt.newQuery(opts).filter(fn), &results)
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Distance != results[j].Distance {
+			return results[i].Distance < results[j].Distance
+		}
+		return results[i].Point.seq < results[j].Point.seq
+	})
+
+	return results
+}
+
+// KNearestWithin returns the k nearest points to the center which are
+// within maxMeters of it and pass the filter, ordered by distance, or
+// fewer if fewer are that near. As for WithinRadius the distance is in
+// the units of the tree's coordinate system if it was created using
+// WithCoordinateSystem, and the search is bounded by the smallest box
+// holding the circle.
+func (qt *QuadTree) KNearestWithin(center *Point, k int, maxMeters float64, fn filter, opts ...QueryOption) []*Point {
+	if center == nil || maxMeters < 0 {
+		return []*Point{}
+	}
+
+	cs := qt.state.coordinates()
+	within := func(p *Point) bool {
+		return cs.Distance(center, p) <= maxMeters && (fn == nil || fn(p))
+	}
+
+	return qt.KNearest(qt.RadiusBox(center, maxMeters), k, within, opts...)
+}
+
+// KNearestDistance is KNearest returning each point along with its
+// distance from the center of the bounding box, in metres or the units of
+// the tree's coordinate system as for WithinRadius, whatever the points
+// were ranked by.
+func (qt *QuadTree) KNearestDistance(a *AABB, i int, fn filter, opts ...QueryOption) []PointDistance {
+	points := qt.KNearest(a, i, fn, opts...)
+	cs := qt.state.coordinates()
+
+	results := make([]PointDistance, len(points))
+	for j, p := range points {
+		results[j] = PointDistance{p, cs.Distance(a.center, p)}
+	}
+	return results
+}
+
+func (qt *QuadTree) within(cs CoordinateSystem, center *Point, meters float64, fn filter, results *[]PointDistance) {
+	if cs.Bound(center, qt.boundary) > meters {
+		return
+	}
+
+	qt.hit()
+
+	for _, p := range qt.leaf() {
+		if d := cs.Distance(center, p); d <= meters && fn(p) {
+			*results = append(*results, PointDistance{p, d})
+		}
+	}
+
+	if qt.nodes[0] == nil {
+		return
+	}
+
+	for _, node := range qt.nodes {
+		node.within(cs, center, meters, fn, results)
+	}
+}
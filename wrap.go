@@ -0,0 +1,91 @@
+package quadtree
+
+import (
+	"math"
+)
+
+// wrapping is implemented by coordinate systems with an axis which wraps
+// around, such as longitude at the antimeridian.
+type wrapping interface {
+	// wrap returns the boxes covering a query box within the coordinate
+	// system, split where it crosses the wrapping edge and clamped to the
+	// limits of the other axis.
+	wrap(a *AABB) []*AABB
+}
+
+// wrap returns the boxes covering a query box if the coordinate system of
+// the tree wraps, or false if the box is used as it is.
+func (s *state) wrap(a *AABB) ([]*AABB, bool) {
+	w, ok := s.coords.(wrapping)
+	if !ok {
+		return nil, false
+	}
+	return w.wrap(a), true
+}
+
+func (wgs84) wrap(a *AABB) []*AABB {
+	return wrapBoxes(a, 90, 180, false)
+}
+
+func (mercator) wrap(a *AABB) []*AABB {
+	extent := math.Pi * mercatorRadius
+	return wrapBoxes(a, extent, extent, true)
+}
+
+// wrapBoxes clamps one axis of the box to [-limit, limit] and wraps the
+// other around [-period, period], latitude and longitude in that order
+// unless swapped.
+func wrapBoxes(a *AABB, limit, period float64, swapped bool) []*AABB {
+	cx, cy, hx, hy := a.center.x, a.center.y, a.half.x, a.half.y
+	if swapped {
+		cx, cy, hx, hy = cy, cx, hy, hx
+	}
+
+	minX, maxX := math.Max(cx-hx, -limit), math.Min(cx+hx, limit)
+	if minX > maxX {
+		return nil
+	}
+
+	var spans [][2]float64
+	if hy >= period {
+		spans = [][2]float64{{-period, period}}
+	} else {
+		// shift the western edge into [-period, period)
+		shift := 2 * period * math.Floor((cy-hy+period)/(2*period))
+		minY, maxY := cy-hy-shift, cy+hy-shift
+		if maxY <= period {
+			spans = [][2]float64{{minY, maxY}}
+		} else {
+			spans = [][2]float64{{minY, period}, {-period, maxY - 2*period}}
+		}
+	}
+
+	boxes := make([]*AABB, len(spans))
+	for i, s := range spans {
+		c := &Point{x: (minX + maxX) / 2, y: (s[0] + s[1]) / 2}
+		h := &Point{x: (maxX - minX) / 2, y: (s[1] - s[0]) / 2}
+		if swapped {
+			c.x, c.y, h.x, h.y = c.y, c.x, h.y, h.x
+		}
+		boxes[i] = &AABB{c, h}
+	}
+	return boxes
+}
+
+func intersectsAny(boxes []*AABB, b *AABB) bool {
+	for _, a := range boxes {
+		if a.Intersect(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(boxes []*AABB, p *Point) bool {
+	for _, a := range boxes {
+		if a.ContainsPoint(p) {
+			return true
+		}
+	}
+	return false
+}